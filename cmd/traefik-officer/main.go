@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	logger "github.com/sirupsen/logrus"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	logprocessing "github.com/mithucste30/traefik-officer-operator/pkg"
@@ -13,19 +16,93 @@ import (
 var EstBytesPerLine = 150
 
 func main() {
-	debugLog := flag.Bool("debug", false, "Enable debug logging. False by default.")
-	configLocation := flag.String("config-file", "", "Path to the config file.")
+	debugLog := flag.Bool("debug", false, "Enable debug logging. False by default. Shorthand for -log-level=debug")
+	logLevel := flag.String("log-level", "", "Level of the process logs: debug, info, warn or error. Default: info, or debug with -debug")
+	logOutputFormat := flag.String("log-format-output", logprocessing.LogOutputText,
+		"Format of the process logs, not the access logs: text or json")
+	configLocation := flag.String("config-file", "", "Path to the config file, JSON or YAML with a .yaml/.yml extension.")
+	watchConfig := flag.Bool("watch-config", true,
+		"Reload the config file when it changes. An invalid file is logged and the previous config kept")
 	servePort := flag.String("listen-port", "8080", "Which port to expose metrics on")
-	jsonLogs := flag.Bool("json-logs", false, "If true, parse JSON logs instead of accessLog format")
+	logFormat := flag.String("log-format", logprocessing.LogFormatCLF,
+		"Access log format: clf, json or logfmt. The LogFormat of the config applies while this is clf")
+	jsonLogs := flag.Bool("json-logs", false, "Deprecated: use -log-format=json")
 	useK8s := flag.Bool("use-k8s", false, "Read logs from Kubernetes pods instead of file")
+	podLabel := flag.Bool("pod-label", false,
+		"Kubernetes mode: count the requests per Traefik pod that logged them on traefik_officer_pod_requests_total. Multiplies the series by the Traefik replicas")
+	exposeSourceMode := flag.Bool("expose-source-mode", false,
+		"Expose the log source mode (file, k8s or ssh) on the traefik_officer_source_info metric")
+	noScrapeReset := flag.Bool("no-scrape-reset", false,
+		"Serve /metrics without resetting the error rate gauges after each scrape")
+	scrapeTimeout := flag.Duration("scrape-timeout", 0,
+		"Maximum time a /metrics scrape may wait for a concurrent scrape and gather metrics. 0 disables the limit")
+	openMetrics := flag.Bool("openmetrics", false, "Serve the OpenMetrics format to scrapers that request it")
+	stateFile := flag.String("state-file", "",
+		"Path to a file persisting top paths and endpoint stats across restarts. Disabled if empty")
+	stateSaveInterval := flag.Duration("state-save-interval", time.Minute, "How often the state file is written")
+	endpointStatsTTL := flag.Duration("endpoint-stats-ttl", 0,
+		"Evict endpoints and router parse stats not seen for this long with their metrics. Overrides EndpointStatsTTLMinutes; 0 uses the config")
+	gaugeStaleness := flag.Duration("gauge-staleness", 0,
+		"Mark the latency and error rate gauges of endpoints idle for this long as stale. Overrides GaugeStalenessMinutes; 0 uses the config")
+	healthStaleness := flag.Duration("health-staleness", 0,
+		"Report log processing as stale on /health when no logs were processed for this long. Overrides HealthStalenessMinutes; 0 uses the config")
+	healthStalenessMode := flag.String("health-staleness-mode", "",
+		"When stale log processing degrades /health: always, source-error (only if the log source also failed) or off. Overrides HealthStalenessMode; empty uses the config")
+	serviceRPSWindow := flag.Duration("service-rps-window", 0,
+		"Sliding window the per-service RPS gauge is averaged over. Overrides ServiceRPSWindowSeconds; 0 uses the config")
+	dedupWindow := flag.Duration("dedup-window", 0,
+		"Drop access log entries identical to one seen within this window, e.g. logged by several replicas. Overrides DedupWindowMs; 0 uses the config")
+	latencyBuckets := flag.String("latency-buckets", "",
+		"Comma-separated bucket upper bounds in seconds of the request duration histograms. Overrides LatencyBuckets of the config")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file. Serves metrics over HTTPS together with -tls-key")
+	tlsKey := flag.String("tls-key", "", "TLS private key file. Serves metrics over HTTPS together with -tls-cert")
+	metricsAuthUser := flag.String("metrics-auth-user", "",
+		"Require HTTP basic auth with this user on the metrics server, together with -metrics-auth-pass")
+	metricsAuthPass := flag.String("metrics-auth-pass", os.Getenv("TRAEFIK_OFFICER_METRICS_AUTH_PASS"),
+		"Basic auth password for the metrics server (env TRAEFIK_OFFICER_METRICS_AUTH_PASS)")
+	metricsAuthExemptHealth := flag.Bool("metrics-auth-exempt-health", true,
+		"Serve /health, /livez and /readyz without basic auth so probes keep working")
+	jsonDurationUnit := flag.String("json-duration-unit", logprocessing.JSONDurationNanoseconds,
+		"Unit of the Duration and Overhead fields of JSON access logs: ns (as logged by Traefik), us or ms")
+	metricsPrefix := flag.String("metrics-prefix", logprocessing.DefaultMetricsPrefix,
+		"Prefix of every metric name, e.g. acme_edge for acme_edge_requests_total")
+	pushGatewayURL := flag.String("push-gateway-url", "",
+		"Push the metrics to this Prometheus Pushgateway, e.g. http://pushgateway:9091, next to serving them. Disabled if empty")
+	pushInterval := flag.Duration("push-interval", logprocessing.DefaultPushInterval, "How often metrics are pushed to the Pushgateway")
+	pushJob := flag.String("push-job", logprocessing.DefaultPushJob, "Job label of the metrics pushed to the Pushgateway")
+	pushInstance := flag.String("push-instance", "",
+		"Instance label of the metrics pushed to the Pushgateway (default is the hostname)")
+	otlpEndpoint := flag.String("otlp-endpoint", "",
+		"Export the request duration histogram to this OTLP collector endpoint, e.g. http://otel-collector:4318. Disabled if empty")
+	otlpProtocol := flag.String("otlp-protocol", logprocessing.OTLPProtocolHTTP,
+		"OTLP protocol of -otlp-endpoint: http/protobuf or grpc, e.g. with http://otel-collector:4317")
+	otlpInterval := flag.Duration("otlp-interval", logprocessing.DefaultOTLPInterval, "How often metrics are exported over OTLP")
+	adminToken := flag.String("admin-token", os.Getenv(logprocessing.AdminTokenEnv),
+		"Bearer token for admin and debug endpoints. If empty, they only accept loopback requests")
+	enablePprof := flag.Bool("enable-pprof", false,
+		"Serve the runtime profiles under /debug/pprof/, restricted like the admin endpoints")
+	pprofAddr := flag.String("pprof-addr", "",
+		"Serve the runtime profiles on this address, e.g. 127.0.0.1:6060, instead of the metrics port. Requires -enable-pprof")
 	logFileConfig := logprocessing.AddFileFlags(flag.CommandLine)
 	k8sConfig := logprocessing.AddKubernetesFlags(flag.CommandLine)
 
 	flag.Parse()
 
-	if *debugLog {
-		logger.SetLevel(logger.DebugLevel)
+	level := *logLevel
+	if level == "" && *debugLog {
+		level = "debug"
 	}
+	if err := logprocessing.ConfigureLogging(*logOutputFormat, level); err != nil {
+		logger.Errorf("Invalid logging flags: %v", err)
+		os.Exit(1)
+	}
+	if err := logprocessing.InitMetrics(*metricsPrefix); err != nil {
+		logger.Errorf("Invalid -metrics-prefix: %v", err)
+		os.Exit(1)
+	}
+	logprocessing.SetAdminToken(*adminToken)
+	logprocessing.SetNoScrapeReset(*noScrapeReset)
+	logprocessing.SetScrapeOptions(*scrapeTimeout, *openMetrics)
 
 	// Load configuration
 	config, err := logprocessing.LoadConfig(*configLocation)
@@ -33,6 +110,36 @@ func main() {
 		logger.Warnf("Failed to load configuration: %v. Using default configuration.", err)
 	}
 
+	format, err := logprocessing.ResolveLogFormat(*logFormat, *jsonLogs, config.LogFormat)
+	if err != nil {
+		logger.Errorf("Invalid -log-format: %v", err)
+		os.Exit(1)
+	}
+	if *jsonLogs {
+		logger.Warn("-json-logs is deprecated, use -log-format=json")
+	}
+	if err := logprocessing.SetJSONDurationUnit(*jsonDurationUnit); err != nil {
+		logger.Errorf("Invalid -json-duration-unit: %v", err)
+		os.Exit(1)
+	}
+
+	if *dedupWindow > 0 {
+		config.DedupWindowMs = int(*dedupWindow / time.Millisecond)
+	}
+
+	buckets := config.LatencyBuckets
+	if *latencyBuckets != "" {
+		if buckets, err = logprocessing.ParseLatencyBuckets(*latencyBuckets); err != nil {
+			logger.Errorf("Invalid -latency-buckets: %v", err)
+			os.Exit(1)
+		}
+	}
+	if err := logprocessing.InitLatencyHistograms(buckets); err != nil {
+		logger.Errorf("Failed to initialize latency histograms: %v", err)
+		os.Exit(1)
+	}
+	logprocessing.SetPodLabel(*podLabel)
+
 	// Log configuration
 	if *useK8s {
 		logger.Infof("Kubernetes Mode - "+
@@ -40,23 +147,155 @@ func main() {
 			"Container: %s, "+
 			"Label Selector: %s",
 			k8sConfig.Namespace, k8sConfig.ContainerName, k8sConfig.LabelSelector)
+	} else if logFileConfig.Stdin {
+		logger.Info("Stdin Mode - Access Logs From Standard Input")
+	} else if logFileConfig.Remote.Host != "" {
+		logger.Infof("Remote Mode - Access Logs At: %s:%s", logFileConfig.Remote.Host, logFileConfig.Remote.LogFile)
 	} else {
-		logger.Info("File Mode - Access Logs At:", logFileConfig.FileLocation)
+		if logFileConfig.LogFiles != "" {
+			logger.Info("File Mode - Access Logs At:", logFileConfig.LogFiles)
+		} else {
+			logger.Info("File Mode - Access Logs At:", logFileConfig.FileLocation)
+		}
+	}
+
+	if *exposeSourceMode {
+		mode := logprocessing.SourceModeFor(*useK8s)
+		if !*useK8s && logFileConfig.Stdin {
+			mode = logprocessing.SourceModeStdin
+		} else if !*useK8s && logFileConfig.Remote.Host != "" {
+			mode = logprocessing.SourceModeSSH
+		}
+		logprocessing.SetSourceMode(mode)
 	}
 
 	logger.Info("Config File At:", *configLocation)
-	logger.Info("JSON Logs:", *jsonLogs)
+	logger.Info("Log Format:", format)
+
+	// Restore top paths before the updater runs so detailed metrics resume immediately
+	if *stateFile != "" {
+		if err := logprocessing.LoadState(*stateFile); err != nil {
+			logger.Warnf("Failed to restore state from %s: %v", *stateFile, err)
+		}
+		stopPersister := make(chan struct{})
+		defer close(stopPersister)
+		logprocessing.StartStatePersister(*stateFile, *stateSaveInterval, stopPersister)
+	}
 
 	// Start background task to update top paths
 	logprocessing.StartTopPathsUpdater(30 * time.Second)
-	//startMetricsCleaner(60 * time.Minute)
+	//startMetricsCleaner(60 * time.Minute, nil)
+
+	// Evict endpoints that stopped receiving traffic
+	ttl := time.Duration(config.EndpointStatsTTLMinutes) * time.Minute
+	if *endpointStatsTTL > 0 {
+		ttl = *endpointStatsTTL
+	}
+	if ttl > 0 {
+		stopSweeper := make(chan struct{})
+		defer close(stopSweeper)
+		logprocessing.StartEndpointStatsSweeper(ttl, stopSweeper)
+	}
+
+	// Stop reporting frozen gauges for endpoints that went idle
+	staleness := time.Duration(config.GaugeStalenessMinutes) * time.Minute
+	if *gaugeStaleness > 0 {
+		staleness = *gaugeStaleness
+	}
+	if staleness > 0 {
+		stopStalenessSweeper := make(chan struct{})
+		defer close(stopStalenessSweeper)
+		logprocessing.StartGaugeStalenessSweeper(staleness, config.GaugeStalenessMode, stopStalenessSweeper)
+	}
+
+	// Degrade health when logs stop being processed
+	healthThreshold := time.Duration(config.HealthStalenessMinutes) * time.Minute
+	if *healthStaleness > 0 {
+		healthThreshold = *healthStaleness
+	}
+	stalenessMode := config.HealthStalenessMode
+	if *healthStalenessMode != "" {
+		stalenessMode = *healthStalenessMode
+	}
+	if err := logprocessing.SetHealthStaleness(healthThreshold, stalenessMode); err != nil {
+		logger.Errorf("Invalid health staleness settings: %v", err)
+		os.Exit(1)
+	}
+
+	// Average the request rate of each service over a sliding window
+	rpsWindow := time.Duration(config.ServiceRPSWindowSeconds) * time.Second
+	if *serviceRPSWindow > 0 {
+		rpsWindow = *serviceRPSWindow
+	}
+	stopRPSUpdater := make(chan struct{})
+	defer close(stopRPSUpdater)
+	logprocessing.StartServiceRPSUpdater(rpsWindow, stopRPSUpdater)
+
+	// Stop processing and shut the metrics server down on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *configLocation != "" && *watchConfig {
+		if err := logprocessing.WatchConfig(ctx, *configLocation); err != nil {
+			logger.Warnf("Config file changes won't be reloaded: %v", err)
+		}
+	}
 
 	// Start metrics server
-	go func() {
-		if err := logprocessing.ServeProm(*servePort); err != nil {
-			logger.Errorf("Metrics server error: %v", err)
+	serverConfig := logprocessing.MetricsServerConfig{
+		TLSCertFile:      *tlsCert,
+		TLSKeyFile:       *tlsKey,
+		AuthUser:         *metricsAuthUser,
+		AuthPass:         *metricsAuthPass,
+		AuthExemptHealth: *metricsAuthExemptHealth,
+		EnablePprof:      *enablePprof && *pprofAddr == "",
+	}
+	if _, err := logprocessing.ServeProm(ctx, *servePort, serverConfig); err != nil {
+		logger.Errorf("Metrics server error: %v", err)
+		os.Exit(1)
+	}
+
+	if *enablePprof && *pprofAddr != "" {
+		if _, err := logprocessing.ServePprof(ctx, *pprofAddr); err != nil {
+			logger.Errorf("pprof server error: %v", err)
+			os.Exit(1)
 		}
-	}()
+	}
+
+	// Push metrics for environments that can't scrape the processor
+	if *pushGatewayURL != "" {
+		instance := *pushInstance
+		if instance == "" {
+			instance, _ = os.Hostname()
+		}
+		pushDone := logprocessing.StartPushgateway(ctx, logprocessing.PushConfig{
+			URL:      *pushGatewayURL,
+			Interval: *pushInterval,
+			Job:      *pushJob,
+			Instance: instance,
+		})
+		defer func() {
+			stop()
+			<-pushDone
+		}()
+	}
+
+	// Export request durations to an OpenTelemetry collector alongside the Prometheus metrics
+	if *otlpEndpoint != "" {
+		otlpDone, err := logprocessing.StartOTLPExporter(ctx, logprocessing.OTLPConfig{
+			Endpoint: *otlpEndpoint,
+			Protocol: *otlpProtocol,
+			Interval: *otlpInterval,
+		})
+		if err != nil {
+			logger.Errorf("Invalid OTLP flags: %v", err)
+			os.Exit(1)
+		}
+		defer func() {
+			stop()
+			<-otlpDone
+		}()
+	}
 
 	// Create log source
 	logSource, err := logprocessing.CreateLogSource(*useK8s, logFileConfig, k8sConfig)
@@ -78,5 +317,11 @@ func main() {
 
 	// Start log processing
 	logger.Info("Starting log processing")
-	logprocessing.ProcessLogs(logSource, config, useK8s, logFileConfig, jsonLogs)
+	logprocessing.ProcessLogs(ctx, logSource, config, useK8s, logFileConfig, format)
+
+	if *stateFile != "" {
+		if err := logprocessing.SaveState(*stateFile); err != nil {
+			logger.Errorf("Failed to save state: %v", err)
+		}
+	}
 }
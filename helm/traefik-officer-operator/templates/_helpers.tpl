@@ -58,3 +58,21 @@ Create the name of the service account to use
 {{- default "default" .Values.rbac.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Name of the admission webhook Service and Certificate
+*/}}
+{{- define "traefik-officer-operator.webhookName" -}}
+{{- printf "%s-webhook" (include "traefik-officer-operator.fullname" . | trunc 55 | trimSuffix "-") }}
+{{- end }}
+
+{{/*
+Name of the TLS secret mounted by the admission webhook server
+*/}}
+{{- define "traefik-officer-operator.webhookSecretName" -}}
+{{- if .Values.operator.admissionWebhook.certManager.enabled }}
+{{- printf "%s-tls" (include "traefik-officer-operator.webhookName" .) }}
+{{- else }}
+{{- required "operator.admissionWebhook.existingSecret is required when certManager is disabled" .Values.operator.admissionWebhook.existingSecret }}
+{{- end }}
+{{- end }}
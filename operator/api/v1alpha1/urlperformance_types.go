@@ -2,14 +2,15 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 )
 
-// TargetReference references a target resource (Ingress or IngressRoute)
+// TargetReference references a target resource (Ingress, IngressRoute, IngressRouteTCP or IngressRouteUDP)
 type TargetReference struct {
-	// Kind of the target resource (Ingress or IngressRoute)
-	// +kubebuilder:validation:Enum=Ingress;IngressRoute
-	// +kubebuilder:default=Ingress
+	// Kind of the target resource (Ingress, IngressRoute, IngressRouteTCP, IngressRouteUDP or Auto).
+	// Auto, the default, detects the kind by looking up both an Ingress and an IngressRoute with the
+	// given name.
+	// +kubebuilder:validation:Enum=Ingress;IngressRoute;IngressRouteTCP;IngressRouteUDP;Auto
+	// +kubebuilder:default=Auto
 	Kind string `json:"kind"`
 
 	// Name of the target resource
@@ -20,6 +21,20 @@ type TargetReference struct {
 	Namespace string `json:"namespace,omitempty"`
 }
 
+// Supported values of TargetReference.Kind
+const (
+	// TargetKindIngress targets a networking.k8s.io Ingress
+	TargetKindIngress = "Ingress"
+	// TargetKindIngressRoute targets a traefik.io IngressRoute
+	TargetKindIngressRoute = "IngressRoute"
+	// TargetKindIngressRouteTCP targets a traefik.io IngressRouteTCP
+	TargetKindIngressRouteTCP = "IngressRouteTCP"
+	// TargetKindIngressRouteUDP targets a traefik.io IngressRouteUDP
+	TargetKindIngressRouteUDP = "IngressRouteUDP"
+	// TargetKindAuto detects whether the target is an Ingress or an IngressRoute
+	TargetKindAuto = "Auto"
+)
+
 // URLPattern defines a custom regex pattern for URL normalization
 type URLPattern struct {
 	// Regex pattern to match URLs
@@ -29,10 +44,29 @@ type URLPattern struct {
 	Replacement string `json:"replacement"`
 }
 
+// NormalizationRuleName names a default URL normalization substitution of the log processor
+// +kubebuilder:validation:Enum=id;uuid;token;query_params
+type NormalizationRuleName string
+
 // UrlPerformanceSpec defines the desired state of UrlPerformance
+// +kubebuilder:validation:XValidation:rule="[has(self.targetRef), has(self.targetRefs), has(self.targetSelector)].filter(x, x).size() == 1",message="exactly one of targetRef, targetRefs and targetSelector must be set"
 type UrlPerformanceSpec struct {
-	// TargetRef references the Ingress or IngressRoute to monitor
-	TargetRef TargetReference `json:"targetRef"`
+	// TargetRef references the Ingress or Traefik IngressRoute to monitor.
+	// Exactly one of TargetRef, TargetRefs and TargetSelector must be set.
+	// +optional
+	TargetRef *TargetReference `json:"targetRef,omitempty"`
+
+	// TargetRefs references several Ingresses or Traefik IngressRoutes monitored with the same rules.
+	// Each target gets its own runtime configuration.
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	TargetRefs []TargetReference `json:"targetRefs,omitempty"`
+
+	// TargetSelector selects the Ingresses and IngressRoutes to monitor by their labels, in the
+	// namespace of the UrlPerformance resource. Targets created or labelled later are picked up
+	// automatically; each target gets its own runtime configuration.
+	// +optional
+	TargetSelector *metav1.LabelSelector `json:"targetSelector,omitempty"`
 
 	// WhitelistPathsRegex is a list of regex patterns.
 	// Only paths matching these patterns will be monitored for the target ingress.
@@ -62,6 +96,40 @@ type UrlPerformanceSpec struct {
 	// +default=20
 	CollectNTop int `json:"collectNTop,omitempty"`
 
+	// DetailedHistogramPaths is a list of regex patterns.
+	// Requests to matching paths are also observed into a fine-grained latency histogram for this target.
+	// +optional
+	DetailedHistogramPaths []string `json:"detailedHistogramPaths,omitempty"`
+
+	// DetailedHistogramBuckets are the increasing upper bounds, in seconds (e.g. "0.005"), of the
+	// detailed latency histogram buckets. Defaults to 30 exponential buckets from 1ms to 10s.
+	// +optional
+	DetailedHistogramBuckets []string `json:"detailedHistogramBuckets,omitempty"`
+
+	// StripTrailingSlash removes trailing slashes from request paths before URL normalization, so
+	// "/users/" and "/users" are one endpoint. Defaults to the log processor's configuration.
+	// +optional
+	StripTrailingSlash *bool `json:"stripTrailingSlash,omitempty"`
+
+	// LowercasePaths lowercases request paths before URL normalization, so "/Users" and "/users" are
+	// one endpoint. Leave it off when path segments are case-sensitive; URL patterns then have to
+	// match lowercase paths. Defaults to the log processor's configuration.
+	// +optional
+	LowercasePaths *bool `json:"lowercasePaths,omitempty"`
+
+	// DisabledNormalizationRules turns off default URL normalization substitutions for the targets:
+	// "id" (numeric segments), "uuid", "token" (alphanumeric segments of 20+ characters) and
+	// "query_params". URLPatterns can then normalize the affected paths instead.
+	// +optional
+	DisabledNormalizationRules []NormalizationRuleName `json:"disabledNormalizationRules,omitempty"`
+
+	// KeepQueryParams lists query parameters kept with their values in normalized paths, e.g. "type"
+	// reports "/export?type=pdf&token=abc" as "/export?type=pdf&{other_params}". Other query strings
+	// still collapse to "?{query_params}". Only list parameters with few distinct values.
+	// Defaults to the log processor's configuration.
+	// +optional
+	KeepQueryParams []string `json:"keepQueryParams,omitempty"`
+
 	// Enabled controls whether monitoring is active for this resource.
 	// +optional
 	Enabled bool `json:"enabled,omitempty"`
@@ -77,6 +145,8 @@ const (
 	ConditionTargetExists ConditionType = "TargetExists"
 	// ConditionConfigGenerated indicates configuration has been generated
 	ConditionConfigGenerated ConditionType = "ConfigGenerated"
+	// ConditionPathRewritten indicates the target's middlewares rewrite paths before they are logged
+	ConditionPathRewritten ConditionType = "PathRewritten"
 )
 
 // Condition defines an observation of a UrlPerformance's state
@@ -137,6 +207,11 @@ type UrlPerformanceStatus struct {
 	// ObservedGeneration is the most recent generation observed by the controller
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ConfigKeys lists the namespace/name keys of the targets whose runtime configuration was
+	// published by the last reconcile
+	// +optional
+	ConfigKeys []string `json:"configKeys,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -166,54 +241,3 @@ type UrlPerformanceList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []UrlPerformance `json:"items"`
 }
-
-// +kubebuilder:object:root=true
-
-// DeepCopy is an autogenerated deepcopy function, creating a deep copy
-func (in *UrlPerformance) DeepCopy() *UrlPerformance {
-	if in == nil {
-		return nil
-	}
-	out := new(UrlPerformance)
-	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
-	return out
-}
-
-// DeepCopyObject is an autogenerated deepcopy function, creating a deep copy as runtime.Object
-func (in *UrlPerformance) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
-// DeepCopy is an autogenerated deepcopy function, creating a deep copy
-func (in *UrlPerformanceList) DeepCopy() *UrlPerformanceList {
-	if in == nil {
-		return nil
-	}
-	out := new(UrlPerformanceList)
-	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]UrlPerformance, len(*in))
-		for i := range *in {
-			(*out)[i] = (*in)[i]
-		}
-	}
-	return out
-}
-
-// DeepCopyObject is an autogenerated deepcopy function, creating a deep copy as runtime.Object
-func (in *UrlPerformanceList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
@@ -0,0 +1,68 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestUrlPerformanceDeepCopy tests that mutating a deep copy, as the reconciler does with objects from
+// the informer cache, leaves the original unchanged
+func TestUrlPerformanceDeepCopy(t *testing.T) {
+	enabled := true
+	now := metav1.Now()
+	original := &UrlPerformance{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop", Labels: map[string]string{"team": "shop"}},
+		Spec: UrlPerformanceSpec{
+			TargetRef:  &TargetReference{Kind: TargetKindIngress, Name: "web"},
+			TargetRefs: []TargetReference{{Kind: TargetKindIngressRoute, Name: "api"}},
+			TargetSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "shop"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"web"}},
+				},
+			},
+			WhitelistPathsRegex:        []string{"^/api/"},
+			URLPatterns:                []URLPattern{{Pattern: `/users/\d+`, Replacement: "/users/{id}"}},
+			StripTrailingSlash:         &enabled,
+			LowercasePaths:             &enabled,
+			DisabledNormalizationRules: []NormalizationRuleName{"token"},
+			KeepQueryParams:            []string{"type"},
+		},
+		Status: UrlPerformanceStatus{
+			Conditions:     []Condition{{Type: ConditionReady, Status: "True", LastTransitionTime: &now}},
+			LastScrapeTime: &now,
+			ConfigKeys:     []string{"shop/web"},
+		},
+	}
+	snapshot := original.DeepCopy()
+
+	copied := original.DeepCopyObject().(*UrlPerformance)
+	copied.Labels["team"] = "other"
+	copied.Spec.TargetRef.Name = "other"
+	copied.Spec.TargetRefs[0].Name = "other"
+	copied.Spec.TargetSelector.MatchLabels["app"] = "other"
+	copied.Spec.TargetSelector.MatchExpressions[0].Values[0] = "other"
+	copied.Spec.WhitelistPathsRegex[0] = "other"
+	copied.Spec.URLPatterns[0].Replacement = "other"
+	*copied.Spec.StripTrailingSlash = false
+	*copied.Spec.LowercasePaths = false
+	copied.Spec.DisabledNormalizationRules[0] = "id"
+	copied.Spec.KeepQueryParams[0] = "other"
+	copied.Status.Conditions[0].Status = "False"
+	copied.Status.Conditions[0].LastTransitionTime.Time = now.Add(1)
+	copied.Status.LastScrapeTime.Time = now.Add(1)
+	copied.Status.ConfigKeys[0] = "other"
+
+	if !reflect.DeepEqual(original, snapshot) {
+		t.Errorf("Expected the original to be unchanged, got\n%+v\nwant\n%+v", original, snapshot)
+	}
+
+	list := &UrlPerformanceList{Items: []UrlPerformance{*snapshot}}
+	copiedList := list.DeepCopyObject().(*UrlPerformanceList)
+	copiedList.Items[0].Spec.TargetRefs[0].Name = "other"
+	if list.Items[0].Spec.TargetRefs[0].Name != "api" {
+		t.Error("Expected the list items to be deep copied")
+	}
+}
@@ -1,8 +1,229 @@
 //go:build !ignore_autogenerated
-// +build !ignore_autogenerated
+
+// Code generated by controller-gen. DO NOT EDIT.
 
 package v1alpha1
 
-// Code generated by controller-gen. DO NOT EDIT.
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetReference) DeepCopyInto(out *TargetReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetReference.
+func (in *TargetReference) DeepCopy() *TargetReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *URLPattern) DeepCopyInto(out *URLPattern) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new URLPattern.
+func (in *URLPattern) DeepCopy() *URLPattern {
+	if in == nil {
+		return nil
+	}
+	out := new(URLPattern)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UrlPerformance) DeepCopyInto(out *UrlPerformance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UrlPerformance.
+func (in *UrlPerformance) DeepCopy() *UrlPerformance {
+	if in == nil {
+		return nil
+	}
+	out := new(UrlPerformance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UrlPerformance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UrlPerformanceList) DeepCopyInto(out *UrlPerformanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]UrlPerformance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UrlPerformanceList.
+func (in *UrlPerformanceList) DeepCopy() *UrlPerformanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(UrlPerformanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UrlPerformanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UrlPerformanceSpec) DeepCopyInto(out *UrlPerformanceSpec) {
+	*out = *in
+	if in.TargetRef != nil {
+		in, out := &in.TargetRef, &out.TargetRef
+		*out = new(TargetReference)
+		**out = **in
+	}
+	if in.TargetRefs != nil {
+		in, out := &in.TargetRefs, &out.TargetRefs
+		*out = make([]TargetReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetSelector != nil {
+		in, out := &in.TargetSelector, &out.TargetSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WhitelistPathsRegex != nil {
+		in, out := &in.WhitelistPathsRegex, &out.WhitelistPathsRegex
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IgnoredPathsRegex != nil {
+		in, out := &in.IgnoredPathsRegex, &out.IgnoredPathsRegex
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MergePathsWithExtensions != nil {
+		in, out := &in.MergePathsWithExtensions, &out.MergePathsWithExtensions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.URLPatterns != nil {
+		in, out := &in.URLPatterns, &out.URLPatterns
+		*out = make([]URLPattern, len(*in))
+		copy(*out, *in)
+	}
+	if in.DetailedHistogramPaths != nil {
+		in, out := &in.DetailedHistogramPaths, &out.DetailedHistogramPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DetailedHistogramBuckets != nil {
+		in, out := &in.DetailedHistogramBuckets, &out.DetailedHistogramBuckets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StripTrailingSlash != nil {
+		in, out := &in.StripTrailingSlash, &out.StripTrailingSlash
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LowercasePaths != nil {
+		in, out := &in.LowercasePaths, &out.LowercasePaths
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DisabledNormalizationRules != nil {
+		in, out := &in.DisabledNormalizationRules, &out.DisabledNormalizationRules
+		*out = make([]NormalizationRuleName, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeepQueryParams != nil {
+		in, out := &in.KeepQueryParams, &out.KeepQueryParams
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UrlPerformanceSpec.
+func (in *UrlPerformanceSpec) DeepCopy() *UrlPerformanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UrlPerformanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UrlPerformanceStatus) DeepCopyInto(out *UrlPerformanceStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastScrapeTime != nil {
+		in, out := &in.LastScrapeTime, &out.LastScrapeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ConfigKeys != nil {
+		in, out := &in.ConfigKeys, &out.ConfigKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
 
-// This file was autogenerated by the controller tool, do not edit it manually to ensure your code compiles.
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UrlPerformanceStatus.
+func (in *UrlPerformanceStatus) DeepCopy() *UrlPerformanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UrlPerformanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Reconcile results counted by reconcileTotal
+const (
+	reconcileResultSuccess = "success"
+	reconcileResultError   = "error"
+)
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "traefik_officer_operator_reconcile_total",
+			Help: "Number of UrlPerformance reconciliations by result (success or error)",
+		},
+		[]string{"result"},
+	)
+
+	activeConfigs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "traefik_officer_operator_active_configs",
+			Help: "Number of runtime configurations currently held by the config manager",
+		},
+	)
+
+	invalidRegexTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "traefik_officer_operator_invalid_regex_total",
+			Help: "Number of invalid regexes found while reconciling UrlPerformance resources",
+		},
+	)
+)
+
+// The controller-runtime registry is served on the manager's metrics endpoint next to its own
+// controller metrics
+func init() {
+	metrics.Registry.MustRegister(reconcileTotal, activeConfigs, invalidRegexTotal)
+}
+
+// recordReconcile counts a finished reconciliation by its result
+func recordReconcile(err error) {
+	result := reconcileResultSuccess
+	if err != nil {
+		result = reconcileResultError
+	}
+	reconcileTotal.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	traefikofficerv1alpha1 "github.com/mithucste30/traefik-officer-operator/operator/api/v1alpha1"
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// newMetricsTestReconciler returns a reconciler backed by a fake client holding objs
+func newMetricsTestReconciler(t *testing.T, funcs interceptor.Funcs, objs ...client.Object) *UrlPerformanceReconciler {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add client-go types to the scheme: %v", err)
+	}
+	if err := traefikofficerv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add UrlPerformance types to the scheme: %v", err)
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&traefikofficerv1alpha1.UrlPerformance{}).
+		WithInterceptorFuncs(funcs).
+		Build()
+	return &UrlPerformanceReconciler{
+		Client:        fakeClient,
+		Scheme:        scheme,
+		ConfigManager: NewConfigManager(),
+	}
+}
+
+// TestReconcileCountsResults tests that reconciliations are counted by result
+func TestReconcileCountsResults(t *testing.T) {
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "shop"}}
+	successes := testutil.ToFloat64(reconcileTotal.WithLabelValues(reconcileResultSuccess))
+	failures := testutil.ToFloat64(reconcileTotal.WithLabelValues(reconcileResultError))
+
+	// A deleted resource is reconciled successfully
+	reconciler := newMetricsTestReconciler(t, interceptor.Funcs{})
+	if _, err := reconciler.Reconcile(context.Background(), request); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := testutil.ToFloat64(reconcileTotal.WithLabelValues(reconcileResultSuccess)); got != successes+1 {
+		t.Errorf("Expected %v successful reconciliations, got %v", successes+1, got)
+	}
+
+	reconciler = newMetricsTestReconciler(t, interceptor.Funcs{
+		Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+			return stderrors.New("API server unavailable")
+		},
+	})
+	if _, err := reconciler.Reconcile(context.Background(), request); err == nil {
+		t.Fatal("Expected Reconcile() to fail when the API server is unavailable")
+	}
+	if got := testutil.ToFloat64(reconcileTotal.WithLabelValues(reconcileResultError)); got != failures+1 {
+		t.Errorf("Expected %v failed reconciliations, got %v", failures+1, got)
+	}
+}
+
+// TestReconcileCountsInvalidRegexes tests that an invalid whitelist regex is counted
+func TestReconcileCountsInvalidRegexes(t *testing.T) {
+	ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shop"}}
+	instance := &traefikofficerv1alpha1.UrlPerformance{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shop"},
+		Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+			TargetRef:           &traefikofficerv1alpha1.TargetReference{Kind: "Ingress", Name: "shop"},
+			WhitelistPathsRegex: []string{"^/api/("},
+			Enabled:             true,
+		},
+	}
+	reconciler := newMetricsTestReconciler(t, interceptor.Funcs{}, ingress, instance)
+	before := testutil.ToFloat64(invalidRegexTotal)
+
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "shop"}}
+	if _, err := reconciler.Reconcile(context.Background(), request); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := testutil.ToFloat64(invalidRegexTotal); got != before+1 {
+		t.Errorf("Expected %v invalid regexes, got %v", before+1, got)
+	}
+	if _, ok := reconciler.ConfigManager.GetConfig(shared.ConfigKey("default", "shop")); ok {
+		t.Error("Expected no runtime configuration for a resource with an invalid regex")
+	}
+}
+
+// TestConfigManagerActiveConfigsGauge tests that the active configs gauge follows the config manager
+func TestConfigManagerActiveConfigsGauge(t *testing.T) {
+	cm := NewConfigManager()
+
+	cm.UpdateConfig(&shared.RuntimeConfig{Key: "shop-cart", Enabled: true})
+	cm.UpdateConfig(&shared.RuntimeConfig{Key: "shop-search", Enabled: true})
+	if got := testutil.ToFloat64(activeConfigs); got != 2 {
+		t.Errorf("Expected 2 active configs, got %v", got)
+	}
+
+	cm.UpdateConfig(&shared.RuntimeConfig{Key: "shop-cart", Enabled: false})
+	if got := testutil.ToFloat64(activeConfigs); got != 1 {
+		t.Errorf("Expected 1 active config after disabling one, got %v", got)
+	}
+}
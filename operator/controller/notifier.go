@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// WebhookFormatGeneric posts the error event as a JSON object
+	WebhookFormatGeneric = "generic"
+	// WebhookFormatSlack posts the error event as a Slack-compatible {"text": ...} message
+	WebhookFormatSlack = "slack"
+
+	// defaultWebhookTimeout bounds how long a reconcile waits on the webhook
+	defaultWebhookTimeout = 5 * time.Second
+)
+
+// ErrorEvent describes a UrlPerformance that transitioned into the Error phase
+type ErrorEvent struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+}
+
+// ErrorNotifier is called by the reconciler when a UrlPerformance transitions into the Error phase
+type ErrorNotifier interface {
+	NotifyError(ctx context.Context, event ErrorEvent) error
+}
+
+// WebhookNotifier posts error events to an HTTP webhook. Notifications for the same UrlPerformance
+// within MinInterval of the previous one are dropped, so a flapping resource doesn't spam the webhook.
+type WebhookNotifier struct {
+	URL         string
+	Format      string
+	MinInterval time.Duration
+	Client      *http.Client
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+	now      func() time.Time
+}
+
+// NewWebhookNotifier creates a notifier posting to url in the given format
+func NewWebhookNotifier(url, format string, minInterval time.Duration) (*WebhookNotifier, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	switch format {
+	case "":
+		format = WebhookFormatGeneric
+	case WebhookFormatGeneric, WebhookFormatSlack:
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q, expected %q or %q", format, WebhookFormatGeneric, WebhookFormatSlack)
+	}
+
+	return &WebhookNotifier{
+		URL:         url,
+		Format:      format,
+		MinInterval: minInterval,
+		Client:      &http.Client{Timeout: defaultWebhookTimeout},
+		lastSent:    make(map[string]time.Time),
+		now:         time.Now,
+	}, nil
+}
+
+// NotifyError posts the event unless the same UrlPerformance was notified within MinInterval
+func (n *WebhookNotifier) NotifyError(ctx context.Context, event ErrorEvent) error {
+	key := event.Namespace + "/" + event.Name
+
+	n.mu.Lock()
+	now := n.now()
+	if last, ok := n.lastSent[key]; ok && now.Sub(last) < n.MinInterval {
+		n.mu.Unlock()
+		return nil
+	}
+	n.lastSent[key] = now
+	n.mu.Unlock()
+
+	body, err := n.payload(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// payload renders the event in the notifier's format
+func (n *WebhookNotifier) payload(event ErrorEvent) ([]byte, error) {
+	if n.Format == WebhookFormatSlack {
+		text := fmt.Sprintf("UrlPerformance %s/%s is in phase %s: %s (%s)",
+			event.Namespace, event.Name, event.Phase, event.Message, event.Reason)
+		return json.Marshal(map[string]string{"text": text})
+	}
+	return json.Marshal(event)
+}
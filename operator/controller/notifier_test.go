@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookRecorder is a fake webhook endpoint that records the JSON bodies it receives
+type webhookRecorder struct {
+	mu     sync.Mutex
+	bodies []map[string]string
+	status int
+}
+
+func (w *webhookRecorder) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	body := map[string]string{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.bodies = append(w.bodies, body)
+	if w.status != 0 {
+		rw.WriteHeader(w.status)
+	}
+}
+
+func (w *webhookRecorder) received() []map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]map[string]string(nil), w.bodies...)
+}
+
+// TestWebhookNotifierDebounce tests that repeated notifications for the same resource are dropped
+// within the minimum interval while other resources are still notified
+func TestWebhookNotifierDebounce(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(server.URL, "", time.Minute)
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier() error = %v", err)
+	}
+	now := time.Now()
+	notifier.now = func() time.Time { return now }
+
+	event := ErrorEvent{Namespace: "shop", Name: "checkout", Phase: "Error", Reason: "NotFound", Message: "Target resource not found"}
+	for i := 0; i < 3; i++ {
+		if err := notifier.NotifyError(context.Background(), event); err != nil {
+			t.Fatalf("NotifyError() error = %v", err)
+		}
+	}
+	other := event
+	other.Name = "search"
+	if err := notifier.NotifyError(context.Background(), other); err != nil {
+		t.Fatalf("NotifyError() error = %v", err)
+	}
+
+	bodies := recorder.received()
+	if len(bodies) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d: %v", len(bodies), bodies)
+	}
+	if bodies[0]["name"] != "checkout" || bodies[0]["reason"] != "NotFound" || bodies[0]["namespace"] != "shop" {
+		t.Errorf("Unexpected generic payload %v", bodies[0])
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := notifier.NotifyError(context.Background(), event); err != nil {
+		t.Fatalf("NotifyError() error = %v", err)
+	}
+	if count := len(recorder.received()); count != 3 {
+		t.Errorf("Expected a notification after the minimum interval, got %d in total", count)
+	}
+}
+
+// TestWebhookNotifierSlackFormat tests the Slack-compatible payload
+func TestWebhookNotifierSlackFormat(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(server.URL, WebhookFormatSlack, 0)
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier() error = %v", err)
+	}
+	event := ErrorEvent{Namespace: "shop", Name: "checkout", Phase: "Error", Reason: "InvalidRegex", Message: "Invalid whitelist regex"}
+	if err := notifier.NotifyError(context.Background(), event); err != nil {
+		t.Fatalf("NotifyError() error = %v", err)
+	}
+
+	expected := "UrlPerformance shop/checkout is in phase Error: Invalid whitelist regex (InvalidRegex)"
+	if bodies := recorder.received(); len(bodies) != 1 || bodies[0]["text"] != expected {
+		t.Errorf("Expected Slack text %q, got %v", expected, bodies)
+	}
+}
+
+// TestWebhookNotifierErrors tests invalid configurations and failing webhooks
+func TestWebhookNotifierErrors(t *testing.T) {
+	if _, err := NewWebhookNotifier("", "", 0); err == nil {
+		t.Error("Expected an error without a URL")
+	}
+	if _, err := NewWebhookNotifier("http://example.com", "teams", 0); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+
+	server := httptest.NewServer(&webhookRecorder{status: http.StatusInternalServerError})
+	defer server.Close()
+	notifier, err := NewWebhookNotifier(server.URL, WebhookFormatGeneric, 0)
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier() error = %v", err)
+	}
+	if err := notifier.NotifyError(context.Background(), ErrorEvent{Namespace: "shop", Name: "checkout"}); err == nil {
+		t.Error("Expected an error for a non-2xx webhook response")
+	}
+}
@@ -60,8 +60,10 @@ var _ = BeforeSuite(func() {
 
 	// Setup the test environment with CRD directory
 	crdPath := filepath.Join(operatorDir, "crd", "bases")
+	// Third-party CRDs the reconciler reads, such as Traefik's IngressRoute
+	testCRDPath := filepath.Join(projectDir, "testdata", "crds")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths:     []string{crdPath},
+		CRDDirectoryPaths:     []string{crdPath, testCRDPath},
 		ErrorIfCRDPathMissing: false,
 	}
 
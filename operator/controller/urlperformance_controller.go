@@ -2,31 +2,108 @@ package controller
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	logger "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	networkingv1 "k8s.io/api/networking/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	traefikofficerv1alpha1 "github.com/mithucste30/traefik-officer-operator/operator/api/v1alpha1"
 	"github.com/mithucste30/traefik-officer-operator/shared"
 )
 
+var (
+	// ingressRouteGVK identifies Traefik IngressRoute resources, which are read as unstructured objects
+	ingressRouteGVK = schema.GroupVersionKind{Group: "traefik.io", Version: "v1alpha1", Kind: "IngressRoute"}
+	// ingressRouteTCPGVK identifies Traefik IngressRouteTCP resources
+	ingressRouteTCPGVK = schema.GroupVersionKind{Group: "traefik.io", Version: "v1alpha1", Kind: "IngressRouteTCP"}
+	// ingressRouteUDPGVK identifies Traefik IngressRouteUDP resources
+	ingressRouteUDPGVK = schema.GroupVersionKind{Group: "traefik.io", Version: "v1alpha1", Kind: "IngressRouteUDP"}
+	// middlewareGVK identifies Traefik Middleware resources, which are read as unstructured objects
+	middlewareGVK = schema.GroupVersionKind{Group: "traefik.io", Version: "v1alpha1", Kind: "Middleware"}
+)
+
+// urlPerformanceFinalizer lets the operator drop the runtime configuration and metrics of a
+// UrlPerformance before it is deleted
+const urlPerformanceFinalizer = "traefikofficer.io/cleanup"
+
+// traefikRouteGVKs maps the Traefik route target kinds to their resources. All of them list their
+// services under spec.routes[].services[].
+var traefikRouteGVKs = map[string]schema.GroupVersionKind{
+	traefikofficerv1alpha1.TargetKindIngressRoute:    ingressRouteGVK,
+	traefikofficerv1alpha1.TargetKindIngressRouteTCP: ingressRouteTCPGVK,
+	traefikofficerv1alpha1.TargetKindIngressRouteUDP: ingressRouteUDPGVK,
+}
+
+// pathRewritingMiddlewares lists the Traefik middleware types that change the request path
+var pathRewritingMiddlewares = []string{"stripPrefix", "stripPrefixRegex", "replacePath", "replacePathRegex", "addPrefix"}
+
+var (
+	// errTargetNotFound is returned when no target of a supported kind exists
+	errTargetNotFound = stderrors.New("target resource not found")
+	// errTargetAmbiguous is returned when auto-detection finds both an Ingress and an IngressRoute
+	errTargetAmbiguous = stderrors.New("target resource is ambiguous")
+)
+
+// resolvedTarget is an existing target of a UrlPerformance with its detected kind and services
+type resolvedTarget struct {
+	namespace    string
+	name         string
+	kind         string
+	serviceNames []string
+}
+
+// targetFailure describes a target of a UrlPerformance that couldn't be resolved
+type targetFailure struct {
+	target  string
+	reason  string
+	message string
+}
+
 // UrlPerformanceReconciler reconciles a UrlPerformance object
 type UrlPerformanceReconciler struct {
 	client.Client
 	Log           logr.Logger
 	Scheme        *runtime.Scheme
 	ConfigManager *ConfigManager
+	// TargetStats reports the paths and last log line the log processor observed per config key.
+	// MonitoredPaths and LastScrapeTime are left unset when nil.
+	TargetStats shared.TargetStatsProvider
+	// StatusRefreshInterval requeues active UrlPerformance objects to refresh their traffic status.
+	// Disabled when zero.
+	StatusRefreshInterval time.Duration
+	// Notifier is called when a UrlPerformance transitions into the Error phase. Optional.
+	Notifier ErrorNotifier
+	// MetricsResetter deletes the metrics of a config key when its UrlPerformance is deleted. Optional.
+	MetricsResetter shared.TargetMetricsResetter
+
+	// MaxConcurrentReconciles is the number of UrlPerformance objects reconciled in parallel.
+	// Defaults to 1 when unset.
+	MaxConcurrentReconciles int
 }
 
 // ConfigManager manages dynamic configuration from CRDs
@@ -47,6 +124,8 @@ func (cm *ConfigManager) UpdateConfig(config *shared.RuntimeConfig) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	defer func() { activeConfigs.Set(float64(len(cm.configs))) }()
+
 	if !config.Enabled {
 		delete(cm.configs, config.Key)
 		logger.Infof("Removed config for %s (disabled)", config.Key)
@@ -82,9 +161,20 @@ func (cm *ConfigManager) GetAllConfigs() []*shared.RuntimeConfig {
 //+kubebuilder:rbac:groups=traefikofficer.io,resources=urlperformances/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=traefikofficer.io,resources=urlperformances/finalizers,verbs=update
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=traefik.io,resources=ingressroutes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=traefik.io,resources=ingressroutetcps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=traefik.io,resources=ingressrouteudps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=traefik.io,resources=middlewares,verbs=get;list;watch
 
 // Reconcile is the main reconciliation loop
 func (r *UrlPerformanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.reconcile(ctx, req)
+	recordReconcile(err)
+	return result, err
+}
+
+// reconcile fetches a UrlPerformance and finalizes or reconciles it
+func (r *UrlPerformanceReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := logr.FromContextOrDiscard(ctx)
 	reqLogger.Info("Reconciling UrlPerformance", "namespace", req.Namespace, "name", req.Name)
 
@@ -99,6 +189,28 @@ func (r *UrlPerformanceReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, err
 	}
 
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, instance)
+	}
+	if controllerutil.AddFinalizer(instance, urlPerformanceFinalizer) {
+		if err := r.Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	previousPhase := instance.Status.Phase
+	result, err := r.reconcileInstance(ctx, instance)
+	if err == nil && instance.Status.Phase == traefikofficerv1alpha1.PhaseError && previousPhase != traefikofficerv1alpha1.PhaseError {
+		r.notifyError(ctx, instance)
+	}
+	return result, err
+}
+
+// reconcileInstance verifies the target of a UrlPerformance, publishes its runtime configuration
+// and updates its status
+func (r *UrlPerformanceReconciler) reconcileInstance(ctx context.Context, instance *traefikofficerv1alpha1.UrlPerformance) (ctrl.Result, error) {
+	reqLogger := logr.FromContextOrDiscard(ctx)
+
 	// Initialize status if needed
 	if instance.Status.ObservedGeneration == 0 {
 		instance.Status.Phase = traefikofficerv1alpha1.PhasePending
@@ -109,43 +221,88 @@ func (r *UrlPerformanceReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return r.handleDisabled(ctx, instance)
 	}
 
-	// Verify target exists
-	targetNamespace := instance.Spec.TargetRef.Namespace
-	if targetNamespace == "" {
-		targetNamespace = instance.Namespace
+	if errs := validateTargetRefs(&instance.Spec, field.NewPath("spec")); len(errs) > 0 {
+		reqLogger.Error(errs.ToAggregate(), "Invalid target references")
+		r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionFalse, "InvalidTargetRef", errs.ToAggregate().Error())
+		instance.Status.Phase = traefikofficerv1alpha1.PhaseError
+		return r.updateStatus(ctx, instance)
 	}
 
-	targetExists := false
-	var targetErr error
-
-	var serviceNames []string
-
-	switch instance.Spec.TargetRef.Kind {
-	case "Ingress":
-		ingress := &networkingv1.Ingress{}
-		targetErr = r.Get(ctx, types.NamespacedName{
-			Namespace: targetNamespace,
-			Name:      instance.Spec.TargetRef.Name,
-		}, ingress)
-		targetExists = (targetErr == nil)
-
-		// Extract service names if ingress exists
-		if targetExists {
-			serviceNames = extractServiceNamesFromIngress(ingress)
+	// Verify the targets exist; the resource stays active while at least one of them does
+	targetRefs := targetRefsOf(instance)
+	if instance.Spec.TargetSelector != nil {
+		selected, err := r.selectTargets(ctx, instance)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
+		if len(selected) == 0 {
+			if err := r.setConfigKeys(ctx, instance, nil); err != nil {
+				return ctrl.Result{}, err
+			}
+			r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionFalse, "NoMatch",
+				"No Ingress or IngressRoute matches the target selector")
+			instance.Status.Phase = traefikofficerv1alpha1.PhaseError
+			return r.updateStatus(ctx, instance)
+		}
+		targetRefs = selected
+	}
+	targets := make([]resolvedTarget, 0, len(targetRefs))
+	failures := make([]targetFailure, 0)
+	for _, ref := range targetRefs {
+		target, failure := r.resolveTarget(ctx, ref)
+		if failure != nil {
+			failures = append(failures, *failure)
+			continue
+		}
+		targets = append(targets, target)
 	}
 
-	if !targetExists {
-		reqLogger.Error(targetErr, "Target resource not found")
-		r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionFalse, "NotFound", "Target resource not found")
+	switch {
+	case len(targets) == 0 && len(failures) == 1:
+		r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionFalse, failures[0].reason, failures[0].message)
 		instance.Status.Phase = traefikofficerv1alpha1.PhaseError
 		return r.updateStatus(ctx, instance)
+	case len(targets) == 0:
+		r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionFalse, "NotFound",
+			"No target resource found: "+describeTargetFailures(failures))
+		instance.Status.Phase = traefikofficerv1alpha1.PhaseError
+		return r.updateStatus(ctx, instance)
+	case len(failures) > 0:
+		r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionFalse, "PartiallyFound",
+			fmt.Sprintf("%d of %d target resources found, missing %s", len(targets), len(targetRefs), describeTargetFailures(failures)))
+	case instance.Spec.TargetSelector != nil:
+		r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionTrue, "Found",
+			fmt.Sprintf("%d target resources match the target selector", len(targets)))
+	case len(targets) > 1:
+		r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionTrue, "Found", "All target resources found")
+	default:
+		r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionTrue, "Found", "Target resource found")
 	}
 
-	r.updateCondition(ctx, instance, "TargetExists", metav1.ConditionTrue, "Found", "Target resource found")
-
-	// Build runtime configuration
-	configKey := fmt.Sprintf("%s-%s", targetNamespace, instance.Spec.TargetRef.Name)
+	// Warn when middlewares rewrite paths, since regexes are matched against the logged (rewritten) path
+	rewriters := make([]string, 0)
+	rewritesInspected := false
+	for _, target := range targets {
+		if target.kind != traefikofficerv1alpha1.TargetKindIngressRoute {
+			continue
+		}
+		targetRewriters, err := r.getPathRewritingMiddlewares(ctx, target.namespace, target.name)
+		if err != nil {
+			reqLogger.Error(err, "Unable to inspect IngressRoute middlewares", "name", target.name)
+			continue
+		}
+		rewritesInspected = true
+		rewriters = append(rewriters, targetRewriters...)
+	}
+	if len(rewriters) > 0 {
+		message := fmt.Sprintf("Logged paths are rewritten by middleware %s; "+
+			"whitelist and ignored path regexes are matched against the rewritten paths", strings.Join(rewriters, ", "))
+		reqLogger.Info("Target rewrites paths before they are logged", "middlewares", rewriters)
+		r.updateCondition(ctx, instance, "PathRewritten", metav1.ConditionTrue, "RewriteMiddleware", message)
+	} else if rewritesInspected {
+		r.updateCondition(ctx, instance, "PathRewritten", metav1.ConditionFalse, "NoRewriteMiddleware",
+			"No middleware rewrites paths of the target routes")
+	}
 
 	// Compile regex patterns
 	whitelistRegex := make([]*regexp.Regexp, 0)
@@ -153,6 +310,7 @@ func (r *UrlPerformanceReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		regex, err := regexp.Compile(pattern)
 		if err != nil {
 			reqLogger.Error(err, "Invalid whitelist regex pattern")
+			invalidRegexTotal.Inc()
 			r.updateCondition(ctx, instance, "ConfigGenerated", metav1.ConditionFalse, "InvalidRegex", "Invalid whitelist regex")
 			instance.Status.Phase = traefikofficerv1alpha1.PhaseError
 			return r.updateStatus(ctx, instance)
@@ -165,6 +323,7 @@ func (r *UrlPerformanceReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		regex, err := regexp.Compile(pattern)
 		if err != nil {
 			reqLogger.Error(err, "Invalid ignored regex pattern")
+			invalidRegexTotal.Inc()
 			r.updateCondition(ctx, instance, "ConfigGenerated", metav1.ConditionFalse, "InvalidRegex", "Invalid ignored regex")
 			instance.Status.Phase = traefikofficerv1alpha1.PhaseError
 			return r.updateStatus(ctx, instance)
@@ -172,12 +331,34 @@ func (r *UrlPerformanceReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		ignoredRegex = append(ignoredRegex, regex)
 	}
 
+	detailedHistogramRegex := make([]*regexp.Regexp, 0)
+	for _, pattern := range instance.Spec.DetailedHistogramPaths {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			reqLogger.Error(err, "Invalid detailed histogram regex pattern")
+			invalidRegexTotal.Inc()
+			r.updateCondition(ctx, instance, "ConfigGenerated", metav1.ConditionFalse, "InvalidRegex", "Invalid detailed histogram regex")
+			instance.Status.Phase = traefikofficerv1alpha1.PhaseError
+			return r.updateStatus(ctx, instance)
+		}
+		detailedHistogramRegex = append(detailedHistogramRegex, regex)
+	}
+
+	detailedHistogramBuckets, err := parseHistogramBuckets(instance.Spec.DetailedHistogramBuckets)
+	if err != nil {
+		reqLogger.Error(err, "Invalid detailed histogram buckets")
+		r.updateCondition(ctx, instance, "ConfigGenerated", metav1.ConditionFalse, "InvalidBuckets", err.Error())
+		instance.Status.Phase = traefikofficerv1alpha1.PhaseError
+		return r.updateStatus(ctx, instance)
+	}
+
 	// Convert URL patterns
 	urlPatterns := make([]shared.URLPattern, 0)
 	for _, pattern := range instance.Spec.URLPatterns {
 		regex, err := regexp.Compile(pattern.Pattern)
 		if err != nil {
 			reqLogger.Error(err, "Invalid URL pattern regex")
+			invalidRegexTotal.Inc()
 			continue
 		}
 		urlPatterns = append(urlPatterns, shared.URLPattern{
@@ -186,25 +367,46 @@ func (r *UrlPerformanceReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		})
 	}
 
-	// Create runtime config
-	runtimeConfig := &shared.RuntimeConfig{
-		Key:            configKey,
-		Namespace:      targetNamespace,
-		TargetName:     instance.Spec.TargetRef.Name,
-		TargetKind:     instance.Spec.TargetRef.Kind,
-		ServiceNames:   serviceNames,
-		WhitelistRegex: whitelistRegex,
-		IgnoredRegex:   ignoredRegex,
-		MergePaths:     instance.Spec.MergePathsWithExtensions,
-		URLPatterns:    urlPatterns,
-		CollectNTop:    instance.Spec.CollectNTop,
-		Enabled:        instance.Spec.Enabled,
-		LastUpdated:    time.Now(),
+	disabledNormalizationRules := make([]string, 0, len(instance.Spec.DisabledNormalizationRules))
+	for _, rule := range instance.Spec.DisabledNormalizationRules {
+		disabledNormalizationRules = append(disabledNormalizationRules, string(rule))
 	}
 
-	// Update config manager
-	if r.ConfigManager != nil {
-		r.ConfigManager.UpdateConfig(runtimeConfig)
+	// Create a runtime config per target, all sharing the same rules
+	configKeys := make([]string, 0, len(targets))
+	for _, target := range targets {
+		runtimeConfig := &shared.RuntimeConfig{
+			Key:            shared.ConfigKey(target.namespace, target.name),
+			Namespace:      target.namespace,
+			TargetName:     target.name,
+			TargetKind:     target.kind,
+			ServiceNames:   target.serviceNames,
+			WhitelistRegex: whitelistRegex,
+			IgnoredRegex:   ignoredRegex,
+			MergePaths:     instance.Spec.MergePathsWithExtensions,
+			URLPatterns:    urlPatterns,
+			CollectNTop:    instance.Spec.CollectNTop,
+			Enabled:        instance.Spec.Enabled,
+			LastUpdated:    time.Now(),
+
+			DetailedHistogramRegex:   detailedHistogramRegex,
+			DetailedHistogramBuckets: detailedHistogramBuckets,
+
+			StripTrailingSlash:         instance.Spec.StripTrailingSlash,
+			LowercasePath:              instance.Spec.LowercasePaths,
+			DisabledNormalizationRules: disabledNormalizationRules,
+			KeepQueryParams:            instance.Spec.KeepQueryParams,
+		}
+		configKeys = append(configKeys, runtimeConfig.Key)
+
+		// Update config manager
+		if r.ConfigManager != nil {
+			r.ConfigManager.UpdateConfig(runtimeConfig)
+		}
+	}
+
+	if err := r.setConfigKeys(ctx, instance, configKeys); err != nil {
+		return ctrl.Result{}, err
 	}
 
 	// Update status
@@ -212,28 +414,443 @@ func (r *UrlPerformanceReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	r.updateCondition(ctx, instance, "Ready", metav1.ConditionTrue, "Ready", "UrlPerformance is active")
 	instance.Status.Phase = traefikofficerv1alpha1.PhaseActive
 	instance.Status.ObservedGeneration = instance.Generation
+	r.updateTargetStats(instance, configKeys)
 
-	return r.updateStatus(ctx, instance)
+	result, err := r.updateStatus(ctx, instance)
+	if err == nil && r.StatusRefreshInterval > 0 {
+		result.RequeueAfter = r.StatusRefreshInterval
+	}
+	return result, err
+}
+
+// updateTargetStats copies the paths and last log line observed for the config keys into the
+// status, summing the paths and keeping the latest log line over all targets
+func (r *UrlPerformanceReconciler) updateTargetStats(instance *traefikofficerv1alpha1.UrlPerformance, configKeys []string) {
+	if r.TargetStats == nil {
+		return
+	}
+	var total shared.TargetStats
+	observed := false
+	for _, configKey := range configKeys {
+		stats, ok := r.TargetStats.GetTargetStats(configKey)
+		if !ok {
+			continue
+		}
+		observed = true
+		total.MonitoredPaths += stats.MonitoredPaths
+		if stats.LastSeen.After(total.LastSeen) {
+			total.LastSeen = stats.LastSeen
+		}
+	}
+	if !observed {
+		return
+	}
+	instance.Status.MonitoredPaths = int32(total.MonitoredPaths)
+	if !total.LastSeen.IsZero() {
+		lastScrapeTime := metav1.NewTime(total.LastSeen)
+		instance.Status.LastScrapeTime = &lastScrapeTime
+	}
+}
+
+// selectTargets lists the Ingresses and IngressRoutes in the namespace of the UrlPerformance that
+// match its targetSelector. IngressRoutes are skipped when Traefik's CRDs aren't installed.
+func (r *UrlPerformanceReconciler) selectTargets(ctx context.Context, instance *traefikofficerv1alpha1.UrlPerformance) ([]traefikofficerv1alpha1.TargetReference, error) {
+	selector, err := metav1.LabelSelectorAsSelector(instance.Spec.TargetSelector)
+	if err != nil {
+		return nil, err
+	}
+	opts := []client.ListOption{client.InNamespace(instance.Namespace), client.MatchingLabelsSelector{Selector: selector}}
+
+	refs := make([]traefikofficerv1alpha1.TargetReference, 0)
+	ingresses := &networkingv1.IngressList{}
+	if err := r.List(ctx, ingresses, opts...); err != nil {
+		return nil, err
+	}
+	for _, ingress := range ingresses.Items {
+		refs = append(refs, traefikofficerv1alpha1.TargetReference{
+			Kind:      traefikofficerv1alpha1.TargetKindIngress,
+			Name:      ingress.Name,
+			Namespace: instance.Namespace,
+		})
+	}
+
+	ingressRoutes := &unstructured.UnstructuredList{}
+	ingressRoutes.SetGroupVersionKind(ingressRouteGVK.GroupVersion().WithKind(ingressRouteGVK.Kind + "List"))
+	if err := r.List(ctx, ingressRoutes, opts...); err != nil {
+		if !meta.IsNoMatchError(err) {
+			return nil, err
+		}
+	}
+	for _, ingressRoute := range ingressRoutes.Items {
+		refs = append(refs, traefikofficerv1alpha1.TargetReference{
+			Kind:      traefikofficerv1alpha1.TargetKindIngressRoute,
+			Name:      ingressRoute.GetName(),
+			Namespace: instance.Namespace,
+		})
+	}
+	return refs, nil
+}
+
+// setConfigKeys records the config keys published for a UrlPerformance in its status and removes
+// the configuration and metrics of the targets it no longer covers, e.g. Ingresses that stopped
+// matching its selector or were dropped from its targetRefs
+func (r *UrlPerformanceReconciler) setConfigKeys(ctx context.Context, instance *traefikofficerv1alpha1.UrlPerformance, configKeys []string) error {
+	current := make(map[string]struct{}, len(configKeys))
+	for _, configKey := range configKeys {
+		current[configKey] = struct{}{}
+	}
+	dropped := make([]string, 0)
+	for _, configKey := range instance.Status.ConfigKeys {
+		if _, ok := current[configKey]; !ok {
+			dropped = append(dropped, configKey)
+		}
+	}
+	if _, err := r.removeConfigs(ctx, instance, dropped, true); err != nil {
+		return err
+	}
+	instance.Status.ConfigKeys = configKeys
+	return nil
+}
+
+// sharedConfigKeys returns the config keys other UrlPerformances publish configs for. Resources
+// being deleted are left out, so deleting several resources sharing a target still cleans it up.
+func (r *UrlPerformanceReconciler) sharedConfigKeys(ctx context.Context, instance *traefikofficerv1alpha1.UrlPerformance) (map[string]struct{}, error) {
+	list := &traefikofficerv1alpha1.UrlPerformanceList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("unable to list UrlPerformances sharing targets: %w", err)
+	}
+
+	keys := make(map[string]struct{})
+	for i := range list.Items {
+		other := &list.Items[i]
+		if (other.Namespace == instance.Namespace && other.Name == instance.Name) || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		for _, configKey := range other.Status.ConfigKeys {
+			keys[configKey] = struct{}{}
+		}
+	}
+	return keys, nil
+}
+
+// removeConfigs removes the runtime configuration, and the metrics when resetMetrics is set, of the
+// config keys no other UrlPerformance targets. It returns the removed keys.
+func (r *UrlPerformanceReconciler) removeConfigs(ctx context.Context, instance *traefikofficerv1alpha1.UrlPerformance, configKeys []string, resetMetrics bool) ([]string, error) {
+	if len(configKeys) == 0 {
+		return nil, nil
+	}
+	sharedKeys, err := r.sharedConfigKeys(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0, len(configKeys))
+	for _, configKey := range configKeys {
+		if _, ok := sharedKeys[configKey]; ok {
+			continue
+		}
+		r.removeConfig(configKey)
+		if resetMetrics && r.MetricsResetter != nil {
+			r.MetricsResetter.ResetTargetMetrics(configKey)
+		}
+		removed = append(removed, configKey)
+	}
+	return removed, nil
+}
+
+// resolveTarget detects the kind of a target when needed and fetches the services it routes to
+func (r *UrlPerformanceReconciler) resolveTarget(ctx context.Context, ref traefikofficerv1alpha1.TargetReference) (resolvedTarget, *targetFailure) {
+	reqLogger := logr.FromContextOrDiscard(ctx)
+	target := resolvedTarget{namespace: ref.Namespace, name: ref.Name, kind: ref.Kind}
+	description := fmt.Sprintf("%s/%s", target.namespace, target.name)
+
+	if target.kind == "" || target.kind == traefikofficerv1alpha1.TargetKindAuto {
+		resolvedKind, err := r.resolveTargetKind(ctx, target.namespace, target.name)
+		if err != nil {
+			reason := "NotFound"
+			if stderrors.Is(err, errTargetAmbiguous) {
+				reason = "Ambiguous"
+			}
+			reqLogger.Error(err, "Unable to detect target kind", "target", description)
+			return target, &targetFailure{target: description, reason: reason, message: err.Error()}
+		}
+		reqLogger.Info("Detected target kind", "target", description, "kind", resolvedKind)
+		target.kind = resolvedKind
+	}
+
+	serviceNames, err := r.getTargetServiceNames(ctx, target.kind, target.namespace, target.name)
+	if err != nil {
+		reqLogger.Error(err, "Target resource not found", "target", description)
+		return target, &targetFailure{
+			target:  fmt.Sprintf("%s %s", target.kind, description),
+			reason:  "NotFound",
+			message: "Target resource not found",
+		}
+	}
+	target.serviceNames = serviceNames
+	return target, nil
+}
+
+// describeTargetFailures lists the unresolved targets with the reason each failed
+func describeTargetFailures(failures []targetFailure) string {
+	descriptions := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", failure.target, failure.reason))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// resolveTargetKind looks up both an Ingress and an IngressRoute with the given name and returns
+// the kind of the one that exists. It fails when both or neither exist.
+func (r *UrlPerformanceReconciler) resolveTargetKind(ctx context.Context, namespace, name string) (string, error) {
+	found := make([]string, 0, 2)
+	for _, kind := range []string{traefikofficerv1alpha1.TargetKindIngress, traefikofficerv1alpha1.TargetKindIngressRoute} {
+		_, err := r.getTargetServiceNames(ctx, kind, namespace, name)
+		if err == nil {
+			found = append(found, kind)
+			continue
+		}
+		if !stderrors.Is(err, errTargetNotFound) {
+			return "", err
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%w: no Ingress or IngressRoute named %s in namespace %s", errTargetNotFound, name, namespace)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%w: both an Ingress and an IngressRoute named %s exist in namespace %s, set targetRef.kind explicitly",
+			errTargetAmbiguous, name, namespace)
+	}
+}
+
+// getTargetServiceNames fetches the target of the given kind and returns the services it routes to.
+// A missing target, or a missing Traefik CRD, is reported as errTargetNotFound.
+func (r *UrlPerformanceReconciler) getTargetServiceNames(ctx context.Context, kind, namespace, name string) ([]string, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+
+	switch kind {
+	case traefikofficerv1alpha1.TargetKindIngress:
+		ingress := &networkingv1.Ingress{}
+		if err := r.Get(ctx, key, ingress); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, fmt.Errorf("%w: %v", errTargetNotFound, err)
+			}
+			return nil, err
+		}
+		return extractServiceNamesFromIngress(ingress), nil
+
+	case traefikofficerv1alpha1.TargetKindIngressRoute, traefikofficerv1alpha1.TargetKindIngressRouteTCP,
+		traefikofficerv1alpha1.TargetKindIngressRouteUDP:
+		ingressRoute := &unstructured.Unstructured{}
+		ingressRoute.SetGroupVersionKind(traefikRouteGVKs[kind])
+		if err := r.Get(ctx, key, ingressRoute); err != nil {
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				return nil, fmt.Errorf("%w: %v", errTargetNotFound, err)
+			}
+			return nil, err
+		}
+		return extractServiceNamesFromIngressRoute(ingressRoute), nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported target kind %q", errTargetNotFound, kind)
+}
+
+// getPathRewritingMiddlewares returns the middlewares referenced by an IngressRoute's routes that
+// rewrite the request path, formatted as "namespace/name (type)". Middlewares from other providers
+// ("name@provider") and middlewares that cannot be found are skipped.
+func (r *UrlPerformanceReconciler) getPathRewritingMiddlewares(ctx context.Context, namespace, name string) ([]string, error) {
+	ingressRoute := &unstructured.Unstructured{}
+	ingressRoute.SetGroupVersionKind(ingressRouteGVK)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, ingressRoute); err != nil {
+		return nil, err
+	}
+
+	rewriters := make([]string, 0)
+	seen := make(map[types.NamespacedName]struct{})
+
+	routes, _, _ := unstructured.NestedSlice(ingressRoute.Object, "spec", "routes")
+	for _, route := range routes {
+		routeMap, ok := route.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		middlewares, _, _ := unstructured.NestedSlice(routeMap, "middlewares")
+		for _, middleware := range middlewares {
+			refMap, ok := middleware.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			refName, _ := refMap["name"].(string)
+			if refName == "" || strings.Contains(refName, "@") {
+				continue
+			}
+			refNamespace, _ := refMap["namespace"].(string)
+			if refNamespace == "" {
+				refNamespace = namespace
+			}
+
+			key := types.NamespacedName{Namespace: refNamespace, Name: refName}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(middlewareGVK)
+			if err := r.Get(ctx, key, obj); err != nil {
+				if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+					continue
+				}
+				return nil, err
+			}
+
+			spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+			for _, middlewareType := range pathRewritingMiddlewares {
+				if _, ok := spec[middlewareType]; ok {
+					rewriters = append(rewriters, fmt.Sprintf("%s (%s)", key.String(), middlewareType))
+					break
+				}
+			}
+		}
+	}
+
+	return rewriters, nil
+}
+
+// parseHistogramBuckets parses bucket upper bounds in seconds, which must be positive and increasing.
+// No buckets returns nil, selecting the default buckets.
+func parseHistogramBuckets(values []string) ([]float64, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	buckets := make([]float64, 0, len(values))
+	for i, value := range values {
+		bucket, err := strconv.ParseFloat(value, 64)
+		if err != nil || bucket <= 0 {
+			return nil, fmt.Errorf("invalid histogram bucket %q: must be a positive number of seconds", value)
+		}
+		if i > 0 && bucket <= buckets[i-1] {
+			return nil, fmt.Errorf("histogram buckets must be increasing, got %q after %q", value, values[i-1])
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+// targetRefsOf returns the targets referenced by TargetRef and TargetRefs, whose namespaces
+// default to the resource's
+func targetRefsOf(instance *traefikofficerv1alpha1.UrlPerformance) []traefikofficerv1alpha1.TargetReference {
+	refs := make([]traefikofficerv1alpha1.TargetReference, 0, len(instance.Spec.TargetRefs)+1)
+	if instance.Spec.TargetRef != nil {
+		refs = append(refs, *instance.Spec.TargetRef)
+	}
+	refs = append(refs, instance.Spec.TargetRefs...)
+	for i := range refs {
+		if refs[i].Namespace == "" {
+			refs[i].Namespace = instance.Namespace
+		}
+	}
+	return refs
+}
+
+// configKeysFor returns the runtime config keys of the targets referenced by a UrlPerformance and
+// of the targets its last reconcile published configs for
+func configKeysFor(instance *traefikofficerv1alpha1.UrlPerformance) []string {
+	refs := targetRefsOf(instance)
+	keys := make([]string, 0, len(refs)+len(instance.Status.ConfigKeys))
+	seen := make(map[string]struct{}, cap(keys))
+	add := func(key string) {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	for _, ref := range refs {
+		add(shared.ConfigKey(ref.Namespace, ref.Name))
+	}
+	for _, key := range instance.Status.ConfigKeys {
+		add(key)
+	}
+	return keys
+}
+
+// notifyError reports a UrlPerformance that transitioned into the Error phase to the notifier,
+// with the reason and message of its failing condition
+func (r *UrlPerformanceReconciler) notifyError(ctx context.Context, instance *traefikofficerv1alpha1.UrlPerformance) {
+	if r.Notifier == nil {
+		return
+	}
+
+	event := ErrorEvent{
+		Namespace: instance.Namespace,
+		Name:      instance.Name,
+		Phase:     string(instance.Status.Phase),
+	}
+	for _, condType := range []string{"TargetExists", "ConfigGenerated"} {
+		for _, cond := range instance.Status.Conditions {
+			if string(cond.Type) == condType && cond.Status == string(metav1.ConditionFalse) {
+				event.Reason, event.Message = cond.Reason, cond.Message
+				break
+			}
+		}
+		if event.Reason != "" {
+			break
+		}
+	}
+
+	if err := r.Notifier.NotifyError(ctx, event); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "Failed to send error notification")
+	}
 }
 
 // handleDisabled handles disabled UrlPerformance resources
 func (r *UrlPerformanceReconciler) handleDisabled(ctx context.Context, instance *traefikofficerv1alpha1.UrlPerformance) (ctrl.Result, error) {
 	reqLogger := logr.FromContextOrDiscard(ctx)
 
-	// Remove configuration
-	configKey := fmt.Sprintf("%s-%s", instance.Spec.TargetRef.Namespace, instance.Spec.TargetRef.Name)
+	if _, err := r.removeConfigs(ctx, instance, configKeysFor(instance), false); err != nil {
+		return ctrl.Result{}, err
+	}
+	instance.Status.ConfigKeys = nil
+
+	instance.Status.Phase = traefikofficerv1alpha1.PhaseDisabled
+	r.updateCondition(ctx, instance, "Ready", metav1.ConditionFalse, "Disabled", "UrlPerformance is disabled")
+
+	reqLogger.Info("UrlPerformance is disabled")
+	return r.updateStatus(ctx, instance)
+}
+
+// removeConfig removes the runtime configuration of a config key
+func (r *UrlPerformanceReconciler) removeConfig(configKey string) {
 	if r.ConfigManager != nil {
 		r.ConfigManager.UpdateConfig(&shared.RuntimeConfig{
 			Key:     configKey,
 			Enabled: false,
 		})
 	}
+}
 
-	instance.Status.Phase = traefikofficerv1alpha1.PhaseDisabled
-	r.updateCondition(ctx, instance, "Ready", metav1.ConditionFalse, "Disabled", "UrlPerformance is disabled")
+// finalize removes the runtime configuration and metrics of a deleted UrlPerformance, except for
+// targets other UrlPerformances still cover, then releases it by removing its finalizer
+func (r *UrlPerformanceReconciler) finalize(ctx context.Context, instance *traefikofficerv1alpha1.UrlPerformance) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(instance, urlPerformanceFinalizer) {
+		return ctrl.Result{}, nil
+	}
 
-	reqLogger.Info("UrlPerformance is disabled")
-	return r.updateStatus(ctx, instance)
+	configKeys, err := r.removeConfigs(ctx, instance, configKeysFor(instance), true)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	controllerutil.RemoveFinalizer(instance, urlPerformanceFinalizer)
+	if err := r.Update(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	logr.FromContextOrDiscard(ctx).Info("Cleaned up deleted UrlPerformance", "configKeys", configKeys)
+	return ctrl.Result{}, nil
 }
 
 // updateCondition updates a condition in the status
@@ -299,9 +916,185 @@ func extractServiceNamesFromIngress(ingress *networkingv1.Ingress) []string {
 	return serviceNames
 }
 
+// extractServiceNamesFromIngressRoute extracts unique service names from the routes of an
+// IngressRoute, IngressRouteTCP or IngressRouteUDP
+func extractServiceNamesFromIngressRoute(ingressRoute *unstructured.Unstructured) []string {
+	serviceSet := make(map[string]struct{})
+
+	routes, _, _ := unstructured.NestedSlice(ingressRoute.Object, "spec", "routes")
+	for _, route := range routes {
+		routeMap, ok := route.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		services, _, _ := unstructured.NestedSlice(routeMap, "services")
+		for _, service := range services {
+			serviceMap, ok := service.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if serviceName, ok := serviceMap["name"].(string); ok && serviceName != "" {
+				serviceSet[serviceName] = struct{}{}
+			}
+		}
+	}
+
+	// Convert set to slice
+	serviceNames := make([]string, 0, len(serviceSet))
+	for serviceName := range serviceSet {
+		serviceNames = append(serviceNames, serviceName)
+	}
+
+	return serviceNames
+}
+
+// findObjectsForIngress enqueues the UrlPerformance resources targeting the changed Ingress
+func (r *UrlPerformanceReconciler) findObjectsForIngress(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.findObjectsForTarget(ctx, traefikofficerv1alpha1.TargetKindIngress, obj)
+}
+
+// findObjectsForTraefikRoute returns a map function enqueueing the UrlPerformance resources
+// targeting the changed Traefik route of the given kind
+func (r *UrlPerformanceReconciler) findObjectsForTraefikRoute(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		return r.findObjectsForTarget(ctx, kind, obj)
+	}
+}
+
+// findObjectsForTarget returns a request for every UrlPerformance with a targetRef or targetRefs
+// entry pointing at the object, or with a targetSelector that matches it or covered it before.
+// Resources with an auto-detected kind are matched by either kind, so creating or deleting an
+// Ingress or IngressRoute of the same name also re-runs detection.
+func (r *UrlPerformanceReconciler) findObjectsForTarget(ctx context.Context, kind string, obj client.Object) []reconcile.Request {
+	list := &traefikofficerv1alpha1.UrlPerformanceList{}
+	if err := r.List(ctx, list); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "Unable to list UrlPerformances for target change",
+			"kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0)
+	for i := range list.Items {
+		item := &list.Items[i]
+		if referencesTarget(item, kind, obj) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// findObjectsSharingTargets enqueues the other UrlPerformance resources publishing configs for the
+// targets of the changed one, so they republish their configs once it is deleted or retargeted
+func (r *UrlPerformanceReconciler) findObjectsSharingTargets(ctx context.Context, obj client.Object) []reconcile.Request {
+	instance, ok := obj.(*traefikofficerv1alpha1.UrlPerformance)
+	if !ok {
+		return nil
+	}
+	list := &traefikofficerv1alpha1.UrlPerformanceList{}
+	if err := r.List(ctx, list); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "Unable to list UrlPerformances sharing targets",
+			"namespace", instance.Namespace, "name", instance.Name)
+		return nil
+	}
+
+	keys := make(map[string]struct{})
+	for _, configKey := range configKeysFor(instance) {
+		keys[configKey] = struct{}{}
+	}
+	requests := make([]reconcile.Request, 0)
+	for i := range list.Items {
+		item := &list.Items[i]
+		if item.Namespace == instance.Namespace && item.Name == instance.Name {
+			continue
+		}
+		for _, configKey := range item.Status.ConfigKeys {
+			if _, ok := keys[configKey]; ok {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name},
+				})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// sharedTargetChange passes the deletions and spec changes of UrlPerformances to
+// findObjectsSharingTargets. Status updates are left out, so resources sharing a target don't
+// keep re-enqueueing each other.
+var sharedTargetChange = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return true },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// referencesTarget reports whether a UrlPerformance targets the object of the given kind
+func referencesTarget(instance *traefikofficerv1alpha1.UrlPerformance, kind string, obj client.Object) bool {
+	if instance.Spec.TargetSelector != nil {
+		if instance.Namespace != obj.GetNamespace() ||
+			(kind != traefikofficerv1alpha1.TargetKindIngress && kind != traefikofficerv1alpha1.TargetKindIngressRoute) {
+			return false
+		}
+		// Targets that stopped matching are still enqueued so their configs get removed
+		key := shared.ConfigKey(obj.GetNamespace(), obj.GetName())
+		for _, configKey := range instance.Status.ConfigKeys {
+			if configKey == key {
+				return true
+			}
+		}
+		selector, err := metav1.LabelSelectorAsSelector(instance.Spec.TargetSelector)
+		return err == nil && selector.Matches(labels.Set(obj.GetLabels()))
+	}
+
+	for _, ref := range targetRefsOf(instance) {
+		if ref.Namespace != obj.GetNamespace() || ref.Name != obj.GetName() {
+			continue
+		}
+		if ref.Kind == "" || ref.Kind == traefikofficerv1alpha1.TargetKindAuto || ref.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
 // SetupWithManager sets up the controller with the Manager
 func (r *UrlPerformanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	maxConcurrent := r.MaxConcurrentReconciles
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+
+	// Concurrent reconciles only share the ConfigManager, which is guarded by its own lock;
+	// each reconcile fetches and updates its own copy of the UrlPerformance object.
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&traefikofficerv1alpha1.UrlPerformance{}).
-		Complete(r)
+		Watches(&networkingv1.Ingress{}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIngress)).
+		Watches(&traefikofficerv1alpha1.UrlPerformance{}, handler.EnqueueRequestsFromMapFunc(r.findObjectsSharingTargets),
+			ctrlbuilder.WithPredicates(sharedTargetChange)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrent})
+
+	// Traefik routes are only watched when their CRDs are installed; a watch on a missing kind
+	// would keep the manager from starting
+	for _, kind := range []string{
+		traefikofficerv1alpha1.TargetKindIngressRoute,
+		traefikofficerv1alpha1.TargetKindIngressRouteTCP,
+		traefikofficerv1alpha1.TargetKindIngressRouteUDP,
+	} {
+		gvk := traefikRouteGVKs[kind]
+		if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			r.Log.Info(kind+" CRD not found, changes to "+kind+"s won't trigger reconciliation", "reason", err.Error())
+			continue
+		}
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(gvk)
+		builder = builder.Watches(route, handler.EnqueueRequestsFromMapFunc(r.findObjectsForTraefikRoute(kind)))
+	}
+
+	return builder.Complete(r)
 }
@@ -2,17 +2,28 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	traefikofficerv1alpha1 "github.com/mithucste30/traefik-officer-operator/operator/api/v1alpha1"
+	"github.com/mithucste30/traefik-officer-operator/shared"
 )
 
 var _ = Describe("UrlPerformance Reconciler", func() {
@@ -95,7 +106,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 					Namespace: testNamespace,
 				},
 				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
-					TargetRef: traefikofficerv1alpha1.TargetReference{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
 						Kind:     "Ingress",
 						Name:     testIngress.Name,
 						Namespace: testNamespace,
@@ -133,7 +144,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			By("verifying config was updated in ConfigManager")
-			configKey := testNamespace + "-" + testIngress.Name
+			configKey := shared.ConfigKey(testNamespace, testIngress.Name)
 			config, exists := configManager.GetConfig(configKey)
 			Expect(exists).To(BeTrue())
 			Expect(config.Enabled).To(BeTrue())
@@ -154,7 +165,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 					Namespace: testNamespace,
 				},
 				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
-					TargetRef: traefikofficerv1alpha1.TargetReference{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
 						Kind:     "Ingress",
 						Name:     "non-existent-ingress",
 						Namespace: testNamespace,
@@ -254,7 +265,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 					Namespace: testNamespace,
 				},
 				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
-					TargetRef: traefikofficerv1alpha1.TargetReference{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
 						Kind:     "Ingress",
 						Name:     testIngress.Name,
 						Namespace: testNamespace,
@@ -288,7 +299,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			By("verifying config was removed from ConfigManager")
-			configKey := testNamespace + "-" + testIngress.Name
+			configKey := shared.ConfigKey(testNamespace, testIngress.Name)
 			_, exists := configManager.GetConfig(configKey)
 			Expect(exists).To(BeFalse())
 		})
@@ -341,7 +352,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 					Namespace: testNamespace,
 				},
 				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
-					TargetRef: traefikofficerv1alpha1.TargetReference{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
 						Kind:     "Ingress",
 						Name:     testIngress.Name,
 						Namespace: testNamespace,
@@ -442,7 +453,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 					Namespace: testNamespace,
 				},
 				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
-					TargetRef: traefikofficerv1alpha1.TargetReference{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
 						Kind:     "Ingress",
 						Name:     testIngress.Name,
 						Namespace: testNamespace,
@@ -560,7 +571,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 					Namespace: testNamespace,
 				},
 				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
-					TargetRef: traefikofficerv1alpha1.TargetReference{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
 						Kind:     "Ingress",
 						Name:     ingress1.Name,
 						Namespace: testNamespace,
@@ -582,7 +593,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 					Namespace: testNamespace,
 				},
 				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
-					TargetRef: traefikofficerv1alpha1.TargetReference{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
 						Kind:     "Ingress",
 						Name:     ingress2.Name,
 						Namespace: testNamespace,
@@ -683,7 +694,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 					Namespace: testNamespace,
 				},
 				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
-					TargetRef: traefikofficerv1alpha1.TargetReference{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
 						Kind:     "Ingress",
 						Name:     testIngress.Name,
 						Namespace: testNamespace,
@@ -742,7 +753,7 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			By("verifying config was updated in ConfigManager")
-			configKey := testNamespace + "-" + testIngress.Name
+			configKey := shared.ConfigKey(testNamespace, testIngress.Name)
 			Eventually(func() bool {
 				config, exists := configManager.GetConfig(configKey)
 				return exists && config.CollectNTop == 50 &&
@@ -750,9 +761,1205 @@ var _ = Describe("UrlPerformance Reconciler", func() {
 			}, timeout, interval).Should(BeTrue())
 		})
 	})
+
+	Context("Scenario H: Concurrent reconciliation", func() {
+		It("should bring many UrlPerformance resources to Active with MaxConcurrentReconciles", func() {
+			const count = 20
+
+			By("starting a manager with concurrent reconciles")
+			mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+				Scheme:  scheme.Scheme,
+				Metrics: metricsserver.Options{BindAddress: "0"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			concurrentReconciler := &UrlPerformanceReconciler{
+				Client:                  mgr.GetClient(),
+				Scheme:                  mgr.GetScheme(),
+				ConfigManager:           configManager,
+				MaxConcurrentReconciles: 4,
+			}
+			Expect(concurrentReconciler.SetupWithManager(mgr)).To(Succeed())
+
+			mgrCtx, stopMgr := context.WithCancel(ctx)
+			defer stopMgr()
+			go func() {
+				defer GinkgoRecover()
+				Expect(mgr.Start(mgrCtx)).To(Succeed())
+			}()
+
+			By("creating Ingresses and UrlPerformance resources")
+			names := make([]string, 0, count)
+			for i := 0; i < count; i++ {
+				name := fmt.Sprintf("test-concurrent-%d", i)
+				names = append(names, name)
+
+				ingress := &networkingv1.Ingress{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+					Spec: networkingv1.IngressSpec{
+						DefaultBackend: &networkingv1.IngressBackend{
+							Service: &networkingv1.IngressServiceBackend{
+								Name: name + "-service",
+								Port: networkingv1.ServiceBackendPort{Number: 80},
+							},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+				DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+				urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+					Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+						TargetRef: &traefikofficerv1alpha1.TargetReference{
+							Kind:      "Ingress",
+							Name:      name,
+							Namespace: testNamespace,
+						},
+						CollectNTop: 20,
+						Enabled:     true,
+					},
+				}
+				Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+				DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+			}
+
+			By("verifying every resource reaches Active")
+			Eventually(func() int {
+				active := 0
+				for _, name := range names {
+					urlPerf := &traefikofficerv1alpha1.UrlPerformance{}
+					if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, urlPerf); err != nil {
+						continue
+					}
+					if urlPerf.Status.Phase == traefikofficerv1alpha1.PhaseActive {
+						active++
+					}
+				}
+				return active
+			}, 4*timeout, interval).Should(Equal(count))
+
+			By("verifying every config was registered")
+			for _, name := range names {
+				_, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+				Expect(exists).To(BeTrue(), "config for %s should exist", name)
+			}
+		})
+	})
+
+	Context("Scenario I: Target kind auto-detection", func() {
+		newAutoIngress := func(name string) *networkingv1.Ingress {
+			return &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "ingress-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+		}
+
+		newAutoIngressRoute := func(name string) *unstructured.Unstructured {
+			ingressRoute := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"entryPoints": []interface{}{"web"},
+					"routes": []interface{}{
+						map[string]interface{}{
+							"match": "PathPrefix(`/`)",
+							"kind":  "Rule",
+							"services": []interface{}{
+								map[string]interface{}{"name": "ingressroute-service", "port": int64(80)},
+							},
+						},
+					},
+				},
+			}}
+			ingressRoute.SetGroupVersionKind(ingressRouteGVK)
+			ingressRoute.SetName(name)
+			ingressRoute.SetNamespace(testNamespace)
+			return ingressRoute
+		}
+
+		reconcileAuto := func(name string) *traefikofficerv1alpha1.UrlPerformance {
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      traefikofficerv1alpha1.TargetKindAuto,
+						Name:      name,
+						Namespace: testNamespace,
+					},
+					CollectNTop: 20,
+					Enabled:     true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			result := &traefikofficerv1alpha1.UrlPerformance{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, result)).To(Succeed())
+			return result
+		}
+
+		targetCondition := func(urlPerf *traefikofficerv1alpha1.UrlPerformance) *traefikofficerv1alpha1.Condition {
+			for i := range urlPerf.Status.Conditions {
+				if string(urlPerf.Status.Conditions[i].Type) == "TargetExists" {
+					return &urlPerf.Status.Conditions[i]
+				}
+			}
+			return nil
+		}
+
+		It("should resolve to Ingress when only an Ingress exists", func() {
+			const name = "test-auto-ingress"
+			ingress := newAutoIngress(name)
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+			urlPerf := reconcileAuto(name)
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+
+			config, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+			Expect(exists).To(BeTrue())
+			Expect(config.TargetKind).To(Equal(traefikofficerv1alpha1.TargetKindIngress))
+			Expect(config.ServiceNames).To(ConsistOf("ingress-service"))
+		})
+
+		It("should resolve to IngressRoute when only an IngressRoute exists", func() {
+			const name = "test-auto-ingressroute"
+			ingressRoute := newAutoIngressRoute(name)
+			Expect(k8sClient.Create(ctx, ingressRoute)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingressRoute) })
+
+			urlPerf := reconcileAuto(name)
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+
+			config, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+			Expect(exists).To(BeTrue())
+			Expect(config.TargetKind).To(Equal(traefikofficerv1alpha1.TargetKindIngressRoute))
+			Expect(config.ServiceNames).To(ConsistOf("ingressroute-service"))
+		})
+
+		It("should set an Ambiguous error when both an Ingress and an IngressRoute exist", func() {
+			const name = "test-auto-ambiguous"
+			ingress := newAutoIngress(name)
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+			ingressRoute := newAutoIngressRoute(name)
+			Expect(k8sClient.Create(ctx, ingressRoute)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingressRoute) })
+
+			urlPerf := reconcileAuto(name)
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseError))
+
+			cond := targetCondition(urlPerf)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal("False"))
+			Expect(cond.Reason).To(Equal("Ambiguous"))
+
+			_, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should set a NotFound error when neither target exists", func() {
+			const name = "test-auto-missing"
+
+			urlPerf := reconcileAuto(name)
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseError))
+
+			cond := targetCondition(urlPerf)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal("False"))
+			Expect(cond.Reason).To(Equal("NotFound"))
+		})
+	})
+
+	Context("Scenario J: IngressRoute middlewares rewriting paths", func() {
+		newRoute := func(name string, middlewares ...string) *unstructured.Unstructured {
+			refs := make([]interface{}, 0, len(middlewares))
+			for _, middleware := range middlewares {
+				refs = append(refs, map[string]interface{}{"name": middleware})
+			}
+			ingressRoute := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"routes": []interface{}{
+						map[string]interface{}{
+							"match":       "PathPrefix(`/api`)",
+							"kind":        "Rule",
+							"middlewares": refs,
+							"services": []interface{}{
+								map[string]interface{}{"name": "api-service", "port": int64(80)},
+							},
+						},
+					},
+				},
+			}}
+			ingressRoute.SetGroupVersionKind(ingressRouteGVK)
+			ingressRoute.SetName(name)
+			ingressRoute.SetNamespace(testNamespace)
+			return ingressRoute
+		}
+
+		reconcileRoute := func(name string) *traefikofficerv1alpha1.UrlPerformance {
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      traefikofficerv1alpha1.TargetKindIngressRoute,
+						Name:      name,
+						Namespace: testNamespace,
+					},
+					WhitelistPathsRegex: []string{"^/api/.*"},
+					CollectNTop:         20,
+					Enabled:             true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			result := &traefikofficerv1alpha1.UrlPerformance{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, result)).To(Succeed())
+			return result
+		}
+
+		pathRewrittenCondition := func(urlPerf *traefikofficerv1alpha1.UrlPerformance) *traefikofficerv1alpha1.Condition {
+			for i := range urlPerf.Status.Conditions {
+				if urlPerf.Status.Conditions[i].Type == traefikofficerv1alpha1.ConditionPathRewritten {
+					return &urlPerf.Status.Conditions[i]
+				}
+			}
+			return nil
+		}
+
+		It("should report a StripPrefix middleware on the target route", func() {
+			const name = "test-route-stripprefix"
+
+			middleware := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"stripPrefix": map[string]interface{}{
+						"prefixes": []interface{}{"/api"},
+					},
+				},
+			}}
+			middleware.SetGroupVersionKind(middlewareGVK)
+			middleware.SetName("strip-api")
+			middleware.SetNamespace(testNamespace)
+			Expect(k8sClient.Create(ctx, middleware)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), middleware) })
+
+			ingressRoute := newRoute(name, "strip-api")
+			Expect(k8sClient.Create(ctx, ingressRoute)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingressRoute) })
+
+			urlPerf := reconcileRoute(name)
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+
+			cond := pathRewrittenCondition(urlPerf)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal("True"))
+			Expect(cond.Reason).To(Equal("RewriteMiddleware"))
+			Expect(cond.Message).To(ContainSubstring(testNamespace + "/strip-api (stripPrefix)"))
+		})
+
+		It("should report no rewrite when the route has no path-rewriting middleware", func() {
+			const name = "test-route-plain"
+
+			ingressRoute := newRoute(name, "missing-middleware")
+			Expect(k8sClient.Create(ctx, ingressRoute)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingressRoute) })
+
+			urlPerf := reconcileRoute(name)
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+
+			cond := pathRewrittenCondition(urlPerf)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal("False"))
+			Expect(cond.Reason).To(Equal("NoRewriteMiddleware"))
+		})
+	})
+
+	Context("Scenario K: Detailed latency histogram", func() {
+		reconcileHistogram := func(name string, paths, buckets []string) *traefikofficerv1alpha1.UrlPerformance {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "histogram-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      traefikofficerv1alpha1.TargetKindIngress,
+						Name:      name,
+						Namespace: testNamespace,
+					},
+					CollectNTop:              20,
+					Enabled:                  true,
+					DetailedHistogramPaths:   paths,
+					DetailedHistogramBuckets: buckets,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			result := &traefikofficerv1alpha1.UrlPerformance{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, result)).To(Succeed())
+			return result
+		}
+
+		It("should carry detailed histogram paths and buckets into the runtime config", func() {
+			const name = "test-detailed-histogram"
+			urlPerf := reconcileHistogram(name, []string{"^/api/checkout$"}, []string{"0.005", "0.01", "0.025"})
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+
+			config, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+			Expect(exists).To(BeTrue())
+			Expect(config.DetailedHistogramRegex).To(HaveLen(1))
+			Expect(config.DetailedHistogramRegex[0].MatchString("/api/checkout")).To(BeTrue())
+			Expect(config.DetailedHistogramBuckets).To(Equal([]float64{0.005, 0.01, 0.025}))
+		})
+
+		It("should set an error status when the buckets aren't increasing", func() {
+			const name = "test-detailed-histogram-invalid"
+			urlPerf := reconcileHistogram(name, []string{"^/api/checkout$"}, []string{"0.05", "0.01"})
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseError))
+
+			var reason string
+			for _, cond := range urlPerf.Status.Conditions {
+				if string(cond.Type) == "ConfigGenerated" {
+					reason = cond.Reason
+				}
+			}
+			Expect(reason).To(Equal("InvalidBuckets"))
+		})
+	})
+
+	Context("Scenario L: Target created after the UrlPerformance", func() {
+		newLateIngress := func(name string) *networkingv1.Ingress {
+			return &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "late-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+		}
+
+		newLateUrlPerformance := func(name, targetName, kind string) *traefikofficerv1alpha1.UrlPerformance {
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      kind,
+						Name:      targetName,
+						Namespace: testNamespace,
+					},
+					CollectNTop: 20,
+					Enabled:     true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+			return urlPerf
+		}
+
+		targetExists := func(name string) string {
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, urlPerf); err != nil {
+				return ""
+			}
+			for _, cond := range urlPerf.Status.Conditions {
+				if string(cond.Type) == "TargetExists" {
+					return string(cond.Status)
+				}
+			}
+			return ""
+		}
+
+		It("should map an Ingress to the UrlPerformance resources targeting it", func() {
+			const target = "test-late-ingress-map"
+			matching := newLateUrlPerformance("test-late-map", target, traefikofficerv1alpha1.TargetKindIngress)
+			auto := newLateUrlPerformance("test-late-map-auto", target, traefikofficerv1alpha1.TargetKindAuto)
+			newLateUrlPerformance("test-late-map-other", "another-ingress", traefikofficerv1alpha1.TargetKindIngress)
+			newLateUrlPerformance("test-late-map-route", target, traefikofficerv1alpha1.TargetKindIngressRoute)
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: matching.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targetExists(matching.Name)).To(Equal("False"))
+
+			By("creating the Ingress after the UrlPerformance")
+			ingress := newLateIngress(target)
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+			requests := reconciler.findObjectsForIngress(ctx, ingress)
+			names := make([]string, 0, len(requests))
+			for _, request := range requests {
+				names = append(names, request.Name)
+			}
+			Expect(names).To(ConsistOf(matching.Name, auto.Name))
+
+			By("reconciling the mapped requests")
+			for _, request := range requests {
+				_, err := reconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(targetExists(matching.Name)).To(Equal("True"))
+		})
+
+		It("should flip TargetExists to True when the manager sees the Ingress created", func() {
+			const name = "test-late-ingress-watch"
+
+			By("starting a manager watching Ingresses")
+			skipNameValidation := true
+			mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+				Scheme:     scheme.Scheme,
+				Metrics:    metricsserver.Options{BindAddress: "0"},
+				Controller: ctrlconfig.Controller{SkipNameValidation: &skipNameValidation},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			watchingReconciler := &UrlPerformanceReconciler{
+				Client:        mgr.GetClient(),
+				Scheme:        mgr.GetScheme(),
+				ConfigManager: configManager,
+			}
+			Expect(watchingReconciler.SetupWithManager(mgr)).To(Succeed())
+
+			mgrCtx, stopMgr := context.WithCancel(ctx)
+			defer stopMgr()
+			go func() {
+				defer GinkgoRecover()
+				Expect(mgr.Start(mgrCtx)).To(Succeed())
+			}()
+
+			newLateUrlPerformance(name, name, traefikofficerv1alpha1.TargetKindIngress)
+			Eventually(func() string { return targetExists(name) }, timeout, interval).Should(Equal("False"))
+
+			By("creating the Ingress after the UrlPerformance")
+			ingress := newLateIngress(name)
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+			Eventually(func() string { return targetExists(name) }, timeout, interval).Should(Equal("True"))
+			_, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+			Expect(exists).To(BeTrue())
+		})
+	})
+
+	Context("Scenario M: Status reports the traffic observed by the log processor", func() {
+		It("should copy monitored paths and last scrape time into the status and requeue", func() {
+			const name = "test-status-traffic"
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "traffic-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      traefikofficerv1alpha1.TargetKindIngress,
+						Name:      name,
+						Namespace: testNamespace,
+					},
+					CollectNTop: 20,
+					Enabled:     true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+
+			stats := &fakeTargetStats{stats: map[string]shared.TargetStats{}}
+			reconciler.TargetStats = stats
+			reconciler.StatusRefreshInterval = time.Minute
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+			key := shared.ConfigKey(testNamespace, name)
+
+			getStatus := func() traefikofficerv1alpha1.UrlPerformanceStatus {
+				current := &traefikofficerv1alpha1.UrlPerformance{}
+				Expect(k8sClient.Get(ctx, request.NamespacedName, current)).To(Succeed())
+				return current.Status
+			}
+
+			By("reconciling before any log line matched the target")
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+			Expect(getStatus().MonitoredPaths).To(BeZero())
+			Expect(getStatus().LastScrapeTime).To(BeNil())
+
+			By("reconciling after the log processor observed paths")
+			lastSeen := time.Now().Add(-time.Minute).Truncate(time.Second)
+			stats.set(key, shared.TargetStats{MonitoredPaths: 3, LastSeen: lastSeen})
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			status := getStatus()
+			Expect(status.MonitoredPaths).To(Equal(int32(3)))
+			Expect(status.LastScrapeTime).NotTo(BeNil())
+			Expect(status.LastScrapeTime.Time.Equal(lastSeen)).To(BeTrue())
+
+			By("refreshing the count as more paths are observed")
+			stats.set(key, shared.TargetStats{MonitoredPaths: 5, LastSeen: time.Now()})
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getStatus().MonitoredPaths).To(Equal(int32(5)))
+		})
+	})
+
+	Context("Scenario N: Error notifications", func() {
+		It("should notify the webhook on transitions into Error and not in steady state", func() {
+			const name = "test-error-webhook"
+			recorder := &webhookRecorder{}
+			server := httptest.NewServer(recorder)
+			DeferCleanup(server.Close)
+
+			notifier, err := NewWebhookNotifier(server.URL, WebhookFormatGeneric, 0)
+			Expect(err).NotTo(HaveOccurred())
+			reconciler.Notifier = notifier
+
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      traefikofficerv1alpha1.TargetKindIngress,
+						Name:      name,
+						Namespace: testNamespace,
+					},
+					CollectNTop: 20,
+					Enabled:     true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+
+			By("reconciling a UrlPerformance whose target is missing")
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.received()).To(HaveLen(1))
+			Expect(recorder.received()[0]).To(HaveKeyWithValue("name", name))
+			Expect(recorder.received()[0]).To(HaveKeyWithValue("reason", "NotFound"))
+
+			By("reconciling again while it stays in Error")
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.received()).To(HaveLen(1))
+
+			By("recovering once the target is created")
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "webhook-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.received()).To(HaveLen(1))
+
+			By("notifying again when the target is deleted")
+			Expect(k8sClient.Delete(ctx, ingress)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.received()).To(HaveLen(2))
+		})
+	})
+
+	Context("Scenario O: Deletion cleans up config and metrics", func() {
+		It("should remove the runtime config and reset metrics before releasing the finalizer", func() {
+			const name = "test-finalizer-cleanup"
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "cleanup-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      traefikofficerv1alpha1.TargetKindIngress,
+						Name:      name,
+						Namespace: testNamespace,
+					},
+					CollectNTop: 20,
+					Enabled:     true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+
+			resetter := &fakeMetricsResetter{}
+			reconciler.MetricsResetter = resetter
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+			key := shared.ConfigKey(testNamespace, name)
+
+			By("adding the finalizer and publishing the config")
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			current := &traefikofficerv1alpha1.UrlPerformance{}
+			Expect(k8sClient.Get(ctx, request.NamespacedName, current)).To(Succeed())
+			Expect(current.Finalizers).To(ContainElement(urlPerformanceFinalizer))
+			_, found := configManager.GetConfig(key)
+			Expect(found).To(BeTrue())
+
+			By("deleting the UrlPerformance")
+			Expect(k8sClient.Delete(ctx, current)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, config := range configManager.GetAllConfigs() {
+				Expect(config.Key).NotTo(Equal(key))
+			}
+			Expect(resetter.resetKeys()).To(Equal([]string{key}))
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, request.NamespacedName, &traefikofficerv1alpha1.UrlPerformance{}))
+			}, timeout, interval).Should(BeTrue())
+		})
+	})
+
+	Context("Scenario Q: One UrlPerformance targeting several resources", func() {
+		newIngress := func(name, service string) *networkingv1.Ingress {
+			return &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: service,
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+		}
+
+		It("should publish a config per target with the shared rules and report missing targets", func() {
+			const name = "test-multi-target"
+			first := newIngress("test-multi-target-shop", "shop-service")
+			Expect(k8sClient.Create(ctx, first)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), first) })
+			second := newIngress("test-multi-target-cart", "cart-service")
+			Expect(k8sClient.Create(ctx, second)).To(Succeed())
+
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRefs: []traefikofficerv1alpha1.TargetReference{
+						{Kind: traefikofficerv1alpha1.TargetKindIngress, Name: first.Name},
+						{Kind: traefikofficerv1alpha1.TargetKindIngress, Name: second.Name},
+					},
+					IgnoredPathsRegex: []string{"^/health$"},
+					CollectNTop:       20,
+					Enabled:           true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+			firstKey := shared.ConfigKey(testNamespace, first.Name)
+			secondKey := shared.ConfigKey(testNamespace, second.Name)
+
+			getResource := func() *traefikofficerv1alpha1.UrlPerformance {
+				current := &traefikofficerv1alpha1.UrlPerformance{}
+				Expect(k8sClient.Get(ctx, request.NamespacedName, current)).To(Succeed())
+				return current
+			}
+
+			By("reconciling while both targets exist")
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getResource().Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+
+			firstConfig, exists := configManager.GetConfig(firstKey)
+			Expect(exists).To(BeTrue())
+			Expect(firstConfig.ServiceNames).To(ConsistOf("shop-service"))
+			secondConfig, exists := configManager.GetConfig(secondKey)
+			Expect(exists).To(BeTrue())
+			Expect(secondConfig.ServiceNames).To(ConsistOf("cart-service"))
+			Expect(secondConfig.TargetName).To(Equal(second.Name))
+			Expect(firstConfig.IgnoredRegex).To(HaveLen(1))
+			Expect(secondConfig.IgnoredRegex).To(HaveLen(1))
+
+			By("reconciling after one of the targets is deleted")
+			Expect(k8sClient.Delete(ctx, second)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			current := getResource()
+			Expect(current.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+			Expect(current.Status.Conditions).To(ContainElement(And(
+				HaveField("Type", traefikofficerv1alpha1.ConditionType("TargetExists")),
+				HaveField("Status", "False"),
+				HaveField("Reason", "PartiallyFound"),
+				HaveField("Message", ContainSubstring(second.Name)),
+			)))
+			Expect(current.Status.Conditions).To(ContainElement(And(
+				HaveField("Type", traefikofficerv1alpha1.ConditionType("Ready")),
+				HaveField("Status", "True"),
+			)))
+
+			By("removing the config of every target when the resource is deleted")
+			Expect(k8sClient.Delete(ctx, current)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			_, exists = configManager.GetConfig(firstKey)
+			Expect(exists).To(BeFalse())
+			_, exists = configManager.GetConfig(secondKey)
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should reject a UrlPerformance setting both targetRef and targetRefs", func() {
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-multi-target-both", Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{Kind: traefikofficerv1alpha1.TargetKindIngress, Name: "shop"},
+					TargetRefs: []traefikofficerv1alpha1.TargetReference{
+						{Kind: traefikofficerv1alpha1.TargetKindIngress, Name: "cart"},
+					},
+					CollectNTop: 20,
+					Enabled:     true,
+				},
+			}
+			err := k8sClient.Create(ctx, urlPerf)
+			Expect(errors.IsInvalid(err)).To(BeTrue(), "expected an Invalid error, got %v", err)
+		})
+	})
+
+	Context("Scenario R: Targets selected by labels", func() {
+		newLabelledIngress := func(name, team string) *networkingv1.Ingress {
+			return &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: map[string]string{"team": team}},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: name + "-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+		}
+
+		It("should publish a config per matching Ingress and follow label changes", func() {
+			const name = "test-selector"
+			shop := newLabelledIngress("test-selector-shop", "storefront")
+			Expect(k8sClient.Create(ctx, shop)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), shop) })
+			search := newLabelledIngress("test-selector-search", "search")
+			Expect(k8sClient.Create(ctx, search)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), search) })
+
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "storefront"}},
+					CollectNTop:    20,
+					Enabled:        true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+
+			resetter := &fakeMetricsResetter{}
+			reconciler.MetricsResetter = resetter
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+			shopKey := shared.ConfigKey(testNamespace, shop.Name)
+			searchKey := shared.ConfigKey(testNamespace, search.Name)
+
+			getResource := func() *traefikofficerv1alpha1.UrlPerformance {
+				current := &traefikofficerv1alpha1.UrlPerformance{}
+				Expect(k8sClient.Get(ctx, request.NamespacedName, current)).To(Succeed())
+				return current
+			}
+			relabel := func(ingress *networkingv1.Ingress, team string) {
+				current := &networkingv1.Ingress{}
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ingress), current)).To(Succeed())
+				current.Labels["team"] = team
+				Expect(k8sClient.Update(ctx, current)).To(Succeed())
+			}
+
+			By("resolving the selector into the matching Ingress")
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getResource().Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+			Expect(getResource().Status.ConfigKeys).To(ConsistOf(shopKey))
+			config, exists := configManager.GetConfig(shopKey)
+			Expect(exists).To(BeTrue())
+			Expect(config.ServiceNames).To(ConsistOf("test-selector-shop-service"))
+			_, exists = configManager.GetConfig(searchKey)
+			Expect(exists).To(BeFalse())
+
+			By("covering an Ingress once it is labelled to match")
+			relabel(search, "storefront")
+			search.Labels["team"] = "storefront"
+			Expect(reconciler.findObjectsForIngress(ctx, search)).To(ContainElement(request))
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getResource().Status.ConfigKeys).To(ConsistOf(shopKey, searchKey))
+			_, exists = configManager.GetConfig(searchKey)
+			Expect(exists).To(BeTrue())
+
+			By("dropping the config and metrics of an Ingress that stops matching")
+			relabel(shop, "checkout")
+			shop.Labels["team"] = "checkout"
+			Expect(reconciler.findObjectsForIngress(ctx, shop)).To(ContainElement(request))
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getResource().Status.ConfigKeys).To(ConsistOf(searchKey))
+			_, exists = configManager.GetConfig(shopKey)
+			Expect(exists).To(BeFalse())
+			Expect(resetter.resetKeys()).To(Equal([]string{shopKey}))
+
+			By("reporting NoMatch once no Ingress matches")
+			relabel(search, "search")
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			current := getResource()
+			Expect(current.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseError))
+			Expect(current.Status.ConfigKeys).To(BeEmpty())
+			Expect(current.Status.Conditions).To(ContainElement(And(
+				HaveField("Type", traefikofficerv1alpha1.ConditionType("TargetExists")),
+				HaveField("Reason", "NoMatch"),
+			)))
+			_, exists = configManager.GetConfig(searchKey)
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should reject a UrlPerformance combining a selector with a targetRef", func() {
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-selector-and-ref", Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef:      &traefikofficerv1alpha1.TargetReference{Kind: traefikofficerv1alpha1.TargetKindIngress, Name: "shop"},
+					TargetSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "storefront"}},
+					CollectNTop:    20,
+					Enabled:        true,
+				},
+			}
+			err := k8sClient.Create(ctx, urlPerf)
+			Expect(errors.IsInvalid(err)).To(BeTrue(), "expected an Invalid error, got %v", err)
+		})
+	})
+
+	Context("Scenario P: IngressRouteTCP and IngressRouteUDP targets", func() {
+		newRoute := func(gvk schema.GroupVersionKind, name, service string) *unstructured.Unstructured {
+			route := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"entryPoints": []interface{}{"tcp"},
+					"routes": []interface{}{
+						map[string]interface{}{
+							"match": "HostSNI(`*`)",
+							"services": []interface{}{
+								map[string]interface{}{"name": service, "port": int64(5432)},
+							},
+						},
+					},
+				},
+			}}
+			route.SetGroupVersionKind(gvk)
+			route.SetName(name)
+			route.SetNamespace(testNamespace)
+			return route
+		}
+
+		reconcileKind := func(kind, name string) *traefikofficerv1alpha1.UrlPerformance {
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      kind,
+						Name:      name,
+						Namespace: testNamespace,
+					},
+					CollectNTop: 20,
+					Enabled:     true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			result := &traefikofficerv1alpha1.UrlPerformance{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, result)).To(Succeed())
+			return result
+		}
+
+		It("should publish the config of an existing IngressRouteTCP", func() {
+			const name = "test-tcp-route"
+			route := newRoute(ingressRouteTCPGVK, name, "postgres")
+			Expect(k8sClient.Create(ctx, route)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), route) })
+
+			urlPerf := reconcileKind(traefikofficerv1alpha1.TargetKindIngressRouteTCP, name)
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+
+			config, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+			Expect(exists).To(BeTrue())
+			Expect(config.TargetKind).To(Equal(traefikofficerv1alpha1.TargetKindIngressRouteTCP))
+			Expect(config.ServiceNames).To(ConsistOf("postgres"))
+		})
+
+		It("should publish the config of an existing IngressRouteUDP", func() {
+			const name = "test-udp-route"
+			route := newRoute(ingressRouteUDPGVK, name, "coredns")
+			Expect(k8sClient.Create(ctx, route)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), route) })
+
+			urlPerf := reconcileKind(traefikofficerv1alpha1.TargetKindIngressRouteUDP, name)
+			Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseActive))
+
+			config, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+			Expect(exists).To(BeTrue())
+			Expect(config.TargetKind).To(Equal(traefikofficerv1alpha1.TargetKindIngressRouteUDP))
+			Expect(config.ServiceNames).To(ConsistOf("coredns"))
+		})
+
+		It("should set a NotFound error when the TCP or UDP route is missing", func() {
+			for _, kind := range []string{
+				traefikofficerv1alpha1.TargetKindIngressRouteTCP,
+				traefikofficerv1alpha1.TargetKindIngressRouteUDP,
+			} {
+				name := "test-missing-" + strings.ToLower(kind)
+				urlPerf := reconcileKind(kind, name)
+				Expect(urlPerf.Status.Phase).To(Equal(traefikofficerv1alpha1.PhaseError))
+
+				Expect(urlPerf.Status.Conditions).To(ContainElement(And(
+					HaveField("Type", traefikofficerv1alpha1.ConditionType("TargetExists")),
+					HaveField("Reason", "NotFound"),
+				)))
+
+				_, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+				Expect(exists).To(BeFalse())
+			}
+		})
+	})
+
+	Context("Scenario S: URL normalization overrides", func() {
+		It("should carry the URL normalization settings into the runtime config", func() {
+			const name = "test-url-normalization"
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "normalization-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+			stripTrailingSlash, lowercasePaths := true, false
+			urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+				Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+					TargetRef: &traefikofficerv1alpha1.TargetReference{
+						Kind:      traefikofficerv1alpha1.TargetKindIngress,
+						Name:      name,
+						Namespace: testNamespace,
+					},
+					CollectNTop:                20,
+					Enabled:                    true,
+					StripTrailingSlash:         &stripTrailingSlash,
+					LowercasePaths:             &lowercasePaths,
+					DisabledNormalizationRules: []traefikofficerv1alpha1.NormalizationRuleName{"token"},
+					KeepQueryParams:            []string{"type"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			config, exists := configManager.GetConfig(shared.ConfigKey(testNamespace, name))
+			Expect(exists).To(BeTrue())
+			Expect(config.StripTrailingSlash).To(HaveValue(BeTrue()))
+			Expect(config.LowercasePath).To(HaveValue(BeFalse()))
+			Expect(config.DisabledNormalizationRules).To(Equal([]string{"token"}))
+			Expect(config.KeepQueryParams).To(Equal([]string{"type"}))
+		})
+	})
+
+	Context("Scenario T: UrlPerformances sharing a target", func() {
+		It("should keep the config and metrics of a shared target until its last UrlPerformance is gone", func() {
+			const target = "test-shared-target"
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: target, Namespace: testNamespace},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "shared-service",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), ingress) })
+
+			resetter := &fakeMetricsResetter{}
+			reconciler.MetricsResetter = resetter
+			key := shared.ConfigKey(testNamespace, target)
+			requests := make([]ctrl.Request, 0, 2)
+			for _, name := range []string{"test-shared-target-a", "test-shared-target-b"} {
+				urlPerf := &traefikofficerv1alpha1.UrlPerformance{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+					Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+						TargetRef: &traefikofficerv1alpha1.TargetReference{
+							Kind:      traefikofficerv1alpha1.TargetKindIngress,
+							Name:      target,
+							Namespace: testNamespace,
+						},
+						CollectNTop: 20,
+						Enabled:     true,
+					},
+				}
+				Expect(k8sClient.Create(ctx, urlPerf)).To(Succeed())
+				DeferCleanup(func() { _ = k8sClient.Delete(context.Background(), urlPerf) })
+
+				request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+				_, err := reconciler.Reconcile(ctx, request)
+				Expect(err).NotTo(HaveOccurred())
+				requests = append(requests, request)
+			}
+			first, second := requests[0], requests[1]
+
+			By("deleting the first UrlPerformance")
+			current := &traefikofficerv1alpha1.UrlPerformance{}
+			Expect(k8sClient.Get(ctx, first.NamespacedName, current)).To(Succeed())
+			Expect(current.Status.ConfigKeys).To(Equal([]string{key}))
+			Expect(k8sClient.Delete(ctx, current)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, first)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, first.NamespacedName, &traefikofficerv1alpha1.UrlPerformance{}))
+			}, timeout, interval).Should(BeTrue())
+
+			_, found := configManager.GetConfig(key)
+			Expect(found).To(BeTrue(), "the second UrlPerformance still targets the Ingress")
+			Expect(resetter.resetKeys()).To(BeEmpty())
+
+			By("republishing the config of the UrlPerformance sharing the target")
+			mapped := reconciler.findObjectsSharingTargets(ctx, current)
+			Expect(mapped).To(ConsistOf(second))
+			_, err = reconciler.Reconcile(ctx, second)
+			Expect(err).NotTo(HaveOccurred())
+			_, found = configManager.GetConfig(key)
+			Expect(found).To(BeTrue())
+
+			By("disabling the second UrlPerformance, which no longer shares the target")
+			Expect(k8sClient.Get(ctx, second.NamespacedName, current)).To(Succeed())
+			current.Spec.Enabled = false
+			Expect(k8sClient.Update(ctx, current)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, second)
+			Expect(err).NotTo(HaveOccurred())
+			_, found = configManager.GetConfig(key)
+			Expect(found).To(BeFalse())
+		})
+	})
 })
 
 const (
 	timeout = 5 * time.Second
 	interval = 250 * time.Millisecond
 )
+
+// fakeTargetStats serves per-target traffic stats set by the test
+type fakeTargetStats struct {
+	mu    sync.Mutex
+	stats map[string]shared.TargetStats
+}
+
+func (f *fakeTargetStats) set(key string, stats shared.TargetStats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stats[key] = stats
+}
+
+func (f *fakeTargetStats) GetTargetStats(key string) (shared.TargetStats, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats, ok := f.stats[key]
+	return stats, ok
+}
+
+// fakeMetricsResetter records the config keys whose metrics were reset
+type fakeMetricsResetter struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (f *fakeMetricsResetter) ResetTargetMetrics(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = append(f.keys, key)
+}
+
+func (f *fakeMetricsResetter) resetKeys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.keys...)
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	traefikofficerv1alpha1 "github.com/mithucste30/traefik-officer-operator/operator/api/v1alpha1"
+)
+
+// +kubebuilder:webhook:path=/validate-traefikofficer-io-v1alpha1-urlperformance,mutating=false,failurePolicy=fail,sideEffects=None,groups=traefikofficer.io,resources=urlperformances,verbs=create;update,versions=v1alpha1,name=vurlperformance.traefikofficer.io,admissionReviewVersions=v1
+
+// UrlPerformanceValidator rejects UrlPerformance resources with regexes that don't compile, or
+// without exactly one of targetRef, targetRefs and targetSelector, when they are applied, instead of
+// leaving them in the Error phase once reconciled. Reconcile still checks resources admitted without
+// the webhook.
+type UrlPerformanceValidator struct{}
+
+var _ admission.CustomValidator = &UrlPerformanceValidator{}
+
+// SetupWebhookWithManager registers the validating webhook with the manager's webhook server
+func (v *UrlPerformanceValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&traefikofficerv1alpha1.UrlPerformance{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate validates the regexes of a new UrlPerformance
+func (v *UrlPerformanceValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, validateUrlPerformance(obj)
+}
+
+// ValidateUpdate validates the regexes of an updated UrlPerformance
+func (v *UrlPerformanceValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, validateUrlPerformance(newObj)
+}
+
+// ValidateDelete accepts every deletion
+func (v *UrlPerformanceValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateUrlPerformance returns an Invalid error listing the field path of every invalid target
+// reference and every regex of the UrlPerformance that doesn't compile
+func validateUrlPerformance(obj runtime.Object) error {
+	instance, ok := obj.(*traefikofficerv1alpha1.UrlPerformance)
+	if !ok {
+		return fmt.Errorf("expected a UrlPerformance, got %T", obj)
+	}
+
+	specPath := field.NewPath("spec")
+	errs := validateTargetRefs(&instance.Spec, specPath)
+	errs = append(errs, validateSpecRegexes(&instance.Spec, specPath)...)
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(traefikofficerv1alpha1.GroupVersion.WithKind("UrlPerformance").GroupKind(),
+		instance.Name, errs)
+}
+
+// validateTargetRefs requires exactly one of targetRef, a non-empty targetRefs and a valid
+// targetSelector
+func validateTargetRefs(spec *traefikofficerv1alpha1.UrlPerformanceSpec, specPath *field.Path) field.ErrorList {
+	const message = "exactly one of targetRef, targetRefs and targetSelector must be set"
+
+	set := make([]*field.Path, 0, 3)
+	if spec.TargetRef != nil {
+		set = append(set, specPath.Child("targetRef"))
+	}
+	if len(spec.TargetRefs) > 0 {
+		set = append(set, specPath.Child("targetRefs"))
+	}
+	if spec.TargetSelector != nil {
+		set = append(set, specPath.Child("targetSelector"))
+	}
+
+	var errs field.ErrorList
+	if len(set) == 0 {
+		errs = append(errs, field.Required(specPath.Child("targetRef"), message))
+	}
+	for _, path := range set[min(len(set), 1):] {
+		errs = append(errs, field.Forbidden(path, message))
+	}
+	if spec.TargetSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.TargetSelector); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("targetSelector"), spec.TargetSelector, err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateSpecRegexes compiles the path regexes and URL patterns of a spec
+func validateSpecRegexes(spec *traefikofficerv1alpha1.UrlPerformanceSpec, specPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	compile := func(path *field.Path, pattern string) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, field.Invalid(path, pattern, err.Error()))
+		}
+	}
+
+	for i, pattern := range spec.WhitelistPathsRegex {
+		compile(specPath.Child("whitelistPathsRegex").Index(i), pattern)
+	}
+	for i, pattern := range spec.IgnoredPathsRegex {
+		compile(specPath.Child("ignoredPathsRegex").Index(i), pattern)
+	}
+	for i, pattern := range spec.DetailedHistogramPaths {
+		compile(specPath.Child("detailedHistogramPaths").Index(i), pattern)
+	}
+	for i, pattern := range spec.URLPatterns {
+		compile(specPath.Child("urlPatterns").Index(i).Child("pattern"), pattern.Pattern)
+	}
+	return errs
+}
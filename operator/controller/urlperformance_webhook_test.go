@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	traefikofficerv1alpha1 "github.com/mithucste30/traefik-officer-operator/operator/api/v1alpha1"
+)
+
+// newValidationTestResource returns a UrlPerformance with valid regexes in every regex field
+func newValidationTestResource() *traefikofficerv1alpha1.UrlPerformance {
+	return &traefikofficerv1alpha1.UrlPerformance{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+		Spec: traefikofficerv1alpha1.UrlPerformanceSpec{
+			TargetRef:              &traefikofficerv1alpha1.TargetReference{Kind: "Ingress", Name: "shop"},
+			WhitelistPathsRegex:    []string{"^/api/"},
+			IgnoredPathsRegex:      []string{"^/health$"},
+			DetailedHistogramPaths: []string{"^/api/checkout"},
+			URLPatterns:            []traefikofficerv1alpha1.URLPattern{{Pattern: `/users/\d+`, Replacement: "/users/{id}"}},
+		},
+	}
+}
+
+// TestUrlPerformanceValidatorAcceptsValidRegexes tests that valid regexes are admitted
+func TestUrlPerformanceValidatorAcceptsValidRegexes(t *testing.T) {
+	validator := &UrlPerformanceValidator{}
+	instance := newValidationTestResource()
+
+	if _, err := validator.ValidateCreate(context.Background(), instance); err != nil {
+		t.Errorf("ValidateCreate() error = %v", err)
+	}
+	if _, err := validator.ValidateUpdate(context.Background(), instance, instance); err != nil {
+		t.Errorf("ValidateUpdate() error = %v", err)
+	}
+}
+
+// TestUrlPerformanceValidatorRejectsInvalidRegexes tests that every invalid regex is rejected with
+// its field path
+func TestUrlPerformanceValidatorRejectsInvalidRegexes(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutate    func(spec *traefikofficerv1alpha1.UrlPerformanceSpec)
+		fieldPath string
+	}{
+		{
+			name:      "whitelist",
+			mutate:    func(spec *traefikofficerv1alpha1.UrlPerformanceSpec) { spec.WhitelistPathsRegex[0] = "^/api/(" },
+			fieldPath: "spec.whitelistPathsRegex[0]",
+		},
+		{
+			name:      "ignored",
+			mutate:    func(spec *traefikofficerv1alpha1.UrlPerformanceSpec) { spec.IgnoredPathsRegex[0] = "[invalid" },
+			fieldPath: "spec.ignoredPathsRegex[0]",
+		},
+		{
+			name:      "detailed histogram",
+			mutate:    func(spec *traefikofficerv1alpha1.UrlPerformanceSpec) { spec.DetailedHistogramPaths[0] = "*checkout" },
+			fieldPath: "spec.detailedHistogramPaths[0]",
+		},
+		{
+			name:      "URL pattern",
+			mutate:    func(spec *traefikofficerv1alpha1.UrlPerformanceSpec) { spec.URLPatterns[0].Pattern = `/users/(\d+` },
+			fieldPath: "spec.urlPatterns[0].pattern",
+		},
+	}
+
+	validator := &UrlPerformanceValidator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newValidationTestResource()
+			tt.mutate(&instance.Spec)
+
+			_, err := validator.ValidateCreate(context.Background(), instance)
+			if !apierrors.IsInvalid(err) {
+				t.Fatalf("Expected an Invalid error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.fieldPath) {
+				t.Errorf("Expected the error to name %s, got %v", tt.fieldPath, err)
+			}
+
+			if _, err := validator.ValidateUpdate(context.Background(), newValidationTestResource(), instance); !apierrors.IsInvalid(err) {
+				t.Errorf("Expected updates to be rejected too, got %v", err)
+			}
+		})
+	}
+}
+
+// TestUrlPerformanceValidatorRequiresExactlyOneTargetField tests that targetRef and targetRefs
+// are mutually exclusive and that one of them is required
+func TestUrlPerformanceValidatorRequiresExactlyOneTargetField(t *testing.T) {
+	validator := &UrlPerformanceValidator{}
+	refs := []traefikofficerv1alpha1.TargetReference{
+		{Kind: "Ingress", Name: "shop"},
+		{Kind: "Ingress", Name: "cart"},
+	}
+
+	instance := newValidationTestResource()
+	instance.Spec.TargetRef = nil
+	instance.Spec.TargetRefs = refs
+	if _, err := validator.ValidateCreate(context.Background(), instance); err != nil {
+		t.Errorf("Expected targetRefs alone to be admitted, got %v", err)
+	}
+
+	instance.Spec.TargetRefs = nil
+	_, err := validator.ValidateCreate(context.Background(), instance)
+	if !apierrors.IsInvalid(err) || !strings.Contains(err.Error(), "spec.targetRef") {
+		t.Errorf("Expected a missing target to be rejected, got %v", err)
+	}
+
+	instance = newValidationTestResource()
+	instance.Spec.TargetRefs = refs
+	_, err = validator.ValidateCreate(context.Background(), instance)
+	if !apierrors.IsInvalid(err) || !strings.Contains(err.Error(), "spec.targetRefs") {
+		t.Errorf("Expected both targetRef and targetRefs to be rejected, got %v", err)
+	}
+}
+
+// TestUrlPerformanceValidatorTargetSelector tests that a target selector is admitted alone and
+// rejected when combined with a named target or invalid
+func TestUrlPerformanceValidatorTargetSelector(t *testing.T) {
+	validator := &UrlPerformanceValidator{}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "storefront"}}
+
+	instance := newValidationTestResource()
+	instance.Spec.TargetRef = nil
+	instance.Spec.TargetSelector = selector
+	if _, err := validator.ValidateCreate(context.Background(), instance); err != nil {
+		t.Errorf("Expected a selector alone to be admitted, got %v", err)
+	}
+
+	instance = newValidationTestResource()
+	instance.Spec.TargetSelector = selector
+	_, err := validator.ValidateCreate(context.Background(), instance)
+	if !apierrors.IsInvalid(err) || !strings.Contains(err.Error(), "spec.targetSelector") {
+		t.Errorf("Expected a selector combined with targetRef to be rejected, got %v", err)
+	}
+
+	instance = newValidationTestResource()
+	instance.Spec.TargetRef = nil
+	instance.Spec.TargetSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "team", Operator: "Matches", Values: []string{"storefront"}},
+	}}
+	_, err = validator.ValidateCreate(context.Background(), instance)
+	if !apierrors.IsInvalid(err) || !strings.Contains(err.Error(), "spec.targetSelector") {
+		t.Errorf("Expected an invalid selector to be rejected, got %v", err)
+	}
+}
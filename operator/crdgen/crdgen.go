@@ -0,0 +1,120 @@
+// Package crdgen renders the UrlPerformance CustomResourceDefinition and the
+// deepcopy functions from the kubebuilder markers in the API type definitions
+// with controller-gen, so the installed CRD always matches what the running
+// operator expects.
+package crdgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/deepcopy"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/yaml"
+)
+
+const controllerToolsModule = "sigs.k8s.io/controller-tools"
+
+// versionAnnotationRegex matches the controller-gen version annotation of a generated CRD
+var versionAnnotationRegex = regexp.MustCompile(`(?m)^(\s+controller-gen\.kubebuilder\.io/version: ).*$`)
+
+// memoryOutput keeps the artifacts written by a generator in memory, keyed by path
+type memoryOutput map[string]*bytes.Buffer
+
+// Open implements genall.OutputRule
+func (o memoryOutput) Open(_ *loader.Package, path string) (io.WriteCloser, error) {
+	buf := &bytes.Buffer{}
+	o[path] = buf
+	return nopCloser{buf}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// run runs a controller-gen generator against the API package, e.g. "./api/v1alpha1", and returns
+// the artifacts it wrote
+func run(generator genall.Generator, apiPackage string) (memoryOutput, error) {
+	runtime, err := genall.Generators{&generator}.ForRoots(apiPackage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", apiPackage, err)
+	}
+	output := memoryOutput{}
+	var errs strings.Builder
+	runtime.OutputRules = genall.OutputRules{Default: output}
+	runtime.ErrorWriter = &errs
+	if runtime.Run() {
+		return nil, fmt.Errorf("failed to generate from %s: %s", apiPackage, strings.TrimSpace(errs.String()))
+	}
+	return output, nil
+}
+
+// GenerateYAML renders the CRD manifest of the API package exactly as
+// `controller-gen crd paths=<apiPackage>` does
+func GenerateYAML(apiPackage string) ([]byte, error) {
+	output, err := run(crd.Generator{}, apiPackage)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) != 1 {
+		return nil, fmt.Errorf("expected a single CRD in %s, got %d", apiPackage, len(output))
+	}
+	var manifest []byte
+	for _, buf := range output {
+		manifest = buf.Bytes()
+	}
+
+	// controller-gen annotates the CRD with its own module version, which is only known when it's
+	// the main module; report the controller-tools version this binary was built with instead
+	return versionAnnotationRegex.ReplaceAll(manifest, []byte("${1}"+controllerToolsVersion())), nil
+}
+
+// Generate renders the CRD of the API package
+func Generate(apiPackage string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	manifest, err := GenerateYAML(apiPackage)
+	if err != nil {
+		return nil, err
+	}
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := yaml.Unmarshal(manifest, &crd); err != nil {
+		return nil, fmt.Errorf("failed to parse generated CRD: %w", err)
+	}
+	return &crd, nil
+}
+
+// GenerateDeepCopy renders the zz_generated.deepcopy.go file of the API package exactly as
+// `controller-gen object paths=<apiPackage>` does
+func GenerateDeepCopy(apiPackage string) ([]byte, error) {
+	output, err := run(deepcopy.Generator{}, apiPackage)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := output["zz_generated.deepcopy.go"]
+	if !ok {
+		return nil, fmt.Errorf("no deepcopy functions generated for %s", apiPackage)
+	}
+	return source.Bytes(), nil
+}
+
+// controllerToolsVersion returns the version of the controller-tools module linked into the binary
+func controllerToolsVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == controllerToolsModule {
+				return dep.Version
+			}
+		}
+	}
+	return "(unknown)"
+}
@@ -0,0 +1,137 @@
+package crdgen
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+const apiPackage = "../api/v1alpha1"
+
+// TestGeneratePrinterColumns tests that the generated CRD carries the printcolumn markers
+func TestGeneratePrinterColumns(t *testing.T) {
+	crd, err := Generate(apiPackage)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if crd.Name != "urlperformances.traefikofficer.io" {
+		t.Errorf("Expected CRD name urlperformances.traefikofficer.io, got %s", crd.Name)
+	}
+	if len(crd.Spec.Names.ShortNames) != 1 || crd.Spec.Names.ShortNames[0] != "urlperf" {
+		t.Errorf("Expected short name urlperf, got %v", crd.Spec.Names.ShortNames)
+	}
+	if len(crd.Spec.Versions) != 1 {
+		t.Fatalf("Expected 1 version, got %d", len(crd.Spec.Versions))
+	}
+
+	version := crd.Spec.Versions[0]
+	if version.Subresources == nil || version.Subresources.Status == nil {
+		t.Error("Expected status subresource to be enabled")
+	}
+
+	expected := map[string]string{
+		"Target Kind": ".spec.targetRef.kind",
+		"Target Name": ".spec.targetRef.name",
+		"Namespace":   ".spec.targetRef.namespace",
+		"Enabled":     ".spec.enabled",
+		"Phase":       ".status.phase",
+		"Age":         ".metadata.creationTimestamp",
+	}
+	if len(version.AdditionalPrinterColumns) != len(expected) {
+		t.Errorf("Expected %d printer columns, got %d", len(expected), len(version.AdditionalPrinterColumns))
+	}
+	for _, column := range version.AdditionalPrinterColumns {
+		if path, ok := expected[column.Name]; !ok || path != column.JSONPath {
+			t.Errorf("Unexpected printer column %s with JSONPath %s", column.Name, column.JSONPath)
+		}
+	}
+}
+
+// TestGenerateValidationRules tests that kubebuilder validation markers end up in the schema
+func TestGenerateValidationRules(t *testing.T) {
+	crd, err := Generate(apiPackage)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+
+	collectNTop := spec.Properties["collectNTop"]
+	if collectNTop.Minimum == nil || *collectNTop.Minimum != 1 {
+		t.Errorf("Expected collectNTop minimum 1, got %v", collectNTop.Minimum)
+	}
+	if collectNTop.Maximum == nil || *collectNTop.Maximum != 1000 {
+		t.Errorf("Expected collectNTop maximum 1000, got %v", collectNTop.Maximum)
+	}
+	if collectNTop.Default == nil || string(collectNTop.Default.Raw) != "20" {
+		t.Errorf("Expected collectNTop default 20, got %v", collectNTop.Default)
+	}
+
+	kind := spec.Properties["targetRef"].Properties["kind"]
+	expectedKinds := []string{`"Ingress"`, `"IngressRoute"`, `"IngressRouteTCP"`, `"IngressRouteUDP"`, `"Auto"`}
+	if len(kind.Enum) != len(expectedKinds) {
+		t.Fatalf("Expected targetRef.kind enum %v, got %d values", expectedKinds, len(kind.Enum))
+	}
+	for i, expected := range expectedKinds {
+		if string(kind.Enum[i].Raw) != expected {
+			t.Errorf("Expected targetRef.kind enum[%d] %s, got %s", i, expected, kind.Enum[i].Raw)
+		}
+	}
+
+	if kind.Default == nil || string(kind.Default.Raw) != `"Auto"` {
+		t.Errorf("Expected targetRef.kind to default to Auto, got %v", kind.Default)
+	}
+
+	if len(spec.Required) != 0 {
+		t.Errorf("Expected targetRef to be optional now that targetRefs can replace it, got %v", spec.Required)
+	}
+	if len(spec.XValidations) != 1 || !strings.Contains(spec.XValidations[0].Rule, "has(self.targetRefs)") {
+		t.Errorf("Expected a spec rule requiring exactly one target field, got %v", spec.XValidations)
+	}
+	if minItems := spec.Properties["targetRefs"].MinItems; minItems == nil || *minItems != 1 {
+		t.Errorf("Expected targetRefs minItems 1, got %v", minItems)
+	}
+	if selector := spec.Properties["targetSelector"]; selector.XMapType == nil || *selector.XMapType != "atomic" ||
+		selector.Properties["matchLabels"].AdditionalProperties == nil {
+		t.Errorf("Expected targetSelector to use the atomic LabelSelector schema, got %+v", selector)
+	}
+}
+
+// TestGenerateYAMLMatchesCommittedCRD tests that the committed CRD manifest is in sync with the Go types
+func TestGenerateYAMLMatchesCommittedCRD(t *testing.T) {
+	generated, err := GenerateYAML(apiPackage)
+	if err != nil {
+		t.Fatalf("GenerateYAML() error = %v", err)
+	}
+	if !strings.HasPrefix(string(generated), "---\n") {
+		t.Error("Expected generated YAML to start with a document separator")
+	}
+
+	committed, err := os.ReadFile("../crd/bases/traefikofficer.io_urlperformances.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read committed CRD: %v", err)
+	}
+
+	if !bytes.Equal(generated, committed) {
+		t.Errorf("Committed CRD is out of date, run make generate-crd\ngot:\n%s", generated)
+	}
+}
+
+// TestGenerateDeepCopyMatchesCommittedFile tests that the committed deepcopy functions are in sync with the Go types
+func TestGenerateDeepCopyMatchesCommittedFile(t *testing.T) {
+	generated, err := GenerateDeepCopy(apiPackage)
+	if err != nil {
+		t.Fatalf("GenerateDeepCopy() error = %v", err)
+	}
+
+	committed, err := os.ReadFile("../api/v1alpha1/zz_generated.deepcopy.go")
+	if err != nil {
+		t.Fatalf("Failed to read committed deepcopy functions: %v", err)
+	}
+
+	if !bytes.Equal(generated, committed) {
+		t.Errorf("Committed deepcopy functions are out of date, run make generate\ngot:\n%s", generated)
+	}
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -11,11 +16,14 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	logger "github.com/sirupsen/logrus"
 
 	traefikofficerv1alpha1 "github.com/mithucste30/traefik-officer-operator/operator/api/v1alpha1"
 	"github.com/mithucste30/traefik-officer-operator/operator/controller"
+	"github.com/mithucste30/traefik-officer-operator/operator/crdgen"
 
 	// Import the pkg functions for log processing
 	logprocessing "github.com/mithucste30/traefik-officer-operator/pkg"
@@ -26,39 +34,89 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// errorWebhookURLEnv is the environment variable the error webhook URL defaults to, so secret
+// webhook URLs such as Slack's don't need to appear in the pod arguments
+const errorWebhookURLEnv = "TRAEFIK_OFFICER_ERROR_WEBHOOK_URL"
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(traefikofficerv1alpha1.AddToScheme(scheme))
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "gen-crd":
+			os.Exit(runGenerate("gen-crd", "CRD", crdgen.GenerateYAML, os.Args[2:]))
+		case "gen-deepcopy":
+			os.Exit(runGenerate("gen-deepcopy", "deepcopy functions", crdgen.GenerateDeepCopy, os.Args[2:]))
+		}
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var maxConcurrentReconciles int
+	var statusRefreshInterval time.Duration
+	var enableWebhooks bool
+	var webhookCertDir string
 
 	// Log processor flags
+	var configFile string
 	var logFile string
+	var logFormat string
 	var jsonLogs bool
 	var useK8s bool
 	var k8sNamespace string
 	var k8sContainer string
 	var k8sLabelSelector string
+	var k8sSinceSeconds int64
 	var enableLogProcessor bool
+	var routerProviders string
+	var exposeSourceMode bool
+	var podLabel bool
+	var logLevel string
+	var logOutputFormat string
 
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the controller and operator metrics endpoint binds to. Use 0 to disable it.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Maximum number of UrlPerformance resources reconciled in parallel")
+	flag.DurationVar(&statusRefreshInterval, "status-refresh-interval", 30*time.Second,
+		"How often the monitored paths and last scrape time of active UrlPerformance resources are refreshed. 0 disables refreshing")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the validating admission webhook rejecting UrlPerformance resources with invalid regexes")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "",
+		"Directory holding tls.crt and tls.key of the webhook server. Default: the controller-runtime default")
 
 	// Log processor flags
+	flag.StringVar(&configFile, "config-file", "",
+		"Path to a baseline log processor config file in JSON or YAML (TopNPaths, AccessLogFormat, ...)")
 	flag.StringVar(&logFile, "log-file", "", "Path to Traefik access log file (for file mode)")
-	flag.BoolVar(&jsonLogs, "json-logs", false, "Parse logs as JSON instead of common log format")
+	flag.StringVar(&logFormat, "log-format", logprocessing.LogFormatCLF,
+		"Access log format: clf, json or logfmt. The LogFormat of the config file applies while this is clf")
+	flag.BoolVar(&jsonLogs, "json-logs", false, "Deprecated: use -log-format=json")
 	flag.BoolVar(&useK8s, "use-k8s", false, "Read logs from Kubernetes pods instead of file")
-	flag.StringVar(&k8sNamespace, "k8s-namespace", "traefik", "Kubernetes namespace for Traefik pods")
+	flag.StringVar(&k8sNamespace, "k8s-namespace", "traefik", "Kubernetes namespace for Traefik pods, or a comma-separated list of namespaces")
 	flag.StringVar(&k8sContainer, "k8s-container", "traefik", "Container name in Traefik pods")
 	flag.StringVar(&k8sLabelSelector, "k8s-label-selector", "app.kubernetes.io/name=traefik", "Label selector for Traefik pods")
+	flag.Int64Var(&k8sSinceSeconds, "k8s-since-seconds", 0,
+		"Replay this many seconds of each Traefik pod's logs when its stream first starts. 0 only follows new lines")
 	flag.BoolVar(&enableLogProcessor, "enable-log-processor", false, "Enable embedded log processor")
+	flag.StringVar(&routerProviders, "router-providers", "",
+		"Additional Traefik providers to match routers from, as provider=kind pairs (e.g. 'file=IngressRoute,docker=Ingress')")
+
+	flag.BoolVar(&exposeSourceMode, "expose-source-mode", false,
+		"Expose the log source mode (file or k8s) of the embedded log processor on the traefik_officer_source_info metric")
+
+	flag.BoolVar(&podLabel, "pod-label", false,
+		"Count the requests per Traefik pod that logged them on traefik_officer_pod_requests_total. Multiplies the series by the Traefik replicas")
+	flag.StringVar(&logLevel, "log-level", "", "Level of the log processor logs: debug, info, warn or error. Default: info")
+	flag.StringVar(&logOutputFormat, "log-format-output", logprocessing.LogOutputText,
+		"Format of the operator and log processor logs, not the access logs: text or json")
 
 	opts := zap.Options{
 		Development: true,
@@ -66,18 +124,29 @@ func main() {
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	zapOpts := []zap.Opts{zap.UseFlagOptions(&opts)}
+	if logOutputFormat == logprocessing.LogOutputJSON {
+		zapOpts = append(zapOpts, zap.JSONEncoder())
+	}
+	ctrl.SetLogger(zap.New(zapOpts...))
 
 	// Enable logrus for pkg functions
-	logger.SetFormatter(&logger.TextFormatter{
-		FullTimestamp: true,
-	})
+	if err := logprocessing.ConfigureLogging(logOutputFormat, logLevel); err != nil {
+		setupLog.Error(err, "invalid logging flags")
+		os.Exit(1)
+	}
+
+	if enableLogProcessor {
+		serveLogProcessorMetrics()
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		Metrics:                metricsServerOptions(metricsAddr, enableLogProcessor),
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "traefik-officer-operator-lock",
+		WebhookServer:          webhook.NewServer(webhook.Options{CertDir: webhookCertDir}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -91,19 +160,47 @@ func main() {
 	if enableLogProcessor {
 		logprocessing.SetOperatorMode(true, configManager)
 		logger.Info("Operator mode enabled in log processor")
+
+		providers, err := logprocessing.ParseRouterProviders(routerProviders)
+		if err != nil {
+			setupLog.Error(err, "invalid router providers")
+			os.Exit(1)
+		}
+		for provider, kind := range providers {
+			logprocessing.RegisterRouterProvider(provider, kind)
+		}
+
+		if exposeSourceMode {
+			logprocessing.SetSourceMode(logprocessing.SourceModeFor(useK8s))
+		}
 	}
 
 	// Setup UrlPerformance controller
-	if err = (&controller.UrlPerformanceReconciler{
+	reconciler := &controller.UrlPerformanceReconciler{
 		Client:        mgr.GetClient(),
 		Log:           ctrl.Log.WithName("controllers").WithName("UrlPerformance"),
 		Scheme:        mgr.GetScheme(),
 		ConfigManager: configManager,
-	}).SetupWithManager(mgr); err != nil {
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		StatusRefreshInterval:   statusRefreshInterval,
+	}
+	if enableLogProcessor {
+		reconciler.TargetStats = logprocessing.NewTargetStatsProvider()
+		reconciler.MetricsResetter = logprocessing.NewTargetMetricsResetter()
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "UrlPerformance")
 		os.Exit(1)
 	}
 
+	if enableWebhooks {
+		if err = (&controller.UrlPerformanceValidator{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "UrlPerformance")
+			os.Exit(1)
+		}
+	}
+
 	// Add health check endpoints
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -115,35 +212,168 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Start log processor if enabled
+	ctx := ctrl.SetupSignalHandler()
+
+	// Start log processor if enabled; it stops together with the manager
+	logProcessorDone := make(chan struct{})
 	if enableLogProcessor {
-		go startLogProcessor(logFile, jsonLogs, useK8s, k8sNamespace, k8sContainer, k8sLabelSelector)
+		go func() {
+			defer close(logProcessorDone)
+			err := startLogProcessor(ctx, logProcessorOptions{
+				configFile:       configFile,
+				logFile:          logFile,
+				logFormat:        logFormat,
+				jsonLogs:         jsonLogs,
+				useK8s:           useK8s,
+				k8sNamespace:     k8sNamespace,
+				k8sContainer:     k8sContainer,
+				k8sLabelSelector: k8sLabelSelector,
+				k8sSinceSeconds:  k8sSinceSeconds,
+				podLabel:         podLabel,
+			})
+			if err != nil {
+				setupLog.Error(err, "embedded log processor failed")
+			}
+		}()
+	} else {
+		close(logProcessorDone)
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+	<-logProcessorDone
+}
+
+// metricsServerOptions returns the options of the manager's metrics server. With the embedded log
+// processor, it also serves the admin-guarded debug endpoints, reachable via port-forward.
+func metricsServerOptions(bindAddress string, enableLogProcessor bool) metricsserver.Options {
+	options := metricsserver.Options{BindAddress: bindAddress}
+	if enableLogProcessor {
+		options.ExtraHandlers = map[string]http.Handler{
+			// Explains why the log lines of a router are or aren't processed
+			"/debug/router": logprocessing.RouterDebugHandler(),
+			// Lists the compiled regexes of the active UrlPerformance configs
+			"/debug/patterns": logprocessing.PatternsDebugHandler(),
+			// Lists the runtime configs generated for the UrlPerformance targets
+			"/config": logprocessing.ConfigHandler(),
+		}
+	}
+	return options
+}
+
+// logProcessorOptions are the flags of the embedded log processor
+type logProcessorOptions struct {
+	configFile       string
+	logFile          string
+	logFormat        string
+	jsonLogs         bool
+	useK8s           bool
+	k8sNamespace     string
+	k8sContainer     string
+	k8sLabelSelector string
+	k8sSinceSeconds  int64
+	podLabel         bool
 }
 
-// startLogProcessor starts the embedded log processor
-func startLogProcessor(logFile string, jsonLogs bool, useK8s bool, k8sNamespace, k8sContainer, k8sLabelSelector string) {
+// topPathsUpdateInterval is how often the embedded log processor recomputes the top paths
+const topPathsUpdateInterval = 30 * time.Second
+
+// startLogProcessor runs the embedded log processor until ctx is cancelled. Routers are filtered and
+// configured by the UrlPerformance configs of the config manager passed to SetOperatorMode.
+func startLogProcessor(ctx context.Context, opts logProcessorOptions) error {
 	logger.Info("Starting embedded log processor")
 
-	// Import the log processing setup from pkg
-	// This will use the operator mode we enabled earlier
+	config, err := logprocessing.LoadConfig(opts.configFile)
+	if err != nil {
+		logger.Warnf("Failed to load log processor configuration: %v. Using default configuration.", err)
+	}
+	logFormat, err := logprocessing.ResolveLogFormat(opts.logFormat, opts.jsonLogs, config.LogFormat)
+	if err != nil {
+		return fmt.Errorf("invalid -log-format: %w", err)
+	}
+	if opts.jsonLogs {
+		logger.Warn("-json-logs is deprecated, use -log-format=json")
+	}
+	if err := logprocessing.InitLatencyHistograms(config.LatencyBuckets); err != nil {
+		return fmt.Errorf("failed to initialize latency histograms: %w", err)
+	}
+	logprocessing.SetPodLabel(opts.podLabel)
+	healthStaleness := time.Duration(config.HealthStalenessMinutes) * time.Minute
+	if err := logprocessing.SetHealthStaleness(healthStaleness, config.HealthStalenessMode); err != nil {
+		return fmt.Errorf("invalid HealthStalenessMode: %w", err)
+	}
+
+	if !opts.useK8s && opts.logFile == "" {
+		err := errors.New("either -log-file or -use-k8s is required for the embedded log processor")
+		logprocessing.UpdateHealthStatus("log_source", "error", err)
+		return err
+	}
+
+	logFileConfig := &logprocessing.LogFileConfig{
+		FileLocation: opts.logFile,
+		MaxFileBytes: 10,
+	}
+	k8sConfig := &logprocessing.K8SConfig{
+		Namespace:     opts.k8sNamespace,
+		ContainerName: opts.k8sContainer,
+		LabelSelector: opts.k8sLabelSelector,
+		SinceSeconds:  opts.k8sSinceSeconds,
+	}
+
+	logSource, err := logprocessing.CreateLogSource(opts.useK8s, logFileConfig, k8sConfig)
+	if err != nil {
+		logprocessing.UpdateHealthStatus("log_source", "error", err)
+		return fmt.Errorf("failed to create log source: %w", err)
+	}
+	defer func() {
+		if err := logSource.Close(); err != nil {
+			logprocessing.UpdateHealthStatus("log_source", "close_error", err)
+			logger.Errorf("Error closing log source: %v", err)
+		} else {
+			logprocessing.UpdateHealthStatus("log_source", "closed", nil)
+		}
+	}()
 
-	// TODO: Implement proper log source creation and processing
-	// For now, this is a placeholder to show where it would go
+	logprocessing.StartTopPathsUpdaterUntil(topPathsUpdateInterval, ctx.Done())
+	logprocessing.StartServiceRPSUpdater(time.Duration(config.ServiceRPSWindowSeconds)*time.Second, ctx.Done())
+	if config.GaugeStalenessMinutes > 0 {
+		staleness := time.Duration(config.GaugeStalenessMinutes) * time.Minute
+		logprocessing.StartGaugeStalenessSweeper(staleness, config.GaugeStalenessMode, ctx.Done())
+	}
+	logprocessing.UpdateHealthStatus("log_processor", "running", nil)
+
+	logprocessing.ProcessLogs(ctx, logSource, config, &opts.useK8s, logFileConfig, logFormat)
+
+	logprocessing.UpdateHealthStatus("log_processor", "stopped", nil)
+	logger.Info("Embedded log processor stopped")
+	return nil
+}
 
-	logger.Info("Log processor placeholder - implementation pending")
-	logger.Infof("Config: logFile=%s, jsonLogs=%v, useK8s=%v, namespace=%s, container=%s, selector=%s",
-		logFile, jsonLogs, useK8s, k8sNamespace, k8sContainer, k8sLabelSelector)
+// runGenerate implements the gen-crd and gen-deepcopy subcommands, which render the UrlPerformance
+// CRD and deepcopy functions from the Go types with controller-gen
+func runGenerate(name, artifact string, generate func(apiPackage string) ([]byte, error), args []string) int {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	apiPackage := fs.String("api", "./api/v1alpha1", "Go package of the UrlPerformance API types")
+	output := fs.String("output", "", "File to write the "+artifact+" to (default is stdout)")
+	_ = fs.Parse(args)
 
-	// The actual implementation would:
-	// 1. Create log source (file or k8s)
-	// 2. Start processing logs
-	// 3. Use IsOperatorMode() to check CRD configs
-	// 4. Filter and process based on UrlPerformance CRDs
+	generated, err := generate(*apiPackage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate %s: %v\n", artifact, err)
+		return 1
+	}
+
+	if *output == "" {
+		_, _ = os.Stdout.Write(generated)
+		return 0
+	}
+
+	if err := os.WriteFile(*output, generated, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %s to %s: %v\n", artifact, *output, err)
+		return 1
+	}
+	return 0
 }
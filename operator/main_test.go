@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/mithucste30/traefik-officer-operator/operator/controller"
+	logprocessing "github.com/mithucste30/traefik-officer-operator/pkg"
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// startMetricsServer starts the manager's metrics server as main does with the embedded log processor
+// and returns the URL of its /metrics endpoint
+func startMetricsServer(t *testing.T) string {
+	t.Helper()
+	registry := metrics.Registry
+	t.Cleanup(func() { metrics.Registry = registry })
+	serveLogProcessorMetrics()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	server, err := metricsserver.NewServer(metricsServerOptions(addr, true), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create metrics server: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = server.Start(ctx) }()
+
+	url := "http://" + addr + "/metrics"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			_ = resp.Body.Close()
+			return url
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Metrics server did not start: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// scrape returns the body of the metrics endpoint
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 scraping metrics, got %d: %s", resp.StatusCode, body)
+	}
+	return string(body)
+}
+
+// requestCount sums the traefik_officer_requests_total series of a router served on the metrics endpoint
+func requestCount(t *testing.T, url, router string) float64 {
+	t.Helper()
+	total := 0.0
+	for _, line := range strings.Split(scrape(t, url), "\n") {
+		if !strings.HasPrefix(line, "traefik_officer_requests_total{") || !strings.Contains(line, `service="`+router+`"`) {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[strings.LastIndex(line, " ")+1:], 64)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", line, err)
+		}
+		total += value
+	}
+	return total
+}
+
+// TestStartLogProcessor feeds access log lines through the embedded log processor and checks, on the
+// manager's metrics endpoint, that only routers with a registered UrlPerformance config produce metrics
+func TestStartLogProcessor(t *testing.T) {
+	metricsURL := startMetricsServer(t)
+
+	configManager := controller.NewConfigManager()
+	configManager.UpdateConfig(&shared.RuntimeConfig{
+		Key:        shared.ConfigKey("shop", "checkout"),
+		Namespace:  "shop",
+		TargetName: "checkout",
+		TargetKind: "Ingress",
+		Enabled:    true,
+	})
+	logprocessing.SetOperatorMode(true, configManager)
+	defer logprocessing.SetOperatorMode(false, nil)
+
+	checkoutRouter := "websecure-shop-checkout-a457d08d5820f79b3e08@kubernetes"
+	unmanagedRouter := "websecure-shop-unmanaged-a457d08d5820f79b3e08@kubernetes"
+
+	lines := make([]string, 0)
+	for i, router := range []string{checkoutRouter, checkoutRouter, checkoutRouter, unmanagedRouter} {
+		lines = append(lines, fmt.Sprintf(
+			`10.0.0.1 - - [01/Jan/2024:12:00:00 +0000] "GET /api/cart HTTP/1.1" 200 512 "-" "curl/8.0" %d "%s" "http://10.0.0.5:8080" 12ms`,
+			i+1, router))
+	}
+	logFile := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(logFile, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write access log: %v", err)
+	}
+
+	// Counters are process-wide, so compare against the counts before processing
+	checkoutBefore := requestCount(t, metricsURL, checkoutRouter)
+	unmanagedBefore := requestCount(t, metricsURL, unmanagedRouter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- startLogProcessor(ctx, logProcessorOptions{logFile: logFile})
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for requestCount(t, metricsURL, checkoutRouter)-checkoutBefore < 3 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got := requestCount(t, metricsURL, checkoutRouter) - checkoutBefore; got != 3 {
+		t.Errorf("Expected 3 requests for shop/checkout, got %v", got)
+	}
+	if got := requestCount(t, metricsURL, unmanagedRouter) - unmanagedBefore; got != 0 {
+		t.Errorf("Expected no requests for a router without a UrlPerformance, got %v", got)
+	}
+
+	if got := strings.Count(scrape(t, metricsURL), "# TYPE go_goroutines "); got != 1 {
+		t.Errorf("Expected the Go runtime metrics of both registries to be served once, got %d", got)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("startLogProcessor() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("startLogProcessor did not return after the context was cancelled")
+	}
+}
+
+// TestStartLogProcessorRequiresSource tests that a missing log source is reported instead of tailing nothing
+func TestStartLogProcessorRequiresSource(t *testing.T) {
+	if err := startLogProcessor(context.Background(), logProcessorOptions{}); err == nil {
+		t.Error("Expected an error without -log-file or -use-k8s")
+	}
+}
+
+// TestMetricsServerOptions tests that the debug endpoints of the embedded log processor, which read the
+// operator's runtime configs, are served by the manager's metrics server
+func TestMetricsServerOptions(t *testing.T) {
+	if options := metricsServerOptions(":8080", false); len(options.ExtraHandlers) != 0 {
+		t.Errorf("Expected no extra handlers without the log processor, got %v", options.ExtraHandlers)
+	}
+
+	configManager := controller.NewConfigManager()
+	configManager.UpdateConfig(&shared.RuntimeConfig{
+		Key:            shared.ConfigKey("shop", "checkout"),
+		Namespace:      "shop",
+		TargetName:     "checkout",
+		Enabled:        true,
+		WhitelistRegex: []*regexp.Regexp{regexp.MustCompile(`^/api/`)},
+	})
+	logprocessing.SetOperatorMode(true, configManager)
+	defer logprocessing.SetOperatorMode(false, nil)
+
+	options := metricsServerOptions(":8080", true)
+	for _, path := range []string{"/debug/router", "/debug/patterns", "/config"} {
+		if options.ExtraHandlers[path] == nil {
+			t.Errorf("Expected a handler for %s", path)
+		}
+	}
+
+	handler := options.ExtraHandlers["/debug/patterns"]
+	if handler == nil {
+		return
+	}
+	req := httptest.NewRequest("GET", "/debug/patterns", nil)
+	req.RemoteAddr = "127.0.0.1:43210"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var patterns map[string]struct {
+		Whitelist []string `json:"whitelist"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&patterns); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got := patterns["shop/checkout"].Whitelist; len(got) != 1 || got[0] != "^/api/" {
+		t.Errorf("Expected the whitelist of shop/checkout, got %v", patterns)
+	}
+
+	req = httptest.NewRequest("GET", "/config", nil)
+	req.RemoteAddr = "127.0.0.1:43210"
+	w = httptest.NewRecorder()
+	options.ExtraHandlers["/config"].ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for /config, got %d", w.Code)
+	}
+	var configs []struct {
+		Key            string   `json:"key"`
+		WhitelistRegex []string `json:"whitelistRegex"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&configs); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(configs) != 1 || configs[0].Key != "shop/checkout" || len(configs[0].WhitelistRegex) != 1 {
+		t.Errorf("Expected the config of shop/checkout, got %+v", configs)
+	}
+
+	// The operator has no admin token, so only loopback requests are served
+	req = httptest.NewRequest("GET", "/config", nil)
+	req.RemoteAddr = "10.0.0.8:43210"
+	w = httptest.NewRecorder()
+	options.ExtraHandlers["/config"].ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Errorf("Expected /config to refuse a remote request, got status %d", w.Code)
+	}
+}
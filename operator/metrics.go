@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// logProcessorRegistry adds the metrics of the embedded log processor, which pkg registers in the
+// default Prometheus registry, to the metrics gathered by the manager's metrics server, which only
+// serves metrics.Registry
+type logProcessorRegistry struct {
+	prometheus.Registerer
+	operator  prometheus.Gatherer
+	processor prometheus.Gatherer
+}
+
+// Gather implements prometheus.Gatherer. Families both registries have, such as the Go runtime
+// metrics, are served from the operator's registry.
+func (r logProcessorRegistry) Gather() ([]*dto.MetricFamily, error) {
+	families, operatorErr := r.operator.Gather()
+	seen := make(map[string]bool, len(families))
+	for _, family := range families {
+		seen[family.GetName()] = true
+	}
+
+	processorFamilies, processorErr := r.processor.Gather()
+	for _, family := range processorFamilies {
+		if !seen[family.GetName()] {
+			families = append(families, family)
+		}
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families, errors.Join(operatorErr, processorErr)
+}
+
+// serveLogProcessorMetrics makes the manager's metrics server serve the metrics of the embedded log
+// processor as well. It must be called before the manager starts.
+func serveLogProcessorMetrics() {
+	if _, ok := metrics.Registry.(logProcessorRegistry); ok {
+		return
+	}
+	metrics.Registry = logProcessorRegistry{
+		Registerer: metrics.Registry,
+		operator:   metrics.Registry,
+		processor:  prometheus.DefaultGatherer,
+	}
+}
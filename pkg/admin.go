@@ -0,0 +1,124 @@
+package logprocessing
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// AdminTokenEnv is the environment variable holding the token for admin and debug endpoints
+const AdminTokenEnv = "TRAEFIK_OFFICER_ADMIN_TOKEN"
+
+var (
+	adminToken      string
+	adminTokenMutex sync.RWMutex
+)
+
+// SetAdminToken sets the bearer token required by admin and debug endpoints.
+// When no token is set, those endpoints only accept requests from loopback addresses.
+func SetAdminToken(token string) {
+	adminTokenMutex.Lock()
+	defer adminTokenMutex.Unlock()
+	adminToken = token
+}
+
+// adminGuard restricts a handler to callers presenting the admin token, or to loopback
+// callers when no token is configured
+func adminGuard(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminTokenMutex.RLock()
+		token := adminToken
+		adminTokenMutex.RUnlock()
+
+		if token != "" {
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		} else if !isLoopbackRequest(r) {
+			logger.Debugf("Rejected admin request to %s from %s", r.URL.Path, r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// isLoopbackRequest reports whether the request originates from a loopback address
+func isLoopbackRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// compiledPatterns lists the source strings of the compiled regexes of a runtime config
+type compiledPatterns struct {
+	Whitelist   []string             `json:"whitelist"`
+	Ignored     []string             `json:"ignored"`
+	URLPatterns []compiledURLPattern `json:"urlPatterns"`
+	MergePaths  []string             `json:"mergePaths"`
+}
+
+type compiledURLPattern struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// debugPatternsHandler returns, per active config key, the compiled regexes and merge prefixes
+func debugPatternsHandler(w http.ResponseWriter, r *http.Request) {
+	response := make(map[string]compiledPatterns)
+
+	operatorConfig.mu.RLock()
+	cm := operatorConfig.configManager
+	operatorConfig.mu.RUnlock()
+
+	if cm != nil {
+		// GetAllConfigs reads under the ConfigManager's lock; configs are replaced, never mutated
+		for _, config := range cm.GetAllConfigs() {
+			patterns := compiledPatterns{
+				Whitelist:   make([]string, 0, len(config.WhitelistRegex)),
+				Ignored:     make([]string, 0, len(config.IgnoredRegex)),
+				URLPatterns: make([]compiledURLPattern, 0, len(config.URLPatterns)),
+				MergePaths:  append([]string{}, config.MergePaths...),
+			}
+			for _, regex := range config.WhitelistRegex {
+				if regex != nil {
+					patterns.Whitelist = append(patterns.Whitelist, regex.String())
+				}
+			}
+			for _, regex := range config.IgnoredRegex {
+				if regex != nil {
+					patterns.Ignored = append(patterns.Ignored, regex.String())
+				}
+			}
+			for _, p := range config.URLPatterns {
+				if p.Pattern != nil {
+					patterns.URLPatterns = append(patterns.URLPatterns, compiledURLPattern{
+						Pattern:     p.Pattern.String(),
+						Replacement: p.Replacement,
+					})
+				}
+			}
+			response[config.Key] = patterns
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// PatternsDebugHandler returns the admin-guarded /debug/patterns handler, for servers other than
+// ServeProm's such as the operator's metrics server
+func PatternsDebugHandler() http.Handler {
+	return adminGuard(debugPatternsHandler)
+}
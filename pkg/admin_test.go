@@ -0,0 +1,175 @@
+package logprocessing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// TestAdminGuard tests access control on admin and debug endpoints
+func TestAdminGuard(t *testing.T) {
+	defer SetAdminToken("")
+
+	handler := adminGuard(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		token      string
+		remoteAddr string
+		authHeader string
+		expectCode int
+	}{
+		{
+			name:       "no token allows loopback",
+			remoteAddr: "127.0.0.1:5555",
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "no token allows IPv6 loopback",
+			remoteAddr: "[::1]:5555",
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "no token rejects remote callers",
+			remoteAddr: "10.0.0.8:5555",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			name:       "token required even from loopback",
+			token:      "s3cret",
+			remoteAddr: "127.0.0.1:5555",
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong token rejected",
+			token:      "s3cret",
+			remoteAddr: "10.0.0.8:5555",
+			authHeader: "Bearer nope",
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "valid token accepted from remote",
+			token:      "s3cret",
+			remoteAddr: "10.0.0.8:5555",
+			authHeader: "Bearer s3cret",
+			expectCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetAdminToken(tt.token)
+
+			req := httptest.NewRequest("GET", "/debug/patterns", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.expectCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectCode, w.Code)
+			}
+		})
+	}
+}
+
+// patternsConfigManager is a ConfigManager returning a fixed set of configs
+type patternsConfigManager struct {
+	configs []*shared.RuntimeConfig
+}
+
+func (m *patternsConfigManager) GetConfig(key string) (*shared.RuntimeConfig, bool) {
+	for _, config := range m.configs {
+		if config.Key == key {
+			return config, true
+		}
+	}
+	return nil, false
+}
+
+func (m *patternsConfigManager) GetAllConfigs() []*shared.RuntimeConfig {
+	return m.configs
+}
+
+// TestDebugPatternsHandler tests rendering of compiled regexes per config key
+func TestDebugPatternsHandler(t *testing.T) {
+	// Save original state
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+
+	operatorConfig = &OperatorModeConfig{
+		enabled: true,
+		configManager: &patternsConfigManager{configs: []*shared.RuntimeConfig{
+			{
+				Key:            "shop-api",
+				WhitelistRegex: []*regexp.Regexp{regexp.MustCompile(`^/api/.*`)},
+				IgnoredRegex:   []*regexp.Regexp{regexp.MustCompile(`\.css$`), nil},
+				MergePaths:     []string{"/static/"},
+				URLPatterns: []shared.URLPattern{
+					{Pattern: regexp.MustCompile(`/users/\d+`), Replacement: "/users/{id}"},
+				},
+			},
+		}},
+	}
+
+	req := httptest.NewRequest("GET", "/debug/patterns", nil)
+	w := httptest.NewRecorder()
+	debugPatternsHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]compiledPatterns
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	patterns, ok := response["shop-api"]
+	if !ok {
+		t.Fatalf("Expected patterns for shop-api, got %v", response)
+	}
+	if len(patterns.Whitelist) != 1 || patterns.Whitelist[0] != `^/api/.*` {
+		t.Errorf("Unexpected whitelist: %v", patterns.Whitelist)
+	}
+	if len(patterns.Ignored) != 1 || patterns.Ignored[0] != `\.css$` {
+		t.Errorf("Unexpected ignored patterns: %v", patterns.Ignored)
+	}
+	if len(patterns.URLPatterns) != 1 || patterns.URLPatterns[0].Pattern != `/users/\d+` || patterns.URLPatterns[0].Replacement != "/users/{id}" {
+		t.Errorf("Unexpected URL patterns: %v", patterns.URLPatterns)
+	}
+	if len(patterns.MergePaths) != 1 || patterns.MergePaths[0] != "/static/" {
+		t.Errorf("Unexpected merge paths: %v", patterns.MergePaths)
+	}
+}
+
+// TestDebugPatternsHandlerWithoutConfigManager tests the response outside operator mode
+func TestDebugPatternsHandlerWithoutConfigManager(t *testing.T) {
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+	operatorConfig = &OperatorModeConfig{}
+
+	req := httptest.NewRequest("GET", "/debug/patterns", nil)
+	w := httptest.NewRecorder()
+	debugPatternsHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if body := w.Body.String(); body != "{}\n" {
+		t.Errorf("Expected empty object, got %q", body)
+	}
+}
@@ -0,0 +1,265 @@
+package logprocessing
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchFlushLines    = 500
+	defaultBatchFlushInterval = time.Second
+)
+
+// MetricsBatching configures batched endpoint stat updates
+type MetricsBatching struct {
+	// Enabled accumulates endpoint stats per processing loop and merges them periodically,
+	// instead of taking the endpointStats lock for every line
+	Enabled bool `json:"Enabled"`
+	// FlushLines is the number of lines after which pending stats are merged
+	FlushLines int `json:"FlushLines"`
+	// FlushIntervalMs is the maximum time in milliseconds pending stats are held before being merged
+	FlushIntervalMs int `json:"FlushIntervalMs"`
+}
+
+// endpointStatDelta holds the stat changes of one endpoint accumulated between two flushes
+type endpointStatDelta struct {
+	service          string
+	endpoint         string
+	requests         int64
+	totalDuration    float64
+	maxDuration      float64
+	errorCount       int64
+	clientErrorCount int64
+	serverErrorCount int64
+	// methods partitions the latency of the requests by HTTP method
+	methods map[string]*methodLatencyDelta
+	// durations are kept individually for the latency quantile estimator
+	durations []float64
+}
+
+// methodLatencyDelta holds the latency changes of the requests made with one HTTP method
+type methodLatencyDelta struct {
+	requests      int64
+	totalDuration float64
+	maxDuration   float64
+}
+
+// add folds a single request into the delta
+func (d *endpointStatDelta) add(method string, duration float64, status int) {
+	if d.methods == nil {
+		d.methods = make(map[string]*methodLatencyDelta)
+	}
+	methodDelta := d.methods[method]
+	if methodDelta == nil {
+		methodDelta = &methodLatencyDelta{}
+		d.methods[method] = methodDelta
+	}
+	methodDelta.requests++
+	methodDelta.totalDuration += duration
+	if duration > methodDelta.maxDuration {
+		methodDelta.maxDuration = duration
+	}
+
+	d.requests++
+	d.totalDuration += duration
+	d.durations = append(d.durations, duration)
+	if duration > d.maxDuration {
+		d.maxDuration = duration
+	}
+	if status >= 400 {
+		d.errorCount++
+		if status >= 500 {
+			d.serverErrorCount++
+		} else {
+			d.clientErrorCount++
+		}
+	}
+}
+
+// MetricsBatcher accumulates endpoint stat updates of a single processing loop and merges
+// them into endpointStats every FlushLines lines or FlushInterval, whichever comes first.
+// Derived gauges (error rates, average and max latency) are refreshed on each flush.
+type MetricsBatcher struct {
+	flushLines int
+
+	// mu guards pending and lines; it is only contended between the owning loop and the ticker
+	mu      sync.Mutex
+	pending map[string]*endpointStatDelta
+	lines   int
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMetricsBatcher creates a batcher and starts its periodic flush.
+// Non-positive arguments fall back to the defaults.
+func NewMetricsBatcher(flushLines int, flushInterval time.Duration) *MetricsBatcher {
+	if flushLines <= 0 {
+		flushLines = defaultBatchFlushLines
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultBatchFlushInterval
+	}
+
+	b := &MetricsBatcher{
+		flushLines: flushLines,
+		pending:    make(map[string]*endpointStatDelta),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.Flush()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+
+	return b
+}
+
+// newMetricsBatcherFromConfig returns a batcher for the given settings, or nil when batching is disabled
+func newMetricsBatcherFromConfig(cfg MetricsBatching) *MetricsBatcher {
+	if !cfg.Enabled {
+		return nil
+	}
+	return NewMetricsBatcher(cfg.FlushLines, time.Duration(cfg.FlushIntervalMs)*time.Millisecond)
+}
+
+// record adds a request for the given endpoint and flushes when FlushLines is reached
+func (b *MetricsBatcher) record(key, service, endpoint, method string, duration float64, status int) {
+	b.mu.Lock()
+	delta := b.pending[key]
+	if delta == nil {
+		delta = &endpointStatDelta{service: service, endpoint: endpoint}
+		b.pending[key] = delta
+	}
+	delta.add(method, duration, status)
+	b.lines++
+	full := b.lines >= b.flushLines
+	b.mu.Unlock()
+
+	if full {
+		b.Flush()
+	}
+}
+
+// Flush merges all pending stats into endpointStats
+func (b *MetricsBatcher) Flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string]*endpointStatDelta, len(pending))
+	b.lines = 0
+	b.mu.Unlock()
+
+	if len(pending) > 0 {
+		mergeEndpointStatDeltas(pending)
+	}
+}
+
+// Close stops the periodic flush and merges any remaining stats. It is safe to call more than once.
+func (b *MetricsBatcher) Close() {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+		<-b.done
+	})
+	b.Flush()
+}
+
+// mergeEndpointStatDeltas applies the deltas to endpointStats under a single lock acquisition
+// and refreshes the derived gauges from a consistent snapshot of each stat
+func mergeEndpointStatDeltas(deltas map[string]*endpointStatDelta) {
+	snapshots := make(map[string]EndpointStat, len(deltas))
+	quantiles := make(map[string][]float64)
+	methodLatencies := make(map[string]map[string]MethodLatency)
+	revived := make(map[string]bool)
+	now := time.Now()
+
+	topPathsMutex.RLock()
+	topPaths := make(map[string]bool)
+	for key, delta := range deltas {
+		topPaths[key] = topPathsPerService[delta.service][key]
+	}
+	topPathsMutex.RUnlock()
+
+	endpointStatsMutex.Lock()
+	for key, delta := range deltas {
+		stat := endpointStats[key]
+		if stat == nil {
+			stat = &EndpointStat{}
+			endpointStats[key] = stat
+		}
+		stat.TotalRequests += delta.requests
+		stat.TotalDuration += delta.totalDuration
+		if delta.requests > 0 {
+			stat.addToMean(delta.requests, delta.totalDuration/float64(delta.requests))
+		}
+		if delta.maxDuration > stat.MaxDuration {
+			stat.MaxDuration = delta.maxDuration
+		}
+		for _, duration := range delta.durations {
+			stat.observeLatency(duration)
+		}
+		methods := make(map[string]MethodLatency, len(delta.methods))
+		for method, methodDelta := range delta.methods {
+			methods[method] = stat.observeMethodLatency(method, methodDelta.requests,
+				methodDelta.totalDuration/float64(methodDelta.requests), methodDelta.maxDuration)
+		}
+		if topPaths[key] {
+			quantiles[key] = stat.latencyQuantileValues()
+			methodLatencies[key] = methods
+		}
+		stat.LastSeen = now
+		if staleEndpoints[key] {
+			revived[key] = true
+			delete(staleEndpoints, key)
+		}
+		stat.ErrorCount += delta.errorCount
+		stat.ClientErrorCount += delta.clientErrorCount
+		stat.ServerErrorCount += delta.serverErrorCount
+		snapshots[key] = *stat
+	}
+	endpointStatsMutex.Unlock()
+
+	for key, delta := range deltas {
+		stat := snapshots[key]
+		if stat.TotalRequests == 0 {
+			continue
+		}
+		namespace, ingress := endpointLabels(delta.service)
+
+		if revived[key] {
+			publishEndpointErrorRates(namespace, ingress, delta.endpoint, stat)
+		}
+		if delta.errorCount > 0 {
+			endpointErrorRate.WithLabelValues(namespace, ingress, delta.endpoint).
+				Set(float64(stat.ErrorCount) / float64(stat.TotalRequests))
+		}
+		if delta.serverErrorCount > 0 {
+			endpointServerErrorRate.WithLabelValues(namespace, ingress, delta.endpoint).
+				Set(float64(stat.ServerErrorCount) / float64(stat.TotalRequests))
+		}
+		if delta.clientErrorCount > 0 {
+			endpointClientErrorRate.WithLabelValues(namespace, ingress, delta.endpoint).
+				Set(float64(stat.ClientErrorCount) / float64(stat.TotalRequests))
+		}
+
+		if topPaths[key] {
+			endpointAvgLatency.WithLabelValues(namespace, ingress, delta.endpoint).
+				Set(stat.MeanDuration)
+			endpointMaxLatency.WithLabelValues(namespace, ingress, delta.endpoint).Set(stat.MaxDuration)
+			for method, latency := range methodLatencies[key] {
+				publishMethodLatency(namespace, ingress, delta.endpoint, method, latency)
+			}
+			publishLatencyQuantiles(namespace, ingress, delta.endpoint, quantiles[key])
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package logprocessing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// resetEndpointStats swaps in empty endpoint stats and top paths for the duration of a test
+func resetEndpointStats(t testing.TB) {
+	endpointStatsMutex.Lock()
+	oldEndpointStats := endpointStats
+	endpointStats = make(map[string]*EndpointStat)
+	oldStaleEndpoints := staleEndpoints
+	staleEndpoints = make(map[string]bool)
+	endpointStatsMutex.Unlock()
+
+	topPathsMutex.Lock()
+	oldTopPaths := topPathsPerService
+	topPathsPerService = make(map[string]map[string]bool)
+	topPathsMutex.Unlock()
+
+	serviceEndpointsMutex.Lock()
+	oldServiceEndpoints := serviceEndpoints
+	serviceEndpoints = make(map[string]map[string]struct{})
+	serviceEndpointsMutex.Unlock()
+
+	t.Cleanup(func() {
+		serviceEndpointsMutex.Lock()
+		serviceEndpoints = oldServiceEndpoints
+		serviceEndpointsMutex.Unlock()
+
+		endpointStatsMutex.Lock()
+		endpointStats = oldEndpointStats
+		staleEndpoints = oldStaleEndpoints
+		endpointStatsMutex.Unlock()
+
+		topPathsMutex.Lock()
+		topPathsPerService = oldTopPaths
+		topPathsMutex.Unlock()
+	})
+}
+
+// TestMetricsBatcherNoDroppedCounts tests that concurrent batchers merge every recorded request
+func TestMetricsBatcherNoDroppedCounts(t *testing.T) {
+	resetEndpointStats(t)
+
+	const (
+		workers        = 8
+		linesPerWorker = 1003
+	)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A long interval makes line-count flushes and the final Close flush do all the work
+			batcher := NewMetricsBatcher(7, time.Hour)
+			defer batcher.Close()
+
+			for i := 0; i < linesPerWorker; i++ {
+				status := 200
+				switch i % 10 {
+				case 0:
+					status = 404
+				case 1:
+					status = 503
+				}
+				entry := &traefikLogConfig{
+					RouterName:    "batch-router",
+					RequestMethod: "GET",
+					RequestPath:   "/api/batch",
+					OriginStatus:  status,
+					Duration:      float64(i % 50),
+				}
+				recordMetrics(entry, []URLPattern{}, nil, batcher)
+			}
+		}()
+	}
+	wg.Wait()
+
+	endpointStatsMutex.RLock()
+	stat := endpointStats["batch-router:/api/batch"]
+	endpointStatsMutex.RUnlock()
+
+	if stat == nil {
+		t.Fatal("Expected stats for batch-router:/api/batch")
+	}
+
+	// Lines with i%10 == 0 and i%10 == 1 for i in [0, 1003)
+	expectedClientErrors := int64(workers * 101)
+	expectedServerErrors := int64(workers * 101)
+
+	if stat.TotalRequests != workers*linesPerWorker {
+		t.Errorf("Expected TotalRequests = %d, got %d", workers*linesPerWorker, stat.TotalRequests)
+	}
+	if stat.ClientErrorCount != expectedClientErrors {
+		t.Errorf("Expected ClientErrorCount = %d, got %d", expectedClientErrors, stat.ClientErrorCount)
+	}
+	if stat.ServerErrorCount != expectedServerErrors {
+		t.Errorf("Expected ServerErrorCount = %d, got %d", expectedServerErrors, stat.ServerErrorCount)
+	}
+	if stat.ErrorCount != expectedClientErrors+expectedServerErrors {
+		t.Errorf("Expected ErrorCount = %d, got %d", expectedClientErrors+expectedServerErrors, stat.ErrorCount)
+	}
+	if stat.MaxDuration != 0.049 {
+		t.Errorf("Expected MaxDuration = 0.049, got %v", stat.MaxDuration)
+	}
+}
+
+// TestMetricsBatcherIntervalFlush tests that pending stats are merged after the flush interval
+func TestMetricsBatcherIntervalFlush(t *testing.T) {
+	resetEndpointStats(t)
+
+	batcher := NewMetricsBatcher(1000, 20*time.Millisecond)
+	defer batcher.Close()
+
+	batcher.record("interval-router:/", "interval-router", "/", "GET", 0.1, 200)
+
+	endpointStatsMutex.RLock()
+	_, mergedEarly := endpointStats["interval-router:/"]
+	endpointStatsMutex.RUnlock()
+	if mergedEarly {
+		t.Fatal("Expected stats to stay pending before the flush interval")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		endpointStatsMutex.RLock()
+		stat := endpointStats["interval-router:/"]
+		var requests int64
+		if stat != nil {
+			requests = stat.TotalRequests
+		}
+		endpointStatsMutex.RUnlock()
+
+		if requests == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("Expected pending stats to be flushed by the interval")
+}
+
+// TestProcessLogsBatchedFlushOnClose tests that ProcessLogs flushes pending stats when its source closes
+func TestProcessLogsBatchedFlushOnClose(t *testing.T) {
+	resetEndpointStats(t)
+
+	lines := make(chan LogLine, 10)
+	for i := 0; i < 5; i++ {
+		lines <- LogLine{
+			Text: `{"RouterName":"shop-checkout-router","RequestMethod":"GET","RequestPath":"/cart","OriginStatus":500,"Duration":12}`,
+			Time: time.Now(),
+		}
+	}
+	close(lines)
+
+	config := TraefikOfficerConfig{
+		AllowedServices: []TraefikService{{Name: "checkout", Namespace: "shop"}},
+		URLPatterns:     []URLPattern{},
+		MetricsBatching: MetricsBatching{Enabled: true, FlushLines: 1000, FlushIntervalMs: 3600000},
+	}
+	useK8s := true // Disable log rotation
+
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, config, &useK8s, nil, LogFormatJSON)
+
+	endpointStatsMutex.RLock()
+	stat := endpointStats["shop-checkout-router:/cart"]
+	endpointStatsMutex.RUnlock()
+
+	if stat == nil || stat.TotalRequests != 5 || stat.ServerErrorCount != 5 {
+		t.Errorf("Expected 5 requests with 5 server errors after shutdown flush, got %+v", stat)
+	}
+}
+
+// BenchmarkEndpointStatsUpdate compares per-line and batched endpoint stat updates under contention
+func BenchmarkEndpointStatsUpdate(b *testing.B) {
+	newEntry := func(i int) *traefikLogConfig {
+		return &traefikLogConfig{
+			RouterName:    "bench-router",
+			RequestMethod: "GET",
+			RequestPath:   fmt.Sprintf("/api/items/%d", i%16),
+			OriginStatus:  200,
+			Duration:      float64(i % 100),
+		}
+	}
+
+	b.Run("per-line", func(b *testing.B) {
+		resetEndpointStats(b)
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				recordMetrics(newEntry(i), nil, nil, nil)
+				i++
+			}
+		})
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		resetEndpointStats(b)
+		b.RunParallel(func(pb *testing.PB) {
+			batcher := NewMetricsBatcher(defaultBatchFlushLines, defaultBatchFlushInterval)
+			defer batcher.Close()
+			i := 0
+			for pb.Next() {
+				recordMetrics(newEntry(i), nil, nil, batcher)
+				i++
+			}
+		})
+	})
+}
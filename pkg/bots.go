@@ -0,0 +1,95 @@
+package logprocessing
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultBotUserAgentPatterns match the User-Agents of common crawlers and link preview fetchers
+var defaultBotUserAgentPatterns = []string{
+	`(?i)bot\b`,
+	`(?i)crawl`,
+	`(?i)spider`,
+	`(?i)slurp`,
+	`(?i)facebookexternalhit`,
+	`(?i)mediapartners-google`,
+	`(?i)bingpreview`,
+}
+
+var (
+	// botUserAgentRegexes classify requests as crawler traffic, compiled once by SetBotUserAgentPatterns
+	botUserAgentRegexes      = mustCompileAll(defaultBotUserAgentPatterns)
+	botUserAgentRegexesMutex sync.RWMutex
+
+	// botRequests is built by buildMetrics
+	botRequests *prometheus.CounterVec
+)
+
+// SetBotUserAgentPatterns replaces the crawler User-Agent patterns. A nil list restores the
+// defaults and an empty list disables bot classification.
+func SetBotUserAgentPatterns(patterns []string) error {
+	regexes, err := compileBotUserAgentPatterns(patterns)
+	if err != nil {
+		return err
+	}
+	setBotUserAgentRegexes(regexes)
+	return nil
+}
+
+// compileBotUserAgentPatterns compiles the crawler User-Agent patterns, the defaults for a nil list
+func compileBotUserAgentPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	if patterns == nil {
+		patterns = defaultBotUserAgentPatterns
+	}
+
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling bot User-Agent pattern %q: %w", pattern, err)
+		}
+		regexes = append(regexes, regex)
+	}
+	return regexes, nil
+}
+
+// setBotUserAgentRegexes replaces the crawler User-Agent regexes with compiled ones
+func setBotUserAgentRegexes(regexes []*regexp.Regexp) {
+	botUserAgentRegexesMutex.Lock()
+	defer botUserAgentRegexesMutex.Unlock()
+	botUserAgentRegexes = regexes
+}
+
+// isBotUserAgent reports whether the User-Agent matches one of the crawler patterns
+func isBotUserAgent(userAgent string) bool {
+	if userAgent == "" {
+		return false
+	}
+
+	botUserAgentRegexesMutex.RLock()
+	defer botUserAgentRegexesMutex.RUnlock()
+	for _, regex := range botUserAgentRegexes {
+		if regex.MatchString(userAgent) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordBotRequest counts the request against its service when it comes from a crawler
+func recordBotRequest(entry *traefikLogConfig) {
+	if isBotUserAgent(entry.UserAgent) {
+		botRequests.WithLabelValues(entry.RouterName).Inc()
+	}
+}
+
+func mustCompileAll(patterns []string) []*regexp.Regexp {
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		regexes = append(regexes, regexp.MustCompile(pattern))
+	}
+	return regexes
+}
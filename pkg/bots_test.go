@@ -0,0 +1,88 @@
+package logprocessing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestBotRequestsCounter tests that crawler requests are counted per service and human requests are not
+func TestBotRequestsCounter(t *testing.T) {
+	resetEndpointStats(t)
+	router := "websecure-shop-bots-a457d08d5820f79b3e08@kubernetes"
+	t.Cleanup(func() {
+		botRequests.DeleteLabelValues(router)
+	})
+
+	userAgents := []struct {
+		userAgent string
+		bot       bool
+	}{
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", true},
+		{"Mozilla/5.0 (compatible; Yahoo! Slurp; http://help.yahoo.com/help/us/ysearch/slurp)", true},
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", true},
+		{"Mozilla/5.0 (compatible; AhrefsBot/7.0; +http://ahrefs.com/robot/)", true},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36", false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Version/17.0 Mobile/15E148 Safari/604.1", false},
+		{"curl/8.0", false},
+		{"-", false},
+	}
+
+	bots := 0
+	for _, ua := range userAgents {
+		line := fmt.Sprintf(`10.0.0.1 - - [01/Jan/2024:12:00:00 +0000] "GET /products HTTP/1.1" 200 512 "-" "%s" 1 "%s" "http://10.0.0.5:8080" 3ms`,
+			ua.userAgent, router)
+		entry, err := parseLine(line)
+		if err != nil {
+			t.Fatalf("parseLine(%q) error = %v", ua.userAgent, err)
+		}
+		if got := isBotUserAgent(entry.UserAgent); got != ua.bot {
+			t.Errorf("isBotUserAgent(%q) = %v, want %v", ua.userAgent, got, ua.bot)
+		}
+		if ua.bot {
+			bots++
+		}
+		recordMetrics(&entry, nil, nil, nil)
+	}
+
+	if got := testutil.ToFloat64(botRequests.WithLabelValues(router)); got != float64(bots) {
+		t.Errorf("Expected %d bot requests, got %v", bots, got)
+	}
+}
+
+// TestSetBotUserAgentPatterns tests replacing, disabling and restoring the crawler patterns
+func TestSetBotUserAgentPatterns(t *testing.T) {
+	defer func() {
+		_ = SetBotUserAgentPatterns(nil)
+	}()
+
+	if err := SetBotUserAgentPatterns([]string{`^internal-prober/`}); err != nil {
+		t.Fatalf("SetBotUserAgentPatterns() error = %v", err)
+	}
+	if !isBotUserAgent("internal-prober/1.0") || isBotUserAgent("Googlebot/2.1") {
+		t.Error("Expected only the configured pattern to classify bots")
+	}
+
+	if err := SetBotUserAgentPatterns([]string{}); err != nil {
+		t.Fatalf("SetBotUserAgentPatterns() error = %v", err)
+	}
+	if isBotUserAgent("Googlebot/2.1") {
+		t.Error("Expected an empty pattern list to disable bot classification")
+	}
+
+	if err := SetBotUserAgentPatterns([]string{`(unclosed`}); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+	if isBotUserAgent("Googlebot/2.1") {
+		t.Error("Expected a rejected pattern list to keep the previous patterns")
+	}
+
+	if err := SetBotUserAgentPatterns(nil); err != nil {
+		t.Fatalf("SetBotUserAgentPatterns() error = %v", err)
+	}
+	if !isBotUserAgent("Googlebot/2.1") {
+		t.Error("Expected nil to restore the default patterns")
+	}
+}
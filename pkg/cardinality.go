@@ -0,0 +1,74 @@
+package logprocessing
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// overflowEndpoint is the normalized endpoint every path of a service beyond the cap collapses into
+const overflowEndpoint = "{overflow}"
+
+var (
+	// maxEndpointsPerService caps the distinct normalized endpoints tracked per service; 0 is unlimited
+	maxEndpointsPerService int
+
+	// serviceEndpoints holds the normalized endpoints tracked per service while a cap is set
+	serviceEndpoints      = make(map[string]map[string]struct{})
+	serviceEndpointsMutex sync.Mutex
+
+	// endpointOverflow is built by buildMetrics
+	endpointOverflow *prometheus.CounterVec
+)
+
+// currentMaxEndpointsPerService returns the MaxEndpointsPerService of the active config
+func currentMaxEndpointsPerService() int {
+	settingsMutex.RLock()
+	defer settingsMutex.RUnlock()
+	return maxEndpointsPerService
+}
+
+// capEndpoint returns the endpoint, or overflowEndpoint when the service already tracks
+// maxEndpointsPerService other endpoints, so pathological paths can't grow series without bound
+func capEndpoint(service, endpoint string) string {
+	limit := currentMaxEndpointsPerService()
+	if limit <= 0 {
+		return endpoint
+	}
+
+	serviceEndpointsMutex.Lock()
+	defer serviceEndpointsMutex.Unlock()
+
+	endpoints := serviceEndpoints[service]
+	if endpoints == nil {
+		endpoints = make(map[string]struct{})
+		serviceEndpoints[service] = endpoints
+	}
+	if _, ok := endpoints[endpoint]; ok {
+		return endpoint
+	}
+	if len(endpoints) >= limit {
+		endpointOverflow.WithLabelValues(service).Inc()
+		return overflowEndpoint
+	}
+	endpoints[endpoint] = struct{}{}
+	return endpoint
+}
+
+// forgetServiceEndpoints releases evicted endpoint stats keys (service:endpoint) from the cap
+func forgetServiceEndpoints(endpointKeys []string) {
+	serviceEndpointsMutex.Lock()
+	defer serviceEndpointsMutex.Unlock()
+
+	for _, key := range endpointKeys {
+		service, endpoint, ok := strings.Cut(key, ":")
+		if !ok {
+			continue
+		}
+		delete(serviceEndpoints[service], endpoint)
+		if len(serviceEndpoints[service]) == 0 {
+			delete(serviceEndpoints, service)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package logprocessing
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestEndpointOverflow tests that paths beyond the per-service cap collapse into the overflow
+// endpoint, that other services keep their own budget, and that eviction frees slots
+func TestEndpointOverflow(t *testing.T) {
+	resetEndpointStats(t)
+	oldMax := maxEndpointsPerService
+	maxEndpointsPerService = 3
+	t.Cleanup(func() { maxEndpointsPerService = oldMax })
+
+	router := "websecure-overflow-a457d08d5820f79b3e08@kubernetes"
+	other := "websecure-overflow-other-a457d08d5820f79b3e08@kubernetes"
+	t.Cleanup(func() {
+		endpointOverflow.DeleteLabelValues(router)
+		endpointOverflow.DeleteLabelValues(other)
+	})
+
+	for i := 0; i < 10; i++ {
+		path := fmt.Sprintf("/shape-%d/x", i)
+		updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: path}, nil, urlNormalization)
+	}
+	// Known endpoints keep being tracked once the cap is reached
+	updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: "/shape-0/x"}, nil, urlNormalization)
+	updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: other, RequestPath: "/shape-9/x"}, nil, urlNormalization)
+
+	endpointStatsMutex.RLock()
+	tracked := 0
+	for key := range endpointStats {
+		if strings.HasPrefix(key, router+":") {
+			tracked++
+		}
+	}
+	overflow := endpointStats[router+":"+overflowEndpoint]
+	known := endpointStats[router+":/shape-0/x"]
+	_, otherTracked := endpointStats[other+":/shape-9/x"]
+	endpointStatsMutex.RUnlock()
+
+	if tracked != 4 {
+		t.Errorf("Expected 3 endpoints plus the overflow endpoint, got %d", tracked)
+	}
+	if overflow == nil || overflow.TotalRequests != 7 {
+		t.Fatalf("Expected 7 requests in the overflow endpoint, got %+v", overflow)
+	}
+	if known == nil || known.TotalRequests != 2 {
+		t.Errorf("Expected a known endpoint to keep counting past the cap, got %+v", known)
+	}
+	if !otherTracked {
+		t.Error("Expected another service to have its own endpoint budget")
+	}
+	if got := testutil.ToFloat64(endpointOverflow.WithLabelValues(router)); got != 7 {
+		t.Errorf("Expected 7 overflowed requests, got %v", got)
+	}
+
+	// Evicting an endpoint frees its slot for a new path
+	endpointStatsMutex.Lock()
+	endpointStats[router+":/shape-1/x"].LastSeen = time.Now().Add(-2 * time.Hour)
+	endpointStatsMutex.Unlock()
+	evictStaleEndpointStats(time.Hour, time.Now())
+	updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: "/fresh/x"}, nil, urlNormalization)
+
+	endpointStatsMutex.RLock()
+	_, fresh := endpointStats[router+":/fresh/x"]
+	endpointStatsMutex.RUnlock()
+	if !fresh {
+		t.Error("Expected a new path to be tracked after eviction freed a slot")
+	}
+}
@@ -0,0 +1,42 @@
+package logprocessing
+
+import (
+	"net"
+	"strings"
+)
+
+// trustForwardedFor makes the effective client IP of JSON log lines come from their
+// X-Forwarded-For headers, set by LoadConfig from TrustForwardedFor
+var trustForwardedFor bool
+
+// trustsForwardedFor returns the TrustForwardedFor of the active config
+func trustsForwardedFor() bool {
+	settingsMutex.RLock()
+	defer settingsMutex.RUnlock()
+	return trustForwardedFor
+}
+
+// clientIP returns the effective client IP of the entry. When forwarded headers are trusted, the
+// left-most address of request_X-Forwarded-For, or else downstream_X-Forwarded-For, replaces
+// ClientHost, which is the load balancer address behind a cloud LB.
+func (l *traefikLogConfig) clientIP() string {
+	if trustsForwardedFor() {
+		for _, header := range []string{l.RequestXForwardedFor, l.DownstreamXForwardedFor} {
+			if ip := firstForwardedIP(header); ip != "" {
+				return ip
+			}
+		}
+	}
+	return l.ClientHost
+}
+
+// firstForwardedIP returns the left-most valid IP address of an X-Forwarded-For value, the
+// original client, or "" when there is none
+func firstForwardedIP(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	first = strings.TrimSpace(first)
+	if ip := net.ParseIP(first); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
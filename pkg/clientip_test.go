@@ -0,0 +1,45 @@
+package logprocessing
+
+import (
+	"os"
+	"testing"
+)
+
+// TestClientIPFromForwardedFor tests the effective client IP of a Traefik JSON log line behind a
+// load balancer, with and without trusting the forwarded headers
+func TestClientIPFromForwardedFor(t *testing.T) {
+	oldTrust := trustForwardedFor
+	t.Cleanup(func() { trustForwardedFor = oldTrust })
+
+	data, err := os.ReadFile("testdata/access_log_forwarded.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	entry, err := parseJSON(string(data))
+	if err != nil {
+		t.Fatalf("parseJSON() error = %v", err)
+	}
+	if entry.RequestXForwardedFor != "203.0.113.7, 10.0.3.17" || entry.DownstreamXForwardedFor != "198.51.100.23" {
+		t.Fatalf("Unexpected forwarded headers %q and %q", entry.RequestXForwardedFor, entry.DownstreamXForwardedFor)
+	}
+
+	trustForwardedFor = false
+	if ip := entry.clientIP(); ip != "10.0.3.17" {
+		t.Errorf("Expected ClientHost without trusting forwarded headers, got %s", ip)
+	}
+
+	trustForwardedFor = true
+	if ip := entry.clientIP(); ip != "203.0.113.7" {
+		t.Errorf("Expected the left-most request_X-Forwarded-For address, got %s", ip)
+	}
+
+	entry.RequestXForwardedFor = "unknown"
+	if ip := entry.clientIP(); ip != "198.51.100.23" {
+		t.Errorf("Expected downstream_X-Forwarded-For when the request header has no IP, got %s", ip)
+	}
+
+	entry.DownstreamXForwardedFor = ""
+	if ip := entry.clientIP(); ip != "10.0.3.17" {
+		t.Errorf("Expected ClientHost without a usable forwarded header, got %s", ip)
+	}
+}
@@ -6,9 +6,13 @@ import (
 	logger "github.com/sirupsen/logrus"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
+
+	"sigs.k8s.io/yaml"
 )
 
 var (
@@ -16,8 +20,54 @@ var (
 	topNPaths          int
 	topPathsMutex      sync.RWMutex
 	topPathsPerService = make(map[string]map[string]bool) // Tracks which paths are in the top N
+	urlNormalization   = URLNormalization{
+		DottedTokenMinParts:  defaultDottedTokenMinParts,
+		DottedTokenMinLength: defaultDottedTokenMinLength,
+	}
+	// settingsMutex guards topNPaths, urlNormalization, maxEndpointsPerService and trustForwardedFor,
+	// which a config reload replaces while logs are processed
+	settingsMutex sync.RWMutex
+)
+
+const (
+	defaultDottedTokenMinParts  = 3
+	defaultDottedTokenMinLength = 12
 )
 
+// URLNormalization toggles and tunes the optional default normalizeURL heuristics
+type URLNormalization struct {
+	// DecodePath decodes percent-encoded characters (e.g. %2F, %20) in the path before URL patterns
+	// and normalization are applied. Off by default, so "/a%2Fb/c" keeps "a%2Fb" as a single segment.
+	DecodePath bool `json:"DecodePath"`
+	// StripMatrixParams removes matrix parameters such as ";jsessionid=..." from path segments
+	StripMatrixParams bool `json:"StripMatrixParams"`
+	// CollapseDottedTokens replaces dotted hash-like segments (e.g. JWTs or "main.3f9a2b1c.js") with {token}
+	CollapseDottedTokens bool `json:"CollapseDottedTokens"`
+	// DottedTokenMinParts is the minimum number of dot-separated parts for a segment to be collapsed
+	DottedTokenMinParts int `json:"DottedTokenMinParts"`
+	// DottedTokenMinLength is the minimum segment length for a segment to be collapsed
+	DottedTokenMinLength int `json:"DottedTokenMinLength"`
+	// StripTrailingSlash removes trailing slashes from the path, so "/users/" and "/users" are one endpoint
+	StripTrailingSlash bool `json:"StripTrailingSlash"`
+	// LowercasePath lowercases the path, so "/Users" and "/users" are one endpoint. Leave it off when
+	// path segments are case-sensitive, e.g. base64 ids. URL patterns then have to match lowercase paths.
+	LowercasePath bool `json:"LowercasePath"`
+	// Rules are custom substitutions applied before the default id, uuid, token and query_params
+	// ones. A rule named like a default rule replaces it, e.g. to only collapse long numeric ids.
+	Rules []NormalizationRule `json:"Rules"`
+	// DisabledRules turns off substitutions by name, e.g. "token" to keep long slugs
+	DisabledRules []string `json:"DisabledRules"`
+	// KeepQueryParams lists query parameters kept with their values by the query_params rule, e.g.
+	// "type" turns "?type=pdf&token=abc" into "?type=pdf&{other_params}". Only list parameters with
+	// few distinct values, as each value becomes its own endpoint.
+	KeepQueryParams []string `json:"KeepQueryParams"`
+
+	// rules are the compiled substitutions, nil for the default rules
+	rules []NormalizationRule
+	// skippedRules are the substitutions turned off for a UrlPerformance target
+	skippedRules []string
+}
+
 type TraefikService struct {
 	Name      string `json:"Name"`
 	Namespace string `json:"Namespace"`
@@ -26,11 +76,73 @@ type TraefikService struct {
 type TraefikOfficerConfig struct {
 	IgnoredRouters           []string         `json:"IgnoredRouters"`
 	IgnoredPathsRegex        []string         `json:"IgnoredPathsRegex"`
+	WhitelistPathsRegex      []string         `json:"WhitelistPathsRegex"`
 	MergePathsWithExtensions []string         `json:"MergePathsWithExtensions"`
 	URLPatterns              []URLPattern     `json:"URLPatterns"`
 	AllowedServices          []TraefikService `json:"AllowedServices"`
 	TopNPaths                int              `json:"TopNPaths"`
 	Debug                    bool             `json:"Debug"`
+	// RouterProviders maps additional Traefik provider suffixes (e.g. "file", "docker") to the
+	// target kind their routers are matched as in operator mode
+	RouterProviders map[string]string `json:"RouterProviders"`
+	// RouterNamePatterns override how the namespace, target name and target kind are extracted from
+	// router names, for router names the built-in heuristic misparses. Tried in order.
+	RouterNamePatterns []RouterNamePattern `json:"RouterNamePatterns"`
+	// URLNormalization enables additional default URL normalization heuristics
+	URLNormalization URLNormalization `json:"URLNormalization"`
+	// JSONFieldPaths maps access log fields to dot-paths for nested JSON logs,
+	// e.g. {"RequestPath": "request.path", "OriginStatus": "response.status"}
+	JSONFieldPaths map[string]string `json:"JSONFieldPaths"`
+	// LogFormat is the access log format (clf, json or logfmt) used when -log-format isn't set
+	LogFormat string `json:"LogFormat"`
+	// AccessLogFormat is a Traefik-style template such as `%h %l %u %t "%r" %s %b` for access logs
+	// that don't use the default common log format. See SetAccessLogFormat for the supported tokens.
+	AccessLogFormat string `json:"AccessLogFormat"`
+	// EndpointStatsTTLMinutes evicts endpoints and router parse stats not seen for this many minutes,
+	// together with their series. 0 keeps endpoints for the lifetime of the process.
+	EndpointStatsTTLMinutes int `json:"EndpointStatsTTLMinutes"`
+	// GaugeStalenessMinutes marks the latency and error rate gauges of endpoints idle for this many
+	// minutes as stale, so they stop reporting frozen values. 0 disables staleness handling.
+	GaugeStalenessMinutes int `json:"GaugeStalenessMinutes"`
+	// GaugeStalenessMode is "drop" (default) to delete stale gauges or "nan" to set them to NaN
+	GaugeStalenessMode string `json:"GaugeStalenessMode"`
+	// HealthStalenessMinutes is how long no logs may be processed before /health reports log_processing
+	// as stale and degrades. 0 uses DefaultHealthStaleness.
+	HealthStalenessMinutes int `json:"HealthStalenessMinutes"`
+	// HealthStalenessMode is "always" (default), "source-error" to only degrade when the log source
+	// also reports an error, or "off" to never degrade for lack of logs
+	HealthStalenessMode string `json:"HealthStalenessMode"`
+	// ServiceRPSWindowSeconds is the sliding window in seconds traefik_officer_service_rps is averaged
+	// over. 0 uses DefaultServiceRPSWindow.
+	ServiceRPSWindowSeconds int `json:"ServiceRPSWindowSeconds"`
+	// TrustForwardedFor takes the client IP of JSON log lines from their X-Forwarded-For headers
+	// instead of ClientHost. Only enable it when the header is set by a trusted load balancer.
+	TrustForwardedFor bool `json:"TrustForwardedFor"`
+	// MaxEndpointsPerService caps the distinct normalized endpoints tracked per service. Further paths
+	// collapse into a single {overflow} endpoint. 0 keeps every endpoint.
+	MaxEndpointsPerService int `json:"MaxEndpointsPerService"`
+	// LatencyBuckets are the bucket upper bounds in seconds of the request duration histograms.
+	// Unset uses prometheus.DefBuckets.
+	LatencyBuckets []float64 `json:"LatencyBuckets"`
+	// MetricsBatching batches endpoint stat updates to reduce lock contention under bursts
+	MetricsBatching MetricsBatching `json:"MetricsBatching"`
+	// DedupWindowMs drops entries identical in StartUTC, ClientHost, RequestPath and RequestCount to
+	// one processed within this many milliseconds. 0 disables deduplication, which costs memory.
+	DedupWindowMs int `json:"DedupWindowMs"`
+	// BotUserAgentPatterns are the regexes of crawler User-Agents counted by traefik_officer_bot_requests_total.
+	// Unset uses a built-in list of common crawlers; an empty list disables the counter.
+	BotUserAgentPatterns []string `json:"BotUserAgentPatterns"`
+
+	// ignoredPaths are the compiled IgnoredPathsRegex
+	ignoredPaths []*regexp.Regexp
+	// whitelistPaths are the compiled WhitelistPathsRegex
+	whitelistPaths []*regexp.Regexp
+	// compiledAccessLogFormat is the compiled AccessLogFormat, nil for the common log format
+	compiledAccessLogFormat *accessLogFormat
+	// botUserAgentRegexes are the compiled BotUserAgentPatterns
+	botUserAgentRegexes []*regexp.Regexp
+	// routerNamePatterns are the compiled RouterNamePatterns
+	routerNamePatterns []compiledRouterNamePattern
 }
 
 type traefikLogConfig struct {
@@ -45,6 +157,19 @@ type traefikLogConfig struct {
 	RequestCount      int     `json:"RequestCount"`
 	Duration          float64 `json:"Duration"`
 	Overhead          float64 `json:"Overhead"`
+	// UserAgent is logged by Traefik in JSON when the User-Agent request header is kept
+	UserAgent string `json:"request_User-Agent"`
+	// RequestContentSize is only logged in JSON access logs; it stays 0 for common log format lines
+	RequestContentSize int `json:"RequestContentSize"`
+	// RequestXForwardedFor and DownstreamXForwardedFor are the X-Forwarded-For headers logged in JSON,
+	// used for the effective client IP when TrustForwardedFor is set, see clientIP
+	RequestXForwardedFor    string `json:"request_X-Forwarded-For"`
+	DownstreamXForwardedFor string `json:"downstream_X-Forwarded-For"`
+	// TLSVersion and TLSCipher describe the negotiated TLS connection, logged in JSON for TLS requests
+	TLSVersion string `json:"tls_version"`
+	TLSCipher  string `json:"tls_cipher"`
+	// Pod is the Traefik pod that logged the line, in Kubernetes mode
+	Pod string `json:"-"`
 }
 
 func LoadConfig(configLocation string) (TraefikOfficerConfig, error) {
@@ -75,8 +200,18 @@ func LoadConfig(configLocation string) (TraefikOfficerConfig, error) {
 		return config, nil
 	}
 
-	if err := json.Unmarshal(byteValue, &config); err != nil {
-		return config, fmt.Errorf("failed to parse config file: %w", err)
+	if err := parseConfig(configLocation, byteValue, &config); err != nil {
+		return config, err
+	}
+	applyConfig(&config)
+	return config, nil
+}
+
+// parseConfig unmarshals, defaults and validates a config file, compiling its regexes and formats
+// into config. It doesn't change any state, so an invalid config leaves the active one untouched.
+func parseConfig(configLocation string, data []byte, config *TraefikOfficerConfig) error {
+	if err := unmarshalConfig(configLocation, data, config); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	if config.IgnoredRouters == nil {
@@ -107,9 +242,119 @@ func LoadConfig(configLocation string) (TraefikOfficerConfig, error) {
 		config.URLPatterns[i].Regex = regex
 	}
 
+	ignoredPaths, err := compilePathRegexes(config.IgnoredPathsRegex)
+	if err != nil {
+		return fmt.Errorf("invalid IgnoredPathsRegex: %w", err)
+	}
+	config.ignoredPaths = ignoredPaths
+	whitelistPaths, err := compilePathRegexes(config.WhitelistPathsRegex)
+	if err != nil {
+		return fmt.Errorf("invalid WhitelistPathsRegex: %w", err)
+	}
+	config.whitelistPaths = whitelistPaths
+
+	if err := validateJSONFieldPaths(config.JSONFieldPaths); err != nil {
+		return fmt.Errorf("invalid JSONFieldPaths: %w", err)
+	}
+
+	if config.AccessLogFormat != "" {
+		compiled, err := compileAccessLogFormat(config.AccessLogFormat)
+		if err != nil {
+			return fmt.Errorf("invalid AccessLogFormat: %w", err)
+		}
+		config.compiledAccessLogFormat = compiled
+	}
+
+	botUserAgentRegexes, err := compileBotUserAgentPatterns(config.BotUserAgentPatterns)
+	if err != nil {
+		return fmt.Errorf("invalid BotUserAgentPatterns: %w", err)
+	}
+	config.botUserAgentRegexes = botUserAgentRegexes
+
+	if _, err := parserFor(config.LogFormat); err != nil {
+		return fmt.Errorf("invalid LogFormat: %w", err)
+	}
+	if err := validateLatencyBuckets(config.LatencyBuckets); err != nil {
+		return fmt.Errorf("invalid LatencyBuckets: %w", err)
+	}
+	if err := validateGaugeStalenessMode(config.GaugeStalenessMode); err != nil {
+		return fmt.Errorf("invalid GaugeStalenessMode: %w", err)
+	}
+	if config.GaugeStalenessMode == "" {
+		config.GaugeStalenessMode = GaugeStalenessDrop
+	}
+
+	routerNamePatterns, err := compileRouterNamePatterns(config.RouterNamePatterns)
+	if err != nil {
+		return fmt.Errorf("invalid RouterNamePatterns: %w", err)
+	}
+	config.routerNamePatterns = routerNamePatterns
+
+	if config.URLNormalization.DottedTokenMinParts <= 0 {
+		config.URLNormalization.DottedTokenMinParts = defaultDottedTokenMinParts
+	}
+	if config.URLNormalization.DottedTokenMinLength <= 0 {
+		config.URLNormalization.DottedTokenMinLength = defaultDottedTokenMinLength
+	}
+	rules, err := compileNormalizationRules(config.URLNormalization.Rules, config.URLNormalization.DisabledRules)
+	if err != nil {
+		return fmt.Errorf("invalid URLNormalization: %w", err)
+	}
+	config.URLNormalization.rules = rules
+
+	if config.MetricsBatching.FlushLines <= 0 {
+		config.MetricsBatching.FlushLines = defaultBatchFlushLines
+	}
+	if config.MetricsBatching.FlushIntervalMs <= 0 {
+		config.MetricsBatching.FlushIntervalMs = int(defaultBatchFlushInterval / time.Millisecond)
+	}
+
+	return nil
+}
+
+// applyConfig makes a config returned by parseConfig the active one. Concurrent loads are applied
+// one after the other, so the settings of two configs are never mixed.
+func applyConfig(config *TraefikOfficerConfig) {
+	settingsMutex.Lock()
+	defer settingsMutex.Unlock()
+
+	setJSONFieldPaths(config.JSONFieldPaths)
+	setAccessLogFormat(config.compiledAccessLogFormat)
+	setBotUserAgentRegexes(config.botUserAgentRegexes)
+	for provider, kind := range config.RouterProviders {
+		RegisterRouterProvider(provider, kind)
+	}
+	setRouterNamePatterns(config.routerNamePatterns)
+
+	urlNormalization = config.URLNormalization
 	topNPaths = config.TopNPaths
+	maxEndpointsPerService = config.MaxEndpointsPerService
+	trustForwardedFor = config.TrustForwardedFor
+}
 
-	return config, nil
+// currentTopNPaths returns the TopNPaths of the active config
+func currentTopNPaths() int {
+	settingsMutex.RLock()
+	defer settingsMutex.RUnlock()
+	return topNPaths
+}
+
+// currentURLNormalization returns the URLNormalization of the active config
+func currentURLNormalization() URLNormalization {
+	settingsMutex.RLock()
+	defer settingsMutex.RUnlock()
+	return urlNormalization
+}
+
+// unmarshalConfig parses a config file as YAML if its extension is .yaml or .yml, and as JSON
+// otherwise. YAML is converted to JSON first, so both use the JSON field names.
+func unmarshalConfig(configLocation string, data []byte, config *TraefikOfficerConfig) error {
+	switch strings.ToLower(filepath.Ext(configLocation)) {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(data, config)
+	default:
+		return json.Unmarshal(data, config)
+	}
 }
 
 type LogSource interface {
@@ -122,4 +367,9 @@ type LogLine struct {
 	Text string
 	Time time.Time
 	Err  error
+	// Source is the file the line was read from, when a source reads several files
+	Source string
+	// Namespace and Pod are the namespace and name of the pod the line was read from, in Kubernetes mode
+	Namespace string
+	Pod       string
 }
@@ -3,6 +3,7 @@ package logprocessing
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -220,6 +221,109 @@ func TestLoadConfigFileNotFound(t *testing.T) {
 	}
 }
 
+// TestLoadConfigURLNormalization tests loading the URL normalization options
+func TestLoadConfigURLNormalization(t *testing.T) {
+	// Save original state
+	oldTopNPaths := topNPaths
+	oldNormalization := urlNormalization
+	defer func() {
+		topNPaths = oldTopNPaths
+		urlNormalization = oldNormalization
+	}()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"URLNormalization":{"StripMatrixParams":true,"CollapseDottedTokens":true,"DottedTokenMinParts":4}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if !config.URLNormalization.StripMatrixParams || !config.URLNormalization.CollapseDottedTokens {
+		t.Errorf("Expected both heuristics enabled, got %+v", config.URLNormalization)
+	}
+	if config.URLNormalization.DottedTokenMinParts != 4 {
+		t.Errorf("Expected DottedTokenMinParts = 4, got %d", config.URLNormalization.DottedTokenMinParts)
+	}
+	if config.URLNormalization.DottedTokenMinLength != defaultDottedTokenMinLength {
+		t.Errorf("Expected default DottedTokenMinLength = %d, got %d", defaultDottedTokenMinLength, config.URLNormalization.DottedTokenMinLength)
+	}
+	if !reflect.DeepEqual(urlNormalization, config.URLNormalization) {
+		t.Errorf("Expected active normalization %+v, got %+v", config.URLNormalization, urlNormalization)
+	}
+}
+
+// TestLoadConfigYAML tests that a YAML config loads into the same config as its JSON equivalent
+func TestLoadConfigYAML(t *testing.T) {
+	// Save original state
+	oldTopNPaths := topNPaths
+	oldNormalization := urlNormalization
+	defer func() {
+		topNPaths = oldTopNPaths
+		urlNormalization = oldNormalization
+	}()
+
+	jsonContent := `{
+  "IgnoredRouters": ["dashboard@internal"],
+  "URLPatterns": [{"service_name": "api", "pattern": "/users/\\d+", "replacement": "/users/{id}", "namespace": "shop"}],
+  "AllowedServices": [{"Name": "api", "Namespace": "shop"}],
+  "TopNPaths": 5,
+  "URLNormalization": {"StripMatrixParams": true},
+  "LogFormat": "json"
+}`
+	yamlContent := `IgnoredRouters:
+  - dashboard@internal
+URLPatterns:
+  - service_name: api
+    pattern: '/users/\d+'
+    replacement: '/users/{id}'
+    namespace: shop
+AllowedServices:
+  - Name: api
+    Namespace: shop
+TopNPaths: 5
+URLNormalization:
+  StripMatrixParams: true
+LogFormat: json
+`
+
+	dir := t.TempDir()
+	load := func(name, content string) TraefikOfficerConfig {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig(%s) error = %v", name, err)
+		}
+		return config
+	}
+
+	fromJSON := load("config.json", jsonContent)
+	for _, name := range []string{"config.yaml", "config.yml"} {
+		fromYAML := load(name, yamlContent)
+		if !reflect.DeepEqual(fromJSON, fromYAML) {
+			t.Errorf("Expected %s to load as\n%+v\ngot\n%+v", name, fromJSON, fromYAML)
+		}
+	}
+	if fromJSON.URLPatterns[0].Regex == nil || fromJSON.URLPatterns[0].Regex.String() != `/users/\d+` {
+		t.Errorf("Expected the URL pattern to be compiled, got %+v", fromJSON.URLPatterns[0])
+	}
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("TopNPaths: [5"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(invalid); err == nil {
+		t.Error("Expected an error for invalid YAML")
+	}
+}
+
 // TestTraefikOfficerConfig tests the TraefikOfficerConfig struct
 func TestTraefikOfficerConfig(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,109 @@
+package logprocessing
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// runtimeConfigView is the JSON form of a RuntimeConfig served by /config, with regexes as their source
+type runtimeConfigView struct {
+	Key          string   `json:"key"`
+	Namespace    string   `json:"namespace"`
+	TargetName   string   `json:"targetName"`
+	TargetKind   string   `json:"targetKind"`
+	ServiceNames []string `json:"serviceNames"`
+	Enabled      bool     `json:"enabled"`
+	CollectNTop  int      `json:"collectNTop"`
+
+	WhitelistRegex []string             `json:"whitelistRegex"`
+	IgnoredRegex   []string             `json:"ignoredRegex"`
+	MergePaths     []string             `json:"mergePaths"`
+	URLPatterns    []compiledURLPattern `json:"urlPatterns"`
+
+	DetailedHistogramRegex   []string  `json:"detailedHistogramRegex"`
+	DetailedHistogramBuckets []float64 `json:"detailedHistogramBuckets,omitempty"`
+
+	StripTrailingSlash         *bool    `json:"stripTrailingSlash,omitempty"`
+	LowercasePath              *bool    `json:"lowercasePath,omitempty"`
+	DisabledNormalizationRules []string `json:"disabledNormalizationRules,omitempty"`
+	KeepQueryParams            []string `json:"keepQueryParams,omitempty"`
+
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+// regexSources returns the source strings of the non-nil regexes
+func regexSources(regexes []*regexp.Regexp) []string {
+	sources := make([]string, 0, len(regexes))
+	for _, regex := range regexes {
+		if regex != nil {
+			sources = append(sources, regex.String())
+		}
+	}
+	return sources
+}
+
+// newRuntimeConfigView returns the JSON form of a runtime config
+func newRuntimeConfigView(config *shared.RuntimeConfig) runtimeConfigView {
+	view := runtimeConfigView{
+		Key:          config.Key,
+		Namespace:    config.Namespace,
+		TargetName:   config.TargetName,
+		TargetKind:   config.TargetKind,
+		ServiceNames: append([]string{}, config.ServiceNames...),
+		Enabled:      config.Enabled,
+		CollectNTop:  config.CollectNTop,
+
+		WhitelistRegex: regexSources(config.WhitelistRegex),
+		IgnoredRegex:   regexSources(config.IgnoredRegex),
+		MergePaths:     append([]string{}, config.MergePaths...),
+		URLPatterns:    make([]compiledURLPattern, 0, len(config.URLPatterns)),
+
+		DetailedHistogramRegex:   regexSources(config.DetailedHistogramRegex),
+		DetailedHistogramBuckets: config.DetailedHistogramBuckets,
+
+		StripTrailingSlash:         config.StripTrailingSlash,
+		LowercasePath:              config.LowercasePath,
+		DisabledNormalizationRules: config.DisabledNormalizationRules,
+		KeepQueryParams:            config.KeepQueryParams,
+
+		LastUpdated: config.LastUpdated,
+	}
+	for _, p := range config.URLPatterns {
+		if p.Pattern != nil {
+			view.URLPatterns = append(view.URLPatterns, compiledURLPattern{Pattern: p.Pattern.String(), Replacement: p.Replacement})
+		}
+	}
+	return view
+}
+
+// configHandler returns the active runtime configs of the UrlPerformance targets, sorted by key, so
+// operators can check what their UrlPerformances produced. It returns an empty list outside operator mode.
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	response := make([]runtimeConfigView, 0)
+
+	operatorConfig.mu.RLock()
+	cm := operatorConfig.configManager
+	operatorConfig.mu.RUnlock()
+
+	if cm != nil {
+		// GetAllConfigs reads under the ConfigManager's lock; configs are replaced, never mutated
+		for _, config := range cm.GetAllConfigs() {
+			response = append(response, newRuntimeConfigView(config))
+		}
+		sort.Slice(response, func(i, j int) bool { return response[i].Key < response[j].Key })
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// ConfigHandler returns the admin-guarded /config handler, for servers other than ServeProm's such
+// as the operator's metrics server
+func ConfigHandler() http.Handler {
+	return adminGuard(configHandler)
+}
@@ -0,0 +1,115 @@
+package logprocessing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// TestConfigHandler tests serializing the active runtime configs, with regexes as their source
+func TestConfigHandler(t *testing.T) {
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+
+	lowercase := true
+	operatorConfig = &OperatorModeConfig{
+		enabled: true,
+		configManager: &patternsConfigManager{configs: []*shared.RuntimeConfig{
+			{
+				Key:          "shop/storefront",
+				Namespace:    "shop",
+				TargetName:   "storefront",
+				TargetKind:   "IngressRoute",
+				ServiceNames: []string{"web"},
+				Enabled:      true,
+				CollectNTop:  10,
+				IgnoredRegex: []*regexp.Regexp{regexp.MustCompile(`\.css$`)},
+			},
+			{
+				Key:            "shop/api",
+				Namespace:      "shop",
+				TargetName:     "api",
+				TargetKind:     "Ingress",
+				ServiceNames:   []string{"api", "api-v2"},
+				Enabled:        true,
+				CollectNTop:    20,
+				WhitelistRegex: []*regexp.Regexp{regexp.MustCompile(`^/api/.*`), nil},
+				MergePaths:     []string{"/static/"},
+				URLPatterns: []shared.URLPattern{
+					{Pattern: regexp.MustCompile(`/users/\d+`), Replacement: "/users/{id}"},
+				},
+				LowercasePath:   &lowercase,
+				KeepQueryParams: []string{"type"},
+			},
+		}},
+	}
+
+	req := httptest.NewRequest("GET", "/config", nil)
+	w := httptest.NewRecorder()
+	configHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected a JSON response, got %q", contentType)
+	}
+
+	var response []map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response) != 2 || response[0]["key"] != "shop/api" || response[1]["key"] != "shop/storefront" {
+		t.Fatalf("Expected both configs sorted by key, got %v", response)
+	}
+
+	api := response[0]
+	expected := map[string]any{
+		"namespace":       "shop",
+		"targetName":      "api",
+		"targetKind":      "Ingress",
+		"serviceNames":    []any{"api", "api-v2"},
+		"enabled":         true,
+		"collectNTop":     float64(20),
+		"whitelistRegex":  []any{`^/api/.*`},
+		"ignoredRegex":    []any{},
+		"mergePaths":      []any{"/static/"},
+		"urlPatterns":     []any{map[string]any{"pattern": `/users/\d+`, "replacement": "/users/{id}"}},
+		"lowercasePath":   true,
+		"keepQueryParams": []any{"type"},
+	}
+	for field, want := range expected {
+		if got := api[field]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s = %v, got %v", field, want, got)
+		}
+	}
+	if _, ok := api["stripTrailingSlash"]; ok {
+		t.Error("Expected unset overrides to be omitted")
+	}
+	if got := response[1]["ignoredRegex"]; !reflect.DeepEqual(got, []any{`\.css$`}) {
+		t.Errorf("Unexpected ignored regexes %v", got)
+	}
+}
+
+// TestConfigHandlerWithoutConfigManager tests the response outside operator mode
+func TestConfigHandlerWithoutConfigManager(t *testing.T) {
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+	operatorConfig = &OperatorModeConfig{}
+
+	w := httptest.NewRecorder()
+	configHandler(w, httptest.NewRequest("GET", "/config", nil))
+
+	if body := w.Body.String(); body != "[]\n" {
+		t.Errorf("Expected an empty list, got %q", body)
+	}
+}
@@ -0,0 +1,107 @@
+package logprocessing
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	logger "github.com/sirupsen/logrus"
+)
+
+// configReloadDelay debounces the bursts of events an editor or a truncate-and-write produces, so
+// the config file is loaded once it is complete
+const configReloadDelay = 200 * time.Millisecond
+
+var (
+	// reloadedConfig is the config of the last successful reload by WatchConfig, nil until then
+	reloadedConfig      *TraefikOfficerConfig
+	reloadedConfigMutex sync.RWMutex
+)
+
+// currentConfig returns the config of the last reload, or config if the file was never reloaded
+func currentConfig(config *TraefikOfficerConfig) *TraefikOfficerConfig {
+	reloadedConfigMutex.RLock()
+	defer reloadedConfigMutex.RUnlock()
+	if reloadedConfig != nil {
+		return reloadedConfig
+	}
+	return config
+}
+
+// setReloadedConfig swaps the active config used by ProcessLogs
+func setReloadedConfig(config *TraefikOfficerConfig) {
+	reloadedConfigMutex.Lock()
+	defer reloadedConfigMutex.Unlock()
+	reloadedConfig = config
+}
+
+// WatchConfig reloads the config file whenever it changes until ctx is cancelled. ProcessLogs uses
+// each successfully reloaded config for the following lines, e.g. new URLPatterns or AllowedServices;
+// MetricsBatching and LogFormat only apply at start. An invalid file is logged and the previous
+// config stays active.
+func WatchConfig(ctx context.Context, configLocation string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	// Watch the directory, as editors and ConfigMap updates replace the file instead of writing it
+	dir := filepath.Dir(configLocation)
+	if err := watcher.Add(dir); err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	logger.Infof("Watching config file %s for changes", configLocation)
+
+	go func() {
+		defer watcher.Close()
+
+		var reload <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if isConfigFileEvent(event, configLocation) {
+					reload = time.After(configReloadDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warnf("Config watcher error: %v", err)
+			case <-reload:
+				reload = nil
+				reloadConfig(configLocation)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// isConfigFileEvent reports whether a change in the watched directory may have changed the config
+// file. Kubernetes updates mounted ConfigMaps by swapping the ..data symlink of the directory.
+func isConfigFileEvent(event fsnotify.Event, configLocation string) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	return name == filepath.Clean(configLocation) || filepath.Base(name) == "..data"
+}
+
+// reloadConfig loads the config file and swaps it in if it is valid
+func reloadConfig(configLocation string) {
+	config, err := LoadConfig(configLocation)
+	if err != nil {
+		logger.Warnf("Failed to reload config file %s, keeping the previous config: %v", configLocation, err)
+		return
+	}
+	setReloadedConfig(&config)
+	logger.Infof("Reloaded config file %s", configLocation)
+}
@@ -0,0 +1,201 @@
+package logprocessing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForConfig polls the active config until check accepts it
+func waitForConfig(t *testing.T, initial *TraefikOfficerConfig, check func(*TraefikOfficerConfig) bool) *TraefikOfficerConfig {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if config := currentConfig(initial); check(config) {
+			return config
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for the config to be reloaded")
+	return nil
+}
+
+// TestWatchConfig tests that rewriting the config file swaps in its URLPatterns and that an invalid
+// file keeps the previous config
+func TestWatchConfig(t *testing.T) {
+	oldTopNPaths := topNPaths
+	t.Cleanup(func() {
+		topNPaths = oldTopNPaths
+		setReloadedConfig(nil)
+	})
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write(`{"URLPatterns":[{"service_name":"api","pattern":"/users/\\d+","replacement":"/users/{id}"}]}`)
+
+	initial, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchConfig(ctx, path); err != nil {
+		t.Fatalf("WatchConfig() error = %v", err)
+	}
+
+	if got := normalizeURL("api", "/orders/42", currentConfig(&initial).URLPatterns, urlNormalization); got == "/orders/{order}" {
+		t.Fatalf("Unexpected normalization before the reload: %s", got)
+	}
+
+	write(`{"URLPatterns":[{"service_name":"api","pattern":"/orders/\\d+","replacement":"/orders/{order}"}]}`)
+	reloaded := waitForConfig(t, &initial, func(config *TraefikOfficerConfig) bool {
+		return len(config.URLPatterns) == 1 && config.URLPatterns[0].Pattern == `/orders/\d+`
+	})
+	if got := normalizeURL("api", "/orders/42", reloaded.URLPatterns, urlNormalization); got != "/orders/{order}" {
+		t.Errorf("Expected the reloaded pattern to normalize /orders/42 to /orders/{order}, got %s", got)
+	}
+
+	// An invalid file is logged and the reloaded config stays active
+	write(`{"URLPatterns": [`)
+	time.Sleep(3 * configReloadDelay)
+	if config := currentConfig(&initial); config != reloaded {
+		t.Errorf("Expected the previous config to stay active after an invalid file, got %+v", config)
+	}
+}
+
+// restoreConfigState restores the settings applied by LoadConfig when the test ends
+func restoreConfigState(t *testing.T) {
+	jsonFieldPathsMutex.RLock()
+	oldJSONFieldPaths := jsonFieldPaths
+	jsonFieldPathsMutex.RUnlock()
+	oldAccessLogFormat := currentAccessLogFormat()
+	botUserAgentRegexesMutex.RLock()
+	oldBotUserAgentRegexes := botUserAgentRegexes
+	botUserAgentRegexesMutex.RUnlock()
+	routerNamePatternsMutex.RLock()
+	oldRouterNamePatterns := routerNamePatterns
+	routerNamePatternsMutex.RUnlock()
+
+	settingsMutex.RLock()
+	oldTopNPaths, oldURLNormalization := topNPaths, urlNormalization
+	oldMaxEndpointsPerService, oldTrustForwardedFor := maxEndpointsPerService, trustForwardedFor
+	settingsMutex.RUnlock()
+
+	t.Cleanup(func() {
+		setJSONFieldPaths(oldJSONFieldPaths)
+		setAccessLogFormat(oldAccessLogFormat)
+		setBotUserAgentRegexes(oldBotUserAgentRegexes)
+		setRouterNamePatterns(oldRouterNamePatterns)
+
+		settingsMutex.Lock()
+		topNPaths, urlNormalization = oldTopNPaths, oldURLNormalization
+		maxEndpointsPerService, trustForwardedFor = oldMaxEndpointsPerService, oldTrustForwardedFor
+		settingsMutex.Unlock()
+		setReloadedConfig(nil)
+	})
+}
+
+// TestReloadConfigInvalidKeepsSettings tests that a config failing validation applies none of its
+// settings, including the ones validated before the failing one
+func TestReloadConfigInvalidKeepsSettings(t *testing.T) {
+	restoreConfigState(t)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write(`{"TopNPaths":5,"JSONFieldPaths":{"RequestPath":"request.path"},"AccessLogFormat":"%h %t \"%r\" %s"}`)
+	if _, err := LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	applied := currentAccessLogFormat()
+
+	// The JSON field paths, access log format and bot patterns are valid, the router name pattern isn't
+	write(`{"TopNPaths":9,"JSONFieldPaths":{"RequestPath":"req.uri"},"AccessLogFormat":"%h %s",` +
+		`"BotUserAgentPatterns":[],"RouterNamePatterns":[{"Pattern":"^(?P<name>.+)$"}]}`)
+	reloadConfig(path)
+
+	jsonFieldPathsMutex.RLock()
+	requestPath := jsonFieldPaths["RequestPath"]
+	jsonFieldPathsMutex.RUnlock()
+	if requestPath != "request.path" {
+		t.Errorf("Expected the JSON field paths to be kept, got RequestPath at %q", requestPath)
+	}
+	if currentAccessLogFormat() != applied {
+		t.Error("Expected the access log format to be kept")
+	}
+	if !isBotUserAgent("Googlebot/2.1") {
+		t.Error("Expected the bot User-Agent patterns to be kept")
+	}
+	if got := currentTopNPaths(); got != 5 {
+		t.Errorf("Expected TopNPaths to stay 5, got %d", got)
+	}
+	if config := currentConfig(nil); config != nil {
+		t.Errorf("Expected the invalid config not to be swapped in, got %+v", config)
+	}
+}
+
+// TestReloadConfigDuringProcessLogs tests that configs can be reloaded while logs are processed.
+// Run with -race to catch unguarded settings.
+func TestReloadConfigDuringProcessLogs(t *testing.T) {
+	resetEndpointStats(t)
+	restoreConfigState(t)
+
+	dir := t.TempDir()
+	paths := make([]string, 2)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("config-%d.json", i))
+		content := fmt.Sprintf(`{"TopNPaths":%d,"MaxEndpointsPerService":%d,"TrustForwardedFor":%t,`+
+			`"URLNormalization":{"StripTrailingSlash":%t},"BotUserAgentPatterns":["bot-%d"]}`, 5+i, 10*i, i == 0, i == 1, i)
+		if err := os.WriteFile(paths[i], []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+
+	router := "websecure-reload-a457d08d5820f79b3e08@kubernetes"
+	source := &mockLogSource{lines: make(chan LogLine)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		useK8s := true // Disable log rotation
+		config := TraefikOfficerConfig{AllowedServices: []TraefikService{{Name: router}}}
+		ProcessLogs(context.Background(), source, config, &useK8s, nil, LogFormatJSON)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			reloadConfig(paths[i%2])
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		source.lines <- LogLine{Text: fmt.Sprintf(
+			`{"ClientHost":"10.0.0.1","request_X-Forwarded-For":"203.0.113.7","request_User-Agent":"bot-1",`+
+				`"RouterName":%q,"RequestMethod":"GET","RequestPath":"/orders/%d/","OriginStatus":200,"Duration":1000000}`,
+			router, i)}
+	}
+	wg.Wait()
+	if err := source.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for ProcessLogs to return")
+	}
+}
@@ -0,0 +1,70 @@
+package logprocessing
+
+import (
+	"hash/fnv"
+	"strconv"
+	"time"
+)
+
+// lineDeduplicator drops access log entries already seen within a short window, for HA Traefik
+// setups where several replicas log the same request or a replayed stream overlaps a followed one.
+// Entries are identified by StartUTC, ClientHost, RequestPath and RequestCount. It isn't safe for
+// concurrent use; each ProcessLogs call owns its own.
+type lineDeduplicator struct {
+	window time.Duration
+	seen   map[uint64]time.Time
+	// lastSweep is when expired entries were last removed from seen
+	lastSweep time.Time
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// newLineDeduplicator returns a deduplicator remembering entries for window, or nil when window
+// isn't positive
+func newLineDeduplicator(window time.Duration) *lineDeduplicator {
+	if window <= 0 {
+		return nil
+	}
+	return &lineDeduplicator{
+		window: window,
+		seen:   make(map[uint64]time.Time),
+		now:    time.Now,
+	}
+}
+
+// isDuplicate reports whether an identical entry was seen within the window, and remembers the
+// entry otherwise. Entries without a start time or request count can't be told apart from other
+// requests and are never duplicates.
+func (d *lineDeduplicator) isDuplicate(entry *traefikLogConfig) bool {
+	if entry.StartUTC == "" && entry.RequestCount == 0 {
+		return false
+	}
+
+	now := d.now()
+	if now.Sub(d.lastSweep) >= d.window {
+		for key, seenAt := range d.seen {
+			if now.Sub(seenAt) > d.window {
+				delete(d.seen, key)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	key := dedupKey(entry)
+	if seenAt, ok := d.seen[key]; ok && now.Sub(seenAt) <= d.window {
+		return true
+	}
+	d.seen[key] = now
+	return false
+}
+
+// dedupKey hashes the fields identifying a request
+func dedupKey(entry *traefikLogConfig) uint64 {
+	h := fnv.New64a()
+	for _, field := range []string{entry.StartUTC, entry.ClientHost, entry.RequestPath, strconv.Itoa(entry.RequestCount)} {
+		_, _ = h.Write([]byte(field))
+		_, _ = h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
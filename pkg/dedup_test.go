@@ -0,0 +1,74 @@
+package logprocessing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestLineDeduplicator tests that identical entries are dropped within the window only
+func TestLineDeduplicator(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	dedup := newLineDeduplicator(time.Second)
+	dedup.now = func() time.Time { return now }
+
+	entry := traefikLogConfig{StartUTC: "2024-01-01T12:00:00.1Z", ClientHost: "10.0.0.1", RequestPath: "/orders", RequestCount: 42}
+	if dedup.isDuplicate(&entry) {
+		t.Fatal("Expected the first entry not to be a duplicate")
+	}
+	if !dedup.isDuplicate(&entry) {
+		t.Error("Expected an identical entry to be a duplicate")
+	}
+
+	other := entry
+	other.RequestCount = 43
+	if dedup.isDuplicate(&other) {
+		t.Error("Expected an entry with another request count not to be a duplicate")
+	}
+
+	now = now.Add(2 * time.Second)
+	if dedup.isDuplicate(&entry) {
+		t.Error("Expected an identical entry after the window not to be a duplicate")
+	}
+	if len(dedup.seen) != 1 {
+		t.Errorf("Expected expired entries to be swept, %d remembered", len(dedup.seen))
+	}
+
+	// Entries that can't be identified are always counted
+	anonymous := traefikLogConfig{RequestPath: "/orders"}
+	if dedup.isDuplicate(&anonymous) || dedup.isDuplicate(&anonymous) {
+		t.Error("Expected entries without start time and request count never to be duplicates")
+	}
+
+	if newLineDeduplicator(0) != nil {
+		t.Error("Expected no deduplicator without a window")
+	}
+}
+
+// TestProcessLogsDedup tests that the same request logged by two replicas is counted once
+func TestProcessLogsDedup(t *testing.T) {
+	resetEndpointStats(t)
+	router := "websecure-dedup-a457d08d5820f79b3e08@kubernetes"
+	before := testutil.ToFloat64(totalRequests.WithLabelValues("GET", "200", router))
+
+	source := &mockLogSource{lines: make(chan LogLine, 4)}
+	for _, pod := range []string{"traefik-a", "traefik-b"} {
+		for _, count := range []int{1, 2} {
+			source.lines <- LogLine{Source: pod, Text: fmt.Sprintf(
+				`{"StartUTC":"2024-01-01T12:00:00.1Z","ClientHost":"10.0.0.1","RouterName":%q,"RequestMethod":"GET","RequestPath":"/orders","RequestCount":%d,"OriginStatus":200,"Duration":1000000}`,
+				router, count)}
+		}
+	}
+	close(source.lines)
+
+	useK8s := true // Disable log rotation
+	config := TraefikOfficerConfig{AllowedServices: []TraefikService{{Name: router}}, DedupWindowMs: 1000}
+	ProcessLogs(context.Background(), source, config, &useK8s, nil, LogFormatJSON)
+
+	if got := testutil.ToFloat64(totalRequests.WithLabelValues("GET", "200", router)) - before; got != 2 {
+		t.Errorf("Expected the 2 distinct requests to be counted once each, got %v", got)
+	}
+}
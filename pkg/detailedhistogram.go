@@ -0,0 +1,114 @@
+package logprocessing
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// defaultDetailedHistogramBuckets are used when a UrlPerformance doesn't configure buckets
+var defaultDetailedHistogramBuckets = prometheus.ExponentialBucketsRange(0.001, 10, 30)
+
+// detailedHistogram is the detailed latency histogram of one UrlPerformance target
+type detailedHistogram struct {
+	buckets []float64
+	vec     *prometheus.HistogramVec
+}
+
+var (
+	// detailedHistograms holds the detailed histogram of each config key. Every target gets its own
+	// collector because buckets are configured per target; the namespace and ingress are constant
+	// labels so the collectors can share one metric name.
+	detailedHistograms      = make(map[string]*detailedHistogram)
+	detailedHistogramsMutex sync.Mutex
+)
+
+// unregisterDetailedHistograms unregisters every detailed histogram, so they are recreated on next use
+func unregisterDetailedHistograms() {
+	detailedHistogramsMutex.Lock()
+	defer detailedHistogramsMutex.Unlock()
+
+	for key, histogram := range detailedHistograms {
+		prometheus.Unregister(histogram.vec)
+		delete(detailedHistograms, key)
+	}
+}
+
+// detailedHistogramFor returns the detailed histogram of the config, registering it on first use
+// and replacing it when the configured buckets changed
+func detailedHistogramFor(config *shared.RuntimeConfig) *prometheus.HistogramVec {
+	buckets := config.DetailedHistogramBuckets
+	if len(buckets) == 0 {
+		buckets = defaultDetailedHistogramBuckets
+	}
+
+	detailedHistogramsMutex.Lock()
+	defer detailedHistogramsMutex.Unlock()
+
+	existing := detailedHistograms[config.Key]
+	if existing != nil && equalBuckets(existing.buckets, buckets) {
+		return existing.vec
+	}
+	if existing != nil {
+		prometheus.Unregister(existing.vec)
+		delete(detailedHistograms, config.Key)
+	}
+
+	vec := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        metricName("endpoint_detailed_duration_seconds"),
+			Help:        "Fine-grained duration of HTTP requests to the detailed histogram paths of a UrlPerformance",
+			Buckets:     buckets,
+			ConstLabels: prometheus.Labels{"namespace": config.Namespace, "ingress": config.TargetName},
+		},
+		[]string{"request_path", "request_method"},
+	)
+	if err := prometheus.Register(vec); err != nil {
+		logger.Errorf("Failed to register detailed histogram for %s: %v", config.Key, err)
+		return nil
+	}
+	detailedHistograms[config.Key] = &detailedHistogram{buckets: buckets, vec: vec}
+	return vec
+}
+
+// observeDetailedHistogram observes the entry into the detailed histogram of its UrlPerformance
+// when the request path matches one of the configured detailed histogram paths
+func observeDetailedHistogram(entry *traefikLogConfig, config *shared.RuntimeConfig, urlPatterns []URLPattern) {
+	if config == nil || len(config.DetailedHistogramRegex) == 0 {
+		return
+	}
+
+	matched := false
+	for _, regex := range config.DetailedHistogramRegex {
+		if regex != nil && regex.MatchString(entry.RequestPath) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return
+	}
+
+	vec := detailedHistogramFor(config)
+	if vec == nil {
+		return
+	}
+	endpoint := normalizeURL(entry.RouterName, entry.RequestPath, urlPatterns, urlNormalizationFor(config))
+	vec.WithLabelValues(endpoint, entry.RequestMethod).Observe(entry.Duration / 1000.0)
+}
+
+// equalBuckets reports whether two bucket layouts are identical
+func equalBuckets(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package logprocessing
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// resetDetailedHistograms unregisters the detailed histograms created by a test
+func resetDetailedHistograms(t *testing.T) {
+	t.Helper()
+	t.Cleanup(unregisterDetailedHistograms)
+}
+
+// TestObserveDetailedHistogram tests that only matching paths feed the detailed histogram
+func TestObserveDetailedHistogram(t *testing.T) {
+	resetDetailedHistograms(t)
+
+	config := &shared.RuntimeConfig{
+		Key:                      shared.ConfigKey("shop", "checkout"),
+		Namespace:                "shop",
+		TargetName:               "checkout",
+		DetailedHistogramRegex:   []*regexp.Regexp{regexp.MustCompile(`^/api/checkout$`)},
+		DetailedHistogramBuckets: []float64{0.005, 0.01, 0.025},
+	}
+
+	for _, path := range []string{"/api/checkout", "/api/checkout", "/api/cart", "/health"} {
+		observeDetailedHistogram(&traefikLogConfig{
+			RouterName:    "websecure-shop-checkout-a457d08d5820f79b3e08@kubernetes",
+			RequestMethod: "POST",
+			RequestPath:   path,
+			Duration:      7, // milliseconds
+		}, config, nil)
+	}
+
+	vec := detailedHistogramFor(config)
+	if got := testutil.CollectAndCount(vec); got != 1 {
+		t.Fatalf("Expected one detailed histogram series, got %d", got)
+	}
+
+	expected := `
+# HELP traefik_officer_endpoint_detailed_duration_seconds Fine-grained duration of HTTP requests to the detailed histogram paths of a UrlPerformance
+# TYPE traefik_officer_endpoint_detailed_duration_seconds histogram
+traefik_officer_endpoint_detailed_duration_seconds_bucket{ingress="checkout",namespace="shop",request_method="POST",request_path="/api/checkout",le="0.005"} 0
+traefik_officer_endpoint_detailed_duration_seconds_bucket{ingress="checkout",namespace="shop",request_method="POST",request_path="/api/checkout",le="0.01"} 2
+traefik_officer_endpoint_detailed_duration_seconds_bucket{ingress="checkout",namespace="shop",request_method="POST",request_path="/api/checkout",le="0.025"} 2
+traefik_officer_endpoint_detailed_duration_seconds_bucket{ingress="checkout",namespace="shop",request_method="POST",request_path="/api/checkout",le="+Inf"} 2
+traefik_officer_endpoint_detailed_duration_seconds_sum{ingress="checkout",namespace="shop",request_method="POST",request_path="/api/checkout"} 0.014
+traefik_officer_endpoint_detailed_duration_seconds_count{ingress="checkout",namespace="shop",request_method="POST",request_path="/api/checkout"} 2
+`
+	if err := testutil.CollectAndCompare(vec, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestDetailedHistogramPerTarget tests that targets get separate histograms and that changed
+// buckets replace the histogram
+func TestDetailedHistogramPerTarget(t *testing.T) {
+	resetDetailedHistograms(t)
+
+	checkout := &shared.RuntimeConfig{Key: "shop/checkout", Namespace: "shop", TargetName: "checkout"}
+	search := &shared.RuntimeConfig{Key: "shop/search", Namespace: "shop", TargetName: "search", DetailedHistogramBuckets: []float64{0.1}}
+
+	checkoutVec := detailedHistogramFor(checkout)
+	searchVec := detailedHistogramFor(search)
+	if checkoutVec == nil || searchVec == nil || checkoutVec == searchVec {
+		t.Fatal("Expected a separate registered histogram per target")
+	}
+	if again := detailedHistogramFor(checkout); again != checkoutVec {
+		t.Error("Expected the histogram to be reused while the buckets are unchanged")
+	}
+
+	search.DetailedHistogramBuckets = []float64{0.1, 0.2}
+	if replaced := detailedHistogramFor(search); replaced == nil || replaced == searchVec {
+		t.Error("Expected the histogram to be replaced after the buckets changed")
+	}
+
+	// Without paths nothing is observed
+	observeDetailedHistogram(&traefikLogConfig{RequestPath: "/api/checkout"}, checkout, nil)
+	if got := testutil.CollectAndCount(checkoutVec); got != 0 {
+		t.Errorf("Expected no observations without detailed histogram paths, got %d series", got)
+	}
+}
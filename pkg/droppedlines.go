@@ -0,0 +1,75 @@
+package logprocessing
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// linesDropped is built by buildMetrics
+var linesDropped *prometheus.CounterVec
+
+// Reasons of traefik_officer_lines_dropped_total
+const (
+	DropReasonNotAccessLog    = "not_access_log"
+	DropReasonInvalidFormat   = "invalid_format"
+	DropReasonInvalidStatus   = "invalid_status"
+	DropReasonInvalidDuration = "invalid_duration"
+	DropReasonInvalidField    = "invalid_field"
+	DropReasonJSONInvalid     = "json_invalid"
+)
+
+// Errors of the access log parsers, see dropReason
+var (
+	errEmptyLine       = errors.New("empty line")
+	errNotAccessLog    = errors.New("not an access log line")
+	errInvalidFormat   = errors.New("invalid access log format")
+	errInvalidStatus   = errors.New("invalid status code")
+	errInvalidDuration = errors.New("invalid duration")
+	errInvalidField    = errors.New("invalid field")
+	errInvalidJSON     = errors.New("invalid JSON format in log line")
+)
+
+// reasonError is a parse error classified by one of the errors above, keeping its message
+type reasonError struct {
+	err    error
+	reason error
+}
+
+func (e *reasonError) Error() string   { return e.err.Error() }
+func (e *reasonError) Unwrap() []error { return []error{e.err, e.reason} }
+
+// withReason classifies err as reason for dropReason without changing its message
+func withReason(err, reason error) error {
+	return &reasonError{err: err, reason: reason}
+}
+
+// fieldReason returns the error classifying an access log field whose value couldn't be parsed
+func fieldReason(field string) error {
+	switch field {
+	case "OriginStatus":
+		return errInvalidStatus
+	case "Duration", "Overhead":
+		return errInvalidDuration
+	default:
+		return errInvalidField
+	}
+}
+
+// dropReason returns the lines_dropped_total reason of a parse error
+func dropReason(err error) string {
+	switch {
+	case errors.Is(err, errNotAccessLog):
+		return DropReasonNotAccessLog
+	case errors.Is(err, errInvalidStatus):
+		return DropReasonInvalidStatus
+	case errors.Is(err, errInvalidDuration):
+		return DropReasonInvalidDuration
+	case errors.Is(err, errInvalidJSON):
+		return DropReasonJSONInvalid
+	case errors.Is(err, errInvalidFormat):
+		return DropReasonInvalidFormat
+	default:
+		return DropReasonInvalidField
+	}
+}
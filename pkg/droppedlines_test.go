@@ -0,0 +1,52 @@
+package logprocessing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestLinesDropped tests that lines failing to parse are counted per reason
+func TestLinesDropped(t *testing.T) {
+	resetEndpointStats(t)
+
+	tests := []struct {
+		format string
+		lines  []string
+		reason string
+	}{
+		{format: LogFormatCLF, lines: []string{`level=info msg="Configuration loaded"`}, reason: DropReasonNotAccessLog},
+		{format: LogFormatCLF, lines: []string{"[traefik-0] 10.0.0.1 - - malformed"}, reason: DropReasonInvalidFormat},
+		{format: LogFormatCLF, lines: []string{strings.Replace(benchmarkAccessLogLine, " 200 ", " OK ", 1)}, reason: DropReasonInvalidStatus},
+		{format: LogFormatCLF, lines: []string{strings.Replace(benchmarkAccessLogLine, "25ms", "fast", 1)}, reason: DropReasonInvalidDuration},
+		{format: LogFormatCLF, lines: []string{strings.Replace(benchmarkAccessLogLine, " 1234 ", " big ", 1)}, reason: DropReasonInvalidField},
+		{format: LogFormatJSON, lines: []string{`{"RouterName":`, `{"OriginStatus":"two hundred"}`}, reason: DropReasonJSONInvalid},
+		{format: LogFormatLogfmt, lines: []string{`RequestMethod=GET Duration=slow`}, reason: DropReasonInvalidDuration},
+		{format: LogFormatLogfmt, lines: []string{`RequestMethod=GET RequestPath="/unclosed`}, reason: DropReasonInvalidFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.reason, func(t *testing.T) {
+			linesDropped.Reset()
+
+			lines := make(chan LogLine, len(tt.lines)+1)
+			for _, line := range tt.lines {
+				lines <- LogLine{Text: line}
+			}
+			// Blank lines aren't counted
+			lines <- LogLine{Text: "  "}
+			close(lines)
+
+			useK8s := true
+			ProcessLogs(context.Background(), &mockLogSource{lines: lines}, TraefikOfficerConfig{}, &useK8s, nil, tt.format)
+
+			if got := testutil.ToFloat64(linesDropped.WithLabelValues(tt.reason)); got != float64(len(tt.lines)) {
+				t.Errorf("Expected %d lines dropped as %s, got %v", len(tt.lines), tt.reason, got)
+			}
+			if got := testutil.CollectAndCount(linesDropped); got != 1 {
+				t.Errorf("Expected only the %s reason, got %d series", tt.reason, got)
+			}
+		})
+	}
+}
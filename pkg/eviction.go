@@ -0,0 +1,118 @@
+package logprocessing
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	logger "github.com/sirupsen/logrus"
+)
+
+// maxEndpointStatsSweepInterval caps how long stale endpoint stats linger past their TTL
+const maxEndpointStatsSweepInterval = time.Minute
+
+// evictStaleEndpointStats deletes the endpoint stats not updated within maxAge, together with
+// their Prometheus series and top path membership, and returns the number of evicted endpoints.
+// Stats without a LastSeen time, e.g. restored from an older state file, are stamped with now.
+func evictStaleEndpointStats(maxAge time.Duration, now time.Time) int {
+	stale := make([]string, 0)
+
+	endpointStatsMutex.Lock()
+	for key, stat := range endpointStats {
+		if stat.LastSeen.IsZero() {
+			stat.LastSeen = now
+			continue
+		}
+		if now.Sub(stat.LastSeen) > maxAge {
+			stale = append(stale, key)
+			delete(endpointStats, key)
+			delete(staleEndpoints, key)
+		}
+	}
+	endpointStatsMutex.Unlock()
+
+	if len(stale) == 0 {
+		return 0
+	}
+	deleteEndpointSeries(stale)
+
+	logger.Debugf("Evicted %d endpoints not seen in the last %s", len(stale), maxAge)
+	return len(stale)
+}
+
+// evictStaleParseStats deletes the router parse stats not updated within maxAge, together with their
+// router_parse_success_ratio series, and returns the number of evicted routers
+func evictStaleParseStats(maxAge time.Duration, now time.Time) int {
+	routerParseStatsMutex.Lock()
+	defer routerParseStatsMutex.Unlock()
+
+	evicted := 0
+	for key, stat := range routerParseStats {
+		if now.Sub(stat.LastSeen) > maxAge {
+			delete(routerParseStats, key)
+			routerParseSuccessRatio.DeleteLabelValues(key)
+			evicted++
+		}
+	}
+	if evicted > 0 {
+		logger.Debugf("Evicted the parse stats of %d routers not seen in the last %s", evicted, maxAge)
+	}
+	return evicted
+}
+
+// deleteEndpointSeries deletes the Prometheus series and top path membership of endpoint stats keys
+// (service:path) whose stats were removed
+func deleteEndpointSeries(keys []string) {
+	forgetTargetPaths(keys)
+	forgetServiceEndpoints(keys)
+
+	topPathsMutex.Lock()
+	for _, key := range keys {
+		parts := strings.SplitN(key, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		service, path := parts[0], parts[1]
+
+		delete(topPathsPerService[service], key)
+		delete(inTopNSeries, [2]string{service, path})
+		endpointInTopN.DeleteLabelValues(service, path)
+
+		namespace, ingress := endpointLabels(service)
+		endpointAvgLatency.DeleteLabelValues(namespace, ingress, path)
+		endpointMaxLatency.DeleteLabelValues(namespace, ingress, path)
+		deleteMethodLatency(namespace, ingress, path)
+		deleteLatencyQuantiles(namespace, ingress, path)
+		endpointErrorRate.DeleteLabelValues(namespace, ingress, path)
+		endpointClientErrorRate.DeleteLabelValues(namespace, ingress, path)
+		endpointServerErrorRate.DeleteLabelValues(namespace, ingress, path)
+
+		endpointSeries := prometheus.Labels{"namespace": namespace, "ingress": ingress, "request_path": path}
+		endpointRequests.DeletePartialMatch(endpointSeries)
+		endpointDuration.DeletePartialMatch(endpointSeries)
+	}
+	topPathsMutex.Unlock()
+}
+
+// StartEndpointStatsSweeper periodically evicts endpoint and router parse stats not updated within
+// maxAge, so high path cardinality doesn't grow memory without bound. It stops when stop is closed.
+func StartEndpointStatsSweeper(maxAge time.Duration, stop <-chan struct{}) {
+	interval := maxAge
+	if interval > maxEndpointStatsSweepInterval {
+		interval = maxEndpointStatsSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				evictStaleEndpointStats(maxAge, now)
+				evictStaleParseStats(maxAge, now)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
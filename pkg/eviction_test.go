@@ -0,0 +1,179 @@
+package logprocessing
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestEvictStaleEndpointStats tests that stale endpoints and their series are removed
+func TestEvictStaleEndpointStats(t *testing.T) {
+	resetEndpointStats(t)
+
+	router := "websecure-evict-shop-a457d08d5820f79b3e08@kubernetes"
+	staleKey := router + ":/api/stale"
+	freshKey := router + ":/api/fresh"
+	now := time.Now()
+	t.Cleanup(func() {
+		series := map[string]string{"ingress": "shop"}
+		for _, vec := range []*prometheus.MetricVec{
+			endpointAvgLatency.MetricVec, endpointMaxLatency.MetricVec, endpointErrorRate.MetricVec,
+			endpointServerErrorRate.MetricVec, endpointRequests.MetricVec, endpointDuration.MetricVec,
+		} {
+			vec.DeletePartialMatch(series)
+		}
+	})
+
+	topPathsMutex.Lock()
+	topPathsPerService[router] = map[string]bool{staleKey: true, freshKey: true}
+	topPathsMutex.Unlock()
+
+	for _, path := range []string{"/api/stale", "/api/fresh"} {
+		updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 500, RouterName: router, RequestPath: path, Duration: 10}, nil, urlNormalization)
+	}
+
+	endpointStatsMutex.Lock()
+	endpointStats[staleKey].LastSeen = now.Add(-2 * time.Hour)
+	endpointStats[freshKey].LastSeen = now.Add(-time.Minute)
+	endpointStats[router+":/api/restored"] = &EndpointStat{TotalRequests: 1}
+	endpointStatsMutex.Unlock()
+
+	if evicted := evictStaleEndpointStats(time.Hour, now); evicted != 1 {
+		t.Errorf("Expected 1 evicted endpoint, got %d", evicted)
+	}
+
+	endpointStatsMutex.RLock()
+	_, staleExists := endpointStats[staleKey]
+	_, freshExists := endpointStats[freshKey]
+	restored := endpointStats[router+":/api/restored"]
+	endpointStatsMutex.RUnlock()
+
+	if staleExists {
+		t.Error("Expected the stale endpoint to be evicted")
+	}
+	if !freshExists {
+		t.Error("Expected the fresh endpoint to be kept")
+	}
+	if restored == nil || !restored.LastSeen.Equal(now) {
+		t.Errorf("Expected the endpoint without LastSeen to be kept and stamped, got %+v", restored)
+	}
+
+	topPathsMutex.RLock()
+	staleTop := topPathsPerService[router][staleKey]
+	topPathsMutex.RUnlock()
+	if staleTop {
+		t.Error("Expected the stale endpoint to leave the top paths")
+	}
+
+	// Deleting an already deleted series reports false
+	namespace, ingress := endpointLabels(router)
+	if endpointErrorRate.DeleteLabelValues(namespace, ingress, "/api/stale") {
+		t.Error("Expected the stale error rate series to be deleted")
+	}
+	if endpointAvgLatency.DeleteLabelValues(namespace, ingress, "/api/stale") {
+		t.Error("Expected the stale average latency series to be deleted")
+	}
+	if endpointRequests.DeleteLabelValues(namespace, ingress, "/api/stale", "GET", "500") {
+		t.Error("Expected the stale request counter series to be deleted")
+	}
+	if !endpointErrorRate.DeleteLabelValues(namespace, ingress, "/api/fresh") {
+		t.Error("Expected the fresh error rate series to be kept")
+	}
+}
+
+// TestStartEndpointStatsSweeper tests that the sweeper evicts endpoints in the background
+func TestStartEndpointStatsSweeper(t *testing.T) {
+	resetEndpointStats(t)
+
+	endpointStatsMutex.Lock()
+	endpointStats["sweep-svc:/old"] = &EndpointStat{TotalRequests: 1, LastSeen: time.Now().Add(-time.Hour)}
+	endpointStatsMutex.Unlock()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	StartEndpointStatsSweeper(20*time.Millisecond, stop)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		endpointStatsMutex.RLock()
+		_, exists := endpointStats["sweep-svc:/old"]
+		endpointStatsMutex.RUnlock()
+		if !exists {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected the sweeper to evict the stale endpoint")
+}
+
+// resetParseStats swaps in empty router parse stats for the duration of a test
+func resetParseStats(t *testing.T) {
+	routerParseStatsMutex.Lock()
+	oldStats := routerParseStats
+	routerParseStats = make(map[string]*parseStat)
+	routerParseStatsMutex.Unlock()
+	routerParseSuccessRatio.Reset()
+
+	t.Cleanup(func() {
+		routerParseStatsMutex.Lock()
+		routerParseStats = oldStats
+		routerParseStatsMutex.Unlock()
+	})
+}
+
+// TestEvictStaleParseStats tests that the parse stats of routers no longer seen and their series are removed
+func TestEvictStaleParseStats(t *testing.T) {
+	resetParseStats(t)
+
+	recordParseResult("shop-old@kubernetes", true)
+	recordParseResult("shop-fresh@kubernetes", false)
+	now := time.Now()
+	routerParseStatsMutex.Lock()
+	routerParseStats["shop-old@kubernetes"].LastSeen = now.Add(-time.Hour)
+	routerParseStatsMutex.Unlock()
+
+	if evicted := evictStaleParseStats(10*time.Minute, now); evicted != 1 {
+		t.Errorf("Expected 1 evicted router, got %d", evicted)
+	}
+	routerParseStatsMutex.Lock()
+	_, oldExists := routerParseStats["shop-old@kubernetes"]
+	_, freshExists := routerParseStats["shop-fresh@kubernetes"]
+	routerParseStatsMutex.Unlock()
+	if oldExists || !freshExists {
+		t.Errorf("Expected only the stale router to be evicted, old kept: %v, fresh kept: %v", oldExists, freshExists)
+	}
+	if routerParseSuccessRatio.DeleteLabelValues("shop-old@kubernetes") {
+		t.Error("Expected the stale series to be deleted")
+	}
+	if !routerParseSuccessRatio.DeleteLabelValues("shop-fresh@kubernetes") {
+		t.Error("Expected the fresh series to be kept")
+	}
+}
+
+// TestRecordParseResultBound tests that the least recently seen router is dropped once the cap is reached
+func TestRecordParseResultBound(t *testing.T) {
+	resetParseStats(t)
+
+	for i := 0; i < maxRouterParseStats; i++ {
+		recordParseResult(fmt.Sprintf("pod:traefik-%d", i), true)
+	}
+	routerParseStatsMutex.Lock()
+	routerParseStats["pod:traefik-0"].LastSeen = time.Now().Add(-time.Hour)
+	routerParseStatsMutex.Unlock()
+
+	recordParseResult("pod:traefik-new", true)
+
+	routerParseStatsMutex.Lock()
+	count := len(routerParseStats)
+	_, oldestExists := routerParseStats["pod:traefik-0"]
+	routerParseStatsMutex.Unlock()
+	if count != maxRouterParseStats || oldestExists {
+		t.Errorf("Expected %d routers without the oldest one, got %d (oldest kept: %v)", maxRouterParseStats, count, oldestExists)
+	}
+	if got := testutil.CollectAndCount(routerParseSuccessRatio); got != maxRouterParseStats {
+		t.Errorf("Expected %d series, got %d", maxRouterParseStats, got)
+	}
+}
@@ -2,19 +2,72 @@ package logprocessing
 
 import (
 	"flag"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/hpcloud/tail"
+	"github.com/prometheus/client_golang/prometheus"
+	logger "github.com/sirupsen/logrus"
+)
+
+// Policies applied when a log source's lines channel is full because processing fell behind
+const (
+	// BufferFullBlock waits for the consumer, pausing the tail until there is room
+	BufferFullBlock = "block"
+	// BufferFullDrop discards the line and counts it, so the tail keeps up with the file
+	BufferFullDrop = "drop"
+)
+
+// defaultLineBufferSize is the capacity of a log source's lines channel
+const defaultLineBufferSize = 100
+
+var (
+	// sourceDroppedLines is built by buildMetrics
+	sourceDroppedLines *prometheus.CounterVec
+
+	// bufferFullLog rate-limits the warnings about dropped lines
+	bufferFullLog = newReasonThrottle(parseFailureSummaryInterval, logger.Warnf)
 )
 
 type LogFileConfig struct {
+	// FileLocation is the access log file, or a glob pattern whose matching files are all tailed.
+	// Files ending in .gz are decompressed and read once instead of tailed.
 	FileLocation string
 	MaxFileBytes int
+	// LogFiles is a comma-separated list of files or glob patterns; when set it replaces FileLocation
+	LogFiles string
+	// BufferSize is the capacity of the lines channel, defaultLineBufferSize if not positive
+	BufferSize int
+	// BufferFullPolicy is BufferFullBlock (default) or BufferFullDrop
+	BufferFullPolicy string
+	// Remote tails the access log of a host without Kubernetes over SSH; it replaces local files when its Host is set
+	Remote SSHConfig
+	// Stdin reads access logs from standard input instead of files
+	Stdin bool
 }
 
-// FileLogSource reads from file using tail
+// FileLogSource reads from file using tail, or reads a gzip-compressed file once
 type FileLogSource struct {
 	tail     *tail.Tail
 	filename string
 	lines    chan LogLine
+	policy   string
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// validateBufferFullPolicy returns the policy, BufferFullBlock if empty, or an error if unknown
+func validateBufferFullPolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return BufferFullBlock, nil
+	case BufferFullBlock, BufferFullDrop:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown buffer full policy %q, expected %s or %s", policy, BufferFullBlock, BufferFullDrop)
+	}
 }
 
 // NewFileLogSource creates a new file-based log source
@@ -26,6 +79,19 @@ func NewFileLogSource(logFileConfig *LogFileConfig) (*FileLogSource, error) {
 		Poll:      true,
 	}
 
+	policy, err := validateBufferFullPolicy(logFileConfig.BufferFullPolicy)
+	if err != nil {
+		return nil, err
+	}
+	bufferSize := logFileConfig.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultLineBufferSize
+	}
+
+	if isGzipFile(logFileConfig.FileLocation) {
+		return newGzipFileLogSource(logFileConfig.FileLocation, bufferSize, policy), nil
+	}
+
 	t, err := tail.TailFile(logFileConfig.FileLocation, tCfg)
 	if err != nil {
 		return nil, err
@@ -34,7 +100,9 @@ func NewFileLogSource(logFileConfig *LogFileConfig) (*FileLogSource, error) {
 	fls := &FileLogSource{
 		tail:     t,
 		filename: logFileConfig.FileLocation,
-		lines:    make(chan LogLine, 100),
+		lines:    make(chan LogLine, bufferSize),
+		policy:   policy,
+		done:     make(chan struct{}),
 	}
 
 	// Start goroutine to convert tail.Line to LogLine
@@ -42,21 +110,83 @@ func NewFileLogSource(logFileConfig *LogFileConfig) (*FileLogSource, error) {
 		defer close(fls.lines)
 		for line := range t.Lines {
 			if line.Err != nil {
-				fls.lines <- LogLine{Text: "", Time: line.Time, Err: line.Err}
+				fls.send(LogLine{Text: "", Time: line.Time, Err: line.Err})
 				continue
 			}
-			fls.lines <- LogLine{Text: line.Text, Time: line.Time, Err: nil}
+			fls.send(LogLine{Text: line.Text, Time: line.Time, Err: nil})
 		}
 	}()
 
 	return fls, nil
 }
 
+// newGzipFileLogSource reads a gzip-compressed file once, e.g. to backfill metrics from rotated
+// logs, and closes its lines channel at the end of the file
+func newGzipFileLogSource(filename string, bufferSize int, policy string) *FileLogSource {
+	fls := &FileLogSource{
+		filename: filename,
+		lines:    make(chan LogLine, bufferSize),
+		policy:   policy,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(fls.lines)
+		err := readGzipFile(filename, func(line LogLine) bool {
+			fls.send(line)
+			select {
+			case <-fls.done:
+				return false
+			default:
+				return true
+			}
+		})
+		if err != nil {
+			fls.send(LogLine{Time: time.Now(), Err: err, Source: filename})
+		}
+	}()
+
+	return fls
+}
+
+// send forwards a line, applying the buffer full policy when processing has fallen behind
+func (fls *FileLogSource) send(line LogLine) {
+	sendLine(fls.lines, fls.done, fls.policy, fls.filename, line)
+}
+
+// sendLine forwards a line read from filename to lines, applying the buffer full policy when
+// processing has fallen behind. It reports false if done was closed before the line was forwarded.
+func sendLine(lines chan<- LogLine, done <-chan struct{}, policy, filename string, line LogLine) bool {
+	if policy != BufferFullDrop {
+		select {
+		case lines <- line:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	select {
+	case lines <- line:
+	case <-done:
+		return false
+	default:
+		sourceDroppedLines.WithLabelValues(SourceModeFile).Inc()
+		bufferFullLog.Logf(filename, "Log processing is falling behind, dropping lines read from %s", filename)
+	}
+	return true
+}
+
 func (fls *FileLogSource) ReadLines() <-chan LogLine {
 	return fls.lines
 }
 
 func (fls *FileLogSource) Close() error {
+	fls.closeOnce.Do(func() {
+		if fls.done != nil {
+			close(fls.done)
+		}
+	})
 	if fls.tail != nil {
 		return fls.tail.Stop()
 	}
@@ -66,8 +196,26 @@ func (fls *FileLogSource) Close() error {
 func AddFileFlags(flags *flag.FlagSet) *LogFileConfig {
 	config := &LogFileConfig{}
 
-	flags.StringVar(&config.FileLocation, "log-file", "./accessLog.txt", "The traefik access log file. Default: ./accessLog.txt")
+	flags.StringVar(&config.FileLocation, "log-file", "./accessLog.txt",
+		"The traefik access log file, or a glob pattern such as /var/log/traefik/access*.log. Default: ./accessLog.txt")
+	flags.StringVar(&config.LogFiles, "log-files", "",
+		"Comma-separated list of traefik access log files or glob patterns to tail concurrently. Overrides -log-file")
 	flags.IntVar(&config.MaxFileBytes, "max-accesslog-size", 10,
 		"How many megabytes should we allow the accesslog to grow to before rotating")
+	flags.IntVar(&config.BufferSize, "log-buffer-size", defaultLineBufferSize,
+		"Number of read log lines buffered ahead of processing")
+	flags.StringVar(&config.BufferFullPolicy, "log-buffer-full-policy", BufferFullBlock,
+		"What to do with read log lines when the buffer is full: block (wait for processing) or drop (count and discard)")
+	flags.StringVar(&config.Remote.Host, "ssh-host", "",
+		"Tail the access log on this host over SSH instead of a local file ([user@]host)")
+	flags.IntVar(&config.Remote.Port, "ssh-port", 22, "SSH port of the remote host")
+	flags.StringVar(&config.Remote.User, "ssh-user", "", "SSH user. Default: the ssh client default")
+	flags.StringVar(&config.Remote.KeyFile, "ssh-key", "", "Private key file used to authenticate to the remote host")
+	flags.StringVar(&config.Remote.KnownHostsFile, "ssh-known-hosts", "",
+		"known_hosts file used to verify the remote host. Default: the ssh client default")
+	flags.StringVar(&config.Remote.LogFile, "ssh-log-file", "/var/log/traefik/access.log",
+		"Path of the traefik access log on the remote host")
+	flags.BoolVar(&config.Stdin, "stdin", false,
+		"Read access logs from standard input instead of files, e.g. piped kubectl logs output")
 	return config
 }
@@ -2,10 +2,13 @@ package logprocessing
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // TestLogFileConfigStruct tests the LogFileConfig struct
@@ -249,6 +252,15 @@ func TestAddFileFlags(t *testing.T) {
 	if config.MaxFileBytes != 10 {
 		t.Errorf("Expected default max file bytes 10, got %d", config.MaxFileBytes)
 	}
+
+	if config.BufferSize != defaultLineBufferSize || config.BufferFullPolicy != BufferFullBlock {
+		t.Errorf("Expected a blocking buffer of %d lines by default, got %d (%s)",
+			defaultLineBufferSize, config.BufferSize, config.BufferFullPolicy)
+	}
+
+	if config.Stdin {
+		t.Error("Expected stdin mode to be disabled by default")
+	}
 }
 
 // TestFileLogSourceIntegration tests file log source with actual log entries
@@ -309,3 +321,78 @@ readLoop:
 		t.Log("No lines read (file may have been read before test started)")
 	}
 }
+
+// TestFileLogSourceBufferFullPolicy tests that a full buffer drops and counts lines with the drop
+// policy and holds them back with the block policy
+func TestFileLogSourceBufferFullPolicy(t *testing.T) {
+	lines := ""
+	for i := 0; i < 10; i++ {
+		lines += fmt.Sprintf("line %d\n", i)
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "access.log")
+		if err := os.WriteFile(tmpFile, []byte(lines), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		before := testutil.ToFloat64(sourceDroppedLines.WithLabelValues(SourceModeFile))
+
+		fls, err := NewFileLogSource(&LogFileConfig{FileLocation: tmpFile, BufferSize: 3, BufferFullPolicy: BufferFullDrop})
+		if err != nil {
+			t.Fatalf("NewFileLogSource() error = %v", err)
+		}
+		defer fls.Close()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for testutil.ToFloat64(sourceDroppedLines.WithLabelValues(SourceModeFile))-before < 7 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if dropped := testutil.ToFloat64(sourceDroppedLines.WithLabelValues(SourceModeFile)) - before; dropped != 7 {
+			t.Errorf("Expected 7 dropped lines, got %v", dropped)
+		}
+		for i := 0; i < 3; i++ {
+			if line := <-fls.ReadLines(); line.Text != fmt.Sprintf("line %d", i) {
+				t.Errorf("Expected the buffered line %d, got %q", i, line.Text)
+			}
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "access.log")
+		if err := os.WriteFile(tmpFile, []byte(lines), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		before := testutil.ToFloat64(sourceDroppedLines.WithLabelValues(SourceModeFile))
+
+		fls, err := NewFileLogSource(&LogFileConfig{FileLocation: tmpFile, BufferSize: 3})
+		if err != nil {
+			t.Fatalf("NewFileLogSource() error = %v", err)
+		}
+		defer fls.Close()
+
+		// Give the tail time to fill the buffer before reading
+		time.Sleep(200 * time.Millisecond)
+		if n := len(fls.lines); n != 3 {
+			t.Errorf("Expected a full buffer of 3 lines, got %d", n)
+		}
+		for i := 0; i < 10; i++ {
+			select {
+			case line := <-fls.ReadLines():
+				if line.Text != fmt.Sprintf("line %d", i) {
+					t.Errorf("Expected line %d, got %q", i, line.Text)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timed out waiting for line %d", i)
+			}
+		}
+		if dropped := testutil.ToFloat64(sourceDroppedLines.WithLabelValues(SourceModeFile)) - before; dropped != 0 {
+			t.Errorf("Expected no dropped lines with the block policy, got %v", dropped)
+		}
+	})
+
+	t.Run("unknown policy", func(t *testing.T) {
+		if _, err := NewFileLogSource(&LogFileConfig{FileLocation: "/tmp/unused.log", BufferFullPolicy: "spill"}); err == nil {
+			t.Error("Expected an unknown policy to be rejected")
+		}
+	})
+}
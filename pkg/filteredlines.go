@@ -0,0 +1,32 @@
+package logprocessing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// linesFiltered is built by buildMetrics
+var linesFiltered *prometheus.CounterVec
+
+// Reasons of traefik_officer_lines_filtered_total
+const (
+	// FilterReasonNoConfig is for routers no enabled UrlPerformance targets, including routers
+	// whose name can't be parsed, in operator mode
+	FilterReasonNoConfig = "no_config"
+	// FilterReasonDisabled is for routers of a disabled UrlPerformance
+	FilterReasonDisabled = "disabled"
+	// FilterReasonKindMismatch is for routers of another kind than the targeted resource
+	FilterReasonKindMismatch = "kind_mismatch"
+	// FilterReasonIgnoredPath is for paths matching an ignore regex
+	FilterReasonIgnoredPath = "ignored_path"
+	// FilterReasonNotWhitelisted is for paths matching none of the whitelist regexes
+	FilterReasonNotWhitelisted = "not_whitelisted"
+	// FilterReasonNotAllowedService is for routers not in AllowedServices, in legacy mode
+	FilterReasonNotAllowedService = "not_allowed_service"
+	// FilterReasonIgnoredRouter is for routers starting with one of the IgnoredRouters, in legacy mode
+	FilterReasonIgnoredRouter = "ignored_router"
+)
+
+// countFilteredLine counts a parsed line skipped by the configuration
+func countFilteredLine(reason string) {
+	linesFiltered.WithLabelValues(reason).Inc()
+}
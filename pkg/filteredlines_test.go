@@ -0,0 +1,95 @@
+package logprocessing
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// filteredLinesLine returns a JSON access log line of a router and path
+func filteredLinesLine(router, path string) LogLine {
+	return LogLine{Text: fmt.Sprintf(`{"RouterName":%q,"RequestMethod":"GET","RequestPath":%q,"OriginStatus":200,"Duration":1000000}`, router, path)}
+}
+
+// TestLinesFilteredOperatorMode tests that lines skipped by the UrlPerformance configs are counted per decision
+func TestLinesFilteredOperatorMode(t *testing.T) {
+	resetEndpointStats(t)
+	linesFiltered.Reset()
+
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+	operatorConfig = &OperatorModeConfig{
+		enabled: true,
+		configManager: &patternsConfigManager{configs: []*shared.RuntimeConfig{
+			{
+				Key: "shop/api", Namespace: "shop", TargetName: "api", TargetKind: "IngressRoute", Enabled: true,
+				IgnoredRegex:   []*regexp.Regexp{regexp.MustCompile(`^/api/health$`)},
+				WhitelistRegex: []*regexp.Regexp{regexp.MustCompile(`^/api/`)},
+			},
+			{Key: "shop/legacy", Namespace: "shop", TargetName: "legacy", TargetKind: "IngressRoute"},
+			{Key: "shop/web", Namespace: "shop", TargetName: "web", TargetKind: "Ingress", Enabled: true},
+		}},
+	}
+
+	lines := make(chan LogLine, 10)
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/api/orders")
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/api/health")
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/admin")
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/login")
+	lines <- filteredLinesLine("shop-cart-a457d08d5820f79b3e08@kubernetescrd", "/cart")
+	lines <- filteredLinesLine("unparseable", "/")
+	lines <- filteredLinesLine("shop-legacy-a457d08d5820f79b3e08@kubernetescrd", "/")
+	lines <- filteredLinesLine("shop-web-a457d08d5820f79b3e08@kubernetescrd", "/")
+	close(lines)
+
+	useK8s := true
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, TraefikOfficerConfig{}, &useK8s, nil, LogFormatJSON)
+
+	expected := map[string]float64{
+		FilterReasonIgnoredPath:    1,
+		FilterReasonNotWhitelisted: 2,
+		FilterReasonNoConfig:       2,
+		FilterReasonDisabled:       1,
+		FilterReasonKindMismatch:   1,
+	}
+	for reason, want := range expected {
+		if got := testutil.ToFloat64(linesFiltered.WithLabelValues(reason)); got != want {
+			t.Errorf("Expected %v lines filtered as %s, got %v", want, reason, got)
+		}
+	}
+	if got := testutil.CollectAndCount(linesFiltered); got != len(expected) {
+		t.Errorf("Expected %d reasons, got %d", len(expected), got)
+	}
+
+	// Looking up a router outside of log processing isn't counted
+	ShouldProcessRouter("shop-cart-a457d08d5820f79b3e08@kubernetescrd")
+	if got := testutil.ToFloat64(linesFiltered.WithLabelValues(FilterReasonNoConfig)); got != 2 {
+		t.Errorf("Expected ShouldProcessRouter not to count lines, got %v", got)
+	}
+}
+
+// TestLinesFilteredLegacyMode tests counting the lines of routers not in AllowedServices
+func TestLinesFilteredLegacyMode(t *testing.T) {
+	resetEndpointStats(t)
+	linesFiltered.Reset()
+
+	lines := make(chan LogLine, 2)
+	lines <- filteredLinesLine("shop-api@kubernetes", "/")
+	lines <- filteredLinesLine("billing-api@kubernetes", "/")
+	close(lines)
+
+	useK8s := true
+	config := TraefikOfficerConfig{AllowedServices: []TraefikService{{Name: "shop"}}}
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, config, &useK8s, nil, LogFormatJSON)
+
+	if got := testutil.ToFloat64(linesFiltered.WithLabelValues(FilterReasonNotAllowedService)); got != 1 {
+		t.Errorf("Expected 1 line filtered as %s, got %v", FilterReasonNotAllowedService, got)
+	}
+}
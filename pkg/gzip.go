@@ -0,0 +1,42 @@
+package logprocessing
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// isGzipFile reports whether path is a gzip-compressed log, such as a rotated access.log.1.gz
+func isGzipFile(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// readGzipFile decompresses a log file and passes each of its lines to send until send returns
+// false. Compressed logs are complete, so they are read once instead of tailed.
+func readGzipFile(path string, send func(LogLine) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if !send(LogLine{Text: scanner.Text(), Time: time.Now(), Source: path}) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
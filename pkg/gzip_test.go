@@ -0,0 +1,133 @@
+package logprocessing
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeGzipLog writes lines to a gzip-compressed log file
+func writeGzipLog(t *testing.T, path string, lines []string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+
+	writer := gzip.NewWriter(f)
+	for _, line := range lines {
+		if _, err := writer.Write([]byte(line + "\n")); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to compress %s: %v", path, err)
+	}
+}
+
+// gzipTestLines returns access log lines with distinct paths
+func gzipTestLines(count int) []string {
+	lines := make([]string, count)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(`10.0.0.1 - - [01/Jan/2024:12:00:00 +0000] "GET /archive/%d HTTP/1.1" 200 512 "-" "curl/8.0" %d `+
+			`"shop-api@kubernetes" "http://10.0.0.5:8080" 3ms`, i, i+1)
+	}
+	return lines
+}
+
+// TestFileLogSourceGzip tests that a compressed file is decompressed, parsed and then closed
+func TestFileLogSourceGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log.1.gz")
+	writeGzipLog(t, path, gzipTestLines(3))
+
+	source, err := NewFileLogSource(&LogFileConfig{FileLocation: path})
+	if err != nil {
+		t.Fatalf("NewFileLogSource() error = %v", err)
+	}
+	defer source.Close()
+
+	var paths []string
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case line, ok := <-source.ReadLines():
+			if !ok {
+				done = true
+				break
+			}
+			if line.Err != nil {
+				t.Fatalf("Unexpected error in log line: %v", line.Err)
+			}
+			entry, err := parseLine(line.Text)
+			if err != nil {
+				t.Fatalf("parseLine(%q) error = %v", line.Text, err)
+			}
+			paths = append(paths, entry.RequestPath)
+		case <-timeout:
+			t.Fatal("Timed out waiting for the compressed file to be read")
+		}
+	}
+
+	if len(paths) != 3 || paths[0] != "/archive/0" || paths[2] != "/archive/2" {
+		t.Errorf("Expected /archive/0 to /archive/2 in order, got %v", paths)
+	}
+}
+
+// TestFileLogSourceGzipInvalid tests that a file that isn't gzip-compressed reports an error
+func TestFileLogSourceGzipInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log.gz")
+	if err := os.WriteFile(path, []byte("not compressed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewFileLogSource(&LogFileConfig{FileLocation: path})
+	if err != nil {
+		t.Fatalf("NewFileLogSource() error = %v", err)
+	}
+	defer source.Close()
+
+	select {
+	case line := <-source.ReadLines():
+		if line.Err == nil {
+			t.Errorf("Expected a decompression error, got line %q", line.Text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the decompression error")
+	}
+}
+
+// TestMultiFileLogSourceGzip tests that a glob reads compressed segments once next to tailed files
+func TestMultiFileLogSourceGzip(t *testing.T) {
+	dir := t.TempDir()
+	compressed := filepath.Join(dir, "access.log.1.gz")
+	current := filepath.Join(dir, "access.log")
+	writeGzipLog(t, compressed, gzipTestLines(2))
+	appendLine(t, current, "current")
+
+	source, err := NewMultiFileLogSource([]string{filepath.Join(dir, "access.log*")}, 50*time.Millisecond, 0, "")
+	if err != nil {
+		t.Fatalf("NewMultiFileLogSource() error = %v", err)
+	}
+	defer source.Close()
+
+	bySource := collectLines(t, source.ReadLines(), 3, 5*time.Second)
+	if len(bySource[compressed]) != 2 || len(bySource[current]) != 1 {
+		t.Fatalf("Expected 2 compressed and 1 tailed line, got %v", bySource)
+	}
+
+	// Rescans must not read the compressed file again
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case line := <-source.ReadLines():
+		t.Errorf("Unexpected line after rescans: %+v", line)
+	default:
+	}
+	if files := source.Files(); len(files) != 1 || files[0] != current {
+		t.Errorf("Expected only %s to be tailed, got %v", current, files)
+	}
+}
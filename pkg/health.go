@@ -2,9 +2,12 @@ package logprocessing
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
+
+	logger "github.com/sirupsen/logrus"
 )
 
 // HealthStatus represents the health status of the service
@@ -13,14 +16,34 @@ type HealthStatus struct {
 	Uptime     string            `json:"uptime,omitempty"`
 	Components map[string]string `json:"components,omitempty"`
 	Error      string            `json:"error,omitempty"`
+	// MaintenanceUntil is set while a maintenance window suppresses staleness degradation
+	MaintenanceUntil string `json:"maintenance_until,omitempty"`
 }
 
+// DefaultHealthStaleness is how long no logs may be processed before log_processing is stale
+const DefaultHealthStaleness = 5 * time.Minute
+
+// Modes of the log_processing staleness check
+const (
+	// HealthStalenessAlways degrades health whenever no logs were processed within the threshold
+	HealthStalenessAlways = "always"
+	// HealthStalenessSourceError only degrades health when, in addition, the log source reports an
+	// error, so ingresses that go quiet stay healthy
+	HealthStalenessSourceError = "source-error"
+	// HealthStalenessOff never degrades health for lack of processed logs
+	HealthStalenessOff = "off"
+)
+
 // Global variables for health status
 var (
 	healthStatus      HealthStatus
 	healthMutex       sync.RWMutex
 	startupTime       = time.Now()
 	lastProcessedTime time.Time
+	maintenanceUntil  time.Time
+	// healthStaleness and healthStalenessMode configure the log_processing staleness check
+	healthStaleness     = DefaultHealthStaleness
+	healthStalenessMode = HealthStalenessAlways
 )
 
 // Initialize health status
@@ -36,6 +59,8 @@ func init() {
 
 // SetServiceReady updates the service status to ready
 func SetServiceReady() {
+	// Deferred first so it runs after the unlock
+	defer updateHealthMetrics()
 	healthMutex.Lock()
 	defer healthMutex.Unlock()
 
@@ -48,6 +73,8 @@ func SetServiceReady() {
 
 // UpdateHealthStatus updates the health status of a component
 func UpdateHealthStatus(component, status string, err error) {
+	// Deferred first so it runs after the unlock
+	defer updateHealthMetrics()
 	healthMutex.Lock()
 	defer healthMutex.Unlock()
 
@@ -71,37 +98,117 @@ func UpdateLastProcessedTime() {
 	lastProcessedTime = time.Now()
 }
 
-// HealthHandler handles health check requests
-func HealthHandler(w http.ResponseWriter, r *http.Request) {
+// SetHealthStaleness configures the log_processing staleness check of /health. A non-positive
+// threshold uses DefaultHealthStaleness and an empty mode HealthStalenessAlways.
+func SetHealthStaleness(threshold time.Duration, mode string) error {
+	switch mode {
+	case "":
+		mode = HealthStalenessAlways
+	case HealthStalenessAlways, HealthStalenessSourceError, HealthStalenessOff:
+	default:
+		return fmt.Errorf("unknown health staleness mode %q, expected %q, %q or %q",
+			mode, HealthStalenessAlways, HealthStalenessSourceError, HealthStalenessOff)
+	}
+	if threshold <= 0 {
+		threshold = DefaultHealthStaleness
+	}
+
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+	healthStaleness = threshold
+	healthStalenessMode = mode
+	return nil
+}
+
+// SetMaintenanceWindow suppresses log-staleness degradation for the given duration.
+// A zero or negative duration ends any active window.
+func SetMaintenanceWindow(duration time.Duration) time.Time {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+
+	if duration <= 0 {
+		maintenanceUntil = time.Time{}
+	} else {
+		maintenanceUntil = time.Now().Add(duration)
+	}
+	return maintenanceUntil
+}
+
+// maintenanceHandler starts (POST ?duration=30m) or ends (DELETE) a maintenance window
+func maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		duration, err := time.ParseDuration(r.URL.Query().Get("duration"))
+		if err != nil || duration <= 0 {
+			http.Error(w, "duration must be a positive Go duration such as 30m", http.StatusBadRequest)
+			return
+		}
+		until := SetMaintenanceWindow(duration)
+		logger.Infof("Maintenance window started, log staleness ignored until %s", until.Format(time.RFC3339))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"maintenance_until": until.Format(time.RFC3339)})
+	case http.MethodDelete:
+		SetMaintenanceWindow(0)
+		logger.Info("Maintenance window ended")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// currentHealth returns a copy of the health status with the uptime and the log_processing
+// staleness check applied
+func currentHealth() HealthStatus {
+	// Copy the components under the lock, UpdateHealthStatus writes to the map concurrently
 	healthMutex.RLock()
-	status := healthStatus
+	response := HealthStatus{
+		Status:     healthStatus.Status,
+		Components: make(map[string]string, len(healthStatus.Components)+1),
+		Error:      healthStatus.Error,
+	}
+	for k, v := range healthStatus.Components {
+		response.Components[k] = v
+	}
 	lastProcessed := lastProcessedTime
+	maintenanceEnd := maintenanceUntil
+	threshold, mode := healthStaleness, healthStalenessMode
 	healthMutex.RUnlock()
 
-	// Create a response copy to avoid concurrent map writes
-	response := HealthStatus{
-		Status:     status.Status,
-		Uptime:     time.Since(startupTime).Round(time.Second).String(),
-		Components: make(map[string]string),
-		Error:      status.Error,
-	}
+	response.Uptime = time.Since(startupTime).Round(time.Second).String()
 
-	// Safely copy the components
-	for k, v := range status.Components {
-		response.Components[k] = v
+	inMaintenance := time.Now().Before(maintenanceEnd)
+	if inMaintenance {
+		response.MaintenanceUntil = maintenanceEnd.Format(time.RFC3339)
 	}
 
-	// Check if we're processing logs
-	if time.Since(lastProcessed) > 5*time.Minute {
+	// Check if we're processing logs. Without logs past the threshold, log_processing is idle when
+	// staleness is ignored and stale otherwise.
+	sourceStatus, hasSource := response.Components["log_source"]
+	sourceFailed := hasSource && !healthyComponentStatuses[sourceStatus]
+	switch {
+	case time.Since(lastProcessed) <= threshold:
+		response.Components["log_processing"] = "active"
+	case mode == HealthStalenessOff || (mode == HealthStalenessSourceError && !sourceFailed):
+		response.Components["log_processing"] = "idle"
+	case inMaintenance:
+		response.Components["log_processing"] = "maintenance"
+	default:
 		response.Components["log_processing"] = "stale"
 		if response.Status == "healthy" {
 			response.Status = "degraded"
-			response.Error = "No logs processed in the last 5 minutes"
+			response.Error = fmt.Sprintf("No logs processed in the last %s", threshold)
 		}
-	} else {
-		response.Components["log_processing"] = "active"
 	}
 
+	return response
+}
+
+// HealthHandler handles health check requests
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	response := currentHealth()
+
 	w.Header().Set("Content-Type", "application/json")
 	if response.Status != "healthy" {
 		w.WriteHeader(http.StatusServiceUnavailable)
@@ -109,3 +216,38 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 
 	_ = json.NewEncoder(w).Encode(response)
 }
+
+// livezHandler reports the process as alive whenever it's serving, so Kubernetes only restarts it
+// when it stopped responding, not when it's idle or its log source failed
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// readinessFailure returns why the process isn't ready to serve metrics, or "" when it is: the log
+// processor must be running, the log source mustn't report a failure and log processing mustn't be
+// stale
+func readinessFailure(status HealthStatus) string {
+	if processor := status.Components["log_processor"]; processor != "running" {
+		return "log processor not running"
+	}
+	if source, ok := status.Components["log_source"]; ok && !healthyComponentStatuses[source] {
+		return "log source " + source
+	}
+	if status.Components["log_processing"] == "stale" {
+		return "no logs processed recently"
+	}
+	return ""
+}
+
+// readyzHandler reports whether the log source is connected and logs are processed, with the same
+// staleness check as /health
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if reason := readinessFailure(currentHealth()); reason != "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprintf(w, "not ready: %s\n", reason)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
@@ -366,3 +366,223 @@ func TestInitHealthStatus(t *testing.T) {
 		t.Error("Expected components map to be initialized")
 	}
 }
+
+// TestHealthHandlerMaintenanceWindow tests that staleness is suppressed during a maintenance window
+func TestHealthHandlerMaintenanceWindow(t *testing.T) {
+	defer SetMaintenanceWindow(0)
+
+	setStale := func() {
+		healthMutex.Lock()
+		healthStatus = HealthStatus{
+			Status:     "healthy",
+			Components: map[string]string{"service": "running"},
+		}
+		lastProcessedTime = time.Now().Add(-10 * time.Minute)
+		healthMutex.Unlock()
+	}
+
+	check := func(expectedCode int, expectedProcessing string, expectMaintenance bool) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/health", nil)
+		w := httptest.NewRecorder()
+		HealthHandler(w, req)
+
+		if w.Code != expectedCode {
+			t.Errorf("Expected status code %d, got %d", expectedCode, w.Code)
+		}
+		var hs HealthStatus
+		if err := json.NewDecoder(w.Body).Decode(&hs); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if hs.Components["log_processing"] != expectedProcessing {
+			t.Errorf("Expected log_processing '%s', got '%s'", expectedProcessing, hs.Components["log_processing"])
+		}
+		if (hs.MaintenanceUntil != "") != expectMaintenance {
+			t.Errorf("Expected maintenance reported = %v, got '%s'", expectMaintenance, hs.MaintenanceUntil)
+		}
+	}
+
+	// Start a window through the admin endpoint
+	setStale()
+	req := httptest.NewRequest("POST", "/admin/maintenance?duration=30m", nil)
+	w := httptest.NewRecorder()
+	maintenanceHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 starting maintenance, got %d", w.Code)
+	}
+	check(http.StatusOK, "maintenance", true)
+
+	// Window expires on its own
+	healthMutex.Lock()
+	maintenanceUntil = time.Now().Add(-time.Second)
+	healthMutex.Unlock()
+	check(http.StatusServiceUnavailable, "stale", false)
+
+	// Window can be ended explicitly
+	SetMaintenanceWindow(time.Hour)
+	req = httptest.NewRequest("DELETE", "/admin/maintenance", nil)
+	w = httptest.NewRecorder()
+	maintenanceHandler(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 ending maintenance, got %d", w.Code)
+	}
+	check(http.StatusServiceUnavailable, "stale", false)
+}
+
+// TestMaintenanceHandlerInvalidRequests tests validation of maintenance requests
+func TestMaintenanceHandlerInvalidRequests(t *testing.T) {
+	defer SetMaintenanceWindow(0)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		expectCode int
+	}{
+		{name: "missing duration", method: "POST", target: "/admin/maintenance", expectCode: http.StatusBadRequest},
+		{name: "invalid duration", method: "POST", target: "/admin/maintenance?duration=soon", expectCode: http.StatusBadRequest},
+		{name: "negative duration", method: "POST", target: "/admin/maintenance?duration=-5m", expectCode: http.StatusBadRequest},
+		{name: "unsupported method", method: "GET", target: "/admin/maintenance", expectCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			w := httptest.NewRecorder()
+			maintenanceHandler(w, req)
+			if w.Code != tt.expectCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectCode, w.Code)
+			}
+		})
+	}
+}
+
+// TestHealthStaleness tests the configurable log_processing staleness threshold and modes
+func TestHealthStaleness(t *testing.T) {
+	defer func() {
+		_ = SetHealthStaleness(0, "")
+	}()
+
+	tests := []struct {
+		name           string
+		threshold      time.Duration
+		mode           string
+		idleFor        time.Duration
+		sourceStatus   string
+		expectedStatus int
+		expectedLog    string
+	}{
+		{name: "below threshold", threshold: time.Hour, idleFor: 30 * time.Minute, expectedStatus: http.StatusOK, expectedLog: "active"},
+		{name: "above threshold", threshold: 10 * time.Minute, idleFor: 15 * time.Minute, expectedStatus: http.StatusServiceUnavailable, expectedLog: "stale"},
+		{name: "default threshold", idleFor: 6 * time.Minute, expectedStatus: http.StatusServiceUnavailable, expectedLog: "stale"},
+		{name: "disabled check", mode: HealthStalenessOff, idleFor: 24 * time.Hour, expectedStatus: http.StatusOK, expectedLog: "idle"},
+		{name: "idle source", mode: HealthStalenessSourceError, idleFor: time.Hour, sourceStatus: "running", expectedStatus: http.StatusOK, expectedLog: "idle"},
+		{name: "failed source", mode: HealthStalenessSourceError, idleFor: time.Hour, sourceStatus: "stopped", expectedStatus: http.StatusServiceUnavailable, expectedLog: "stale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetHealthStaleness(tt.threshold, tt.mode); err != nil {
+				t.Fatalf("SetHealthStaleness() error = %v", err)
+			}
+			healthMutex.Lock()
+			healthStatus = HealthStatus{
+				Status:     "healthy",
+				Components: map[string]string{"service": "running"},
+			}
+			if tt.sourceStatus != "" {
+				healthStatus.Components["log_source"] = tt.sourceStatus
+			}
+			lastProcessedTime = time.Now().Add(-tt.idleFor)
+			healthMutex.Unlock()
+
+			w := httptest.NewRecorder()
+			HealthHandler(w, httptest.NewRequest("GET", "/health", nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			var response HealthStatus
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Components["log_processing"] != tt.expectedLog {
+				t.Errorf("Expected log_processing %q, got %q", tt.expectedLog, response.Components["log_processing"])
+			}
+		})
+	}
+
+	if err := SetHealthStaleness(time.Minute, "never"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+// TestLivezHandler tests that liveness doesn't depend on the health of the components
+func TestLivezHandler(t *testing.T) {
+	healthMutex.Lock()
+	healthStatus = HealthStatus{
+		Status:     "error",
+		Components: map[string]string{"service": "running", "log_source": "error"},
+		Error:      "stream closed",
+	}
+	lastProcessedTime = time.Now().Add(-time.Hour)
+	healthMutex.Unlock()
+
+	w := httptest.NewRecorder()
+	livezHandler(w, httptest.NewRequest("GET", "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", w.Code)
+	}
+}
+
+// TestReadyzHandler tests readiness for each condition of the log source and processing
+func TestReadyzHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		components     map[string]string
+		idleFor        time.Duration
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "processing logs",
+			components:     map[string]string{"log_processor": "running", "log_source": "running"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ok\n",
+		},
+		{
+			name:           "log processor not started",
+			components:     map[string]string{"service": "running"},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready: log processor not running\n",
+		},
+		{
+			name:           "log source reconnecting",
+			components:     map[string]string{"log_processor": "running", "log_source": "reconnecting"},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready: log source reconnecting\n",
+		},
+		{
+			name:           "no logs processed recently",
+			components:     map[string]string{"log_processor": "running"},
+			idleFor:        time.Hour,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready: no logs processed recently\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthMutex.Lock()
+			healthStatus = HealthStatus{Status: "healthy", Components: tt.components}
+			lastProcessedTime = time.Now().Add(-tt.idleFor)
+			healthMutex.Unlock()
+
+			w := httptest.NewRecorder()
+			readyzHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if body := w.Body.String(); body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package logprocessing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// healthUp and componentHealthy are built by buildMetrics
+	healthUp         prometheus.Gauge
+	componentHealthy *prometheus.GaugeVec
+)
+
+// healthyComponentStatuses are the component statuses reported as healthy. Others, such as error,
+// stale, stopped or reconnecting, aren't.
+var healthyComponentStatuses = map[string]bool{
+	"initializing": true,
+	"running":      true,
+	"active":       true,
+	"idle":         true,
+	"maintenance":  true,
+}
+
+// updateHealthMetrics sets the health gauges from the status HealthHandler serves. It's called when
+// a component status changes and on every scrape, so the log_processing staleness shows up too.
+func updateHealthMetrics() {
+	status := currentHealth()
+
+	if status.Status == "healthy" {
+		healthUp.Set(1)
+	} else {
+		healthUp.Set(0)
+	}
+	for component, componentStatus := range status.Components {
+		if healthyComponentStatuses[componentStatus] {
+			componentHealthy.WithLabelValues(component).Set(1)
+		} else {
+			componentHealthy.WithLabelValues(component).Set(0)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package logprocessing
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestHealthMetrics tests that the health gauges flip when a component errors and when log processing goes stale
+func TestHealthMetrics(t *testing.T) {
+	healthMutex.Lock()
+	oldStatus, oldLastProcessed := healthStatus, lastProcessedTime
+	healthStatus = HealthStatus{
+		Status:     "starting",
+		Components: map[string]string{"service": "initializing"},
+	}
+	lastProcessedTime = time.Now()
+	healthMutex.Unlock()
+	defer func() {
+		healthMutex.Lock()
+		healthStatus, lastProcessedTime = oldStatus, oldLastProcessed
+		healthMutex.Unlock()
+	}()
+
+	SetServiceReady()
+	UpdateHealthStatus("log_source", "running", nil)
+	if got := testutil.ToFloat64(healthUp); got != 1 {
+		t.Errorf("Expected up to be 1, got %v", got)
+	}
+	for _, component := range []string{"service", "log_source", "log_processing"} {
+		if got := testutil.ToFloat64(componentHealthy.WithLabelValues(component)); got != 1 {
+			t.Errorf("Expected %s to be healthy, got %v", component, got)
+		}
+	}
+
+	UpdateHealthStatus("log_source", "error", errors.New("stream closed"))
+	if got := testutil.ToFloat64(healthUp); got != 0 {
+		t.Errorf("Expected up to be 0 after an error, got %v", got)
+	}
+	if got := testutil.ToFloat64(componentHealthy.WithLabelValues("log_source")); got != 0 {
+		t.Errorf("Expected log_source to be unhealthy, got %v", got)
+	}
+	if got := testutil.ToFloat64(componentHealthy.WithLabelValues("service")); got != 1 {
+		t.Errorf("Expected service to stay healthy, got %v", got)
+	}
+
+	// Staleness only depends on time, so it's picked up when scraping
+	healthMutex.Lock()
+	healthStatus.Status, healthStatus.Error = "healthy", ""
+	healthStatus.Components["log_source"] = "running"
+	lastProcessedTime = time.Now().Add(-6 * time.Minute)
+	healthMutex.Unlock()
+	metricsHandlerWithGaugeReset(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+	if got := testutil.ToFloat64(componentHealthy.WithLabelValues("log_processing")); got != 0 {
+		t.Errorf("Expected stale log_processing to be unhealthy, got %v", got)
+	}
+	if got := testutil.ToFloat64(healthUp); got != 0 {
+		t.Errorf("Expected up to be 0 while degraded, got %v", got)
+	}
+}
@@ -1,57 +1,247 @@
 package logprocessing
 
 import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	logger "github.com/sirupsen/logrus"
 )
 
-func ServeProm(port string) error {
+// serverShutdownTimeout bounds how long in-flight requests may take once the server is stopping
+const serverShutdownTimeout = 5 * time.Second
+
+// MetricsServerConfig secures the metrics server. The zero value serves plaintext HTTP without
+// authentication.
+type MetricsServerConfig struct {
+	// TLSCertFile and TLSKeyFile serve HTTPS when both are set
+	TLSCertFile string
+	TLSKeyFile  string
+	// AuthUser and AuthPass require HTTP basic auth on every endpoint when both are set
+	AuthUser string
+	AuthPass string
+	// AuthExemptHealth serves /health, /livez and /readyz without basic auth, so probes keep working
+	AuthExemptHealth bool
+	// EnablePprof serves the runtime profiles under /debug/pprof/
+	EnablePprof bool
+}
+
+// validate returns an error if only half of the TLS or basic auth settings are given
+func (c MetricsServerConfig) validate() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("both a TLS certificate and a TLS key are required to serve metrics over TLS")
+	}
+	if (c.AuthUser == "") != (c.AuthPass == "") {
+		return errors.New("both a user and a password are required for metrics basic auth")
+	}
+	return nil
+}
+
+// probePaths are the health endpoints AuthExemptHealth exempts from basic auth
+var probePaths = map[string]bool{"/health": true, "/livez": true, "/readyz": true}
+
+// basicAuth requires the configured credentials on every request, except the probes when exempted
+func (c MetricsServerConfig) basicAuth(next http.Handler) http.Handler {
+	if c.AuthUser == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.AuthExemptHealth && probePaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, pass, ok := r.BasicAuth()
+		userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(c.AuthUser)) == 1
+		passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(c.AuthPass)) == 1
+		if !ok || !userMatch || !passMatch {
+			w.Header().Set("WWW-Authenticate", `Basic realm="traefik-officer"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServeProm starts the metrics server on port and returns it once it is listening. The server
+// uses its own mux, so it can be started more than once in a process, and is shut down
+// gracefully when ctx is cancelled.
+func ServeProm(ctx context.Context, port string, serverConfig MetricsServerConfig) (*http.Server, error) {
 	if port == "" {
-		return errors.New("port cannot be empty")
+		return nil, errors.New("port cannot be empty")
+	}
+	if err := serverConfig.validate(); err != nil {
+		return nil, err
 	}
 
 	addr := ":" + port
 
 	// Register handlers
-	http.Handle("/metrics", http.HandlerFunc(metricsHandlerWithGaugeReset))
-	http.HandleFunc("/health", HealthHandler)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", http.HandlerFunc(metricsHandlerWithGaugeReset))
+	mux.HandleFunc("/health", HealthHandler)
+	mux.HandleFunc("/livez", livezHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.HandleFunc("/config", adminGuard(configHandler))
+	mux.HandleFunc("/debug/patterns", adminGuard(debugPatternsHandler))
+	mux.HandleFunc("/debug/router", adminGuard(debugRouterHandler))
+	mux.HandleFunc("/admin/maintenance", adminGuard(maintenanceHandler))
+	if serverConfig.EnablePprof {
+		registerPprof(mux)
+	}
 
-	logger.Infof("Starting metrics server on %s/metrics", addr)
-	logger.Infof("Health check available at %s/health", addr)
+	var tlsConfig *tls.Config
+	if serverConfig.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(serverConfig.TLSCertFile, serverConfig.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load metrics TLS certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start metrics server: %w", err)
+	}
 
 	server := &http.Server{
-		Addr: addr,
+		Addr:      listener.Addr().String(),
+		Handler:   serverConfig.basicAuth(mux),
+		TLSConfig: tlsConfig,
 	}
 
+	if tlsConfig != nil {
+		logger.Info("Serving metrics over TLS")
+	}
+	if serverConfig.AuthUser != "" {
+		logger.Info("Metrics server requires basic auth")
+	}
+	logger.Infof("Starting metrics server on %s/metrics", server.Addr)
+	logger.Infof("Health check available at %s/health, probes at /livez and /readyz", server.Addr)
+
 	// Update health status to indicate service is running
 	UpdateHealthStatus("http_server", "running", nil)
 
-	errChan := make(chan error, 1)
 	go func() {
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			errChan <- fmt.Errorf("failed to start metrics server: %w", err)
+		var err error
+		if tlsConfig != nil {
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			UpdateHealthStatus("http_server", "error", err)
+			logger.Errorf("Metrics server error: %v", err)
 		}
 	}()
 
-	// Check if server started successfully
-	select {
-	case err := <-errChan:
-		return err
-	default:
-		// Server started successfully
-		SetServiceReady()
-		logger.Info("Metrics server started successfully")
-		return nil
-	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("Error shutting down metrics server: %v", err)
+		}
+		UpdateHealthStatus("http_server", "stopped", nil)
+		logger.Info("Metrics server stopped")
+	}()
+
+	SetServiceReady()
+	logger.Info("Metrics server started successfully")
+	return server, nil
+}
+
+// noScrapeReset disables resetting the error rate gauges after each scrape
+var noScrapeReset atomic.Bool
+
+// SetNoScrapeReset makes /metrics a plain Prometheus handler without side effects on scrape.
+// The per-scrape reset of the error rate gauges stays the default for backward compatibility.
+func SetNoScrapeReset(disabled bool) {
+	noScrapeReset.Store(disabled)
+}
+
+var (
+	// scrapeSlot serializes scrapes so a scrape never observes a reset done by a concurrent one
+	scrapeSlot = make(chan struct{}, 1)
+
+	scrapeOptionsMutex sync.RWMutex
+	scrapeTimeout      time.Duration
+	scrapeHandler      = newScrapeHandler(0, false)
+)
+
+// newScrapeHandler builds the Prometheus handler serving /metrics
+func newScrapeHandler(timeout time.Duration, enableOpenMetrics bool) http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			Timeout:           timeout,
+			EnableOpenMetrics: enableOpenMetrics,
+		}))
+}
+
+// SetScrapeOptions configures the /metrics handler. A positive timeout bounds both the wait for a
+// concurrent scrape and the metrics gathering; scrapes exceeding it get a 503 and don't reset gauges.
+// enableOpenMetrics serves the OpenMetrics format to scrapers that negotiate it.
+func SetScrapeOptions(timeout time.Duration, enableOpenMetrics bool) {
+	scrapeOptionsMutex.Lock()
+	defer scrapeOptionsMutex.Unlock()
+	scrapeTimeout = timeout
+	scrapeHandler = newScrapeHandler(timeout, enableOpenMetrics)
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
 }
 
 func metricsHandlerWithGaugeReset(w http.ResponseWriter, r *http.Request) {
+	scrapeOptionsMutex.RLock()
+	timeout := scrapeTimeout
+	handler := scrapeHandler
+	scrapeOptionsMutex.RUnlock()
+
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+
+	// Scrape and reset atomically with respect to other scrapes
+	select {
+	case scrapeSlot <- struct{}{}:
+	case <-timedOut:
+		http.Error(w, "timed out waiting for a concurrent scrape", http.StatusServiceUnavailable)
+		return
+	case <-r.Context().Done():
+		return
+	}
+	defer func() { <-scrapeSlot }()
+
+	// Refresh the health gauges, whose log_processing staleness changes with time alone
+	updateHealthMetrics()
+
 	// Serve metrics
-	promhttp.Handler().ServeHTTP(w, r)
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	handler.ServeHTTP(recorder, r)
+
+	// Keep the gauges when the scrape failed or timed out, so their values aren't lost
+	if noScrapeReset.Load() || recorder.status != http.StatusOK {
+		return
+	}
 
 	endpointErrorRate.Reset()
 	endpointClientErrorRate.Reset()
@@ -0,0 +1,34 @@
+package logprocessing
+
+import "fmt"
+
+// Units of the Duration and Overhead fields of JSON access logs, see SetJSONDurationUnit
+const (
+	// JSONDurationNanoseconds is the unit Traefik logs durations in
+	JSONDurationNanoseconds = "ns"
+	// JSONDurationMicroseconds is for pipelines that convert durations to microseconds
+	JSONDurationMicroseconds = "us"
+	// JSONDurationMilliseconds is for pipelines that convert durations to milliseconds
+	JSONDurationMilliseconds = "ms"
+)
+
+// jsonDurationDivisor converts the Duration and Overhead fields of JSON logs to milliseconds,
+// set by SetJSONDurationUnit
+var jsonDurationDivisor float64 = 1e6
+
+// SetJSONDurationUnit sets the unit of the Duration and Overhead fields of JSON access logs:
+// ns (the default, as logged by Traefik), us or ms
+func SetJSONDurationUnit(unit string) error {
+	switch unit {
+	case "", JSONDurationNanoseconds:
+		jsonDurationDivisor = 1e6
+	case JSONDurationMicroseconds:
+		jsonDurationDivisor = 1e3
+	case JSONDurationMilliseconds:
+		jsonDurationDivisor = 1
+	default:
+		return fmt.Errorf("unknown JSON duration unit %q, expected %q, %q or %q",
+			unit, JSONDurationNanoseconds, JSONDurationMicroseconds, JSONDurationMilliseconds)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package logprocessing
+
+import "testing"
+
+// TestParseJSONDurationUnit tests that Duration and Overhead are converted to milliseconds from each unit
+func TestParseJSONDurationUnit(t *testing.T) {
+	defer func() {
+		if err := SetJSONDurationUnit(JSONDurationNanoseconds); err != nil {
+			t.Fatalf("SetJSONDurationUnit() error = %v", err)
+		}
+	}()
+
+	tests := []struct {
+		unit string
+		line string
+	}{
+		{unit: "", line: `{"RouterName":"r","Duration":45000000,"Overhead":1500000}`},
+		{unit: JSONDurationNanoseconds, line: `{"RouterName":"r","Duration":45000000,"Overhead":1500000}`},
+		{unit: JSONDurationMicroseconds, line: `{"RouterName":"r","Duration":45000,"Overhead":1500}`},
+		{unit: JSONDurationMilliseconds, line: `{"RouterName":"r","Duration":45,"Overhead":1.5}`},
+	}
+
+	for _, tt := range tests {
+		t.Run("unit "+tt.unit, func(t *testing.T) {
+			if err := SetJSONDurationUnit(tt.unit); err != nil {
+				t.Fatalf("SetJSONDurationUnit(%q) error = %v", tt.unit, err)
+			}
+			log, err := parseJSON(tt.line)
+			if err != nil {
+				t.Fatalf("parseJSON() error = %v", err)
+			}
+			if log.Duration != 45 {
+				t.Errorf("Duration = %v, want 45", log.Duration)
+			}
+			if log.Overhead != 1.5 {
+				t.Errorf("Overhead = %v, want 1.5", log.Overhead)
+			}
+		})
+	}
+}
+
+// TestSetJSONDurationUnitInvalid tests that unknown units are rejected and keep the current unit
+func TestSetJSONDurationUnitInvalid(t *testing.T) {
+	if err := SetJSONDurationUnit("s"); err == nil {
+		t.Error("Expected an error for an unknown unit")
+	}
+	if jsonDurationDivisor != 1e6 {
+		t.Errorf("Divisor = %v, want the nanosecond default", jsonDurationDivisor)
+	}
+}
@@ -0,0 +1,137 @@
+package logprocessing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+var (
+	// jsonFieldPaths maps traefikLogConfig field names to dot-paths in nested JSON access logs
+	jsonFieldPaths      map[string]string
+	jsonFieldPathsMutex sync.RWMutex
+)
+
+// jsonFieldSetters assigns a decoded JSON value to a traefikLogConfig field, reporting whether
+// the value had a usable type
+var jsonFieldSetters = map[string]func(*traefikLogConfig, interface{}) bool{
+	"ClientHost":         stringField(func(l *traefikLogConfig, v string) { l.ClientHost = v }),
+	"StartUTC":           stringField(func(l *traefikLogConfig, v string) { l.StartUTC = v }),
+	"RouterName":         stringField(func(l *traefikLogConfig, v string) { l.RouterName = v }),
+	"RequestMethod":      stringField(func(l *traefikLogConfig, v string) { l.RequestMethod = v }),
+	"RequestPath":        stringField(func(l *traefikLogConfig, v string) { l.RequestPath = v }),
+	"RequestProtocol":    stringField(func(l *traefikLogConfig, v string) { l.RequestProtocol = v }),
+	"OriginStatus":       numberField(func(l *traefikLogConfig, v float64) { l.OriginStatus = int(v) }),
+	"OriginContentSize":  numberField(func(l *traefikLogConfig, v float64) { l.OriginContentSize = int(v) }),
+	"RequestContentSize": numberField(func(l *traefikLogConfig, v float64) { l.RequestContentSize = int(v) }),
+	"RequestCount":       numberField(func(l *traefikLogConfig, v float64) { l.RequestCount = int(v) }),
+	"Duration":           numberField(func(l *traefikLogConfig, v float64) { l.Duration = v }),
+	"Overhead":           numberField(func(l *traefikLogConfig, v float64) { l.Overhead = v }),
+	"UserAgent":          stringField(func(l *traefikLogConfig, v string) { l.UserAgent = v }),
+	"TLSVersion":         stringField(func(l *traefikLogConfig, v string) { l.TLSVersion = v }),
+	"TLSCipher":          stringField(func(l *traefikLogConfig, v string) { l.TLSCipher = v }),
+}
+
+func stringField(set func(*traefikLogConfig, string)) func(*traefikLogConfig, interface{}) bool {
+	return func(l *traefikLogConfig, value interface{}) bool {
+		s, ok := value.(string)
+		if ok {
+			set(l, s)
+		}
+		return ok
+	}
+}
+
+func numberField(set func(*traefikLogConfig, float64)) func(*traefikLogConfig, interface{}) bool {
+	return func(l *traefikLogConfig, value interface{}) bool {
+		switch v := value.(type) {
+		case float64:
+			set(l, v)
+			return true
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return false
+			}
+			set(l, f)
+			return true
+		}
+		return false
+	}
+}
+
+// SetJSONFieldPaths sets the dot-path of each access log field in nested JSON logs,
+// e.g. {"RequestPath": "request.path"}. Unknown field names are rejected.
+func SetJSONFieldPaths(paths map[string]string) error {
+	if err := validateJSONFieldPaths(paths); err != nil {
+		return err
+	}
+	setJSONFieldPaths(paths)
+	return nil
+}
+
+// validateJSONFieldPaths returns an error naming the unknown field names of the field paths
+func validateJSONFieldPaths(paths map[string]string) error {
+	unknown := make([]string, 0)
+	for field := range paths {
+		if _, ok := jsonFieldSetters[field]; !ok {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown JSON log fields: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// setJSONFieldPaths replaces the field paths with validated ones
+func setJSONFieldPaths(paths map[string]string) {
+	jsonFieldPathsMutex.Lock()
+	defer jsonFieldPathsMutex.Unlock()
+	jsonFieldPaths = paths
+}
+
+// applyJSONFieldPaths fills the fields with a configured dot-path from the decoded log line.
+// Fields whose path is missing from the line keep the value decoded from the flat schema.
+func applyJSONFieldPaths(raw map[string]interface{}, log *traefikLogConfig) {
+	jsonFieldPathsMutex.RLock()
+	defer jsonFieldPathsMutex.RUnlock()
+
+	for field, path := range jsonFieldPaths {
+		value, ok := lookupJSONPath(raw, path)
+		if !ok {
+			continue
+		}
+		if !jsonFieldSetters[field](log, value) {
+			logger.Debugf("Ignoring JSON field %s at %s: unexpected type %T", field, path, value)
+		}
+	}
+}
+
+// hasJSONFieldPaths reports whether nested field paths are configured
+func hasJSONFieldPaths() bool {
+	jsonFieldPathsMutex.RLock()
+	defer jsonFieldPathsMutex.RUnlock()
+	return len(jsonFieldPaths) > 0
+}
+
+// lookupJSONPath resolves a dot-separated path such as "request.path" in a decoded JSON object
+func lookupJSONPath(raw map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = raw
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,99 @@
+package logprocessing
+
+import (
+	"testing"
+)
+
+// TestParseJSONNestedFieldPaths tests that flat and nested JSON logs parse to the same entry
+func TestParseJSONNestedFieldPaths(t *testing.T) {
+	defer func() {
+		_ = SetJSONFieldPaths(nil)
+	}()
+
+	flat := `{"ClientHost":"10.0.0.1","RouterName":"shop-api@kubernetes","RequestMethod":"POST",` +
+		`"RequestPath":"/api/orders","RequestProtocol":"HTTP/1.1","OriginStatus":201,` +
+		`"OriginContentSize":512,"Duration":25000000,"Overhead":1000000}`
+	nested := `{"client":{"host":"10.0.0.1"},"router":{"name":"shop-api@kubernetes"},` +
+		`"request":{"method":"POST","path":"/api/orders","protocol":"HTTP/1.1"},` +
+		`"response":{"status":201,"size":"512"},"timing":{"duration":25000000,"overhead":1000000}}`
+
+	if err := SetJSONFieldPaths(nil); err != nil {
+		t.Fatalf("SetJSONFieldPaths(nil) error = %v", err)
+	}
+	expected, err := parseJSON(flat)
+	if err != nil {
+		t.Fatalf("parseJSON(flat) error = %v", err)
+	}
+
+	err = SetJSONFieldPaths(map[string]string{
+		"ClientHost":        "client.host",
+		"RouterName":        "router.name",
+		"RequestMethod":     "request.method",
+		"RequestPath":       "request.path",
+		"RequestProtocol":   "request.protocol",
+		"OriginStatus":      "response.status",
+		"OriginContentSize": "response.size",
+		"Duration":          "timing.duration",
+		"Overhead":          "timing.overhead",
+	})
+	if err != nil {
+		t.Fatalf("SetJSONFieldPaths() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "nested schema", line: nested},
+		{name: "flat schema falls back to flat keys", line: flat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseJSON(tt.line)
+			if err != nil {
+				t.Fatalf("parseJSON() error = %v", err)
+			}
+			if result != expected {
+				t.Errorf("parseJSON() = %+v, want %+v", result, expected)
+			}
+		})
+	}
+}
+
+// TestParseJSONNestedFieldTypeMismatch tests that a nested value of the wrong type is ignored
+func TestParseJSONNestedFieldTypeMismatch(t *testing.T) {
+	defer func() {
+		_ = SetJSONFieldPaths(nil)
+	}()
+
+	if err := SetJSONFieldPaths(map[string]string{"OriginStatus": "response.status", "RequestPath": "request.path"}); err != nil {
+		t.Fatalf("SetJSONFieldPaths() error = %v", err)
+	}
+
+	result, err := parseJSON(`{"OriginStatus":200,"request":{"path":"/nested"},"response":{"status":{"code":500}}}`)
+	if err != nil {
+		t.Fatalf("parseJSON() error = %v", err)
+	}
+	if result.OriginStatus != 200 {
+		t.Errorf("Expected flat OriginStatus 200 to be kept, got %d", result.OriginStatus)
+	}
+	if result.RequestPath != "/nested" {
+		t.Errorf("Expected RequestPath /nested, got %q", result.RequestPath)
+	}
+}
+
+// TestSetJSONFieldPathsUnknownField tests that unknown field names are rejected
+func TestSetJSONFieldPathsUnknownField(t *testing.T) {
+	defer func() {
+		_ = SetJSONFieldPaths(nil)
+	}()
+
+	err := SetJSONFieldPaths(map[string]string{"RequestPath": "request.path", "Latency": "timing.latency"})
+	if err == nil {
+		t.Fatal("Expected an error for an unknown field")
+	}
+	if hasJSONFieldPaths() {
+		t.Error("Expected field paths to stay unset after a rejected mapping")
+	}
+}
@@ -5,49 +5,83 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"k8s.io/client-go/tools/clientcmd"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	logger "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
+// Defaults of the pod discovery and log stream retry settings of K8SConfig
 const (
 	maxRetries          = 10
 	initialBackoff      = 1 * time.Second
 	maxBackoff          = 1 * time.Minute  // Reduced from 5 minutes
-	syncInterval        = 10 * time.Second // Reduced from 30s for faster recovery
-	podDiscoveryTimeout = 15 * time.Second // Reduced from 5m for faster pod discovery
+	syncInterval        = 10 * time.Second // Resync period of the pod informer
+	podDiscoveryTimeout = 15 * time.Second // How long to wait for the initial pod list
+)
+
+var (
+	// activePodStreams and podStreamReconnects are built by buildMetrics
+	activePodStreams    prometheus.Gauge
+	podStreamReconnects prometheus.Counter
 )
 
 // podStream represents a running log stream for a pod
 type podStream struct {
 	cancelFunc context.CancelFunc
+	namespace  string
 	podName    string
 }
 
 // KubernetesLogSource reads from Kubernetes pod logs
 type KubernetesLogSource struct {
-	clientSet     *kubernetes.Clientset
-	namespace     string
+	clientSet     kubernetes.Interface
+	namespaces    []string
 	containerName string
 	labelSelector string
+	fieldSelector string
 	lines         chan LogLine
 
-	// For managing pod streams
-	podStreams  map[string]*podStream
-	podMutex    sync.Mutex
-	lastPodSync time.Time
-	lastPodList []v1.Pod
+	// For managing pod streams, started and stopped by pod informer events and keyed by namespace/pod
+	podStreams map[string]*podStream
+	podMutex   sync.Mutex
+
+	// For draining the logs of crashed containers, guarded by podMutex and keyed by namespace/pod.
+	// restartCounts holds the container restarts last seen, pendingPrevious the pods whose previous
+	// container wasn't drained yet and lastLineTimes the timestamp of the last line emitted.
+	includePrevious bool
+	restartCounts   map[string]int32
+	pendingPrevious map[string]bool
+	lastLineTimes   map[string]time.Time
+
+	// Pod discovery and log stream retry settings, falling back to the defaults when not positive
+	syncInterval        time.Duration
+	podDiscoveryTimeout time.Duration
+	maxRetries          int
+	initialBackoff      time.Duration
+	maxBackoff          time.Duration
+
+	// For replaying recent logs on startup, see backfillSeconds. backfilled holds the pods whose
+	// history was already replayed, guarded by podMutex and keyed by namespace/pod.
+	sinceSeconds int64
+	backfilled   map[string]bool
 
 	// For graceful shutdown
 	stopCh chan struct{}
@@ -56,12 +90,33 @@ type KubernetesLogSource struct {
 
 // K8SConfig holds the Kubernetes configuration options
 type K8SConfig struct {
-	InCluster     bool
-	KubeConfig    string
-	Context       string
+	InCluster  bool
+	KubeConfig string
+	Context    string
+	// Namespace is the namespace of the Traefik pods, or a comma-separated list of namespaces
 	Namespace     string
 	ContainerName string
 	LabelSelector string
+	// FieldSelector further restricts the Traefik pods, e.g. status.phase=Running to skip terminating
+	// and evicted pods the label selector still matches
+	FieldSelector string
+	// NodeLocal only streams the Traefik pods of the node this process runs on, read from the NODE_NAME
+	// environment variable, for DaemonSet deployments
+	NodeLocal bool
+	// IncludePrevious drains the logs of a crashed Traefik container before following its replacement
+	IncludePrevious bool
+	// SyncInterval is the resync period of the pod informer
+	SyncInterval time.Duration
+	// PodDiscoveryTimeout is how long to wait for the initial pod list
+	PodDiscoveryTimeout time.Duration
+	// MaxRetries, InitialBackoff and MaxBackoff tune the exponential backoff between attempts to
+	// reopen a failed pod log stream
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// SinceSeconds replays this many seconds of each Traefik pod's logs when its stream first starts,
+	// so metrics have no gap after a restart. 0 only follows new lines.
+	SinceSeconds int64
 }
 
 // NewKubernetesConfig creates a new Kubernetes client configuration
@@ -108,135 +163,326 @@ func NewKubernetesClientset(config K8SConfig) (*kubernetes.Clientset, error) {
 	return kubernetes.NewForConfig(kubeConfig)
 }
 
-// NewKubernetesLogSource creates a new Kubernetes-based log source
+// NewKubernetesLogSource creates a new Kubernetes-based log source.
+// Client creation failures are returned rather than exiting, so embedding processes such as the
+// operator can report them through the health status and keep running.
 func NewKubernetesLogSource(k8sConfig *K8SConfig) (*KubernetesLogSource, error) {
+	fieldSelector, err := podFieldSelector(*k8sConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	clientSet, err := NewKubernetesClientset(*k8sConfig)
 	if err != nil {
 		return nil, fmt.Errorf("error creating Kubernetes client: %w", err)
 	}
 
+	return newKubernetesLogSource(clientSet, k8sConfig, fieldSelector), nil
+}
+
+// newKubernetesLogSource creates a log source reading the pods of clientSet
+func newKubernetesLogSource(clientSet kubernetes.Interface, k8sConfig *K8SConfig, fieldSelector string) *KubernetesLogSource {
 	return &KubernetesLogSource{
 		clientSet:     clientSet,
-		namespace:     k8sConfig.Namespace,
+		namespaces:    ParseNamespaces(k8sConfig.Namespace),
 		containerName: k8sConfig.ContainerName,
 		labelSelector: k8sConfig.LabelSelector,
+		fieldSelector: fieldSelector,
 		lines:         make(chan LogLine, 1000),
 		podStreams:    make(map[string]*podStream),
 		stopCh:        make(chan struct{}),
-	}, nil
+
+		syncInterval:        k8sConfig.SyncInterval,
+		podDiscoveryTimeout: k8sConfig.PodDiscoveryTimeout,
+		maxRetries:          k8sConfig.MaxRetries,
+		initialBackoff:      k8sConfig.InitialBackoff,
+		maxBackoff:          k8sConfig.MaxBackoff,
+
+		includePrevious: k8sConfig.IncludePrevious,
+		sinceSeconds:    k8sConfig.SinceSeconds,
+	}
+}
+
+// durationOrDefault returns value, or def when value isn't positive
+func durationOrDefault(value, def time.Duration) time.Duration {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
+
+// streamBackoff returns the backoff between attempts to reopen a failed pod log stream
+func (kls *KubernetesLogSource) streamBackoff() wait.Backoff {
+	steps := kls.maxRetries
+	if steps <= 0 {
+		steps = maxRetries
+	}
+	return wait.Backoff{
+		Steps:    steps,
+		Duration: durationOrDefault(kls.initialBackoff, initialBackoff),
+		Factor:   2.0,
+		Jitter:   0.1,
+		Cap:      durationOrDefault(kls.maxBackoff, maxBackoff),
+	}
+}
+
+// NodeNameEnv is the environment variable NodeLocal reads the node name from, set through the
+// downward API from spec.nodeName
+const NodeNameEnv = "NODE_NAME"
+
+// podFieldSelector returns the field selector of the Traefik pods: FieldSelector, restricted to the
+// current node in NodeLocal mode
+func podFieldSelector(config K8SConfig) (string, error) {
+	if !config.NodeLocal {
+		return config.FieldSelector, nil
+	}
+	node := os.Getenv(NodeNameEnv)
+	if node == "" {
+		return "", fmt.Errorf("node-local mode requires the %s environment variable", NodeNameEnv)
+	}
+	nodeSelector := "spec.nodeName=" + node
+	if config.FieldSelector == "" {
+		return nodeSelector, nil
+	}
+	return config.FieldSelector + "," + nodeSelector, nil
+}
+
+// ParseNamespaces splits a comma-separated list of namespaces, dropping blanks and duplicates.
+// A single namespace yields a one-element list, and an empty value watches all namespaces.
+func ParseNamespaces(value string) []string {
+	namespaces := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, namespace := range strings.Split(value, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	if len(namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return namespaces
 }
 
 func (kls *KubernetesLogSource) ReadLines() <-chan LogLine {
 	return kls.lines
 }
 
-// startStreaming starts the log streaming process
+// startStreaming starts a pod informer per namespace filtered by the label and field selectors, which starts
+// a log stream when a matching pod's container becomes ready and cancels it when the pod goes away,
+// and waits for the initial pod lists
 func (kls *KubernetesLogSource) startStreaming() error {
-	// Start the pod watcher in the background
-	kls.wg.Add(1)
-	go kls.watchPods()
-	// Initial sync of pods
-	_, err := kls.syncPods()
-	return err
-}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    kls.onPodChanged,
+		UpdateFunc: func(_, newObj interface{}) { kls.onPodChanged(newObj) },
+		DeleteFunc: kls.onPodDeleted,
+	}
 
-// watchPods watches for pod changes and updates log streams accordingly
-func (kls *KubernetesLogSource) watchPods() {
-	defer kls.wg.Done()
+	listers := make([]corelisters.PodLister, 0, len(kls.namespaces))
+	synced := make([]cache.InformerSynced, 0, len(kls.namespaces))
+	for _, namespace := range kls.namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(kls.clientSet,
+			durationOrDefault(kls.syncInterval, syncInterval),
+			informers.WithNamespace(namespace),
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.LabelSelector = kls.labelSelector
+				options.FieldSelector = kls.fieldSelector
+			}))
+		pods := factory.Core().V1().Pods()
+
+		if _, err := pods.Informer().AddEventHandler(handler); err != nil {
+			return fmt.Errorf("error watching pods in namespace %q: %v", namespace, err)
+		}
+		factory.Start(kls.stopCh)
 
-	backoff := wait.Backoff{
-		Steps:    maxRetries,
-		Duration: initialBackoff,
-		Factor:   2.0,
-		Jitter:   0.1,
-		Cap:      maxBackoff,
+		listers = append(listers, pods.Lister())
+		synced = append(synced, pods.Informer().HasSynced)
 	}
 
-	ticker := time.NewTicker(syncInterval)
-	defer ticker.Stop()
-
-	for {
+	// Give up on the initial pod lists after the discovery timeout
+	syncStop := make(chan struct{})
+	timer := time.AfterFunc(durationOrDefault(kls.podDiscoveryTimeout, podDiscoveryTimeout), func() { close(syncStop) })
+	defer timer.Stop()
+	go func() {
 		select {
 		case <-kls.stopCh:
-			return
-		case <-ticker.C:
-			// Only sync if we haven't synced recently
-			if time.Since(kls.lastPodSync) < syncInterval {
-				continue
+			if timer.Stop() {
+				close(syncStop)
 			}
+		case <-syncStop:
+		}
+	}()
+	if !cache.WaitForCacheSync(syncStop, synced...) {
+		return fmt.Errorf("timed out listing pods with selector %s in namespaces %s",
+			kls.labelSelector, strings.Join(kls.namespaces, ","))
+	}
 
-			err := wait.ExponentialBackoff(backoff, func() (bool, error) {
-				success, err := kls.syncPods()
-				if success {
-					kls.lastPodSync = time.Now()
-				}
-				return success, err
-			})
-
-			if err != nil {
-				logger.Warnf("Failed to sync pods: %v", err)
-			}
+	found := 0
+	for _, lister := range listers {
+		listed, err := lister.List(labels.Everything())
+		if err != nil {
+			return fmt.Errorf("error listing pods: %v", err)
 		}
+		found += len(listed)
+	}
+	if found == 0 {
+		logger.Warnf("No pods found with selector: %s, waiting for them to start", kls.labelSelector)
+	} else if logger.GetLevel() >= logger.DebugLevel {
+		logger.Debugf("Found %d pods with selector %s in %d namespaces", found, kls.labelSelector, len(kls.namespaces))
 	}
+	return nil
 }
 
-// syncPods synchronizes the current state of pods with the desired state
-func (kls *KubernetesLogSource) syncPods() (bool, error) {
-	// Only use cache if we have a recent successful sync
-	if !kls.lastPodSync.IsZero() && time.Since(kls.lastPodSync) < podDiscoveryTimeout {
-		return true, nil
+// onPodChanged streams the logs of a pod whose container is ready and stops streaming a pod
+// that is no longer running or ready
+func (kls *KubernetesLogSource) onPodChanged(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
 	}
+	if kls.includePrevious {
+		kls.trackRestarts(pod)
+	}
+	if pod.DeletionTimestamp == nil && pod.Status.Phase == v1.PodRunning && isContainerReady(pod, kls.containerName) {
+		kls.ensurePodStream(pod.Namespace, pod.Name)
+		return
+	}
+	kls.stopPodStream(pod.Namespace, pod.Name, "pod is not ready")
+}
 
-	// List all pods matching the label selector
-	pods, err := kls.clientSet.CoreV1().Pods(kls.namespace).List(context.Background(), metav1.ListOptions{
-		LabelSelector: kls.labelSelector,
-	})
+// onPodDeleted stops streaming a deleted pod
+func (kls *KubernetesLogSource) onPodDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if pod, ok := obj.(*v1.Pod); ok {
+		kls.stopPodStream(pod.Namespace, pod.Name, "pod no longer exists")
+		kls.forgetRestarts(pod.Namespace, pod.Name)
+		kls.forgetBackfill(pod.Namespace, pod.Name)
+	}
+}
 
-	if err != nil {
-		logger.Warnf("Error listing pods: %v", err)
-		return false, fmt.Errorf("error listing pods: %v", err)
+// trackRestarts marks a pod whose container crashed and restarted since the pod was first seen, so
+// its stream drains the previous container's logs before following the new one. Restarts that
+// happened before the pod was first seen aren't drained, to avoid replaying old logs on startup.
+func (kls *KubernetesLogSource) trackRestarts(pod *v1.Pod) {
+	var restarts int32
+	terminated := false
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == kls.containerName {
+			restarts = status.RestartCount
+			terminated = status.LastTerminationState.Terminated != nil
+		}
 	}
 
-	if len(pods.Items) == 0 {
-		logger.Warnf("No pods found with selector: %s", kls.labelSelector)
-		return false, fmt.Errorf("no pods found with selector: %s", kls.labelSelector)
+	kls.podMutex.Lock()
+	defer kls.podMutex.Unlock()
+
+	if kls.restartCounts == nil {
+		kls.restartCounts = make(map[string]int32)
+		kls.pendingPrevious = make(map[string]bool)
+		kls.lastLineTimes = make(map[string]time.Time)
+	}
+	key := podStreamKey(pod.Namespace, pod.Name)
+	seen, known := kls.restartCounts[key]
+	kls.restartCounts[key] = restarts
+	if known && restarts > seen && terminated {
+		logger.Infof("Container %s of pod %s restarted, draining its previous logs", kls.containerName, key)
+		kls.pendingPrevious[key] = true
 	}
+}
+
+// forgetRestarts drops the restart tracking of a deleted pod
+func (kls *KubernetesLogSource) forgetRestarts(namespace, podName string) {
+	kls.podMutex.Lock()
+	defer kls.podMutex.Unlock()
+
+	key := podStreamKey(namespace, podName)
+	delete(kls.restartCounts, key)
+	delete(kls.pendingPrevious, key)
+	delete(kls.lastLineTimes, key)
+}
 
-	if logger.GetLevel() >= logger.DebugLevel {
-		logger.Debugf("Found %d pods with selector %s", len(pods.Items), kls.labelSelector)
+// backfillSeconds returns the SinceSeconds of the next stream of a pod: the configured replay for
+// its first stream and 0 once it was backfilled, so reconnects don't count lines twice. The replay
+// starts no earlier than when the state file restored on startup was saved, as the requests logged
+// before are already part of the restored stats.
+func (kls *KubernetesLogSource) backfillSeconds(namespace, podName string, now time.Time) int64 {
+	if kls.sinceSeconds <= 0 {
+		return 0
 	}
 
-	// Update the cached pod list and sync time
 	kls.podMutex.Lock()
-	kls.lastPodList = pods.Items
+	backfilled := kls.backfilled[podStreamKey(namespace, podName)]
 	kls.podMutex.Unlock()
-	kls.lastPodSync = time.Now()
-
-	// Track current pods to detect removed ones
-	currentPods := make(map[string]bool)
+	if backfilled {
+		return 0
+	}
 
-	// Ensure log streams for all running pods
-	for _, pod := range pods.Items {
-		if pod.Status.Phase == v1.PodRunning && isContainerReady(&pod, kls.containerName) {
-			podName := pod.Name
-			currentPods[podName] = true
-			kls.ensurePodStream(podName)
+	seconds := kls.sinceSeconds
+	if savedAt := restoredStateSavedAt(); !savedAt.IsZero() {
+		if sinceSave := int64(math.Ceil(now.Sub(savedAt).Seconds())); sinceSave < seconds {
+			seconds = sinceSave
 		}
 	}
+	return seconds
+}
 
-	// Clean up streams for pods that no longer exist
+// markBackfilled records that the history of a pod was replayed
+func (kls *KubernetesLogSource) markBackfilled(namespace, podName string) {
 	kls.podMutex.Lock()
 	defer kls.podMutex.Unlock()
 
-	for podName, stream := range kls.podStreams {
-		if !currentPods[podName] {
-			logger.Infof("Removing log stream for pod %s (pod no longer exists)", podName)
-			stream.cancelFunc()
-			delete(kls.podStreams, podName)
-		}
+	if kls.backfilled == nil {
+		kls.backfilled = make(map[string]bool)
+	}
+	kls.backfilled[podStreamKey(namespace, podName)] = true
+}
+
+// forgetBackfill drops the backfill tracking of a deleted pod
+func (kls *KubernetesLogSource) forgetBackfill(namespace, podName string) {
+	kls.podMutex.Lock()
+	defer kls.podMutex.Unlock()
+
+	delete(kls.backfilled, podStreamKey(namespace, podName))
+}
+
+// takePendingPrevious reports whether the previous container of a pod still has to be drained,
+// and clears the mark
+func (kls *KubernetesLogSource) takePendingPrevious(namespace, podName string) bool {
+	kls.podMutex.Lock()
+	defer kls.podMutex.Unlock()
+
+	key := podStreamKey(namespace, podName)
+	pending := kls.pendingPrevious[key]
+	delete(kls.pendingPrevious, key)
+	return pending
+}
+
+// podStreamKey returns the podStreams key of a pod, since pod names are only unique per namespace
+func podStreamKey(namespace, podName string) string {
+	return namespace + "/" + podName
+}
+
+// stopPodStream cancels the log stream of a pod if one is running
+func (kls *KubernetesLogSource) stopPodStream(namespace, podName, reason string) {
+	kls.podMutex.Lock()
+	defer kls.podMutex.Unlock()
+
+	key := podStreamKey(namespace, podName)
+	if stream, exists := kls.podStreams[key]; exists {
+		logger.Infof("Removing log stream for pod %s (%s)", key, reason)
+		stream.cancelFunc()
+		delete(kls.podStreams, key)
+		kls.updateActivePodStreams()
 	}
+}
 
-	return true, nil
+// updateActivePodStreams sets the active pod streams gauge. The caller must hold podMutex.
+func (kls *KubernetesLogSource) updateActivePodStreams() {
+	activePodStreams.Set(float64(len(kls.podStreams)))
 }
 
 // isContainerReady checks if the specified container in the pod is ready
@@ -250,42 +496,55 @@ func isContainerReady(pod *v1.Pod, containerName string) bool {
 }
 
 // ensurePodStream ensures that a pod's logs are being streamed
-func (kls *KubernetesLogSource) ensurePodStream(podName string) {
+func (kls *KubernetesLogSource) ensurePodStream(namespace, podName string) {
 	kls.podMutex.Lock()
 	defer kls.podMutex.Unlock()
 
 	// Skip if already streaming this pod
-	if _, exists := kls.podStreams[podName]; exists {
+	key := podStreamKey(namespace, podName)
+	if _, exists := kls.podStreams[key]; exists {
+		return
+	}
+
+	// Skip pod events delivered while closing
+	select {
+	case <-kls.stopCh:
 		return
+	default:
 	}
 
 	// Set up context for this pod's log stream
 	ctx, cancel := context.WithCancel(context.Background())
 	stream := &podStream{
 		cancelFunc: cancel,
+		namespace:  namespace,
 		podName:    podName,
 	}
-	kls.podStreams[podName] = stream
+	kls.podStreams[key] = stream
+	kls.updateActivePodStreams()
 
-	// Start the log stream in a goroutine
+	// Start the log stream in a goroutine, which forgets the stream when it gives up on the pod
+	// so a later pod event can start a new one
 	kls.wg.Add(1)
 	go func() {
 		defer kls.wg.Done()
-		kls.streamPodLogsWithRetry(ctx, podName)
+		kls.streamPodLogsWithRetry(ctx, namespace, podName)
+
+		kls.podMutex.Lock()
+		if kls.podStreams[key] == stream {
+			delete(kls.podStreams, key)
+			kls.updateActivePodStreams()
+		}
+		kls.podMutex.Unlock()
+		cancel()
 	}()
 
-	logger.Infof("Started log streaming for pod: %s", podName)
+	logger.Infof("Started log streaming for pod: %s", key)
 }
 
 // streamPodLogsWithRetry handles retries for pod log streaming
-func (kls *KubernetesLogSource) streamPodLogsWithRetry(ctx context.Context, podName string) {
-	backoff := wait.Backoff{
-		Steps:    maxRetries,
-		Duration: initialBackoff,
-		Factor:   2.0,
-		Jitter:   0.1,
-		Cap:      maxBackoff,
-	}
+func (kls *KubernetesLogSource) streamPodLogsWithRetry(ctx context.Context, namespace, podName string) {
+	backoff := kls.streamBackoff()
 
 	for {
 		select {
@@ -293,7 +552,7 @@ func (kls *KubernetesLogSource) streamPodLogsWithRetry(ctx context.Context, podN
 			return
 		default:
 			// Check if pod still exists before trying to stream
-			exists, err := kls.podExists(podName)
+			exists, err := kls.podExists(namespace, podName)
 			if err != nil {
 				logger.Warnf("Error checking pod %s existence: %v", podName, err)
 			}
@@ -302,36 +561,57 @@ func (kls *KubernetesLogSource) streamPodLogsWithRetry(ctx context.Context, podN
 				return
 			}
 
-			err = kls.streamPodLogs(ctx, podName)
+			if kls.includePrevious && kls.takePendingPrevious(namespace, podName) {
+				if err := kls.streamPreviousPodLogs(ctx, namespace, podName); err != nil {
+					logger.Warnf("Error reading previous container logs from pod %s: %v", podName, err)
+				}
+			}
+
+			err = kls.streamPodLogs(ctx, namespace, podName)
 			if err != nil {
 				if wait.Interrupted(err) {
 					logger.Infof("Stopping log streaming for pod %s", podName)
 					return
 				}
 
-				// If pod is not found, force a pod resync
-				if strings.Contains(err.Error(), "not found") {
-					logger.Debugf("Pod %s not found, forcing pod resync", podName)
-					kls.forcePodResync()
-				}
-
 				// Log the error and retry with backoff
 				delay := backoff.Step()
 				logger.Warnf("Error streaming logs from pod %s (retrying in %v): %v", podName, delay, err)
-				time.Sleep(delay)
+				podStreamReconnects.Inc()
+				if !kls.sleep(ctx, delay) {
+					return
+				}
 				continue
 			}
 
 			// If we get here, the stream ended unexpectedly but without an error
 			logger.Debugf("Log stream ended for pod %s, reconnecting...", podName)
-			time.Sleep(time.Second)
+			podStreamReconnects.Inc()
+			if !kls.sleep(ctx, time.Second) {
+				return
+			}
 		}
 	}
 }
 
+// sleep waits for the delay between stream retries and reports whether it elapsed, returning early
+// when the stream is cancelled or the log source closed
+func (kls *KubernetesLogSource) sleep(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-kls.stopCh:
+		return false
+	}
+}
+
 // podExists checks if a pod exists in the cluster
-func (kls *KubernetesLogSource) podExists(podName string) (bool, error) {
-	_, err := kls.clientSet.CoreV1().Pods(kls.namespace).Get(context.Background(), podName, metav1.GetOptions{})
+func (kls *KubernetesLogSource) podExists(namespace, podName string) (bool, error) {
+	_, err := kls.clientSet.CoreV1().Pods(namespace).Get(context.Background(), podName, metav1.GetOptions{})
 	if err == nil {
 		return true, nil
 	}
@@ -341,67 +621,140 @@ func (kls *KubernetesLogSource) podExists(podName string) (bool, error) {
 	return false, err
 }
 
-// forcePodResync forces an immediate pod resync by clearing the last sync time
-func (kls *KubernetesLogSource) forcePodResync() {
-	kls.podMutex.Lock()
-	defer kls.podMutex.Unlock()
-	kls.lastPodSync = time.Time{} // Zero time will force a resync
-}
-
 // streamPodLogs handles the actual log streaming for a single pod
-func (kls *KubernetesLogSource) streamPodLogs(ctx context.Context, podName string) error {
-	// Get current time to only stream logs from this point forward
-	sinceTime := metav1.NewTime(time.Now())
-
-	req := kls.clientSet.CoreV1().Pods(kls.namespace).GetLogs(podName, &v1.PodLogOptions{
+func (kls *KubernetesLogSource) streamPodLogs(ctx context.Context, namespace, podName string) error {
+	now := time.Now()
+	options := &v1.PodLogOptions{
 		Container: kls.containerName,
 		Follow:    true,
-		SinceTime: &sinceTime, // Only get logs from this time forward
-	})
+		// Timestamps let draining a crashed container skip the lines already followed
+		Timestamps: kls.includePrevious,
+	}
+	backfill := kls.backfillSeconds(namespace, podName, now)
+	if backfill > 0 {
+		// Replay the recent history of the pod once
+		options.SinceSeconds = &backfill
+	} else {
+		// Only get logs from this time forward
+		sinceTime := metav1.NewTime(now)
+		options.SinceTime = &sinceTime
+	}
+
+	req := kls.clientSet.CoreV1().Pods(namespace).GetLogs(podName, options)
 
 	podLogs, err := req.Stream(ctx)
 	if err != nil {
 		return fmt.Errorf("error opening log stream for pod %s: %v", podName, err)
 	}
+	if backfill > 0 {
+		logger.Infof("Replaying the last %ds of logs from pod %s", backfill, podName)
+		kls.markBackfilled(namespace, podName)
+	}
 	defer func() {
 		if err := podLogs.Close(); err != nil {
 			logger.Warnf("Error closing log stream for pod %s: %v", podName, err)
 		}
 	}()
 
-	scanner := bufio.NewScanner(podLogs)
+	if err := kls.emitPodLines(ctx, podLogs, namespace, podName); err != nil {
+		return fmt.Errorf("error reading log stream from pod %s: %v", podName, err)
+	}
+
+	return nil
+}
+
+// streamPreviousPodLogs reads the logs of the pod's previous, crashed container to the end
+func (kls *KubernetesLogSource) streamPreviousPodLogs(ctx context.Context, namespace, podName string) error {
+	req := kls.clientSet.CoreV1().Pods(namespace).GetLogs(podName, &v1.PodLogOptions{
+		Container:  kls.containerName,
+		Previous:   true,
+		Timestamps: true,
+	})
+
+	podLogs, err := req.Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("error opening previous log stream for pod %s: %v", podName, err)
+	}
+	defer func() {
+		if err := podLogs.Close(); err != nil {
+			logger.Warnf("Error closing previous log stream for pod %s: %v", podName, err)
+		}
+	}()
+
+	return kls.emitPodLines(ctx, podLogs, namespace, podName)
+}
+
+// emitPodLines sends the lines of a pod log stream prefixed with the pod name. When previous
+// container logs are drained, lines carry a timestamp, and lines not newer than the last one
+// emitted for the pod are skipped so the crash boundary isn't counted twice.
+func (kls *KubernetesLogSource) emitPodLines(ctx context.Context, reader io.Reader, namespace, podName string) error {
+	key := podStreamKey(namespace, podName)
+	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
+		text := scanner.Text()
+		if kls.includePrevious {
+			var fresh bool
+			if text, fresh = kls.acceptTimestampedLine(key, text); !fresh {
+				continue
+			}
+		}
+
+		// Give up on a full buffer once the stream is cancelled or the source closed, so Close
+		// doesn't wait forever on a reader that stopped draining the lines
 		select {
+		case kls.lines <- LogLine{
+			Text:      fmt.Sprintf("[%s] %s", podName, text),
+			Time:      time.Now(),
+			Err:       nil,
+			Namespace: namespace,
+			Pod:       podName,
+		}:
 		case <-ctx.Done():
 			return nil
-		default:
-			kls.lines <- LogLine{
-				Text: fmt.Sprintf("[%s] %s", podName, scanner.Text()),
-				Time: time.Now(),
-				Err:  nil,
-			}
+		case <-kls.stopCh:
+			return nil
 		}
 	}
+	return scanner.Err()
+}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading log stream from pod %s: %v", podName, err)
+// acceptTimestampedLine strips the timestamp Kubernetes prepends to a log line and reports whether
+// the line is newer than the last one emitted for the pod. Lines without a timestamp are accepted.
+func (kls *KubernetesLogSource) acceptTimestampedLine(key, line string) (string, bool) {
+	stamp, text, found := strings.Cut(line, " ")
+	if !found {
+		return line, true
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return line, true
 	}
 
-	return nil
+	kls.podMutex.Lock()
+	defer kls.podMutex.Unlock()
+
+	if kls.lastLineTimes == nil {
+		kls.lastLineTimes = make(map[string]time.Time)
+	}
+	if !timestamp.After(kls.lastLineTimes[key]) {
+		return "", false
+	}
+	kls.lastLineTimes[key] = timestamp
+	return text, true
 }
 
 func (kls *KubernetesLogSource) Close() error {
 	// Signal all goroutines to stop
 	close(kls.stopCh)
 
-	// Cancel all pod streams
+	// Cancel all pod streams. The lock is released before waiting, since finishing streams
+	// remove themselves from podStreams.
 	kls.podMutex.Lock()
-	defer kls.podMutex.Unlock()
-
 	for podName, stream := range kls.podStreams {
 		logger.Infof("Stopping log stream for pod: %s", podName)
 		stream.cancelFunc()
 	}
+	kls.podMutex.Unlock()
 
 	// Wait for all goroutines to finish
 	kls.wg.Wait()
@@ -419,11 +772,29 @@ func AddKubernetesFlags(flags *flag.FlagSet) *K8SConfig {
 	flags.StringVar(&config.Context, "kube-context", "",
 		"Kubernetes context to use (default is current context)")
 	flags.StringVar(&config.Namespace, "namespace", "ingress-controller",
-		"Kubernetes namespace to monitor")
+		"Kubernetes namespace to monitor, or a comma-separated list of namespaces (e.g. 'tenant-a,tenant-b')")
 	flags.StringVar(&config.LabelSelector, "pod-label-selector", "app.kubernetes.io/name=traefik",
 		"Label selector for pods (e.g., 'app=myapp')")
+	flags.StringVar(&config.FieldSelector, "pod-field-selector", "",
+		"Field selector for pods, combined with the label selector (e.g., 'status.phase=Running')")
+	flags.BoolVar(&config.NodeLocal, "node-local", false,
+		"Only stream the Traefik pods of the node named by $NODE_NAME, for DaemonSet deployments")
 	flags.StringVar(&config.ContainerName, "container-name", "traefik",
 		"Container name in the pods")
+	flags.BoolVar(&config.IncludePrevious, "include-previous-logs", false,
+		"Drain the logs of a crashed Traefik container before following its replacement")
+	flags.DurationVar(&config.SyncInterval, "pod-sync-interval", syncInterval,
+		"Resync period of the pod informer")
+	flags.DurationVar(&config.PodDiscoveryTimeout, "pod-discovery-timeout", podDiscoveryTimeout,
+		"How long to wait for the initial list of Traefik pods")
+	flags.IntVar(&config.MaxRetries, "pod-stream-max-retries", maxRetries,
+		"Number of backoff steps between attempts to reopen a failed pod log stream")
+	flags.DurationVar(&config.InitialBackoff, "pod-stream-initial-backoff", initialBackoff,
+		"Initial delay before reopening a failed pod log stream")
+	flags.DurationVar(&config.MaxBackoff, "pod-stream-max-backoff", maxBackoff,
+		"Maximum delay before reopening a failed pod log stream")
+	flags.Int64Var(&config.SinceSeconds, "since-seconds", 0,
+		"Replay this many seconds of each Traefik pod's logs when its stream first starts. 0 only follows new lines")
 
 	return config
 }
@@ -1,13 +1,22 @@
 package logprocessing
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
-	"sync"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 // TestHomeDir tests the homeDir utility function
@@ -90,7 +99,7 @@ func TestK8SConfig(t *testing.T) {
 // TestKubernetesLogSourceStruct tests the KubernetesLogSource struct
 func TestKubernetesLogSourceStruct(t *testing.T) {
 	kls := &KubernetesLogSource{
-		namespace:     "default",
+		namespaces:    []string{"default"},
 		containerName: "traefik",
 		labelSelector: "app=traefik",
 		lines:         make(chan LogLine, 100),
@@ -98,8 +107,8 @@ func TestKubernetesLogSourceStruct(t *testing.T) {
 		stopCh:        make(chan struct{}),
 	}
 
-	if kls.namespace != "default" {
-		t.Errorf("Expected namespace 'default', got %s", kls.namespace)
+	if len(kls.namespaces) != 1 || kls.namespaces[0] != "default" {
+		t.Errorf("Expected namespaces [default], got %v", kls.namespaces)
 	}
 
 	if kls.containerName != "traefik" {
@@ -281,21 +290,31 @@ func TestNewKubernetesConfigErrorPaths(t *testing.T) {
 	}
 }
 
-// TestForcePodResync tests the forcePodResync method
-func TestForcePodResync(t *testing.T) {
-	kls := &KubernetesLogSource{
-		podMutex:    sync.Mutex{},
-		lastPodSync: time.Now(),
-	}
-
-	// Set lastPodSync to a non-zero time
-	kls.lastPodSync = time.Now()
+// TestNewKubernetesLogSourceClientError tests that a client creation failure is returned as an error
+func TestNewKubernetesLogSourceClientError(t *testing.T) {
+	oldServiceHost := os.Getenv("KUBERNETES_SERVICE_HOST")
+	defer os.Setenv("KUBERNETES_SERVICE_HOST", oldServiceHost)
+	os.Unsetenv("KUBERNETES_SERVICE_HOST")
 
-	// Force resync should set lastPodSync to zero time
-	kls.forcePodResync()
+	kubeConfig := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(kubeConfig, []byte("not: [valid"), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
 
-	if !kls.lastPodSync.IsZero() {
-		t.Error("Expected lastPodSync to be zero after force resync")
+	kls, err := NewKubernetesLogSource(&K8SConfig{
+		KubeConfig:    kubeConfig,
+		Namespace:     "traefik",
+		ContainerName: "traefik",
+		LabelSelector: "app=traefik",
+	})
+	if err == nil {
+		t.Fatal("Expected an error for an invalid kubeconfig")
+	}
+	if kls != nil {
+		t.Error("Expected no log source on error")
+	}
+	if !strings.Contains(err.Error(), "error creating Kubernetes client") {
+		t.Errorf("Expected a client creation error, got %v", err)
 	}
 }
 
@@ -320,12 +339,12 @@ func TestPodExists(t *testing.T) {
 	t.Skip("Skipping podExists test - requires Kubernetes clientset")
 
 	kls := &KubernetesLogSource{
-		namespace: "default",
+		namespaces: []string{"default"},
 	}
 
 	// Without a real clientset, this would fail
 	// In a real test, you'd use a mock clientset
-	_, err := kls.podExists("test-pod")
+	_, err := kls.podExists("default", "test-pod")
 
 	// We expect an error because clientSet is nil
 	if err == nil {
@@ -382,12 +401,24 @@ func TestAddKubernetesFlags(t *testing.T) {
 	if config.InCluster {
 		t.Error("Expected InCluster to be false by default")
 	}
+
+	if config.IncludePrevious {
+		t.Error("Expected IncludePrevious to be false by default")
+	}
+
+	if config.FieldSelector != "" {
+		t.Errorf("Expected no field selector by default, got %s", config.FieldSelector)
+	}
+
+	if config.SinceSeconds != 0 {
+		t.Errorf("Expected SinceSeconds to be 0 by default, got %d", config.SinceSeconds)
+	}
 }
 
 // TestKubernetesLogSourceMethods tests various methods of KubernetesLogSource
 func TestKubernetesLogSourceMethods(t *testing.T) {
 	kls := &KubernetesLogSource{
-		namespace:     "test-ns",
+		namespaces:    []string{"test-ns"},
 		containerName: "test-container",
 		labelSelector: "app=test",
 		lines:         make(chan LogLine, 100),
@@ -418,3 +449,579 @@ func TestKubernetesLogSourceMethods(t *testing.T) {
 		}
 	})
 }
+
+// newTestPod returns a pod of the ingress namespace whose traefik container has the given readiness
+func newTestPod(name string, ready bool) *v1.Pod {
+	return newTestPodIn("ingress", name, ready)
+}
+
+// newTestPodIn returns a traefik pod of a namespace whose container has the given readiness
+func newTestPodIn(namespace, name string, ready bool) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": "traefik"}},
+		Status: v1.PodStatus{
+			Phase:             v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "traefik", Ready: ready}},
+		},
+	}
+}
+
+// streamedPods returns the names of the pods with a running log stream
+func streamedPods(kls *KubernetesLogSource) map[string]bool {
+	kls.podMutex.Lock()
+	defer kls.podMutex.Unlock()
+	pods := make(map[string]bool, len(kls.podStreams))
+	for name := range kls.podStreams {
+		pods[name] = true
+	}
+	return pods
+}
+
+// waitForStreams waits until exactly the expected namespace/pod keys are streamed
+func waitForStreams(t *testing.T, kls *KubernetesLogSource, expected ...string) {
+	t.Helper()
+	want := make(map[string]bool, len(expected))
+	for _, name := range expected {
+		want[name] = true
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := streamedPods(kls)
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected streams for %v, got %v", want, got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestKubernetesLogSourceInformer tests that pod events start and stop log streams
+func TestKubernetesLogSourceInformer(t *testing.T) {
+	clientSet := fake.NewClientset(
+		newTestPod("traefik-a", true),
+		newTestPod("traefik-starting", false),
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ingress", Labels: map[string]string{"app": "other"}},
+			Status: v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: []v1.ContainerStatus{{Name: "traefik", Ready: true}}}},
+	)
+	kls := &KubernetesLogSource{
+		clientSet:     clientSet,
+		namespaces:    []string{"ingress"},
+		containerName: "traefik",
+		labelSelector: "app=traefik",
+		lines:         make(chan LogLine, 1000),
+		podStreams:    make(map[string]*podStream),
+		stopCh:        make(chan struct{}),
+	}
+	if err := kls.startStreaming(); err != nil {
+		t.Fatalf("startStreaming() error = %v", err)
+	}
+	defer kls.Close()
+
+	// Only ready pods matching the selector are streamed
+	waitForStreams(t, kls, "ingress/traefik-a")
+
+	pods := clientSet.CoreV1().Pods("ingress")
+	ctx := context.Background()
+
+	if _, err := pods.Create(ctx, newTestPod("traefik-b", true), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	waitForStreams(t, kls, "ingress/traefik-a", "ingress/traefik-b")
+
+	if _, err := pods.Update(ctx, newTestPod("traefik-starting", true), metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update pod: %v", err)
+	}
+	waitForStreams(t, kls, "ingress/traefik-a", "ingress/traefik-b", "ingress/traefik-starting")
+
+	if err := pods.Delete(ctx, "traefik-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete pod: %v", err)
+	}
+	waitForStreams(t, kls, "ingress/traefik-b", "ingress/traefik-starting")
+
+	// A pod whose container stops being ready stops streaming
+	if _, err := pods.Update(ctx, newTestPod("traefik-b", false), metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update pod: %v", err)
+	}
+	waitForStreams(t, kls, "ingress/traefik-starting")
+}
+
+// waitForActivePodStreams waits until the active pod streams gauge reaches the expected value
+func waitForActivePodStreams(t *testing.T, expected float64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := testutil.ToFloat64(activePodStreams)
+		if got == expected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %v active pod streams, got %v", expected, got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestKubernetesLogSourcePodStreamMetrics tests that the active pod streams gauge follows the streamed
+// pods and that reopened streams are counted
+func TestKubernetesLogSourcePodStreamMetrics(t *testing.T) {
+	clientSet := fake.NewClientset(newTestPod("traefik-a", true), newTestPod("traefik-b", true))
+	kls := &KubernetesLogSource{
+		clientSet:     clientSet,
+		namespaces:    []string{"ingress"},
+		containerName: "traefik",
+		labelSelector: "app=traefik",
+		lines:         make(chan LogLine, 1000),
+		podStreams:    make(map[string]*podStream),
+		stopCh:        make(chan struct{}),
+	}
+	reconnects := testutil.ToFloat64(podStreamReconnects)
+	if err := kls.startStreaming(); err != nil {
+		t.Fatalf("startStreaming() error = %v", err)
+	}
+	defer kls.Close()
+
+	waitForStreams(t, kls, "ingress/traefik-a", "ingress/traefik-b")
+	waitForActivePodStreams(t, 2)
+
+	pods := clientSet.CoreV1().Pods("ingress")
+	ctx := context.Background()
+	if _, err := pods.Create(ctx, newTestPod("traefik-c", true), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	waitForActivePodStreams(t, 3)
+
+	if err := pods.Delete(ctx, "traefik-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete pod: %v", err)
+	}
+	if _, err := pods.Update(ctx, newTestPod("traefik-b", false), metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update pod: %v", err)
+	}
+	waitForActivePodStreams(t, 1)
+
+	// The fake log streams end right away, so they are reopened
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(podStreamReconnects) <= reconnects {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected ended pod log streams to be counted as reconnects")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestParseNamespaces tests the single and comma-separated namespace forms
+func TestParseNamespaces(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{value: "ingress-controller", expected: []string{"ingress-controller"}},
+		{value: "tenant-a, tenant-b,,tenant-a", expected: []string{"tenant-a", "tenant-b"}},
+		{value: "", expected: []string{metav1.NamespaceAll}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseNamespaces(tt.value); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseNamespaces(%q) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+// TestKubernetesLogSourceMultipleNamespaces tests that pods are discovered in each listed namespace
+// only, and that their lines carry the namespace they were read from
+func TestKubernetesLogSourceMultipleNamespaces(t *testing.T) {
+	clientSet := fake.NewClientset(
+		newTestPodIn("tenant-a", "traefik", true),
+		newTestPodIn("tenant-b", "traefik", true),
+		newTestPodIn("tenant-c", "traefik", true),
+	)
+	kls := &KubernetesLogSource{
+		clientSet:     clientSet,
+		namespaces:    ParseNamespaces("tenant-a,tenant-b"),
+		containerName: "traefik",
+		labelSelector: "app=traefik",
+		lines:         make(chan LogLine, 1000),
+		podStreams:    make(map[string]*podStream),
+		stopCh:        make(chan struct{}),
+	}
+	if err := kls.startStreaming(); err != nil {
+		t.Fatalf("startStreaming() error = %v", err)
+	}
+	defer kls.Close()
+
+	// Pods sharing a name in different namespaces get their own streams
+	waitForStreams(t, kls, "tenant-a/traefik", "tenant-b/traefik")
+
+	if _, err := clientSet.CoreV1().Pods("tenant-b").Create(context.Background(),
+		newTestPodIn("tenant-b", "traefik-2", true), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	waitForStreams(t, kls, "tenant-a/traefik", "tenant-b/traefik", "tenant-b/traefik-2")
+
+	namespaces := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(namespaces) < 2 {
+		select {
+		case line := <-kls.ReadLines():
+			if !strings.HasPrefix(line.Text, "[traefik") {
+				t.Errorf("Expected the pod name prefix, got %q", line.Text)
+			}
+			namespaces[line.Namespace] = true
+		case <-timeout:
+			t.Fatalf("Timed out waiting for lines from both namespaces, got %v", namespaces)
+		}
+	}
+	if namespaces["tenant-c"] {
+		t.Error("Expected no lines from an unlisted namespace")
+	}
+}
+
+// previousLogRequests returns the pods whose previous container logs were requested
+func previousLogRequests(clientSet *fake.Clientset) []string {
+	pods := make([]string, 0)
+	for _, action := range clientSet.Actions() {
+		generic, ok := action.(k8stesting.GenericAction)
+		if !ok || action.GetSubresource() != "log" {
+			continue
+		}
+		if options, ok := generic.GetValue().(*v1.PodLogOptions); ok && options.Previous {
+			pods = append(pods, action.GetNamespace()+"/"+options.Container)
+		}
+	}
+	return pods
+}
+
+// TestKubernetesLogSourceIncludePrevious tests that a container restart observed while streaming
+// drains the crashed container's logs, while restarts from before the pod was first seen don't
+func TestKubernetesLogSourceIncludePrevious(t *testing.T) {
+	restarted := newTestPod("traefik-a", true)
+	restarted.Status.ContainerStatuses[0].RestartCount = 2
+	restarted.Status.ContainerStatuses[0].LastTerminationState.Terminated = &v1.ContainerStateTerminated{ExitCode: 1}
+
+	clientSet := fake.NewClientset(restarted)
+	kls := &KubernetesLogSource{
+		clientSet:       clientSet,
+		namespaces:      []string{"ingress"},
+		containerName:   "traefik",
+		labelSelector:   "app=traefik",
+		lines:           make(chan LogLine, 1000),
+		podStreams:      make(map[string]*podStream),
+		stopCh:          make(chan struct{}),
+		includePrevious: true,
+	}
+	if err := kls.startStreaming(); err != nil {
+		t.Fatalf("startStreaming() error = %v", err)
+	}
+	defer kls.Close()
+	waitForStreams(t, kls, "ingress/traefik-a")
+
+	// Wait for a follow stream so the startup restarts had their chance to be drained
+	select {
+	case <-kls.ReadLines():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the follow stream")
+	}
+	if requests := previousLogRequests(clientSet); len(requests) != 0 {
+		t.Fatalf("Expected restarts from before startup not to be drained, got %v", requests)
+	}
+
+	crashed := restarted.DeepCopy()
+	crashed.Status.ContainerStatuses[0].RestartCount = 3
+	if _, err := clientSet.CoreV1().Pods("ingress").Update(context.Background(), crashed, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update pod: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(previousLogRequests(clientSet)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if requests := previousLogRequests(clientSet); len(requests) != 1 || requests[0] != "ingress/traefik" {
+		t.Errorf("Expected the previous traefik container logs to be drained once, got %v", requests)
+	}
+}
+
+// TestEmitPodLinesSkipsFollowedLines tests that draining a crashed container skips the lines its
+// follow stream already emitted and strips the timestamps
+func TestEmitPodLinesSkipsFollowedLines(t *testing.T) {
+	kls := &KubernetesLogSource{lines: make(chan LogLine, 10), includePrevious: true}
+	ctx := context.Background()
+
+	followed := "2024-01-01T12:00:00.100000000Z first\n2024-01-01T12:00:00.200000000Z second\n"
+	if err := kls.emitPodLines(ctx, strings.NewReader(followed), "ingress", "traefik-a"); err != nil {
+		t.Fatalf("emitPodLines() error = %v", err)
+	}
+	previous := "2024-01-01T12:00:00.100000000Z first\n2024-01-01T12:00:00.200000000Z second\n" +
+		"2024-01-01T12:00:00.300000000Z lost before the crash\nnot timestamped\n"
+	if err := kls.emitPodLines(ctx, strings.NewReader(previous), "ingress", "traefik-a"); err != nil {
+		t.Fatalf("emitPodLines() error = %v", err)
+	}
+	close(kls.lines)
+
+	expected := []string{"[traefik-a] first", "[traefik-a] second", "[traefik-a] lost before the crash", "[traefik-a] not timestamped"}
+	got := make([]string, 0)
+	for line := range kls.lines {
+		got = append(got, line.Text)
+		if line.Pod != "traefik-a" || line.Namespace != "ingress" {
+			t.Errorf("Expected the line to carry its pod, got %s/%s", line.Namespace, line.Pod)
+		}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected lines %q, got %q", expected, got)
+	}
+}
+
+// TestEmitPodLinesFullBuffer tests that sending to a buffer nobody drains gives up on cancellation
+// or when the source is closed, so Close doesn't hang
+func TestEmitPodLinesFullBuffer(t *testing.T) {
+	for _, name := range []string{"cancelled", "closed"} {
+		t.Run(name, func(t *testing.T) {
+			kls := &KubernetesLogSource{lines: make(chan LogLine), stopCh: make(chan struct{})}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- kls.emitPodLines(ctx, strings.NewReader("first\nsecond\n"), "ingress", "traefik-a")
+			}()
+			if name == "cancelled" {
+				cancel()
+			} else {
+				close(kls.stopCh)
+			}
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("emitPodLines() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Expected emitPodLines to return on a full buffer")
+			}
+		})
+	}
+}
+
+// TestKubernetesLogSourceSleep tests that the retry delay returns early on cancellation and close
+func TestKubernetesLogSourceSleep(t *testing.T) {
+	kls := &KubernetesLogSource{stopCh: make(chan struct{})}
+	if !kls.sleep(context.Background(), time.Millisecond) {
+		t.Error("Expected the delay to elapse")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if kls.sleep(ctx, time.Hour) || time.Since(start) > 5*time.Second {
+		t.Error("Expected the delay to end on cancellation")
+	}
+
+	close(kls.stopCh)
+	if kls.sleep(context.Background(), time.Hour) {
+		t.Error("Expected the delay to end when the source is closed")
+	}
+}
+
+// followLogRequests returns the options of the follow log requests made, in order
+func followLogRequests(clientSet *fake.Clientset) []*v1.PodLogOptions {
+	requests := make([]*v1.PodLogOptions, 0)
+	for _, action := range clientSet.Actions() {
+		generic, ok := action.(k8stesting.GenericAction)
+		if !ok || action.GetSubresource() != "log" {
+			continue
+		}
+		if options, ok := generic.GetValue().(*v1.PodLogOptions); ok && options.Follow {
+			requests = append(requests, options)
+		}
+	}
+	return requests
+}
+
+// TestKubernetesLogSourceSinceSeconds tests that the first stream of a pod replays SinceSeconds of
+// its logs, and that reconnects only follow new lines
+func TestKubernetesLogSourceSinceSeconds(t *testing.T) {
+	clientSet := fake.NewClientset(newTestPod("traefik-a", true))
+	kls := &KubernetesLogSource{
+		clientSet:     clientSet,
+		namespaces:    []string{"ingress"},
+		containerName: "traefik",
+		labelSelector: "app=traefik",
+		lines:         make(chan LogLine, 1000),
+		podStreams:    make(map[string]*podStream),
+		stopCh:        make(chan struct{}),
+		sinceSeconds:  300,
+	}
+	if err := kls.startStreaming(); err != nil {
+		t.Fatalf("startStreaming() error = %v", err)
+	}
+	defer kls.Close()
+
+	// The fake log stream ends right away, so the pod reconnects after a second
+	deadline := time.Now().Add(5 * time.Second)
+	for len(followLogRequests(clientSet)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	requests := followLogRequests(clientSet)
+	if len(requests) < 2 {
+		t.Fatalf("Expected a reconnect, got %d log requests", len(requests))
+	}
+
+	if first := requests[0]; first.SinceSeconds == nil || *first.SinceSeconds != 300 || first.SinceTime != nil {
+		t.Errorf("Expected the first stream to replay 300s, got SinceSeconds %v and SinceTime %v", first.SinceSeconds, first.SinceTime)
+	}
+	if second := requests[1]; second.SinceSeconds != nil || second.SinceTime == nil {
+		t.Errorf("Expected the reconnect to follow new lines only, got SinceSeconds %v and SinceTime %v", second.SinceSeconds, second.SinceTime)
+	}
+}
+
+// TestBackfillSecondsRestoredState tests that the replay doesn't reach back before the restored state was saved
+func TestBackfillSecondsRestoredState(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	endpointStatsMutex.Lock()
+	stateSavedAt = now.Add(-90 * time.Second)
+	endpointStatsMutex.Unlock()
+	defer func() {
+		endpointStatsMutex.Lock()
+		stateSavedAt = time.Time{}
+		endpointStatsMutex.Unlock()
+	}()
+
+	kls := &KubernetesLogSource{sinceSeconds: 300}
+	if got := kls.backfillSeconds("ingress", "traefik-a", now); got != 90 {
+		t.Errorf("backfillSeconds() = %d, want 90", got)
+	}
+
+	kls.sinceSeconds = 60
+	if got := kls.backfillSeconds("ingress", "traefik-a", now); got != 60 {
+		t.Errorf("backfillSeconds() = %d, want 60", got)
+	}
+
+	kls.markBackfilled("ingress", "traefik-a")
+	if got := kls.backfillSeconds("ingress", "traefik-a", now); got != 0 {
+		t.Errorf("backfillSeconds() after the backfill = %d, want 0", got)
+	}
+}
+
+// podListFieldSelectors returns the field selectors of the pod list and watch requests made
+func podListFieldSelectors(clientSet *fake.Clientset) []string {
+	selectors := make([]string, 0)
+	for _, action := range clientSet.Actions() {
+		if action.GetResource().Resource != "pods" {
+			continue
+		}
+		switch action := action.(type) {
+		case k8stesting.ListAction:
+			selectors = append(selectors, action.GetListRestrictions().Fields.String())
+		case k8stesting.WatchAction:
+			selectors = append(selectors, action.GetWatchRestrictions().Fields.String())
+		}
+	}
+	return selectors
+}
+
+// TestKubernetesLogSourceFieldSelector tests that the field selector is forwarded to the pod list and watch
+func TestKubernetesLogSourceFieldSelector(t *testing.T) {
+	clientSet := fake.NewClientset(newTestPod("traefik-a", true))
+	kls := &KubernetesLogSource{
+		clientSet:     clientSet,
+		namespaces:    []string{"ingress"},
+		containerName: "traefik",
+		labelSelector: "app=traefik",
+		fieldSelector: "status.phase=Running",
+		lines:         make(chan LogLine, 1000),
+		podStreams:    make(map[string]*podStream),
+		stopCh:        make(chan struct{}),
+	}
+	if err := kls.startStreaming(); err != nil {
+		t.Fatalf("startStreaming() error = %v", err)
+	}
+	defer kls.Close()
+
+	selectors := podListFieldSelectors(clientSet)
+	if len(selectors) == 0 {
+		t.Fatal("Expected the pods to be listed")
+	}
+	for _, selector := range selectors {
+		if selector != "status.phase=Running" {
+			t.Errorf("Expected field selector status.phase=Running, got %q", selector)
+		}
+	}
+}
+
+// TestPodFieldSelectorNodeLocal tests that node-local mode restricts the pods to the node named by NODE_NAME
+func TestPodFieldSelectorNodeLocal(t *testing.T) {
+	t.Setenv(NodeNameEnv, "worker-1")
+
+	tests := []struct {
+		name     string
+		config   K8SConfig
+		expected string
+	}{
+		{name: "disabled", config: K8SConfig{FieldSelector: "status.phase=Running"}, expected: "status.phase=Running"},
+		{name: "node only", config: K8SConfig{NodeLocal: true}, expected: "spec.nodeName=worker-1"},
+		{
+			name:     "combined with the field selector",
+			config:   K8SConfig{NodeLocal: true, FieldSelector: "status.phase=Running"},
+			expected: "status.phase=Running,spec.nodeName=worker-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := podFieldSelector(tt.config)
+			if err != nil {
+				t.Fatalf("podFieldSelector() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("podFieldSelector() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPodFieldSelectorNodeLocalWithoutNodeName tests that node-local mode fails without NODE_NAME
+func TestPodFieldSelectorNodeLocalWithoutNodeName(t *testing.T) {
+	t.Setenv(NodeNameEnv, "")
+
+	if _, err := podFieldSelector(K8SConfig{NodeLocal: true}); err == nil {
+		t.Error("Expected an error without NODE_NAME")
+	}
+}
+
+// TestKubernetesLogSourceCustomTiming tests that the pod discovery and retry settings of the config are used
+func TestKubernetesLogSourceCustomTiming(t *testing.T) {
+	clientSet := fake.NewClientset()
+	// Pods can never be listed, so discovery times out
+	clientSet.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("apiserver unavailable")
+	})
+
+	kls := newKubernetesLogSource(clientSet, &K8SConfig{
+		Namespace:           "ingress",
+		ContainerName:       "traefik",
+		LabelSelector:       "app=traefik",
+		PodDiscoveryTimeout: 100 * time.Millisecond,
+		MaxRetries:          3,
+		InitialBackoff:      50 * time.Millisecond,
+		MaxBackoff:          200 * time.Millisecond,
+	}, "")
+	defer kls.Close()
+
+	start := time.Now()
+	if err := kls.startStreaming(); err == nil {
+		t.Fatal("Expected pod discovery to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected discovery to give up after the configured timeout, took %v", elapsed)
+	}
+
+	backoff := kls.streamBackoff()
+	if backoff.Steps != 3 || backoff.Duration != 50*time.Millisecond || backoff.Cap != 200*time.Millisecond {
+		t.Errorf("Unexpected backoff %+v", backoff)
+	}
+
+	// Unset settings fall back to the defaults
+	defaults := newKubernetesLogSource(clientSet, &K8SConfig{}, "").streamBackoff()
+	if defaults.Steps != maxRetries || defaults.Duration != initialBackoff || defaults.Cap != maxBackoff {
+		t.Errorf("Unexpected default backoff %+v", defaults)
+	}
+}
@@ -0,0 +1,97 @@
+package logprocessing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// latencyHistogramBuckets are the buckets of requestDuration and endpointDuration
+var latencyHistogramBuckets = prometheus.DefBuckets
+
+// newLatencyHistograms builds the request duration histograms with the given buckets
+func newLatencyHistograms(buckets []float64) (request, endpoint *prometheus.HistogramVec) {
+	request = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricName("request_duration_seconds"),
+			Help:    "Duration of HTTP requests in seconds",
+			Buckets: buckets,
+		},
+		[]string{"request_method", "response_code", "service"},
+	)
+	endpoint = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricName("endpoint_request_duration_seconds"),
+			Help:    "Duration of HTTP requests per endpoint in seconds",
+			Buckets: buckets,
+		},
+		[]string{"namespace", "ingress", "request_path", "request_method", "response_code"},
+	)
+	return request, endpoint
+}
+
+// InitLatencyHistograms recreates the request_duration_seconds and endpoint_request_duration_seconds
+// histograms with the given buckets, prometheus.DefBuckets
+// when empty. The histograms are registered with the default buckets at startup so the package
+// works without it; it must be called after loading the config and before log processing starts.
+func InitLatencyHistograms(buckets []float64) error {
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	if err := validateLatencyBuckets(buckets); err != nil {
+		return err
+	}
+	if equalBuckets(buckets, latencyHistogramBuckets) {
+		return nil
+	}
+
+	request, endpoint := newLatencyHistograms(buckets)
+	prometheus.Unregister(requestDuration)
+	prometheus.Unregister(endpointDuration)
+	if err := prometheus.Register(request); err != nil {
+		return fmt.Errorf("failed to register request duration histogram: %w", err)
+	}
+	if err := prometheus.Register(endpoint); err != nil {
+		prometheus.Unregister(request)
+		return fmt.Errorf("failed to register endpoint duration histogram: %w", err)
+	}
+
+	requestDuration, endpointDuration = request, endpoint
+	latencyHistogramBuckets = buckets
+	return nil
+}
+
+// validateLatencyBuckets returns an error unless the buckets are positive and strictly increasing
+func validateLatencyBuckets(buckets []float64) error {
+	for i, bucket := range buckets {
+		if bucket <= 0 {
+			return fmt.Errorf("latency bucket %v must be positive", bucket)
+		}
+		if i > 0 && bucket <= buckets[i-1] {
+			return fmt.Errorf("latency buckets must be strictly increasing, got %v after %v", bucket, buckets[i-1])
+		}
+	}
+	return nil
+}
+
+// ParseLatencyBuckets parses a comma-separated list of bucket upper bounds in seconds,
+// e.g. "0.005,0.01,0.025,0.05,0.1"
+func ParseLatencyBuckets(value string) ([]float64, error) {
+	buckets := make([]float64, 0)
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		bucket, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latency bucket %q: %w", field, err)
+		}
+		buckets = append(buckets, bucket)
+	}
+	if err := validateLatencyBuckets(buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
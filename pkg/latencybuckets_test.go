@@ -0,0 +1,83 @@
+package logprocessing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gatheredBuckets returns the bucket upper bounds of the first series of the named histogram
+func gatheredBuckets(t *testing.T, name string) []float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name || len(family.GetMetric()) == 0 {
+			continue
+		}
+		var bounds []float64
+		for _, bucket := range family.GetMetric()[0].GetHistogram().GetBucket() {
+			bounds = append(bounds, bucket.GetUpperBound())
+		}
+		return bounds
+	}
+	t.Fatalf("Histogram %s not found", name)
+	return nil
+}
+
+// TestInitLatencyHistograms tests that custom buckets replace the default ones under the same names
+func TestInitLatencyHistograms(t *testing.T) {
+	t.Cleanup(func() {
+		if err := InitLatencyHistograms(nil); err != nil {
+			t.Errorf("Failed to restore the default buckets: %v", err)
+		}
+	})
+
+	buckets := []float64{0.005, 0.01, 0.025, 0.05, 0.1}
+	if err := InitLatencyHistograms(buckets); err != nil {
+		t.Fatalf("InitLatencyHistograms() error = %v", err)
+	}
+	// Re-initializing with the same buckets keeps the histograms
+	request := requestDuration
+	if err := InitLatencyHistograms(buckets); err != nil || requestDuration != request {
+		t.Errorf("Expected the histograms to be kept for unchanged buckets, error = %v", err)
+	}
+
+	router := "websecure-buckets-a457d08d5820f79b3e08@kubernetes"
+	resetEndpointStats(t)
+	topPathsMutex.Lock()
+	topPathsPerService[router] = map[string]bool{router + ":/api": true}
+	topPathsMutex.Unlock()
+	updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: "/api", Duration: 20}, nil, urlNormalization)
+
+	for _, name := range []string{"traefik_officer_request_duration_seconds", "traefik_officer_endpoint_request_duration_seconds"} {
+		if got := gatheredBuckets(t, name); !reflect.DeepEqual(got, buckets) {
+			t.Errorf("%s buckets = %v, want %v", name, got, buckets)
+		}
+	}
+
+	if err := InitLatencyHistograms([]float64{0.1, 0.05}); err == nil {
+		t.Error("Expected an error for decreasing buckets")
+	}
+	if requestDuration != request {
+		t.Error("Expected invalid buckets to keep the current histograms")
+	}
+}
+
+// TestParseLatencyBuckets tests parsing the -latency-buckets value
+func TestParseLatencyBuckets(t *testing.T) {
+	got, err := ParseLatencyBuckets(" 0.005, 0.01 ,0.1,")
+	if err != nil || !reflect.DeepEqual(got, []float64{0.005, 0.01, 0.1}) {
+		t.Errorf("ParseLatencyBuckets() = %v, %v", got, err)
+	}
+
+	for _, value := range []string{"0.1,fast", "0.1,0.1", "-1,2"} {
+		if _, err := ParseLatencyBuckets(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
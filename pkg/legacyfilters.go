@@ -0,0 +1,54 @@
+package logprocessing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compilePathRegexes compiles the path regexes of the legacy config once on load
+func compilePathRegexes(patterns []string) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		regexes = append(regexes, regex)
+	}
+	return regexes, nil
+}
+
+// isIgnoredRouter reports whether a router name starts with one of the IgnoredRouters, so hashed
+// router names can be ignored by their stable prefix
+func isIgnoredRouter(ignoredRouters []string, router string) bool {
+	for _, ignored := range ignoredRouters {
+		if ignored != "" && strings.HasPrefix(router, ignored) {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyFilterReason returns the reason a parsed line of an allowed service is skipped by the
+// IgnoredRouters, IgnoredPathsRegex and WhitelistPathsRegex of the legacy config, or "" if it is
+// processed. Like ApplyOperatorConfigToLog, ignored paths take precedence over the whitelist.
+func legacyFilterReason(config *TraefikOfficerConfig, entry *traefikLogConfig) string {
+	if isIgnoredRouter(config.IgnoredRouters, entry.RouterName) {
+		return FilterReasonIgnoredRouter
+	}
+	for _, regex := range config.ignoredPaths {
+		if regex.MatchString(entry.RequestPath) {
+			return FilterReasonIgnoredPath
+		}
+	}
+	if len(config.whitelistPaths) > 0 {
+		for _, regex := range config.whitelistPaths {
+			if regex.MatchString(entry.RequestPath) {
+				return ""
+			}
+		}
+		return FilterReasonNotWhitelisted
+	}
+	return ""
+}
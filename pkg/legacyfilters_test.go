@@ -0,0 +1,178 @@
+package logprocessing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// legacyEndpointKeys returns the keys of the endpoints recorded so far
+func legacyEndpointKeys() []string {
+	endpointStatsMutex.Lock()
+	defer endpointStatsMutex.Unlock()
+	keys := make([]string, 0, len(endpointStats))
+	for key := range endpointStats {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// TestLegacyIgnoreLists tests that ignored routers and paths are excluded from the metrics in legacy mode
+func TestLegacyIgnoreLists(t *testing.T) {
+	resetEndpointStats(t)
+	linesFiltered.Reset()
+
+	ignoredPaths, err := compilePathRegexes([]string{`^/health$`, `\.css$`})
+	if err != nil {
+		t.Fatalf("compilePathRegexes() error = %v", err)
+	}
+	config := TraefikOfficerConfig{
+		AllowedServices: []TraefikService{{Namespace: "shop"}},
+		IgnoredRouters:  []string{"shop-admin"},
+		ignoredPaths:    ignoredPaths,
+	}
+
+	lines := make(chan LogLine, 4)
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/orders")
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/health")
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/static/site.css")
+	lines <- filteredLinesLine("shop-admin-a457d08d5820f79b3e08@kubernetescrd", "/orders")
+	close(lines)
+
+	useK8s := true
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, config, &useK8s, nil, LogFormatJSON)
+
+	keys := legacyEndpointKeys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], "shop-api-a457d08d5820f79b3e08@kubernetescrd:") {
+		t.Errorf("Expected only the /orders endpoint of shop-api, got %v", keys)
+	}
+	if got := testutil.ToFloat64(linesFiltered.WithLabelValues(FilterReasonIgnoredPath)); got != 2 {
+		t.Errorf("Expected 2 lines filtered as %s, got %v", FilterReasonIgnoredPath, got)
+	}
+	if got := testutil.ToFloat64(linesFiltered.WithLabelValues(FilterReasonIgnoredRouter)); got != 1 {
+		t.Errorf("Expected 1 line filtered as %s, got %v", FilterReasonIgnoredRouter, got)
+	}
+}
+
+// TestLoadConfigPathRegexes tests that IgnoredPathsRegex and WhitelistPathsRegex are compiled once on load
+func TestLoadConfigPathRegexes(t *testing.T) {
+	oldTopNPaths := topNPaths
+	oldNormalization := urlNormalization
+	defer func() {
+		topNPaths = oldTopNPaths
+		urlNormalization = oldNormalization
+	}()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"IgnoredPathsRegex":["^/health$"],"WhitelistPathsRegex":["^/api/"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(config.ignoredPaths) != 1 || !config.ignoredPaths[0].MatchString("/health") {
+		t.Errorf("Expected the ignored path to be compiled, got %v", config.ignoredPaths)
+	}
+	if len(config.whitelistPaths) != 1 || !config.whitelistPaths[0].MatchString("/api/orders") {
+		t.Errorf("Expected the whitelisted path to be compiled, got %v", config.whitelistPaths)
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"IgnoredPathsRegex":["/("]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected an error for an invalid ignored path regex")
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"WhitelistPathsRegex":["["]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected an error for an invalid whitelisted path regex")
+	}
+}
+
+// TestLegacyMergePaths tests that the paths under a MergePathsWithExtensions prefix are recorded as
+// the prefix in legacy mode
+func TestLegacyMergePaths(t *testing.T) {
+	resetEndpointStats(t)
+
+	config := TraefikOfficerConfig{
+		AllowedServices:          []TraefikService{{Namespace: "shop"}},
+		MergePathsWithExtensions: []string{"/static/"},
+	}
+
+	lines := make(chan LogLine, 3)
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/static/css/site.css")
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/static/js/app.js")
+	lines <- filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetescrd", "/orders")
+	close(lines)
+
+	useK8s := true
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, config, &useK8s, nil, LogFormatJSON)
+
+	router := "shop-api-a457d08d5820f79b3e08@kubernetescrd"
+	endpointStatsMutex.Lock()
+	defer endpointStatsMutex.Unlock()
+	if len(endpointStats) != 2 {
+		t.Errorf("Expected the static paths to be merged into one endpoint, got %v", endpointStats)
+	}
+	if stat := endpointStats[router+":/static/"]; stat == nil || stat.TotalRequests != 2 {
+		t.Errorf("Expected 2 requests to the merged /static/ endpoint, got %+v", stat)
+	}
+	if stat := endpointStats[router+":/orders"]; stat == nil || stat.TotalRequests != 1 {
+		t.Errorf("Expected the other paths to be kept, got %+v", stat)
+	}
+}
+
+// TestLegacyWhitelistPaths tests that only the whitelisted paths of the allowed services are processed
+// in legacy mode, with ignored paths taking precedence
+func TestLegacyWhitelistPaths(t *testing.T) {
+	resetEndpointStats(t)
+	linesFiltered.Reset()
+
+	whitelistPaths, err := compilePathRegexes([]string{`^/api/`, `^/checkout$`})
+	if err != nil {
+		t.Fatalf("compilePathRegexes() error = %v", err)
+	}
+	ignoredPaths, err := compilePathRegexes([]string{`^/api/health$`})
+	if err != nil {
+		t.Fatalf("compilePathRegexes() error = %v", err)
+	}
+	config := TraefikOfficerConfig{
+		AllowedServices: []TraefikService{{Namespace: "shop"}},
+		ignoredPaths:    ignoredPaths,
+		whitelistPaths:  whitelistPaths,
+	}
+
+	router := "shop-api-a457d08d5820f79b3e08@kubernetescrd"
+	lines := make(chan LogLine, 5)
+	lines <- filteredLinesLine(router, "/api/orders")
+	lines <- filteredLinesLine(router, "/checkout")
+	lines <- filteredLinesLine(router, "/api/health")
+	lines <- filteredLinesLine(router, "/admin")
+	lines <- filteredLinesLine(router, "/checkout/confirm")
+	close(lines)
+
+	useK8s := true
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, config, &useK8s, nil, LogFormatJSON)
+
+	keys := legacyEndpointKeys()
+	sort.Strings(keys)
+	if expected := []string{router + ":/api/orders", router + ":/checkout"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected only the whitelisted paths %v, got %v", expected, keys)
+	}
+	if got := testutil.ToFloat64(linesFiltered.WithLabelValues(FilterReasonNotWhitelisted)); got != 2 {
+		t.Errorf("Expected 2 lines filtered as %s, got %v", FilterReasonNotWhitelisted, got)
+	}
+	if got := testutil.ToFloat64(linesFiltered.WithLabelValues(FilterReasonIgnoredPath)); got != 1 {
+		t.Errorf("Expected 1 line filtered as %s, got %v", FilterReasonIgnoredPath, got)
+	}
+}
@@ -0,0 +1,30 @@
+package logprocessing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// linesRead is built by buildMetrics
+var linesRead *prometheus.CounterVec
+
+// Sources of traefik_officer_lines_read_total
+const (
+	LinesReadSourceFile       = "file"
+	LinesReadSourceKubernetes = "kubernetes"
+	LinesReadSourceStdin      = "stdin"
+	LinesReadSourceSSH        = "ssh"
+)
+
+// linesReadSource returns the lines_read_total source label of the log source ProcessLogs reads
+func linesReadSource(useK8s bool, logFileConfig *LogFileConfig) string {
+	switch {
+	case useK8s:
+		return LinesReadSourceKubernetes
+	case logFileConfig != nil && logFileConfig.Stdin:
+		return LinesReadSourceStdin
+	case logFileConfig != nil && logFileConfig.Remote.Host != "":
+		return LinesReadSourceSSH
+	default:
+		return LinesReadSourceFile
+	}
+}
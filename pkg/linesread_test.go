@@ -0,0 +1,67 @@
+package logprocessing
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestLinesRead tests that every line received is counted under the source it was read from
+func TestLinesRead(t *testing.T) {
+	resetEndpointStats(t)
+	linesRead.Reset()
+
+	readLines := func(useK8s bool, logFileConfig *LogFileConfig, texts ...string) {
+		lines := make(chan LogLine, len(texts))
+		for _, text := range texts {
+			lines <- LogLine{Text: text}
+		}
+		close(lines)
+		ProcessLogs(context.Background(), &mockLogSource{lines: lines}, TraefikOfficerConfig{}, &useK8s, logFileConfig, LogFormatJSON)
+	}
+
+	// Unparseable and blank lines are read too
+	readLines(true, nil, filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetes", "/").Text, "not a log line", "")
+	fileConfig := &LogFileConfig{FileLocation: filepath.Join(t.TempDir(), "access.log"), MaxFileBytes: 10}
+	readLines(false, fileConfig, filteredLinesLine("shop-api-a457d08d5820f79b3e08@kubernetes", "/").Text, "{")
+	readLines(false, &LogFileConfig{Stdin: true}, "{")
+
+	expected := map[string]float64{
+		LinesReadSourceKubernetes: 3,
+		LinesReadSourceFile:       2,
+		LinesReadSourceStdin:      1,
+	}
+	for source, want := range expected {
+		if got := testutil.ToFloat64(linesRead.WithLabelValues(source)); got != want {
+			t.Errorf("Expected %v lines read from %s, got %v", want, source, got)
+		}
+	}
+	if got := testutil.CollectAndCount(linesRead); got != len(expected) {
+		t.Errorf("Expected %d sources, got %d", len(expected), got)
+	}
+}
+
+// TestLinesReadSource tests the source label of each log source
+func TestLinesReadSource(t *testing.T) {
+	tests := []struct {
+		name          string
+		useK8s        bool
+		logFileConfig *LogFileConfig
+		expected      string
+	}{
+		{name: "kubernetes", useK8s: true, logFileConfig: &LogFileConfig{FileLocation: "access.log"}, expected: LinesReadSourceKubernetes},
+		{name: "file", logFileConfig: &LogFileConfig{FileLocation: "access.log"}, expected: LinesReadSourceFile},
+		{name: "stdin", logFileConfig: &LogFileConfig{Stdin: true}, expected: LinesReadSourceStdin},
+		{name: "ssh", logFileConfig: &LogFileConfig{Remote: SSHConfig{Host: "edge-1"}}, expected: LinesReadSourceSSH},
+		{name: "no file config", expected: LinesReadSourceFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := linesReadSource(tt.useK8s, tt.logFileConfig); got != tt.expected {
+				t.Errorf("linesReadSource() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package logprocessing
 
 import (
+	"context"
+	"errors"
 	_ "flag"
 	"fmt"
 	logger "github.com/sirupsen/logrus"
-	_ "time"
+	"strings"
+	"time"
 )
 
 // EstBytesPerLine Estimated number of bytes per line - for log rotation
@@ -12,10 +15,16 @@ const EstBytesPerLine = 150
 
 type parser func(line string) (traefikLogConfig, error)
 
-func ProcessLogs(logSource LogSource, config TraefikOfficerConfig, useK8sPtr *bool, logFileConfig *LogFileConfig, jsonLogsPtr *bool) {
-	// Only set up log rotation for file mode
+// ProcessLogs parses the lines of the log source in the given format (see ResolveLogFormat) and
+// records their metrics until the source is drained or ctx is cancelled. On cancellation the lines
+// already buffered by the source are still processed. The log source isn't closed, as it stays owned
+// by the caller.
+func ProcessLogs(ctx context.Context, logSource LogSource, config TraefikOfficerConfig, useK8sPtr *bool,
+	logFileConfig *LogFileConfig, logFormat string) {
+	// Only set up log rotation for local file mode; remote logs are rotated on their host
 	var linesToRotate int
-	if !*useK8sPtr {
+	rotate := !*useK8sPtr && logFileConfig.Remote.Host == "" && !logFileConfig.Stdin
+	if rotate {
 		if logFileConfig.MaxFileBytes <= 0 {
 			logFileConfig.MaxFileBytes = 10 // Default to 10MB if invalid value provided
 			logger.Warnf("Invalid max-accesslog-size %d, using default: 10MB", logFileConfig.MaxFileBytes)
@@ -29,58 +38,108 @@ func ProcessLogs(logSource LogSource, config TraefikOfficerConfig, useK8sPtr *bo
 	}
 
 	// Set up parser
-	var parse parser
-	if *jsonLogsPtr {
-		logger.Info("Setting parser to JSON")
-		parse = parseJSON
-	} else {
-		parse = parseLine
+	parse, err := parserFor(logFormat)
+	if err != nil {
+		logger.Errorf("%v, using %s", err, LogFormatCLF)
+		parse, logFormat = parseLine, LogFormatCLF
 	}
-	// Main processing loop
-	i := 0
-	for logLine := range logSource.ReadLines() {
+	logger.Infof("Parsing access logs as %s", logFormat)
+
+	// Batch endpoint stat updates if configured; the deferred Close flushes what is still pending
+	batcher := newMetricsBatcherFromConfig(config.MetricsBatching)
+	if batcher != nil {
+		logger.Infof("Batching metric updates (flush every %d lines or %dms)",
+			config.MetricsBatching.FlushLines, config.MetricsBatching.FlushIntervalMs)
+		defer batcher.Close()
+	}
+
+	// Drop entries logged twice if configured
+	dedup := newLineDeduplicator(time.Duration(config.DedupWindowMs) * time.Millisecond)
+	if dedup != nil {
+		logger.Infof("Dropping duplicate entries within %dms", config.DedupWindowMs)
+	}
+
+	// Lines are counted per file so each file is rotated on its own
+	linesPerFile := make(map[string]int)
+	// Every line received is counted, so the dropped and filtered lines can be related to it
+	linesReceived := linesRead.WithLabelValues(linesReadSource(*useK8sPtr, logFileConfig))
+	processLine := func(logLine LogLine) {
+		linesReceived.Inc()
+
 		// Update last processed time for health checks
 		UpdateLastProcessedTime()
 
 		if logLine.Err != nil {
 			logger.Error("Log reading error:", logLine.Err)
-			continue
+			return
 		}
 
-		// Only rotate logs in file mode
-		if !*useK8sPtr {
-			i++
-			if i >= linesToRotate {
-				i = 0
-				if err := logRotate(logFileConfig.FileLocation); err != nil {
-					logger.Errorf("Error rotating log file: %v", err)
+		// Only rotate logs in file mode; compressed files are rotated logs already
+		file := logLine.Source
+		if file == "" && logFileConfig != nil {
+			file = logFileConfig.FileLocation
+		}
+		if rotate && !isGzipFile(file) {
+			linesPerFile[file]++
+			if linesPerFile[file] >= linesToRotate {
+				linesPerFile[file] = 0
+				if err := logRotate(file); err != nil {
+					logger.Errorf("Error rotating log file %s: %v", file, err)
 				}
 			}
 		}
 
+		// Parse the log text without the [pod-name] prefix of Kubernetes lines, keeping the pod
+		text := logLine.Text
+		if pod, rest, ok := splitPodPrefix(text); ok {
+			text = rest
+			if logLine.Pod == "" {
+				logLine.Pod = pod
+			}
+		}
+
 		//logger.Debugf("Read Line: %s", logLine.Text)
-		d, err := parse(logLine.Text)
+		d, err := parse(text)
 		if err != nil {
-			// Skip lines that couldn't be parsed (already logged in parseLine)
-			if err.Error() != "not an access log line" &&
-				err.Error() != "empty line" &&
-				err.Error() != "invalid access log format" {
-				logger.Debugf("Parse error (%v) for line: %s", err, logLine.Text)
+			blank := strings.TrimSpace(text) == ""
+			if !errors.Is(err, errNotAccessLog) && !blank {
+				recordParseResult(parseStatsKey(d.RouterName, logLine.Text), false)
 			}
-			continue
+			// Count the dropped lines per reason, so a format drift shows up in the metrics
+			if !blank {
+				linesDropped.WithLabelValues(dropReason(err)).Inc()
+			}
+			// Skip lines that couldn't be parsed, warning once per distinct reason and then
+			// periodically with a count so a format drift doesn't flood the logs
+			if !errors.Is(err, errNotAccessLog) && !errors.Is(err, errEmptyLine) {
+				parseFailureLog.Logf(err.Error(), "Parse error (%v) for line: %s", err, logLine.Text)
+			}
+			return
+		}
+		recordParseResult(parseStatsKey(d.RouterName, logLine.Text), true)
+		d.Pod = logLine.Pod
+
+		if dedup != nil && dedup.isDuplicate(&d) {
+			logger.Debugf("Dropping duplicate entry for %s %s", d.RouterName, d.RequestPath)
+			return
 		}
 
+		// Pick up a config reloaded by WatchConfig
+		active := currentConfig(&config)
+
 		// Operator mode: Check if we should process this router based on CRD configs
 		if IsOperatorMode() {
-			shouldProcess, runtimeConfig := ShouldProcessRouter(d.RouterName)
-			if !shouldProcess {
+			decision := decideRouter(d.RouterName)
+			if !decision.Process {
 				logger.Debugf("Skipping router (not in CRD configs): %s", d.RouterName)
-				continue
+				countFilteredLine(decision.filterReason)
+				return
 			}
+			runtimeConfig := decision.config
 
 			// Apply operator configuration filters
 			if !ApplyOperatorConfigToLog(&d, runtimeConfig) {
-				continue
+				return
 			}
 
 			// Apply path merging if configured
@@ -88,29 +147,77 @@ func ProcessLogs(logSource LogSource, config TraefikOfficerConfig, useK8sPtr *bo
 				d.RequestPath = MergePathsWithOperatorConfig(d.RequestPath, runtimeConfig)
 				// Get URL patterns from CRD config
 				urlPatterns := GetURLPatternsFromConfig(runtimeConfig)
-				updateMetrics(&d, urlPatterns)
+				observeDetailedHistogram(&d, runtimeConfig, urlPatterns)
+				endpoint := recordMetrics(&d, urlPatterns, runtimeConfig, batcher)
+				recordTargetPath(runtimeConfig.Key, d.RouterName, endpoint, time.Now())
 			} else {
-				updateMetrics(&d, config.URLPatterns)
+				recordMetrics(&d, active.URLPatterns, nil, batcher)
 			}
 		} else {
 			// Legacy mode: Check if this service should be ignored
-			if !startsWith(config.AllowedServices, d.RouterName) {
-				logger.Debugf("Ignoring service: %s, not in allowed list %s", d.RouterName, config.AllowedServices)
-				continue
+			if !startsWith(active.AllowedServices, d.RouterName) {
+				logger.Debugf("Ignoring service: %s, not in allowed list %s", d.RouterName, active.AllowedServices)
+				countFilteredLine(FilterReasonNotAllowedService)
+				return
 			}
 			logger.Debugf("Found Matching service: %s, in allowed list", d.RouterName)
-			updateMetrics(&d, config.URLPatterns)
+			if reason := legacyFilterReason(active, &d); reason != "" {
+				logger.Debugf("Ignoring %s %s: %s", d.RouterName, d.RequestPath, reason)
+				countFilteredLine(reason)
+				return
+			}
+			// Merge the paths under the configured prefixes into a single endpoint
+			d.RequestPath = mergePaths(d.RequestPath, active.MergePathsWithExtensions)
+			recordMetrics(&d, active.URLPatterns, nil, batcher)
 		}
 
-		// Only JSON logs have Overhead metrics
-		if *jsonLogsPtr {
+		// Only JSON and logfmt logs have Overhead metrics
+		if logFormat == LogFormatJSON || logFormat == LogFormatLogfmt {
 			traefikOverhead.Observe(d.Overhead)
 		}
 	}
+
+	// Main processing loop
+	lines := logSource.ReadLines()
+	for {
+		select {
+		case logLine, ok := <-lines:
+			if !ok {
+				return
+			}
+			processLine(logLine)
+		case <-ctx.Done():
+			drainLines(lines, processLine)
+			return
+		}
+	}
+}
+
+// drainLines processes the lines buffered in the channel when called, without waiting for more
+func drainLines(lines <-chan LogLine, process func(LogLine)) {
+	for buffered := len(lines); buffered > 0; buffered-- {
+		select {
+		case logLine, ok := <-lines:
+			if !ok {
+				return
+			}
+			process(logLine)
+		default:
+			return
+		}
+	}
 }
 
 // createLogSource creates the appropriate log source based on configuration
 func CreateLogSource(useK8s bool, logFileConfig *LogFileConfig, k8sConfig *K8SConfig) (LogSource, error) {
+	if logFileConfig != nil && logFileConfig.Stdin {
+		if useK8s || logFileConfig.Remote.Host != "" || logFileConfig.LogFiles != "" {
+			return nil, fmt.Errorf("-stdin can't be combined with -use-k8s, -ssh-host or -log-files")
+		}
+		logger.Info("Creating stdin log source")
+		return NewStdinLogSource(), nil
+	}
+
 	if useK8s {
 		logger.Info("Creating Kubernetes log source with label selector:", k8sConfig.LabelSelector)
 
@@ -123,6 +230,17 @@ func CreateLogSource(useK8s bool, logFileConfig *LogFileConfig, k8sConfig *K8SCo
 			return nil, fmt.Errorf("failed to start Kubernetes log streaming: %v", err)
 		}
 		return kls, nil
+	} else if logFileConfig.Remote.Host != "" {
+		logger.Infof("Creating remote log source for %s:%s", logFileConfig.Remote.Host, logFileConfig.Remote.LogFile)
+		return NewRemoteLogSource(&logFileConfig.Remote)
+	} else if logFileConfig.LogFiles != "" {
+		logger.Info("Creating multi-file log source for:", logFileConfig.LogFiles)
+		return NewMultiFileLogSource(ParseLogFiles(logFileConfig.LogFiles), defaultLogFilesRescanInterval,
+			logFileConfig.BufferSize, logFileConfig.BufferFullPolicy)
+	} else if isGlobPattern(logFileConfig.FileLocation) {
+		logger.Info("Creating multi-file log source for pattern:", logFileConfig.FileLocation)
+		return NewMultiFileLogSource([]string{logFileConfig.FileLocation}, defaultLogFilesRescanInterval,
+			logFileConfig.BufferSize, logFileConfig.BufferFullPolicy)
 	} else {
 		logger.Info("Creating file log source")
 		return NewFileLogSource(logFileConfig)
@@ -1,9 +1,15 @@
 package logprocessing
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
 )
 
 // TestEstBytesPerLine tests the EstBytesPerLine constant
@@ -26,7 +32,16 @@ func TestStartTopPathsUpdater(t *testing.T) {
 	interval := 100 * time.Millisecond
 
 	// Start the updater - should not panic
-	StartTopPathsUpdater(interval)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runTopPathsUpdater(interval, stop)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
 
 	// Wait for at least one update cycle
 	time.Sleep(interval + 50*time.Millisecond)
@@ -58,24 +73,28 @@ func TestUpdateTopPaths(t *testing.T) {
 	// Add some endpoint stats
 	endpointStats["service1:/api/fast"] = &EndpointStat{
 		TotalRequests: 100,
+		MeanDuration:  5.0,
 		TotalDuration: 500.0, // avg: 5ms
 		MaxDuration:   10.0,
 		ErrorCount:    0,
 	}
 	endpointStats["service1:/api/slow"] = &EndpointStat{
 		TotalRequests: 50,
+		MeanDuration:  20.0,
 		TotalDuration: 1000.0, // avg: 20ms
 		MaxDuration:   50.0,
 		ErrorCount:    0,
 	}
 	endpointStats["service1:/api/medium"] = &EndpointStat{
 		TotalRequests: 75,
+		MeanDuration:  8.0,
 		TotalDuration: 600.0, // avg: 8ms
 		MaxDuration:   15.0,
 		ErrorCount:    0,
 	}
 	endpointStats["service2:/api/other"] = &EndpointStat{
 		TotalRequests: 200,
+		MeanDuration:  2.0,
 		TotalDuration: 400.0, // avg: 2ms
 		MaxDuration:   5.0,
 		ErrorCount:    0,
@@ -102,6 +121,152 @@ func TestUpdateTopPaths(t *testing.T) {
 	}
 }
 
+// TestUpdateTopPathsTieBreak tests that tied latencies produce a stable top N selection
+func TestUpdateTopPathsTieBreak(t *testing.T) {
+	// Save original state
+	oldEndpointStats := endpointStats
+	oldTopPaths := topPathsPerService
+	oldTopNPaths := topNPaths
+	defer func() {
+		endpointStats = oldEndpointStats
+		topPathsMutex.Lock()
+		topPathsPerService = oldTopPaths
+		topPathsMutex.Unlock()
+		topNPaths = oldTopNPaths
+	}()
+
+	topNPaths = 3
+	endpointStats = make(map[string]*EndpointStat)
+
+	// All paths share the same 10ms average latency
+	endpointStats["svc:/busy"] = &EndpointStat{TotalRequests: 40, TotalDuration: 400.0, MeanDuration: 10.0}
+	endpointStats["svc:/quiet"] = &EndpointStat{TotalRequests: 10, TotalDuration: 100.0, MeanDuration: 10.0}
+	for _, path := range []string{"/d", "/c", "/b", "/a", "/e"} {
+		endpointStats["svc:"+path] = &EndpointStat{TotalRequests: 20, TotalDuration: 200.0, MeanDuration: 10.0}
+	}
+
+	expected := map[string]bool{
+		"svc:/busy": true, // most requests wins the tie
+		"svc:/a":    true, // then paths in lexical order
+		"svc:/b":    true,
+	}
+
+	for cycle := 0; cycle < 20; cycle++ {
+		updateTopPaths()
+
+		topPathsMutex.RLock()
+		selected := topPathsPerService["svc"]
+		topPathsMutex.RUnlock()
+
+		if len(selected) != len(expected) {
+			t.Fatalf("Cycle %d: expected %d top paths, got %d: %v", cycle, len(expected), len(selected), selected)
+		}
+		for key := range expected {
+			if !selected[key] {
+				t.Fatalf("Cycle %d: expected %s in top paths, got %v", cycle, key, selected)
+			}
+		}
+	}
+}
+
+// TestEndpointInTopNGauge tests that top N membership flips as latencies change
+func TestEndpointInTopNGauge(t *testing.T) {
+	resetEndpointStats(t)
+	oldTopNPaths := topNPaths
+	defer func() {
+		topNPaths = oldTopNPaths
+	}()
+	topNPaths = 1
+
+	endpointStatsMutex.Lock()
+	endpointStats["topn-svc:/a"] = &EndpointStat{TotalRequests: 1, TotalDuration: 0.5, MeanDuration: 0.5}
+	endpointStats["topn-svc:/b"] = &EndpointStat{TotalRequests: 1, TotalDuration: 0.1, MeanDuration: 0.1}
+	endpointStatsMutex.Unlock()
+
+	updateTopPaths()
+	if v := testutil.ToFloat64(endpointInTopN.WithLabelValues("topn-svc", "/a")); v != 1 {
+		t.Errorf("Expected /a in top N, got %v", v)
+	}
+	if v := testutil.ToFloat64(endpointInTopN.WithLabelValues("topn-svc", "/b")); v != 0 {
+		t.Errorf("Expected /b outside top N, got %v", v)
+	}
+
+	// /b becomes slower than /a
+	endpointStatsMutex.Lock()
+	endpointStats["topn-svc:/b"].TotalDuration = 2.0
+	endpointStats["topn-svc:/b"].MeanDuration = 2.0
+	endpointStatsMutex.Unlock()
+
+	updateTopPaths()
+	if v := testutil.ToFloat64(endpointInTopN.WithLabelValues("topn-svc", "/a")); v != 0 {
+		t.Errorf("Expected /a to leave top N, got %v", v)
+	}
+	if v := testutil.ToFloat64(endpointInTopN.WithLabelValues("topn-svc", "/b")); v != 1 {
+		t.Errorf("Expected /b to enter top N, got %v", v)
+	}
+
+	// Endpoints that are no longer tracked lose their series
+	endpointStatsMutex.Lock()
+	delete(endpointStats, "topn-svc:/a")
+	endpointStatsMutex.Unlock()
+
+	updateTopPaths()
+	if count := testutil.CollectAndCount(endpointInTopN); count != 1 {
+		t.Errorf("Expected only tracked endpoints to have a series, got %d", count)
+	}
+}
+
+// TestUpdateTopPathsCollectNTop tests that each target keeps the number of top paths its
+// UrlPerformance configures, and other routers fall back to the global default
+func TestUpdateTopPathsCollectNTop(t *testing.T) {
+	resetEndpointStats(t)
+	oldTopNPaths := topNPaths
+	oldConfig := operatorConfig
+	defer func() {
+		topNPaths = oldTopNPaths
+		operatorConfig = oldConfig
+	}()
+	topNPaths = 2
+
+	checkoutRouter := "websecure-shop-checkout-a457d08d5820f79b3e08@kubernetes"
+	searchRouter := "websecure-shop-search-a457d08d5820f79b3e08@kubernetes"
+	otherRouter := "websecure-shop-other-a457d08d5820f79b3e08@kubernetes"
+
+	operatorConfig = &OperatorModeConfig{
+		enabled: true,
+		configManager: &patternsConfigManager{configs: []*shared.RuntimeConfig{
+			{Key: shared.ConfigKey("shop", "checkout"), TargetKind: "Ingress", Enabled: true, CollectNTop: 1},
+			{Key: shared.ConfigKey("shop", "search"), TargetKind: "Ingress", Enabled: true, CollectNTop: 4},
+		}},
+	}
+
+	endpointStatsMutex.Lock()
+	for _, router := range []string{checkoutRouter, searchRouter, otherRouter} {
+		for i := 1; i <= 5; i++ {
+			latency := float64(i) / 10
+			endpointStats[fmt.Sprintf("%s:/path/%d", router, i)] = &EndpointStat{
+				TotalRequests: 1, TotalDuration: latency, MeanDuration: latency,
+			}
+		}
+	}
+	endpointStatsMutex.Unlock()
+
+	updateTopPaths()
+
+	topPathsMutex.RLock()
+	defer topPathsMutex.RUnlock()
+
+	expected := map[string]int{checkoutRouter: 1, searchRouter: 4, otherRouter: 2}
+	for router, count := range expected {
+		if got := len(topPathsPerService[router]); got != count {
+			t.Errorf("Expected %d top paths for %s, got %d", count, router, got)
+		}
+	}
+	if !topPathsPerService[checkoutRouter][checkoutRouter+":/path/5"] {
+		t.Errorf("Expected the slowest checkout path in top paths, got %v", topPathsPerService[checkoutRouter])
+	}
+}
+
 // TestCreateLogSource tests the CreateLogSource function
 func TestCreateLogSource(t *testing.T) {
 	tests := []struct {
@@ -170,7 +335,7 @@ func TestProcessLogs(t *testing.T) {
 		setupLogSource func() LogSource
 		useK8sPtr    bool
 		logFileConfig *LogFileConfig
-		jsonLogsPtr  bool
+		logFormat    string
 	}{
 		{
 			name: "process logs from file source",
@@ -189,7 +354,7 @@ func TestProcessLogs(t *testing.T) {
 			},
 			useK8sPtr: true, // Use K8s mode to avoid log rotation
 			logFileConfig: nil,
-			jsonLogsPtr: true,
+			logFormat: LogFormatJSON,
 		},
 	}
 
@@ -206,7 +371,7 @@ func TestProcessLogs(t *testing.T) {
 			}
 
 			// ProcessLogs should not panic
-			ProcessLogs(logSource, config, &tt.useK8sPtr, tt.logFileConfig, &tt.jsonLogsPtr)
+			ProcessLogs(context.Background(), logSource, config, &tt.useK8sPtr, tt.logFileConfig, tt.logFormat)
 		})
 	}
 }
@@ -264,10 +429,9 @@ func TestProcessLogsWithErrorLine(t *testing.T) {
 	}
 
 	useK8s := true // Disable log rotation
-	jsonLogs := true
 
 	// Should not panic on error lines
-	ProcessLogs(logSource, config, &useK8s, nil, &jsonLogs)
+	ProcessLogs(context.Background(), logSource, config, &useK8s, nil, LogFormatJSON)
 }
 
 // TestLogLineStruct tests the LogLine struct
@@ -359,10 +523,9 @@ func TestProcessLogsWithK8sMode(t *testing.T) {
 	}
 
 	useK8s := true
-	jsonLogs := false
 
 	// Process in K8s mode (no log rotation)
-	ProcessLogs(logSource, config, &useK8s, nil, &jsonLogs)
+	ProcessLogs(context.Background(), logSource, config, &useK8s, nil, LogFormatCLF)
 }
 
 // TestProcessLogsWithInvalidMaxFileSize tests handling of invalid max file size
@@ -400,13 +563,69 @@ func TestProcessLogsWithInvalidMaxFileSize(t *testing.T) {
 		FileLocation: "/tmp/test.log",
 		MaxFileBytes: -1, // Invalid size
 	}
-	jsonLogs := false
 
 	// Should handle invalid MaxFileBytes gracefully
-	ProcessLogs(logSource, config, &useK8s, logFileConfig, &jsonLogs)
+	ProcessLogs(context.Background(), logSource, config, &useK8s, logFileConfig, LogFormatCLF)
 
 	// Verify MaxFileBytes was set to default
 	if logFileConfig.MaxFileBytes != 10 {
 		t.Errorf("Expected MaxFileBytes to be reset to default 10, got %d", logFileConfig.MaxFileBytes)
 	}
 }
+
+// TestProcessLogsContextCancel tests that cancelling the context stops processing while the source stays open
+func TestProcessLogsContextCancel(t *testing.T) {
+	source := &mockLogSource{lines: make(chan LogLine)}
+	defer source.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		useK8s := true // Disable log rotation
+		ProcessLogs(ctx, source, TraefikOfficerConfig{}, &useK8s, nil, LogFormatCLF)
+	}()
+
+	source.lines <- LogLine{Text: "not an access log line"}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ProcessLogs did not return after the context was cancelled")
+	}
+}
+
+// TestProcessLogsContextCancelDrains tests that lines buffered before cancellation are still processed
+func TestProcessLogsContextCancelDrains(t *testing.T) {
+	resetEndpointStats(t)
+	router := "websecure-drain-a457d08d5820f79b3e08@kubernetes"
+	before := testutil.ToFloat64(totalRequests.WithLabelValues("GET", "200", router))
+
+	source := &mockLogSource{lines: make(chan LogLine, 3)}
+	defer source.Close()
+	for i := 0; i < 3; i++ {
+		source.lines <- LogLine{Text: fmt.Sprintf(
+			`{"RouterName":%q,"RequestMethod":"GET","RequestPath":"/drain","OriginStatus":200,"Duration":1000000}`, router)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		useK8s := true // Disable log rotation
+		config := TraefikOfficerConfig{AllowedServices: []TraefikService{{Name: router}}}
+		ProcessLogs(ctx, source, config, &useK8s, nil, LogFormatJSON)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ProcessLogs did not return after the context was cancelled")
+	}
+	if got := testutil.ToFloat64(totalRequests.WithLabelValues("GET", "200", router)) - before; got != 3 {
+		t.Errorf("Expected the 3 buffered lines to be processed, got %v", got)
+	}
+}
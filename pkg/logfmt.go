@@ -0,0 +1,103 @@
+package logprocessing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// logfmtKeyAliases maps logfmt keys that follow the JSON access log names to traefikLogConfig fields
+var logfmtKeyAliases = map[string]string{
+	"request_User-Agent": "UserAgent",
+	"tls_version":        "TLSVersion",
+	"tls_cipher":         "TLSCipher",
+}
+
+// parseLogfmt parses a logfmt access log line whose keys are the Traefik JSON access log fields,
+// such as ClientHost, RouterName, RequestPath, OriginStatus and Duration (in nanoseconds)
+func parseLogfmt(line string) (traefikLogConfig, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return traefikLogConfig{}, errEmptyLine
+	}
+
+	pairs, err := splitLogfmt(line)
+	if err != nil {
+		return traefikLogConfig{}, withReason(fmt.Errorf("invalid logfmt line: %w", err), errInvalidFormat)
+	}
+	if pairs["RequestMethod"] == "" && pairs["RequestPath"] == "" {
+		return traefikLogConfig{}, errNotAccessLog
+	}
+
+	var log traefikLogConfig
+	for key, value := range pairs {
+		field := key
+		if alias, ok := logfmtKeyAliases[key]; ok {
+			field = alias
+		}
+		set, ok := jsonFieldSetters[field]
+		if !ok {
+			continue
+		}
+		if !set(&log, value) {
+			return traefikLogConfig{}, withReason(fmt.Errorf("invalid value %q for %s", value, key), fieldReason(field))
+		}
+	}
+
+	log.Duration = log.Duration / 1000000 // Same as JSON logs: latency in nanoseconds, convert to ms
+	log.Overhead = log.Overhead / 1000000
+
+	logger.Debugf("Logfmt Parsed: %+v", log)
+	return log, nil
+}
+
+// splitLogfmt splits a logfmt line into its key=value pairs. Values may be double-quoted with
+// Go-style escapes; keys without a value are ignored.
+func splitLogfmt(line string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		key := line[start:i]
+		if i >= len(line) || line[i] != '=' {
+			continue
+		}
+		i++ // skip '='
+
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated quoted value for %s", key)
+			}
+			value, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value for %s: %w", key, err)
+			}
+			pairs[key] = value
+			i = end + 1
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		pairs[key] = line[start:i]
+	}
+	return pairs, nil
+}
@@ -0,0 +1,76 @@
+package logprocessing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// logfmtAccessLogLine is a Traefik access log entry as written by a Loki logfmt formatter
+const logfmtAccessLogLine = `ClientHost=10.0.3.17 StartUTC=2024-01-01T12:00:00Z RouterName=websecure-shop-api@kubernetes ` +
+	`RequestMethod=GET RequestPath="/search?q=hello world" RequestProtocol=HTTP/1.1 OriginStatus=200 ` +
+	`OriginContentSize=312 RequestCount=42 Duration=4500000 Overhead=250000 ` +
+	`request_User-Agent="Mozilla/5.0 (X11; Linux x86_64) \"quoted\"" level=info msg=""`
+
+// TestParseLogfmt tests mapping a logfmt line onto the access log fields
+func TestParseLogfmt(t *testing.T) {
+	result, err := parseLogfmt(logfmtAccessLogLine)
+	if err != nil {
+		t.Fatalf("parseLogfmt() error = %v", err)
+	}
+
+	expected := traefikLogConfig{
+		ClientHost:        "10.0.3.17",
+		StartUTC:          "2024-01-01T12:00:00Z",
+		RouterName:        "websecure-shop-api@kubernetes",
+		RequestMethod:     "GET",
+		RequestPath:       "/search?q=hello world",
+		RequestProtocol:   "HTTP/1.1",
+		OriginStatus:      200,
+		OriginContentSize: 312,
+		RequestCount:      42,
+		Duration:          4.5,
+		Overhead:          0.25,
+		UserAgent:         `Mozilla/5.0 (X11; Linux x86_64) "quoted"`,
+	}
+	if result != expected {
+		t.Errorf("parseLogfmt() = %+v, want %+v", result, expected)
+	}
+}
+
+// TestParseLogfmtErrors tests non-access lines and malformed values
+func TestParseLogfmtErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                                      "empty line",
+		`level=info msg="Configuration loaded"`: "not an access log line",
+		`RequestMethod=GET RequestPath="/unclosed`: "invalid logfmt line: unterminated quoted value for RequestPath",
+		`RequestMethod=GET OriginStatus=ok flag`:   `invalid value "ok" for OriginStatus`,
+	}
+	for line, expected := range tests {
+		if _, err := parseLogfmt(line); err == nil || err.Error() != expected {
+			t.Errorf("parseLogfmt(%q) error = %v, want %q", line, err, expected)
+		}
+	}
+}
+
+// TestProcessLogsLogfmt tests that ProcessLogs parses logfmt lines with LogFormatLogfmt
+func TestProcessLogsLogfmt(t *testing.T) {
+	resetEndpointStats(t)
+	router := "websecure-logfmt-a457d08d5820f79b3e08@kubernetes"
+	before := testutil.ToFloat64(totalRequests.WithLabelValues("POST", "201", router))
+
+	source := &mockLogSource{lines: make(chan LogLine, 2)}
+	source.lines <- LogLine{Text: fmt.Sprintf(`RouterName=%s RequestMethod=POST RequestPath=/orders OriginStatus=201 Duration=1000000`, router)}
+	source.lines <- LogLine{Text: `level=info msg="not an access log"`}
+	_ = source.Close()
+
+	config := TraefikOfficerConfig{AllowedServices: []TraefikService{{Name: router}}}
+	useK8s := true // Disable log rotation
+	ProcessLogs(context.Background(), source, config, &useK8s, nil, LogFormatLogfmt)
+
+	if got := testutil.ToFloat64(totalRequests.WithLabelValues("POST", "201", router)) - before; got != 1 {
+		t.Errorf("Expected 1 request parsed from logfmt, got %v", got)
+	}
+}
@@ -0,0 +1,185 @@
+package logprocessing
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// accessLogFormat is a compiled AccessLogFormat template
+type accessLogFormat struct {
+	template string
+	regex    *regexp.Regexp
+	// fields holds the traefikLogConfig field set by each capture group, "" for ignored groups
+	fields []string
+}
+
+var (
+	// customAccessLogFormat replaces the default common log format regex when set
+	customAccessLogFormat      *accessLogFormat
+	customAccessLogFormatMutex sync.RWMutex
+)
+
+// accessLogFormatTokens maps single letter tokens to the traefikLogConfig fields they capture.
+// The letters follow Apache's LogFormat where Traefik logs the same value.
+var accessLogFormatTokens = map[byte][]string{
+	'h': {"ClientHost"},
+	'l': {""},
+	'u': {""},
+	't': {"StartUTC"},
+	'r': {"RequestMethod", "RequestPath", "RequestProtocol"},
+	'm': {"RequestMethod"},
+	'U': {"RequestPath"},
+	'H': {"RequestProtocol"},
+	's': {"OriginStatus"},
+	'b': {"OriginContentSize"},
+}
+
+// SetAccessLogFormat compiles a Traefik-style access log template used by parseLine instead of the
+// default common log format. An empty format restores the default. Supported tokens:
+//
+//	%h client host, %l and %u ignored, %t [timestamp], %r "method path protocol" request line,
+//	%m method, %U path, %H protocol, %s status, %b content size,
+//	%{Header}i a request header (ignored except User-Agent), %{Field}x any access log field such as RouterName,
+//	RequestCount or Duration, and %% a literal percent sign.
+func SetAccessLogFormat(format string) error {
+	var compiled *accessLogFormat
+	if format != "" {
+		var err error
+		compiled, err = compileAccessLogFormat(format)
+		if err != nil {
+			return err
+		}
+	}
+	setAccessLogFormat(compiled)
+	return nil
+}
+
+// setAccessLogFormat replaces the custom access log format with a compiled one, nil for the default
+func setAccessLogFormat(compiled *accessLogFormat) {
+	customAccessLogFormatMutex.Lock()
+	defer customAccessLogFormatMutex.Unlock()
+	customAccessLogFormat = compiled
+}
+
+// currentAccessLogFormat returns the custom access log format, or nil for the default
+func currentAccessLogFormat() *accessLogFormat {
+	customAccessLogFormatMutex.RLock()
+	defer customAccessLogFormatMutex.RUnlock()
+	return customAccessLogFormat
+}
+
+// compileAccessLogFormat translates a format template into a regex with one group per captured value.
+// Whitespace matches any run of whitespace and other characters match literally. Values directly
+// preceded by a quote may contain spaces.
+func compileAccessLogFormat(format string) (*accessLogFormat, error) {
+	var pattern strings.Builder
+	fields := make([]string, 0)
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c == ' ' || c == '\t' {
+			for i+1 < len(format) && (format[i+1] == ' ' || format[i+1] == '\t') {
+				i++
+			}
+			pattern.WriteString(`\s+`)
+			continue
+		}
+		if c != '%' {
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+			continue
+		}
+
+		if i+1 >= len(format) {
+			return nil, fmt.Errorf("dangling %% at the end of access log format %q", format)
+		}
+		quoted := i > 0 && format[i-1] == '"'
+		value := `(\S+)`
+		if quoted {
+			value = `([^"]*)`
+		}
+
+		i++
+		switch token := format[i]; {
+		case token == '%':
+			pattern.WriteString("%")
+		case token == '{':
+			end := strings.IndexByte(format[i:], '}')
+			if end == -1 || i+end+1 >= len(format) {
+				return nil, fmt.Errorf("unterminated %%{ token at position %d in access log format", i-1)
+			}
+			name := format[i+1 : i+end]
+			kind := format[i+end+1]
+			i += end + 1
+
+			switch kind {
+			case 'i':
+				pattern.WriteString(value)
+				if strings.EqualFold(name, "User-Agent") {
+					fields = append(fields, "UserAgent")
+				} else {
+					fields = append(fields, "")
+				}
+			case 'x':
+				if _, ok := jsonFieldSetters[name]; !ok {
+					return nil, fmt.Errorf("unknown access log field %q in %%{%s}x", name, name)
+				}
+				pattern.WriteString(value)
+				fields = append(fields, name)
+			default:
+				return nil, fmt.Errorf("unknown access log format token %%{%s}%c", name, kind)
+			}
+		case token == 't':
+			pattern.WriteString(`\[([^\]]+)\]`)
+			fields = append(fields, "StartUTC")
+		case token == 'r':
+			pattern.WriteString(`(\S+)\s+(\S+)\s+([^"\s]*)`)
+			fields = append(fields, accessLogFormatTokens['r']...)
+		default:
+			captured, ok := accessLogFormatTokens[token]
+			if !ok {
+				return nil, fmt.Errorf("unknown access log format token %%%c at position %d", token, i-1)
+			}
+			pattern.WriteString(value)
+			fields = append(fields, captured...)
+		}
+	}
+
+	regex, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile access log format %q: %w", format, err)
+	}
+	return &accessLogFormat{template: format, regex: regex, fields: fields}, nil
+}
+
+// parse extracts the fields of a line logged with the format. Numeric values logged as "-" are left
+// at zero, and durations may carry Traefik's "ms" suffix.
+func (f *accessLogFormat) parse(line string) (traefikLogConfig, error) {
+	submatch := f.regex.FindStringSubmatch(line)
+	if submatch == nil {
+		if !isAccessLogLine(line) {
+			return traefikLogConfig{}, errNotAccessLog
+		}
+		return traefikLogConfig{}, errInvalidFormat
+	}
+
+	var log traefikLogConfig
+	var parseErr error
+	for i, field := range f.fields {
+		value := submatch[i+1]
+		if field == "" || value == "-" {
+			continue
+		}
+		if field == "Duration" || field == "Overhead" {
+			if ms, err := parseDurationMs(value); err == nil {
+				value = strconv.FormatFloat(ms, 'f', -1, 64)
+			}
+		}
+		if !jsonFieldSetters[field](&log, value) {
+			parseErr = withReason(fmt.Errorf("invalid %s %q", field, value), fieldReason(field))
+		}
+	}
+	return log, parseErr
+}
@@ -0,0 +1,140 @@
+package logprocessing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAccessLogFormat tests parsing lines logged with non-default formats
+func TestAccessLogFormat(t *testing.T) {
+	defer func() {
+		_ = SetAccessLogFormat("")
+	}()
+
+	tests := []struct {
+		name     string
+		format   string
+		line     string
+		expected traefikLogConfig
+	}{
+		{
+			name:   "extra fields and no frontend name",
+			format: `%h %l %u %t "%r" %s %b "%{Referer}i" "%{User-Agent}i" %{RequestCount}x "%{X-Forwarded-Host}i" %{Duration}x %{RouterName}x`,
+			line: `10.0.0.1 - alice [01/Jan/2024:12:00:00 +0000] "POST /api/orders HTTP/2.0" 201 512 "-" ` +
+				`"Mozilla/5.0 (X11; Linux x86_64)" 7 "shop.example.com" 25ms shop-api@kubernetes`,
+			expected: traefikLogConfig{
+				ClientHost:        "10.0.0.1",
+				StartUTC:          "01/Jan/2024:12:00:00 +0000",
+				RequestMethod:     "POST",
+				RequestPath:       "/api/orders",
+				RequestProtocol:   "HTTP/2.0",
+				OriginStatus:      201,
+				OriginContentSize: 512,
+				RequestCount:      7,
+				RouterName:        "shop-api@kubernetes",
+				Duration:          25,
+				UserAgent:         "Mozilla/5.0 (X11; Linux x86_64)",
+			},
+		},
+		{
+			name:   "separate request fields in a different order",
+			format: `%t %{RouterName}x %m %U %s %{Duration}x %b %%%{Overhead}x`,
+			line:   `[01/Jan/2024:12:00:00 +0000] shop-api@kubernetes GET /api/users/42 404 3.5 - %0.2`,
+			expected: traefikLogConfig{
+				StartUTC:      "01/Jan/2024:12:00:00 +0000",
+				RouterName:    "shop-api@kubernetes",
+				RequestMethod: "GET",
+				RequestPath:   "/api/users/42",
+				OriginStatus:  404,
+				Duration:      3.5,
+				Overhead:      0.2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetAccessLogFormat(tt.format); err != nil {
+				t.Fatalf("SetAccessLogFormat() error = %v", err)
+			}
+
+			result, err := parseLine(tt.line)
+			if err != nil {
+				t.Fatalf("parseLine() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("parseLine() = %+v, want %+v", result, tt.expected)
+			}
+
+			// Lines in the default format no longer match
+			if _, err := parseLine(benchmarkAccessLogLine); err == nil {
+				t.Error("Expected a default format line not to match the custom format")
+			}
+		})
+	}
+}
+
+// TestAccessLogFormatErrors tests that invalid templates are rejected with a helpful error
+func TestAccessLogFormatErrors(t *testing.T) {
+	defer func() {
+		_ = SetAccessLogFormat("")
+	}()
+
+	tests := []struct {
+		name    string
+		format  string
+		wantErr string
+	}{
+		{name: "unknown token", format: `%h %q %s`, wantErr: "%q"},
+		{name: "unknown field", format: `%h %{Latency}x`, wantErr: `"Latency"`},
+		{name: "unknown brace token", format: `%h %{Referer}z`, wantErr: "%{Referer}z"},
+		{name: "unterminated brace", format: `%h %{Referer`, wantErr: "unterminated"},
+		{name: "dangling percent", format: `%h %`, wantErr: "dangling"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetAccessLogFormat(tt.format)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error to mention %s, got %v", tt.wantErr, err)
+			}
+			if currentAccessLogFormat() != nil {
+				t.Error("Expected the default format to stay active after a rejected format")
+			}
+		})
+	}
+}
+
+// TestLoadConfigAccessLogFormat tests that LoadConfig compiles the format and reports invalid ones
+func TestLoadConfigAccessLogFormat(t *testing.T) {
+	oldTopNPaths := topNPaths
+	defer func() {
+		topNPaths = oldTopNPaths
+		_ = SetAccessLogFormat("")
+	}()
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	if err := os.WriteFile(valid, []byte(`{"AccessLogFormat":"%h %t \"%r\" %s %b"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := LoadConfig(valid); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if format := currentAccessLogFormat(); format == nil || format.template != `%h %t "%r" %s %b` {
+		t.Errorf("Expected the configured format to be active, got %+v", format)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"AccessLogFormat":"%h %Z"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := LoadConfig(invalid); err == nil || !strings.Contains(err.Error(), "AccessLogFormat") {
+		t.Errorf("Expected an AccessLogFormat error, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package logprocessing
+
+import (
+	"fmt"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// Output formats of the process logs, as opposed to the access log formats
+const (
+	LogOutputText = "text"
+	LogOutputJSON = "json"
+)
+
+// ConfigureLogging sets the formatter and level of the process logs. format is text (the default
+// when empty) or json, for log pipelines expecting one JSON object per line. level is a logrus level
+// such as debug or warn, info when empty.
+func ConfigureLogging(format, level string) error {
+	var formatter logger.Formatter
+	switch format {
+	case "", LogOutputText:
+		formatter = &logger.TextFormatter{FullTimestamp: true}
+	case LogOutputJSON:
+		formatter = &logger.JSONFormatter{}
+	default:
+		return fmt.Errorf("unknown log output format %q, expected %q or %q", format, LogOutputText, LogOutputJSON)
+	}
+
+	parsedLevel := logger.InfoLevel
+	if level != "" {
+		var err error
+		if parsedLevel, err = logger.ParseLevel(level); err != nil {
+			return err
+		}
+	}
+
+	logger.SetFormatter(formatter)
+	logger.SetLevel(parsedLevel)
+	return nil
+}
@@ -0,0 +1,46 @@
+package logprocessing
+
+import (
+	"testing"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// TestConfigureLogging tests that the formatter and level follow the flags
+func TestConfigureLogging(t *testing.T) {
+	oldFormatter, oldLevel := logger.StandardLogger().Formatter, logger.GetLevel()
+	defer func() {
+		logger.SetFormatter(oldFormatter)
+		logger.SetLevel(oldLevel)
+	}()
+
+	if err := ConfigureLogging(LogOutputJSON, "debug"); err != nil {
+		t.Fatalf("ConfigureLogging() error = %v", err)
+	}
+	if _, ok := logger.StandardLogger().Formatter.(*logger.JSONFormatter); !ok {
+		t.Errorf("Expected a JSON formatter, got %T", logger.StandardLogger().Formatter)
+	}
+	if logger.GetLevel() != logger.DebugLevel {
+		t.Errorf("Expected the debug level, got %s", logger.GetLevel())
+	}
+
+	if err := ConfigureLogging("", ""); err != nil {
+		t.Fatalf("ConfigureLogging() error = %v", err)
+	}
+	if _, ok := logger.StandardLogger().Formatter.(*logger.TextFormatter); !ok {
+		t.Errorf("Expected a text formatter by default, got %T", logger.StandardLogger().Formatter)
+	}
+	if logger.GetLevel() != logger.InfoLevel {
+		t.Errorf("Expected the info level by default, got %s", logger.GetLevel())
+	}
+
+	if err := ConfigureLogging("xml", ""); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if err := ConfigureLogging(LogOutputText, "verbose"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if _, ok := logger.StandardLogger().Formatter.(*logger.TextFormatter); !ok {
+		t.Error("Expected the formatter to be kept on error")
+	}
+}
@@ -0,0 +1,86 @@
+package logprocessing
+
+import (
+	"sync"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+const (
+	// parseFailureSummaryInterval is how often repeated parse failures of one reason are summarized
+	parseFailureSummaryInterval = time.Minute
+	// maxThrottledReasons bounds the number of distinct reasons tracked; the rest share one entry
+	maxThrottledReasons = 1000
+	overflowReason      = "other"
+)
+
+// reasonThrottle logs the first occurrence of each distinct reason and then, at most once per
+// interval, a summary with the number of occurrences suppressed since the last message
+type reasonThrottle struct {
+	interval time.Duration
+	now      func() time.Time
+	logf     func(format string, args ...interface{})
+
+	mu      sync.Mutex
+	reasons map[string]*throttledReason
+}
+
+type throttledReason struct {
+	suppressed int64
+	lastLogged time.Time
+}
+
+// newReasonThrottle creates a throttle logging through logf
+func newReasonThrottle(interval time.Duration, logf func(format string, args ...interface{})) *reasonThrottle {
+	return &reasonThrottle{
+		interval: interval,
+		now:      time.Now,
+		logf:     logf,
+		reasons:  make(map[string]*throttledReason),
+	}
+}
+
+// parseFailureLog rate-limits parse failure warnings so a log format drift doesn't flood the output
+var parseFailureLog = newReasonThrottle(parseFailureSummaryInterval, logger.Warnf)
+
+// Logf logs the message if it is the first for its reason, or a summary if the interval has passed
+// since the reason was last logged. Other occurrences are only counted.
+func (t *reasonThrottle) Logf(reason, format string, args ...interface{}) {
+	now := t.now()
+
+	t.mu.Lock()
+	state, ok := t.reasons[reason]
+	if !ok {
+		if len(t.reasons) >= maxThrottledReasons {
+			reason = overflowReason
+			state, ok = t.reasons[reason]
+		}
+		if !ok {
+			state = &throttledReason{}
+			t.reasons[reason] = state
+			state.lastLogged = now
+			t.mu.Unlock()
+			t.logf(format, args...)
+			return
+		}
+	}
+
+	if now.Sub(state.lastLogged) < t.interval {
+		state.suppressed++
+		t.mu.Unlock()
+		return
+	}
+
+	suppressed := state.suppressed
+	state.suppressed = 0
+	state.lastLogged = now
+	t.mu.Unlock()
+
+	if suppressed > 0 {
+		t.logf("Suppressed %d repeats of %q in the last %s, latest: "+format,
+			append([]interface{}{suppressed, reason, t.interval}, args...)...)
+		return
+	}
+	t.logf(format, args...)
+}
@@ -0,0 +1,73 @@
+package logprocessing
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReasonThrottle tests that repeated identical failures are suppressed and summarized
+func TestReasonThrottle(t *testing.T) {
+	var messages []string
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	throttle := newReasonThrottle(time.Minute, func(format string, args ...interface{}) {
+		messages = append(messages, fmt.Sprintf(format, args...))
+	})
+	throttle.now = func() time.Time { return now }
+
+	// First occurrence of each reason is logged
+	throttle.Logf("invalid duration", "Parse error (%s) for line: %s", "invalid duration", "line-1")
+	throttle.Logf("invalid status code", "Parse error (%s) for line: %s", "invalid status code", "line-2")
+	if len(messages) != 2 {
+		t.Fatalf("Expected first occurrence of each reason to be logged, got %v", messages)
+	}
+
+	// Repeats within the interval are suppressed
+	for i := 0; i < 1000; i++ {
+		now = now.Add(time.Millisecond)
+		throttle.Logf("invalid duration", "Parse error (%s) for line: %s", "invalid duration", "line-x")
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected repeats within the interval to be suppressed, got %d messages", len(messages))
+	}
+
+	// After the interval a summary with the suppressed count is logged
+	now = now.Add(time.Minute)
+	throttle.Logf("invalid duration", "Parse error (%s) for line: %s", "invalid duration", "line-last")
+	if len(messages) != 3 {
+		t.Fatalf("Expected a summary after the interval, got %v", messages)
+	}
+	summary := messages[2]
+	if !strings.Contains(summary, "Suppressed 1000 repeats") || !strings.Contains(summary, "line-last") {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+
+	// A reason logged again after a quiet interval is logged as-is
+	now = now.Add(2 * time.Minute)
+	throttle.Logf("invalid status code", "Parse error (%s) for line: %s", "invalid status code", "line-3")
+	if len(messages) != 4 || strings.Contains(messages[3], "Suppressed") {
+		t.Errorf("Expected plain message for a reason without suppressed repeats, got %v", messages)
+	}
+}
+
+// TestReasonThrottleBoundsReasons tests that distinct reasons beyond the limit share one entry
+func TestReasonThrottleBoundsReasons(t *testing.T) {
+	logged := 0
+	throttle := newReasonThrottle(time.Hour, func(format string, args ...interface{}) {
+		logged++
+	})
+
+	for i := 0; i < maxThrottledReasons+50; i++ {
+		throttle.Logf(fmt.Sprintf("reason-%d", i), "failure %d", i)
+	}
+
+	if len(throttle.reasons) != maxThrottledReasons+1 {
+		t.Errorf("Expected %d tracked reasons, got %d", maxThrottledReasons+1, len(throttle.reasons))
+	}
+	// Each tracked reason plus the first overflow occurrence is logged
+	if logged != maxThrottledReasons+1 {
+		t.Errorf("Expected %d logged messages, got %d", maxThrottledReasons+1, logged)
+	}
+}
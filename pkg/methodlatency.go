@@ -0,0 +1,79 @@
+package logprocessing
+
+import (
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// endpointMethodAvgLatency and endpointMethodMaxLatency are built by buildMetrics
+var (
+	endpointMethodAvgLatency *prometheus.GaugeVec
+	endpointMethodMaxLatency *prometheus.GaugeVec
+)
+
+// MethodLatency holds the latency of the requests made to an endpoint with one HTTP method
+type MethodLatency struct {
+	TotalRequests int64
+	// MeanDuration is the running mean of the request durations, like EndpointStat.MeanDuration
+	MeanDuration float64
+	MaxDuration  float64
+}
+
+// add folds count requests with the given mean and max duration into the latency
+func (m *MethodLatency) add(count int64, mean, max float64) {
+	if count <= 0 {
+		return
+	}
+	m.TotalRequests += count
+	m.MeanDuration += (mean - m.MeanDuration) * float64(count) / float64(m.TotalRequests)
+	if max > m.MaxDuration {
+		m.MaxDuration = max
+	}
+}
+
+// observeMethodLatency folds count requests made with method into the endpoint's per-method
+// latency. Callers must hold endpointStatsMutex.
+func (s *EndpointStat) observeMethodLatency(method string, count int64, mean, max float64) MethodLatency {
+	if s.Methods == nil {
+		s.Methods = make(map[string]*MethodLatency)
+	}
+	latency := s.Methods[method]
+	if latency == nil {
+		latency = &MethodLatency{}
+		s.Methods[method] = latency
+	}
+	latency.add(count, mean, max)
+	return *latency
+}
+
+// methodNames returns the HTTP methods the endpoint has latency for. Callers must hold endpointStatsMutex.
+func (s *EndpointStat) methodNames() []string {
+	methods := make([]string, 0, len(s.Methods))
+	for method := range s.Methods {
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// publishMethodLatency sets the per-method latency gauges of an endpoint
+func publishMethodLatency(namespace, ingress, endpoint, method string, latency MethodLatency) {
+	endpointMethodAvgLatency.WithLabelValues(namespace, ingress, endpoint, method).Set(latency.MeanDuration)
+	endpointMethodMaxLatency.WithLabelValues(namespace, ingress, endpoint, method).Set(latency.MaxDuration)
+}
+
+// deleteMethodLatency removes the per-method latency gauges of an endpoint and reports whether any existed
+func deleteMethodLatency(namespace, ingress, endpoint string) bool {
+	series := prometheus.Labels{"namespace": namespace, "ingress": ingress, "request_path": endpoint}
+	deleted := endpointMethodAvgLatency.DeletePartialMatch(series)
+	deleted += endpointMethodMaxLatency.DeletePartialMatch(series)
+	return deleted > 0
+}
+
+// markMethodLatencyStale sets the per-method latency gauges of an endpoint to NaN
+func markMethodLatencyStale(namespace, ingress, endpoint string, methods []string) {
+	for _, method := range methods {
+		publishMethodLatency(namespace, ingress, endpoint, method,
+			MethodLatency{MeanDuration: math.NaN(), MaxDuration: math.NaN()})
+	}
+}
@@ -0,0 +1,95 @@
+package logprocessing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// mixedMethodTraffic is fast GET and slow POST traffic, with durations in milliseconds
+var mixedMethodTraffic = []struct {
+	method   string
+	duration float64
+}{
+	{"GET", 10}, {"POST", 400}, {"GET", 30}, {"POST", 800}, {"GET", 20},
+}
+
+// setupMethodLatencyRouter makes /api/top the only top path of router and returns its endpoint labels
+func setupMethodLatencyRouter(t *testing.T, router string) (namespace, ingress string) {
+	t.Helper()
+	namespace, ingress = setupQuantileRouter(t, router)
+	t.Cleanup(func() {
+		deleteMethodLatency(namespace, ingress, "/api/top")
+		deleteMethodLatency(namespace, ingress, "/api/other")
+	})
+	return namespace, ingress
+}
+
+// assertMethodLatency checks the per-method latency gauges of /api/top fed with mixedMethodTraffic
+func assertMethodLatency(t *testing.T, namespace, ingress string) {
+	t.Helper()
+	want := map[string][2]float64{
+		"GET":  {0.02, 0.03},
+		"POST": {0.6, 0.8},
+	}
+	for method, latency := range want {
+		if got := testutil.ToFloat64(endpointMethodAvgLatency.WithLabelValues(namespace, ingress, "/api/top", method)); !almostEqual(got, latency[0]) {
+			t.Errorf("Average %s latency = %v, want %v", method, got, latency[0])
+		}
+		if got := testutil.ToFloat64(endpointMethodMaxLatency.WithLabelValues(namespace, ingress, "/api/top", method)); !almostEqual(got, latency[1]) {
+			t.Errorf("Max %s latency = %v, want %v", method, got, latency[1])
+		}
+	}
+	if got := testutil.ToFloat64(endpointAvgLatency.WithLabelValues(namespace, ingress, "/api/top")); !almostEqual(got, 0.252) {
+		t.Errorf("Average latency across methods = %v, want 0.252", got)
+	}
+}
+
+// almostEqual compares floats within the rounding error of the running means
+func almostEqual(a, b float64) bool {
+	diff := a - b
+	return diff < 1e-9 && diff > -1e-9
+}
+
+// TestEndpointMethodLatency tests that the latency of a top path is broken down by HTTP method,
+// and that other paths get no per-method series
+func TestEndpointMethodLatency(t *testing.T) {
+	router := "websecure-methods-a457d08d5820f79b3e08@kubernetes"
+	namespace, ingress := setupMethodLatencyRouter(t, router)
+
+	for _, request := range mixedMethodTraffic {
+		for _, path := range []string{"/api/top", "/api/other"} {
+			updateMetrics(&traefikLogConfig{RequestMethod: request.method, OriginStatus: 200, RouterName: router, RequestPath: path, Duration: request.duration}, nil, urlNormalization)
+		}
+	}
+
+	assertMethodLatency(t, namespace, ingress)
+	if deleteMethodLatency(namespace, ingress, "/api/other") {
+		t.Error("Expected no per-method latency series outside the top paths")
+	}
+
+	// Eviction drops the per-method series along with the endpoint
+	endpointStatsMutex.Lock()
+	endpointStats[router+":/api/top"].LastSeen = time.Now().Add(-2 * time.Hour)
+	endpointStatsMutex.Unlock()
+	evictStaleEndpointStats(time.Hour, time.Now())
+	if deleteMethodLatency(namespace, ingress, "/api/top") {
+		t.Error("Expected the per-method latency series to be deleted on eviction")
+	}
+}
+
+// TestEndpointMethodLatencyBatched tests that batched updates keep the latency of each method apart
+func TestEndpointMethodLatencyBatched(t *testing.T) {
+	router := "websecure-methods-batched-a457d08d5820f79b3e08@kubernetes"
+	namespace, ingress := setupMethodLatencyRouter(t, router)
+
+	// Flush mid-way so the per-method means are merged across batches
+	batcher := NewMetricsBatcher(2, time.Hour)
+	for _, request := range mixedMethodTraffic {
+		updateMetricsBatched(&traefikLogConfig{RequestMethod: request.method, OriginStatus: 200, RouterName: router, RequestPath: "/api/top", Duration: request.duration}, nil, urlNormalization, batcher)
+	}
+	batcher.Close()
+
+	assertMethodLatency(t, namespace, ingress)
+}
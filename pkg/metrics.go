@@ -2,10 +2,12 @@ package logprocessing
 
 import (
 	"fmt"
+	"github.com/beorn7/perks/quantile"
+	"github.com/mithucste30/traefik-officer-operator/shared"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -25,99 +27,418 @@ var (
 	endpointStatsMutex sync.RWMutex
 )
 
+// parseStat tracks parse attempts and successes for a single router or log source
+type parseStat struct {
+	Attempts  int64
+	Successes int64
+	LastSeen  time.Time
+}
+
+// maxRouterParseStats caps the routers tracked by router_parse_success_ratio. Routers and pods come
+// and go, so once reached the least recently seen router is dropped for a new one.
+const maxRouterParseStats = 1000
+
+var (
+	// Track parse results keyed by router name (or pod/source when the router is unknown)
+	routerParseStats      = make(map[string]*parseStat)
+	routerParseStatsMutex sync.Mutex
+
+	routerNameHintRegex = regexp.MustCompile(`"RouterName"\s*:\s*"([^"]*)"`)
+	podPrefixRegex      = regexp.MustCompile(`^\[([^\]]+)\]`)
+)
+
 type EndpointStat struct {
-	TotalRequests    int64
-	TotalDuration    float64
+	TotalRequests int64
+	TotalDuration float64
+	// MeanDuration is the running mean of the request durations, see addToMean
+	MeanDuration     float64
 	MaxDuration      float64
 	ErrorCount       int64
 	ClientErrorCount int64
 	ServerErrorCount int64
+	// LastSeen is when the endpoint was last updated, used to evict stale endpoints
+	LastSeen time.Time
+
+	// Methods partitions the average and max latency by HTTP method, see observeMethodLatency
+	Methods map[string]*MethodLatency `json:",omitempty"`
+
+	// latencies estimates the latency quantiles; it is not persisted and starts empty after a restore
+	latencies *quantile.Stream
 }
 
+// DefaultMetricsPrefix is the prefix of every metric name unless InitMetrics sets another one
+const DefaultMetricsPrefix = "traefik_officer"
+
+var (
+	// metricsPrefix is prepended to every metric name, see metricName
+	metricsPrefix = DefaultMetricsPrefix
+
+	// metricsPrefixRegex matches prefixes that form valid Prometheus metric names
+	metricsPrefixRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+)
+
+// Metrics built by buildMetrics with the current prefix
 var (
-	traefikOverhead = promauto.NewSummary(prometheus.SummaryOpts{
-		Name: "traefik_officer_traefik_overhead",
+	traefikOverhead prometheus.Summary
+
+	// Original metrics
+	totalRequests *prometheus.CounterVec
+
+	// requestDuration and endpointDuration are replaced by InitLatencyHistograms
+	requestDuration, endpointDuration *prometheus.HistogramVec
+
+	requestsByClass *prometheus.CounterVec
+
+	responseBytes *prometheus.HistogramVec
+	requestBytes  *prometheus.HistogramVec
+
+	// New endpoint-specific metrics
+	endpointRequests        *prometheus.CounterVec
+	endpointAvgLatency      *prometheus.GaugeVec
+	endpointMaxLatency      *prometheus.GaugeVec
+	endpointLatencyQuantile *prometheus.GaugeVec
+	endpointErrorRate       *prometheus.GaugeVec
+	endpointClientErrorRate *prometheus.GaugeVec
+	endpointServerErrorRate *prometheus.GaugeVec
+	endpointInTopN          *prometheus.GaugeVec
+
+	sourceInfo              *prometheus.GaugeVec
+	routerParseSuccessRatio *prometheus.GaugeVec
+)
+
+// The metrics are registered with the default prefix at startup so the package works without
+// InitMetrics
+func init() {
+	buildMetrics()
+	prometheus.MustRegister(metricCollectors()...)
+}
+
+// metricName returns the full name of a metric, prefixed with the configured metrics prefix
+func metricName(name string) string {
+	return metricsPrefix + "_" + name
+}
+
+// InitMetrics rebuilds every metric with the given name prefix, DefaultMetricsPrefix when empty. It must
+// be called after flag parsing and before InitLatencyHistograms and log processing, since the values
+// recorded so far are dropped together with the old metrics.
+func InitMetrics(prefix string) error {
+	if prefix == "" {
+		prefix = DefaultMetricsPrefix
+	}
+	if !metricsPrefixRegex.MatchString(prefix) {
+		return fmt.Errorf("invalid metrics prefix %q", prefix)
+	}
+	if prefix == metricsPrefix {
+		return nil
+	}
+
+	for _, collector := range metricCollectors() {
+		prometheus.Unregister(collector)
+	}
+	unregisterDetailedHistograms()
+
+	metricsPrefix = prefix
+	buildMetrics()
+	for _, collector := range metricCollectors() {
+		if err := prometheus.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metrics with prefix %s: %w", prefix, err)
+		}
+	}
+	return nil
+}
+
+// metricCollectors returns every metric built by buildMetrics
+func metricCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		traefikOverhead, totalRequests, requestDuration, endpointDuration, requestsByClass,
+		responseBytes, requestBytes, endpointRequests, endpointAvgLatency, endpointMaxLatency,
+		endpointMethodAvgLatency, endpointMethodMaxLatency, endpointLatencyQuantile, endpointErrorRate,
+		endpointClientErrorRate, endpointServerErrorRate, endpointInTopN, sourceInfo, routerParseSuccessRatio,
+		routerInfo, botRequests, endpointOverflow, sourceDroppedLines, tlsHandshakes, activePodStreams,
+		podStreamReconnects, serviceRPS, healthUp, componentHealthy, podRequests, linesDropped, linesFiltered,
+		linesRead,
+	}
+}
+
+// buildMetrics creates every metric with the current prefix, without registering them
+func buildMetrics() {
+	traefikOverhead = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: metricName("traefik_overhead"),
 		Help: "The overhead caused by traefik processing of requests",
 	})
 
-	// Original metrics
-	totalRequests = promauto.NewCounterVec(
+	totalRequests = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "traefik_officer_requests_total",
+			Name: metricName("requests_total"),
 			Help: "Total number of HTTP requests",
 		},
 		[]string{"request_method", "response_code", "service"},
 	)
 
-	requestDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "traefik_officer_request_duration_seconds",
-			Help:    "Duration of HTTP requests in seconds",
-			Buckets: prometheus.DefBuckets,
+	requestDuration, endpointDuration = newLatencyHistograms(latencyHistogramBuckets)
+
+	requestsByClass = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("requests_by_class_total"),
+			Help: "Total number of HTTP requests per status class (2xx, 3xx, 4xx, 5xx or unknown)",
 		},
-		[]string{"request_method", "response_code", "service"},
+		[]string{"service", "status_class"},
 	)
 
-	// New endpoint-specific metrics
-	endpointRequests = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "traefik_officer_endpoint_requests_total",
-			Help: "Total number of HTTP requests per endpoint",
+	// contentSizeBuckets span 100 bytes to 100 megabytes
+	responseBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricName("response_bytes"),
+			Help:    "Size of HTTP response bodies in bytes",
+			Buckets: prometheus.ExponentialBuckets(100, 10, 7),
 		},
-		[]string{"namespace", "ingress", "request_path", "request_method", "response_code"},
+		[]string{"service"},
 	)
 
-	endpointDuration = promauto.NewHistogramVec(
+	requestBytes = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "traefik_officer_endpoint_request_duration_seconds",
-			Help:    "Duration of HTTP requests per endpoint in seconds",
-			Buckets: prometheus.DefBuckets,
+			Name:    metricName("request_bytes"),
+			Help:    "Size of HTTP request bodies in bytes, for access logs that record it (JSON)",
+			Buckets: prometheus.ExponentialBuckets(100, 10, 7),
+		},
+		[]string{"service"},
+	)
+
+	endpointRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("endpoint_requests_total"),
+			Help: "Total number of HTTP requests per endpoint",
 		},
 		[]string{"namespace", "ingress", "request_path", "request_method", "response_code"},
 	)
 
-	endpointAvgLatency = promauto.NewGaugeVec(
+	endpointAvgLatency = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "traefik_officer_endpoint_avg_latency_seconds",
+			Name: metricName("endpoint_avg_latency_seconds"),
 			Help: "Average latency per endpoint in seconds",
 		},
 		[]string{"namespace", "ingress", "request_path"},
 	)
 
-	endpointMaxLatency = promauto.NewGaugeVec(
+	endpointMaxLatency = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "traefik_officer_endpoint_max_latency_seconds",
+			Name: metricName("endpoint_max_latency_seconds"),
 			Help: "Maximum latency per endpoint in seconds",
 		},
 		[]string{"namespace", "ingress", "request_path"},
 	)
 
-	endpointErrorRate = promauto.NewGaugeVec(
+	endpointMethodAvgLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("endpoint_method_avg_latency_seconds"),
+			Help: "Average latency per endpoint and HTTP method in seconds",
+		},
+		[]string{"namespace", "ingress", "request_path", "request_method"},
+	)
+
+	endpointMethodMaxLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("endpoint_method_max_latency_seconds"),
+			Help: "Maximum latency per endpoint and HTTP method in seconds",
+		},
+		[]string{"namespace", "ingress", "request_path", "request_method"},
+	)
+
+	endpointLatencyQuantile = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "traefik_officer_endpoint_error_rate",
+			Name: metricName("endpoint_latency_quantile"),
+			Help: "Estimated latency quantiles (p50/p90/p95/p99) per endpoint in seconds",
+		},
+		[]string{"namespace", "ingress", "request_path", "quantile"},
+	)
+
+	endpointErrorRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("endpoint_error_rate"),
 			Help: "Error rate per endpoint (ratio of 4xx/5xx responses)",
 		},
 		[]string{"namespace", "ingress", "request_path"},
 	)
 
-	endpointClientErrorRate = promauto.NewGaugeVec(
+	endpointClientErrorRate = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "traefik_officer_endpoint_client_error_rate",
+			Name: metricName("endpoint_client_error_rate"),
 			Help: "Error rate per endpoint (ratio of 4xx responses)",
 		},
 		[]string{"namespace", "ingress", "request_path"},
 	)
 
-	endpointServerErrorRate = promauto.NewGaugeVec(
+	endpointServerErrorRate = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "traefik_officer_endpoint_server_error_rate",
+			Name: metricName("endpoint_server_error_rate"),
 			Help: "Error rate per endpoint (ratio of 5xx responses)",
 		},
 		[]string{"namespace", "ingress", "request_path"},
 	)
+
+	endpointInTopN = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("endpoint_in_top_n"),
+			Help: "Whether an endpoint is in its service's top N paths (1) and has detailed metrics, or not (0)",
+		},
+		[]string{"service", "endpoint"},
+	)
+
+	sourceInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("source_info"),
+			Help: "Log source mode this processor ingests from, set to 1 for the active mode",
+		},
+		[]string{"source_mode"},
+	)
+
+	routerParseSuccessRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("router_parse_success_ratio"),
+			Help: "Ratio of successfully parsed access log lines per router (or pod/source when the router is unknown)",
+		},
+		[]string{"router"},
+	)
+
+	routerInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("router_info"),
+			Help: "Namespace, ingress and target kind a Traefik router resolves to, set to 1 for each router seen",
+		},
+		[]string{"router", "namespace", "ingress", "target_kind"},
+	)
+
+	botRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("bot_requests_total"),
+			Help: "Total number of HTTP requests whose User-Agent matches a crawler pattern",
+		},
+		[]string{"service"},
+	)
+
+	endpointOverflow = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("endpoint_overflow_total"),
+			Help: "Total number of requests whose path was collapsed into the {overflow} endpoint because the service reached MaxEndpointsPerService",
+		},
+		[]string{"service"},
+	)
+
+	sourceDroppedLines = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("source_dropped_lines_total"),
+			Help: "Total number of log lines dropped by a log source because its buffer was full",
+		},
+		[]string{"source"},
+	)
+
+	tlsHandshakes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("tls_handshakes_total"),
+			Help: "Total number of TLS requests per negotiated TLS version and cipher, from JSON access logs",
+		},
+		[]string{"namespace", "tls_version", "tls_cipher"},
+	)
+
+	activePodStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: metricName("active_pod_streams"),
+		Help: "Number of Traefik pods whose logs are currently streamed in Kubernetes mode",
+	})
+
+	podStreamReconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: metricName("pod_stream_reconnects_total"),
+		Help: "Total number of times a Kubernetes pod log stream was reopened after it ended or failed",
+	})
+
+	serviceRPS = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("service_rps"),
+			Help: "Requests per second per service, averaged over a sliding window",
+		},
+		[]string{"service"},
+	)
+
+	healthUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: metricName("up"),
+		Help: "Whether the health status served by /health is healthy (1) or not (0)",
+	})
+
+	componentHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricName("component_healthy"),
+			Help: "Whether a component reported by /health is healthy (1) or not (0)",
+		},
+		[]string{"component"},
+	)
+
+	podRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("pod_requests_total"),
+			Help: "Total number of HTTP requests per Traefik pod that logged them, when enabled",
+		},
+		[]string{"request_method", "response_code", "service", "pod"},
+	)
+
+	linesDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("lines_dropped_total"),
+			Help: "Total number of log lines skipped because they couldn't be parsed, per reason",
+		},
+		[]string{"reason"},
+	)
+
+	linesFiltered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("lines_filtered_total"),
+			Help: "Total number of parsed log lines skipped by the UrlPerformance or legacy configuration, per reason",
+		},
+		[]string{"reason"},
+	)
+
+	linesRead = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricName("lines_read_total"),
+			Help: "Total number of log lines received from the log source, including read errors",
+		},
+		[]string{"source"},
+	)
+}
+
+// addToMean folds count requests with the given mean duration into MeanDuration. TotalRequests must
+// already include them. The incremental (Welford) update keeps the average accurate on long-running
+// processes, where dividing the float64 sum TotalDuration drifts as the sum grows.
+func (s *EndpointStat) addToMean(count int64, mean float64) {
+	if count <= 0 || s.TotalRequests <= 0 {
+		return
+	}
+	s.MeanDuration += (mean - s.MeanDuration) * float64(count) / float64(s.TotalRequests)
+}
+
+// Source modes identifying how a processor ingests access logs
+const (
+	SourceModeFile  = "file"
+	SourceModeK8s   = "k8s"
+	SourceModeStdin = "stdin"
+	SourceModeSSH   = "ssh"
 )
 
-func updateMetrics(entry *traefikLogConfig, urlPatterns []URLPattern) {
+// SourceModeFor returns the source mode of the selected log source
+func SourceModeFor(useK8s bool) string {
+	if useK8s {
+		return SourceModeK8s
+	}
+	return SourceModeFile
+}
+
+// SetSourceMode exposes the source mode on the traefik_officer_source_info gauge, so series from
+// processors with different ingestion paths can be told apart. It is meant to be called once at startup.
+func SetSourceMode(mode string) {
+	sourceInfo.Reset()
+	sourceInfo.WithLabelValues(mode).Set(1)
+}
+
+// updateMetrics updates the metrics and endpoint stats of an entry and returns its normalized endpoint
+func updateMetrics(entry *traefikLogConfig, urlPatterns []URLPattern, normalization URLNormalization) string {
 	method := entry.RequestMethod
 	code := strconv.Itoa(entry.OriginStatus)
 	service := entry.RouterName
@@ -125,83 +446,244 @@ func updateMetrics(entry *traefikLogConfig, urlPatterns []URLPattern) {
 
 	// Original metrics (keeping existing functionality)
 	totalRequests.WithLabelValues(method, code, service).Inc()
+	requestsByClass.WithLabelValues(service, statusClass(entry.OriginStatus)).Inc()
 	requestDuration.WithLabelValues(method, code, service).Observe(duration)
+	observeContentSizes(entry)
+	observeTLSHandshake(entry)
 
 	// New endpoint-specific metrics
-	endpoint := normalizeURL(service, entry.RequestPath, urlPatterns)
+	endpoint := capEndpoint(service, normalizeURL(service, entry.RequestPath, urlPatterns, normalization))
 
 	key := fmt.Sprintf("%s:%s", service, endpoint)
-	endpointStatsMutex.RLock()
-	if endpointStats[key] == nil {
-		endpointStatsMutex.RUnlock()
-		endpointStatsMutex.Lock()
-		endpointStats[key] = &EndpointStat{}
-		endpointStatsMutex.Unlock()
-	} else {
-		endpointStatsMutex.RUnlock()
-	}
-	endpointStatsMutex.RLock()
-	stat := endpointStats[key]
-	endpointStatsMutex.RUnlock()
+	namespace, ingress := endpointLabels(service)
+
+	// Check if this is a top path for its service
+	topPathsMutex.RLock()
+	isTopPath := topPathsPerService[service][key]
+	topPathsMutex.RUnlock()
 
+	// Mutate the stat under a single lock acquisition and derive the gauges from a snapshot,
+	// so concurrent sources never observe or publish partially updated counters
 	endpointStatsMutex.Lock()
+	stat := endpointStats[key]
+	if stat == nil {
+		stat = &EndpointStat{}
+		endpointStats[key] = stat
+	}
 	stat.TotalRequests++
 	stat.TotalDuration += duration
-
+	stat.addToMean(1, duration)
+	stat.observeLatency(duration)
+	methodLatency := stat.observeMethodLatency(method, 1, duration, duration)
+	stat.LastSeen = time.Now()
+	wasStale := staleEndpoints[key]
+	delete(staleEndpoints, key)
 	if duration > stat.MaxDuration {
 		stat.MaxDuration = duration
 	}
-	endpointStatsMutex.Unlock()
-
-	isError := entry.OriginStatus >= 400
-	if isError {
-		endpointStatsMutex.Lock()
+	if entry.OriginStatus >= 400 {
 		stat.ErrorCount++
-		endpointStatsMutex.Unlock()
-		errorRate := float64(stat.ErrorCount) / float64(stat.TotalRequests)
-		endpointErrorRate.WithLabelValues(service, endpoint).Set(errorRate)
 		if entry.OriginStatus >= 500 {
-			endpointStatsMutex.Lock()
 			stat.ServerErrorCount++
-			endpointStatsMutex.Unlock()
-			serverErrorRate := float64(stat.ServerErrorCount) / float64(stat.TotalRequests)
-			endpointServerErrorRate.WithLabelValues(service, endpoint).Set(serverErrorRate)
 		} else {
-			endpointStatsMutex.Lock()
 			stat.ClientErrorCount++
-			endpointStatsMutex.Unlock()
-			clientErrorRate := float64(stat.ClientErrorCount) / float64(stat.TotalRequests)
-			endpointClientErrorRate.WithLabelValues(service, endpoint).Set(clientErrorRate)
 		}
 	}
+	snapshot := *stat
+	var quantiles []float64
+	if isTopPath {
+		quantiles = stat.latencyQuantileValues()
+	}
+	endpointStatsMutex.Unlock()
+
+	if wasStale {
+		publishEndpointErrorRates(namespace, ingress, endpoint, snapshot)
+	} else if entry.OriginStatus >= 400 {
+		errorRate := float64(snapshot.ErrorCount) / float64(snapshot.TotalRequests)
+		endpointErrorRate.WithLabelValues(namespace, ingress, endpoint).Set(errorRate)
+		if entry.OriginStatus >= 500 {
+			serverErrorRate := float64(snapshot.ServerErrorCount) / float64(snapshot.TotalRequests)
+			endpointServerErrorRate.WithLabelValues(namespace, ingress, endpoint).Set(serverErrorRate)
+		} else {
+			clientErrorRate := float64(snapshot.ClientErrorCount) / float64(snapshot.TotalRequests)
+			endpointClientErrorRate.WithLabelValues(namespace, ingress, endpoint).Set(clientErrorRate)
+		}
+	}
+
+	if isTopPath {
+		endpointAvgLatency.WithLabelValues(namespace, ingress, endpoint).Set(snapshot.MeanDuration)
+		endpointMaxLatency.WithLabelValues(namespace, ingress, endpoint).Set(snapshot.MaxDuration)
+		publishMethodLatency(namespace, ingress, endpoint, method, methodLatency)
+		publishLatencyQuantiles(namespace, ingress, endpoint, quantiles)
+		endpointRequests.WithLabelValues(namespace, ingress, endpoint, method, code).Inc()
+		endpointDuration.WithLabelValues(namespace, ingress, endpoint, method, code).Observe(duration)
+	}
+	return endpoint
+}
+
+// statusClass returns the class of an HTTP status code: 2xx, 3xx, 4xx, 5xx, or unknown for
+// anything else, including 1xx informational responses Traefik doesn't log as final status
+func statusClass(code int) string {
+	switch {
+	case code >= 200 && code < 300:
+		return "2xx"
+	case code >= 300 && code < 400:
+		return "3xx"
+	case code >= 400 && code < 500:
+		return "4xx"
+	case code >= 500 && code < 600:
+		return "5xx"
+	default:
+		return "unknown"
+	}
+}
+
+// observeContentSizes records the response size of an entry and its request size when logged.
+// Requests without a body aren't observed, as they can't be told apart from logs lacking the field.
+func observeContentSizes(entry *traefikLogConfig) {
+	responseBytes.WithLabelValues(entry.RouterName).Observe(float64(entry.OriginContentSize))
+	if entry.RequestContentSize > 0 {
+		requestBytes.WithLabelValues(entry.RouterName).Observe(float64(entry.RequestContentSize))
+	}
+}
+
+// updateMetricsBatched updates the per-request metrics of an entry immediately and hands its
+// endpoint stats to the batcher, which merges them into endpointStats on its next flush.
+// It returns the normalized endpoint of the entry.
+func updateMetricsBatched(entry *traefikLogConfig, urlPatterns []URLPattern, normalization URLNormalization,
+	batcher *MetricsBatcher) string {
+	method := entry.RequestMethod
+	code := strconv.Itoa(entry.OriginStatus)
+	service := entry.RouterName
+	duration := float64(entry.Duration) / 1000.0 // Convert to seconds
+
+	totalRequests.WithLabelValues(method, code, service).Inc()
+	requestsByClass.WithLabelValues(service, statusClass(entry.OriginStatus)).Inc()
+	requestDuration.WithLabelValues(method, code, service).Observe(duration)
+	observeContentSizes(entry)
+	observeTLSHandshake(entry)
+
+	endpoint := capEndpoint(service, normalizeURL(service, entry.RequestPath, urlPatterns, normalization))
+	key := fmt.Sprintf("%s:%s", service, endpoint)
+	batcher.record(key, service, endpoint, method, duration, entry.OriginStatus)
 
-	// Check if this is a top path for its service
 	topPathsMutex.RLock()
 	isTopPath := topPathsPerService[service][key]
 	topPathsMutex.RUnlock()
 
 	if isTopPath {
-		avgLatency := stat.TotalDuration / float64(stat.TotalRequests)
-		endpointAvgLatency.WithLabelValues(service, endpoint).Set(avgLatency)
-		endpointMaxLatency.WithLabelValues(service, endpoint).Set(stat.MaxDuration)
-		endpointRequests.WithLabelValues(service, endpoint, method, code).Inc()
-		endpointDuration.WithLabelValues(service, endpoint, method, code).Observe(duration)
+		namespace, ingress := endpointLabels(service)
+		endpointRequests.WithLabelValues(namespace, ingress, endpoint, method, code).Inc()
+		endpointDuration.WithLabelValues(namespace, ingress, endpoint, method, code).Observe(duration)
+	}
+	return endpoint
+}
+
+// recordMetrics updates the metrics for an entry, batching endpoint stats when a batcher is given,
+// and returns the normalized endpoint of the entry. target is the entry's UrlPerformance runtime
+// config in operator mode, nil otherwise.
+func recordMetrics(entry *traefikLogConfig, urlPatterns []URLPattern, target *shared.RuntimeConfig,
+	batcher *MetricsBatcher) string {
+	recordRouterInfo(entry.RouterName)
+	recordServiceRequest(entry.RouterName)
+	recordBotRequest(entry)
+	recordOTLP(entry)
+	recordPodRequest(entry)
+	normalization := urlNormalizationFor(target)
+	if batcher != nil {
+		return updateMetricsBatched(entry, urlPatterns, normalization, batcher)
+	}
+	return updateMetrics(entry, urlPatterns, normalization)
+}
+
+// endpointLabels returns the namespace and ingress label values of the endpoint metrics for a router.
+// Routers whose name doesn't follow a known provider format are reported under their full name.
+func endpointLabels(service string) (namespace, ingress string) {
+	labels := GetRouterLabels(service)
+	ingress = labels["ingress"]
+	if ingress == "" {
+		return labels["namespace"], service
+	}
+	return labels["namespace"], ingress
+}
+
+// parseStatsKey returns the key used to track parse results for a log line.
+// The router name is only reliably known after a successful parse, so fall back
+// to a RouterName field found in the raw line, then to the pod prefix added in
+// Kubernetes mode, and finally to "unknown".
+func parseStatsKey(routerName, line string) string {
+	if routerName != "" {
+		return routerName
+	}
+	if m := routerNameHintRegex.FindStringSubmatch(line); len(m) == 2 && m[1] != "" {
+		return m[1]
+	}
+	if m := podPrefixRegex.FindStringSubmatch(strings.TrimSpace(line)); len(m) == 2 {
+		return "pod:" + m[1]
+	}
+	return "unknown"
+}
+
+// recordParseResult records a parse attempt for the given key and updates the success ratio gauge
+func recordParseResult(key string, success bool) {
+	// The gauge is set under the lock so an eviction can't leave a series without its stat
+	routerParseStatsMutex.Lock()
+	defer routerParseStatsMutex.Unlock()
+
+	stat := routerParseStats[key]
+	if stat == nil {
+		if len(routerParseStats) >= maxRouterParseStats {
+			evictOldestParseStat()
+		}
+		stat = &parseStat{}
+		routerParseStats[key] = stat
+	}
+	stat.Attempts++
+	if success {
+		stat.Successes++
+	}
+	stat.LastSeen = time.Now()
+
+	routerParseSuccessRatio.WithLabelValues(key).Set(float64(stat.Successes) / float64(stat.Attempts))
+}
+
+// evictOldestParseStat drops the least recently seen router parse stat and its series. The caller
+// must hold routerParseStatsMutex.
+func evictOldestParseStat() {
+	oldestKey := ""
+	var oldest time.Time
+	for key, stat := range routerParseStats {
+		if oldestKey == "" || stat.LastSeen.Before(oldest) {
+			oldestKey, oldest = key, stat.LastSeen
+		}
 	}
+	delete(routerParseStats, oldestKey)
+	routerParseSuccessRatio.DeleteLabelValues(oldestKey)
 }
 
 func clearAllPathMetrics() {
 	// Clear latency metrics
 	endpointAvgLatency.Reset()
 	endpointMaxLatency.Reset()
+	endpointMethodAvgLatency.Reset()
+	endpointMethodMaxLatency.Reset()
+	endpointLatencyQuantile.Reset()
 	endpointDuration.Reset()
 	endpointRequests.Reset()
 }
 
-func startMetricsCleaner(interval time.Duration) {
+// startMetricsCleaner clears the per-path metrics every interval until stop is closed
+func startMetricsCleaner(interval time.Duration, stop <-chan struct{}) {
 	ticker := time.NewTicker(interval)
 	go func() {
-		for range ticker.C {
-			clearAllPathMetrics()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				clearAllPathMetrics()
+			case <-stop:
+				return
+			}
 		}
 	}()
 }
@@ -1,16 +1,21 @@
 package logprocessing
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"regexp"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 )
 
 // TestUpdateMetrics tests the updateMetrics function
 func TestUpdateMetrics(t *testing.T) {
-	t.Skip("Skipping updateMetrics tests - Prometheus metrics require specific label cardinality (5 labels needed)")
-
 	// Save original state
 	oldEndpointStats := endpointStats
 	oldTopPaths := topPathsPerService
@@ -62,7 +67,7 @@ func TestUpdateMetrics(t *testing.T) {
 				RequestMethod:  "GET",
 				OriginStatus:   404,
 				RouterName:     "test-router",
-				RequestPath:    "/api/users/456",
+				RequestPath:    "/api/orders/456",
 				Duration:       500.0,
 				Overhead:       25.0,
 			},
@@ -107,10 +112,10 @@ func TestUpdateMetrics(t *testing.T) {
 			}
 
 			// Run updateMetrics - this should not panic
-			updateMetrics(tt.entry, patterns)
+			updateMetrics(tt.entry, patterns, urlNormalization)
 
-			// Verify endpoint stats were updated
-			key := tt.entry.RouterName + ":" + tt.entry.RequestPath
+			// Verify endpoint stats were updated under the normalized endpoint
+			key := tt.entry.RouterName + ":" + normalizeURL(tt.entry.RouterName, tt.entry.RequestPath, patterns, urlNormalization)
 			endpointStatsMutex.RLock()
 			stat, exists := endpointStats[key]
 			endpointStatsMutex.RUnlock()
@@ -140,8 +145,6 @@ func TestUpdateMetrics(t *testing.T) {
 
 // TestUpdateMetricsConcurrency tests concurrent metric updates
 func TestUpdateMetricsConcurrency(t *testing.T) {
-	t.Skip("Skipping concurrent metrics test - Prometheus metrics require specific label cardinality")
-
 	// Save original state
 	oldEndpointStats := endpointStats
 	oldTopPaths := topPathsPerService
@@ -176,7 +179,7 @@ func TestUpdateMetricsConcurrency(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			updateMetrics(entry, []URLPattern{})
+			updateMetrics(entry, []URLPattern{}, urlNormalization)
 		}()
 	}
 	wg.Wait()
@@ -192,6 +195,82 @@ func TestUpdateMetricsConcurrency(t *testing.T) {
 	}
 }
 
+// TestUpdateMetricsRaceHammer hammers updateMetrics from many goroutines with mixed statuses while
+// top paths are recomputed; run with -race. Counters must add up and the error rate gauges must
+// match a consistent snapshot of the counters.
+func TestUpdateMetricsRaceHammer(t *testing.T) {
+	resetEndpointStats(t)
+
+	router := "websecure-hammer-shop-a457d08d5820f79b3e08@kubernetes"
+	paths := []string{"/api/cart", "/api/orders", "/api/users"}
+	statuses := []int{200, 201, 404, 500, 503}
+	const goroutines, perGoroutine = 32, 200
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				updateTopPaths()
+			}
+		}
+	}()
+
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				updateMetrics(&traefikLogConfig{
+					RequestMethod: "GET",
+					OriginStatus:  statuses[(g+i)%len(statuses)],
+					RouterName:    router,
+					RequestPath:   paths[i%len(paths)],
+					Duration:      float64(i % 50),
+				}, nil, urlNormalization)
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(stop)
+
+	// One more failing request per path publishes the final counters
+	for _, path := range paths {
+		updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 500, RouterName: router, RequestPath: path}, nil, urlNormalization)
+	}
+
+	namespace, ingress := endpointLabels(router)
+	var total int64
+	endpointStatsMutex.RLock()
+	defer endpointStatsMutex.RUnlock()
+	for _, path := range paths {
+		stat := endpointStats[router+":"+path]
+		if stat == nil {
+			t.Fatalf("Expected stats for %s", path)
+		}
+		total += stat.TotalRequests
+		if stat.ErrorCount != stat.ClientErrorCount+stat.ServerErrorCount {
+			t.Errorf("%s: ErrorCount %d != client %d + server %d", path, stat.ErrorCount, stat.ClientErrorCount, stat.ServerErrorCount)
+		}
+
+		errorRate := testutil.ToFloat64(endpointErrorRate.WithLabelValues(namespace, ingress, path))
+		expected := float64(stat.ErrorCount) / float64(stat.TotalRequests)
+		if errorRate != expected {
+			t.Errorf("%s: error rate gauge %v, want %v from the counters", path, errorRate, expected)
+		}
+		serverErrorRate := testutil.ToFloat64(endpointServerErrorRate.WithLabelValues(namespace, ingress, path))
+		if expected := float64(stat.ServerErrorCount) / float64(stat.TotalRequests); serverErrorRate != expected {
+			t.Errorf("%s: server error rate gauge %v, want %v from the counters", path, serverErrorRate, expected)
+		}
+	}
+	if total != goroutines*perGoroutine+int64(len(paths)) {
+		t.Errorf("Expected %d requests in total, got %d", goroutines*perGoroutine+len(paths), total)
+	}
+}
+
 // TestClearAllPathMetrics tests the clearAllPathMetrics function
 func TestClearAllPathMetrics(t *testing.T) {
 	tests := []struct {
@@ -252,7 +331,9 @@ func TestStartMetricsCleaner(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Start the cleaner
-			startMetricsCleaner(tt.interval)
+			stop := make(chan struct{})
+			defer close(stop)
+			startMetricsCleaner(tt.interval, stop)
 
 			// Wait for at least one cleanup cycle
 			time.Sleep(tt.wait)
@@ -312,3 +393,306 @@ func TestEndpointStat(t *testing.T) {
 		})
 	}
 }
+
+// TestParseStatsKey tests the key derivation used for per-router parse tracking
+func TestParseStatsKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		routerName string
+		line       string
+		expected   string
+	}{
+		{
+			name:       "parsed router name wins",
+			routerName: "default-api@kubernetes",
+			line:       `[traefik-abc] garbage`,
+			expected:   "default-api@kubernetes",
+		},
+		{
+			name:     "router name extracted from malformed JSON",
+			line:     `{"RouterName":"default-web@kubernetes","Duration":"oops"`,
+			expected: "default-web@kubernetes",
+		},
+		{
+			name:     "falls back to pod prefix",
+			line:     `[traefik-7d9f] 10.0.0.1 - - malformed`,
+			expected: "pod:traefik-7d9f",
+		},
+		{
+			name:     "unknown source",
+			line:     `10.0.0.1 - - malformed`,
+			expected: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseStatsKey(tt.routerName, tt.line); got != tt.expected {
+				t.Errorf("parseStatsKey() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestRouterParseSuccessRatio tests parse tracking with a mix of good and bad lines per source
+func TestRouterParseSuccessRatio(t *testing.T) {
+	// Save original state
+	oldStats := routerParseStats
+	defer func() {
+		routerParseStatsMutex.Lock()
+		routerParseStats = oldStats
+		routerParseStatsMutex.Unlock()
+	}()
+
+	routerParseStatsMutex.Lock()
+	routerParseStats = make(map[string]*parseStat)
+	routerParseStatsMutex.Unlock()
+
+	lines := make(chan LogLine, 10)
+	lines <- LogLine{Text: `{"RouterName":"ns-good@kubernetes","RequestPath":"/a","OriginStatus":200,"Duration":1000000}`}
+	lines <- LogLine{Text: `{"RouterName":"ns-good@kubernetes","RequestPath":"/b","OriginStatus":200,"Duration":1000000}`}
+	lines <- LogLine{Text: `{"RouterName":"ns-bad@kubernetes","RequestPath":"/a","OriginStatus":200,"Duration":1000000}`}
+	lines <- LogLine{Text: `{"RouterName":"ns-bad@kubernetes","RequestPath":"/a","OriginStatus":"two hundred"}`}
+	lines <- LogLine{Text: `{"RouterName":"ns-bad@kubernetes","RequestPath":`}
+	lines <- LogLine{Text: `[traefik-0] {"broken"`}
+	lines <- LogLine{Text: ""}
+	close(lines)
+
+	useK8s := true
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, TraefikOfficerConfig{}, &useK8s, nil, LogFormatJSON)
+
+	expected := map[string]parseStat{
+		"ns-good@kubernetes": {Attempts: 2, Successes: 2},
+		"ns-bad@kubernetes":  {Attempts: 3, Successes: 1},
+		"pod:traefik-0":      {Attempts: 1, Successes: 0},
+	}
+
+	routerParseStatsMutex.Lock()
+	defer routerParseStatsMutex.Unlock()
+
+	if len(routerParseStats) != len(expected) {
+		t.Errorf("Expected %d tracked keys, got %d", len(expected), len(routerParseStats))
+	}
+	for key, want := range expected {
+		got, ok := routerParseStats[key]
+		if !ok {
+			t.Errorf("Expected parse stats for %s", key)
+			continue
+		}
+		if got.Attempts != want.Attempts || got.Successes != want.Successes || got.LastSeen.IsZero() {
+			t.Errorf("Parse stats for %s = %+v, expected %+v seen recently", key, *got, want)
+		}
+	}
+}
+
+// TestSetSourceMode tests that the source_info gauge reflects the selected log source
+func TestSetSourceMode(t *testing.T) {
+	defer sourceInfo.Reset()
+
+	if count := testutil.CollectAndCount(sourceInfo); count != 0 {
+		t.Fatalf("Expected no source_info series before SetSourceMode, got %d", count)
+	}
+
+	tests := []struct {
+		name     string
+		useK8s   bool
+		expected string
+	}{
+		{name: "file source", useK8s: false, expected: SourceModeFile},
+		{name: "kubernetes source", useK8s: true, expected: SourceModeK8s},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetSourceMode(SourceModeFor(tt.useK8s))
+
+			if count := testutil.CollectAndCount(sourceInfo); count != 1 {
+				t.Errorf("Expected exactly one source_info series, got %d", count)
+			}
+			if value := testutil.ToFloat64(sourceInfo.WithLabelValues(tt.expected)); value != 1 {
+				t.Errorf("Expected source_mode=%q to be 1, got %v", tt.expected, value)
+			}
+		})
+	}
+}
+
+// TestEndpointStatMeanDrift tests that the running mean stays accurate over a long synthetic
+// sequence, where the average computed from the float sum drifts
+func TestEndpointStatMeanDrift(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping long accumulation in short mode")
+	}
+
+	durations := []float64{0.001, 0.0037, 0.012, 0.0453}
+	exact := (durations[0] + durations[1] + durations[2] + durations[3]) / 4
+
+	var stat EndpointStat
+	for i := 0; i < 10_000_000; i++ {
+		duration := durations[i%len(durations)]
+		stat.TotalRequests++
+		stat.TotalDuration += duration
+		stat.addToMean(1, duration)
+	}
+
+	naiveErr := math.Abs(stat.TotalDuration/float64(stat.TotalRequests)-exact) / exact
+	stableErr := math.Abs(stat.MeanDuration-exact) / exact
+	t.Logf("Relative error after %d requests: sum/count %.3g, running mean %.3g", stat.TotalRequests, naiveErr, stableErr)
+
+	if stableErr > 1e-12 {
+		t.Errorf("Expected running mean relative error below 1e-12, got %g", stableErr)
+	}
+	if stableErr*10 > naiveErr {
+		t.Errorf("Expected running mean (%g) to drift far less than sum/count (%g)", stableErr, naiveErr)
+	}
+}
+
+// TestEndpointStatAddToMeanBatches tests that merging batches gives the same mean as single updates
+func TestEndpointStatAddToMeanBatches(t *testing.T) {
+	var single, batched EndpointStat
+	for _, d := range []float64{0.1, 0.2, 0.3, 0.6} {
+		single.TotalRequests++
+		single.addToMean(1, d)
+	}
+
+	batched.TotalRequests += 3
+	batched.addToMean(3, 0.2) // 0.1, 0.2, 0.3
+	batched.TotalRequests++
+	batched.addToMean(1, 0.6)
+
+	if math.Abs(single.MeanDuration-0.3) > 1e-15 || math.Abs(batched.MeanDuration-0.3) > 1e-15 {
+		t.Errorf("Expected mean 0.3, got %v single and %v batched", single.MeanDuration, batched.MeanDuration)
+	}
+}
+
+// histogramSample returns the sample count and sum of a histogram series
+func histogramSample(t *testing.T, observer prometheus.Observer) (uint64, float64) {
+	t.Helper()
+	var metric dto.Metric
+	if err := observer.(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("Failed to read histogram: %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+// TestContentSizeMetrics tests that response sizes are observed for common log format and JSON
+// lines, and request sizes only for JSON lines that log them
+func TestContentSizeMetrics(t *testing.T) {
+	resetEndpointStats(t)
+	router := "websecure-sizes-a457d08d5820f79b3e08@kubernetes"
+	t.Cleanup(func() {
+		responseBytes.DeleteLabelValues(router)
+		requestBytes.DeleteLabelValues(router)
+	})
+
+	clf, err := parseLine(fmt.Sprintf(
+		`10.0.0.1 - - [01/Jan/2024:12:00:00 +0000] "GET /products HTTP/1.1" 200 512 "-" "curl/8.0" 1 "%s" "http://10.0.0.5:8080" 3ms`, router))
+	if err != nil {
+		t.Fatalf("parseLine() error = %v", err)
+	}
+	updateMetrics(&clf, nil, urlNormalization)
+
+	if count, sum := histogramSample(t, responseBytes.WithLabelValues(router)); count != 1 || sum != 512 {
+		t.Errorf("Expected one 512 byte response, got %d responses totalling %v bytes", count, sum)
+	}
+	if count, _ := histogramSample(t, requestBytes.WithLabelValues(router)); count != 0 {
+		t.Errorf("Expected no request size from a common log format line, got %d", count)
+	}
+
+	jsonEntry, err := parseJSON(fmt.Sprintf(
+		`{"RouterName":%q,"RequestMethod":"POST","RequestPath":"/orders","OriginStatus":201,"OriginContentSize":2048,"RequestContentSize":4096,"Duration":1000000}`,
+		router))
+	if err != nil {
+		t.Fatalf("parseJSON() error = %v", err)
+	}
+	batcher := NewMetricsBatcher(100, time.Hour)
+	updateMetricsBatched(&jsonEntry, nil, urlNormalization, batcher)
+	batcher.Close()
+
+	if count, sum := histogramSample(t, responseBytes.WithLabelValues(router)); count != 2 || sum != 512+2048 {
+		t.Errorf("Expected two responses totalling %d bytes, got %d totalling %v", 512+2048, count, sum)
+	}
+	if count, sum := histogramSample(t, requestBytes.WithLabelValues(router)); count != 1 || sum != 4096 {
+		t.Errorf("Expected one 4096 byte request, got %d requests totalling %v bytes", count, sum)
+	}
+}
+
+// TestStatusClass tests the status classes around their boundaries
+func TestStatusClass(t *testing.T) {
+	tests := map[int]string{
+		0: "unknown", 199: "unknown", 200: "2xx", 299: "2xx", 300: "3xx", 399: "3xx",
+		400: "4xx", 499: "4xx", 500: "5xx", 599: "5xx", 600: "unknown",
+	}
+	for code, expected := range tests {
+		if got := statusClass(code); got != expected {
+			t.Errorf("statusClass(%d) = %s, want %s", code, got, expected)
+		}
+	}
+}
+
+// TestRequestsByClass tests that requests are counted per status class on both update paths
+func TestRequestsByClass(t *testing.T) {
+	resetEndpointStats(t)
+	router := "websecure-classes-a457d08d5820f79b3e08@kubernetes"
+	t.Cleanup(func() {
+		requestsByClass.DeletePartialMatch(prometheus.Labels{"service": router})
+	})
+
+	updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 204, RouterName: router, RequestPath: "/a"}, nil, urlNormalization)
+	updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 503, RouterName: router, RequestPath: "/a"}, nil, urlNormalization)
+	batcher := NewMetricsBatcher(100, time.Hour)
+	updateMetricsBatched(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: "/a"}, nil, urlNormalization, batcher)
+	batcher.Close()
+
+	for class, expected := range map[string]float64{"2xx": 2, "5xx": 1, "4xx": 0} {
+		if got := testutil.ToFloat64(requestsByClass.WithLabelValues(router, class)); got != expected {
+			t.Errorf("Expected %v %s requests, got %v", expected, class, got)
+		}
+	}
+}
+
+// TestInitMetricsPrefix tests that InitMetrics renames every metric and that the default prefix
+// restores the original names
+func TestInitMetricsPrefix(t *testing.T) {
+	t.Cleanup(func() {
+		if err := InitMetrics(DefaultMetricsPrefix); err != nil {
+			t.Errorf("Failed to restore the default metrics prefix: %v", err)
+		}
+	})
+
+	if err := InitMetrics("acme_edge"); err != nil {
+		t.Fatalf("InitMetrics() error = %v", err)
+	}
+	totalRequests.WithLabelValues("GET", "200", "shop").Inc()
+	requestDuration.WithLabelValues("GET", "200", "shop").Observe(0.1)
+	endpointAvgLatency.WithLabelValues("shop", "api", "/cart").Set(0.1)
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	for _, expected := range []string{"acme_edge_requests_total", "acme_edge_request_duration_seconds", "acme_edge_endpoint_avg_latency_seconds"} {
+		if !names[expected] {
+			t.Errorf("Expected metric %s to be scraped", expected)
+		}
+	}
+	for name := range names {
+		if regexp.MustCompile(`^traefik_officer_`).MatchString(name) {
+			t.Errorf("Expected no metric with the default prefix, got %s", name)
+		}
+	}
+
+	if err := InitMetrics("acme-edge"); err == nil {
+		t.Error("Expected a prefix that isn't a valid metric name to be rejected")
+	}
+
+	if err := InitMetrics(""); err != nil {
+		t.Fatalf("InitMetrics() error = %v", err)
+	}
+	if got := metricName("requests_total"); got != "traefik_officer_requests_total" {
+		t.Errorf("Expected the empty prefix to restore the default names, got %s", got)
+	}
+}
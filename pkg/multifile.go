@@ -0,0 +1,262 @@
+package logprocessing
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hpcloud/tail"
+	logger "github.com/sirupsen/logrus"
+)
+
+// defaultLogFilesRescanInterval is how often glob patterns are re-evaluated for new or removed files
+const defaultLogFilesRescanInterval = 10 * time.Second
+
+// MultiFileLogSource tails several access log files concurrently and merges their lines into
+// one channel. Each line carries the path of the file it was read from in LogLine.Source.
+// Glob patterns are re-evaluated periodically so files appearing later are picked up and files
+// no longer matching are released; plain paths are tailed for the lifetime of the source.
+type MultiFileLogSource struct {
+	paths    []string
+	patterns []string
+	lines    chan LogLine
+	policy   string
+
+	mu    sync.Mutex
+	tails map[string]*tail.Tail
+
+	// compressed are the gzip-compressed files read so far, guarded by mu; they are read once
+	compressed map[string]struct{}
+
+	wg        sync.WaitGroup
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// ParseLogFiles splits a comma-separated list of files and glob patterns
+func ParseLogFiles(value string) []string {
+	files := make([]string, 0)
+	for _, file := range strings.Split(value, ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// isGlobPattern reports whether the path contains glob metacharacters
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// NewMultiFileLogSource starts tailing every listed file and every file matching a listed glob
+// pattern. A non-positive rescanInterval uses the default, as does a non-positive bufferSize for the
+// capacity of the lines channel. bufferFullPolicy applies to the lines of every file, like
+// LogFileConfig.BufferFullPolicy.
+func NewMultiFileLogSource(files []string, rescanInterval time.Duration, bufferSize int, bufferFullPolicy string) (*MultiFileLogSource, error) {
+	if rescanInterval <= 0 {
+		rescanInterval = defaultLogFilesRescanInterval
+	}
+	if bufferSize <= 0 {
+		bufferSize = defaultLineBufferSize
+	}
+	policy, err := validateBufferFullPolicy(bufferFullPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MultiFileLogSource{
+		lines:      make(chan LogLine, bufferSize),
+		policy:     policy,
+		tails:      make(map[string]*tail.Tail),
+		compressed: make(map[string]struct{}),
+		stop:       make(chan struct{}),
+	}
+
+	for _, file := range files {
+		if isGlobPattern(file) {
+			if _, err := filepath.Match(file, ""); err != nil {
+				return nil, err
+			}
+			m.patterns = append(m.patterns, file)
+		} else {
+			m.paths = append(m.paths, file)
+		}
+	}
+
+	for _, path := range m.paths {
+		if err := m.startTail(path); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	m.rescan()
+
+	if len(m.patterns) > 0 {
+		go func() {
+			ticker := time.NewTicker(rescanInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					m.rescan()
+				case <-m.stop:
+					return
+				}
+			}
+		}()
+	}
+
+	return m, nil
+}
+
+// startTail tails path and forwards its lines, unless it is already tailed. Gzip-compressed files
+// are read once instead.
+func (m *MultiFileLogSource) startTail(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tails[path]; ok {
+		return nil
+	}
+	select {
+	case <-m.stop:
+		return nil
+	default:
+	}
+
+	if isGzipFile(path) {
+		if _, ok := m.compressed[path]; ok {
+			return nil
+		}
+		m.compressed[path] = struct{}{}
+		logger.Infof("Reading compressed access log file %s", path)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			if err := readGzipFile(path, m.send); err != nil {
+				m.send(LogLine{Time: time.Now(), Err: err, Source: path})
+			}
+		}()
+		return nil
+	}
+
+	t, err := tail.TailFile(path, tail.Config{
+		Follow:    true,
+		ReOpen:    true,
+		MustExist: false,
+		Poll:      true,
+	})
+	if err != nil {
+		return err
+	}
+	m.tails[path] = t
+	logger.Infof("Tailing access log file %s", path)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		// Keep draining after stop so the tail can shut down; it blocks on unread lines
+		for line := range t.Lines {
+			logLine := LogLine{Text: line.Text, Time: line.Time, Err: line.Err, Source: path}
+			if line.Err != nil {
+				logLine.Text = ""
+			}
+			m.send(logLine)
+		}
+	}()
+
+	return nil
+}
+
+// send forwards a line, applying the buffer full policy, and reports whether the source is still open
+func (m *MultiFileLogSource) send(line LogLine) bool {
+	return sendLine(m.lines, m.stop, m.policy, line.Source, line)
+}
+
+// rescan starts tailing new files matching the glob patterns and stops tailing files that
+// no longer match
+func (m *MultiFileLogSource) rescan() {
+	if len(m.patterns) == 0 {
+		return
+	}
+
+	matched := make(map[string]struct{})
+	for _, path := range m.paths {
+		matched[path] = struct{}{}
+	}
+	for _, pattern := range m.patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			logger.Errorf("Invalid log file pattern %s: %v", pattern, err)
+			continue
+		}
+		for _, file := range files {
+			matched[file] = struct{}{}
+			if err := m.startTail(file); err != nil {
+				logger.Errorf("Failed to tail %s: %v", file, err)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	removed := make([]*tail.Tail, 0)
+	for path, t := range m.tails {
+		if _, ok := matched[path]; !ok {
+			logger.Infof("Access log file %s no longer matches, stopped tailing", path)
+			removed = append(removed, t)
+			delete(m.tails, path)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, t := range removed {
+		if err := t.Stop(); err != nil {
+			logger.Debugf("Error stopping tail of %s: %v", t.Filename, err)
+		}
+	}
+}
+
+// Files returns the paths currently being tailed
+func (m *MultiFileLogSource) Files() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := make([]string, 0, len(m.tails))
+	for path := range m.tails {
+		files = append(files, path)
+	}
+	return files
+}
+
+func (m *MultiFileLogSource) ReadLines() <-chan LogLine {
+	return m.lines
+}
+
+// Close stops all tails and closes the lines channel once every forwarder has exited
+func (m *MultiFileLogSource) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.stop)
+
+		m.mu.Lock()
+		tails := make([]*tail.Tail, 0, len(m.tails))
+		for _, t := range m.tails {
+			tails = append(tails, t)
+		}
+		m.tails = make(map[string]*tail.Tail)
+		m.mu.Unlock()
+
+		for _, t := range tails {
+			if stopErr := t.Stop(); stopErr != nil && err == nil {
+				err = stopErr
+			}
+		}
+
+		m.wg.Wait()
+		close(m.lines)
+	})
+	return err
+}
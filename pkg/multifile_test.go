@@ -0,0 +1,250 @@
+package logprocessing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// collectLines reads lines from the source until want lines arrived or the timeout expires,
+// and returns the texts grouped by source file
+func collectLines(t *testing.T, lines <-chan LogLine, want int, timeout time.Duration) map[string][]string {
+	t.Helper()
+
+	bySource := make(map[string][]string)
+	deadline := time.After(timeout)
+	for received := 0; received < want; {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return bySource
+			}
+			if line.Err != nil {
+				t.Errorf("Unexpected error in log line: %v", line.Err)
+				continue
+			}
+			bySource[line.Source] = append(bySource[line.Source], line.Text)
+			received++
+		case <-deadline:
+			t.Fatalf("Timed out after receiving %d of %d lines: %v", received, want, bySource)
+		}
+	}
+	return bySource
+}
+
+// appendLine appends a line to a file, creating it if needed
+func appendLine(t *testing.T, path, line string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// TestMultiFileLogSourceTwoFiles tests tailing two files with per-file tagging
+func TestMultiFileLogSourceTwoFiles(t *testing.T) {
+	dir := t.TempDir()
+	web := filepath.Join(dir, "access-web.log")
+	websecure := filepath.Join(dir, "access-websecure.log")
+	appendLine(t, web, "web-1")
+	appendLine(t, websecure, "websecure-1")
+
+	source, err := NewMultiFileLogSource([]string{web, websecure}, time.Hour, 0, "")
+	if err != nil {
+		t.Fatalf("NewMultiFileLogSource() error = %v", err)
+	}
+	defer source.Close()
+
+	appendLine(t, web, "web-2")
+	appendLine(t, websecure, "websecure-2")
+
+	got := collectLines(t, source.ReadLines(), 4, 5*time.Second)
+	expected := map[string][]string{
+		web:       {"web-1", "web-2"},
+		websecure: {"websecure-1", "websecure-2"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Lines by source = %v, want %v", got, expected)
+	}
+}
+
+// TestCreateLogSourceFileLocationGlob tests that a glob in -log-file tails every matching file,
+// with both files written concurrently, while a plain path keeps the single-file source
+func TestCreateLogSourceFileLocationGlob(t *testing.T) {
+	dir := t.TempDir()
+	web := filepath.Join(dir, "access-web.log")
+	websecure := filepath.Join(dir, "access-websecure.log")
+	appendLine(t, web, "web-0")
+	appendLine(t, websecure, "websecure-0")
+
+	source, err := CreateLogSource(false, &LogFileConfig{FileLocation: filepath.Join(dir, "access*.log")}, nil)
+	if err != nil {
+		t.Fatalf("CreateLogSource() error = %v", err)
+	}
+	defer source.Close()
+	if _, ok := source.(*MultiFileLogSource); !ok {
+		t.Fatalf("Expected a *MultiFileLogSource for a glob, got %T", source)
+	}
+
+	const linesPerFile = 20
+	var wg sync.WaitGroup
+	for _, path := range []string{web, websecure} {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			for i := 1; i < linesPerFile; i++ {
+				appendLine(t, path, fmt.Sprintf("%s-%d", filepath.Base(path), i))
+			}
+		}(path)
+	}
+	wg.Wait()
+
+	got := collectLines(t, source.ReadLines(), 2*linesPerFile, 5*time.Second)
+	for _, path := range []string{web, websecure} {
+		if len(got[path]) != linesPerFile {
+			t.Errorf("Expected %d lines from %s, got %d: %v", linesPerFile, path, len(got[path]), got[path])
+		}
+	}
+
+	single, err := CreateLogSource(false, &LogFileConfig{FileLocation: web}, nil)
+	if err != nil {
+		t.Fatalf("CreateLogSource() error = %v", err)
+	}
+	defer single.Close()
+	if _, ok := single.(*FileLogSource); !ok {
+		t.Errorf("Expected a *FileLogSource for a plain path, got %T", single)
+	}
+}
+
+// TestMultiFileLogSourceGlob tests that files matching a glob after startup are picked up and
+// files no longer matching are released
+func TestMultiFileLogSourceGlob(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "access-web.log")
+	second := filepath.Join(dir, "access-websecure.log")
+	appendLine(t, first, "first-1")
+	appendLine(t, filepath.Join(dir, "other.txt"), "ignored")
+
+	source, err := NewMultiFileLogSource([]string{filepath.Join(dir, "access-*.log")}, 50*time.Millisecond, 0, "")
+	if err != nil {
+		t.Fatalf("NewMultiFileLogSource() error = %v", err)
+	}
+	defer source.Close()
+
+	if files := source.Files(); !reflect.DeepEqual(files, []string{first}) {
+		t.Fatalf("Expected only %s to be tailed, got %v", first, files)
+	}
+
+	appendLine(t, second, "second-1")
+
+	got := collectLines(t, source.ReadLines(), 2, 5*time.Second)
+	if !reflect.DeepEqual(got[first], []string{"first-1"}) || !reflect.DeepEqual(got[second], []string{"second-1"}) {
+		t.Errorf("Unexpected lines by source: %v", got)
+	}
+
+	if err := os.Remove(first); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if files := source.Files(); reflect.DeepEqual(files, []string{second}) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("Expected removed file to be released, still tailing %v", source.Files())
+}
+
+// TestMultiFileLogSourceClose tests that Close closes the lines channel and is idempotent
+func TestMultiFileLogSourceClose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	appendLine(t, path, "line")
+
+	source, err := NewMultiFileLogSource([]string{path}, time.Hour, 0, "")
+	if err != nil {
+		t.Fatalf("NewMultiFileLogSource() error = %v", err)
+	}
+
+	if err := source.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := source.Close(); err != nil {
+		t.Errorf("Second Close() error = %v", err)
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-source.ReadLines():
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected lines channel to be closed")
+		}
+	}
+}
+
+// TestMultiFileLogSourceBufferFullPolicy tests that the buffer size and full policy of -log-file globs
+// apply like they do to a single file
+func TestMultiFileLogSourceBufferFullPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access-1.log")
+	for i := 0; i < 10; i++ {
+		appendLine(t, path, fmt.Sprintf("line %d", i))
+	}
+	before := testutil.ToFloat64(sourceDroppedLines.WithLabelValues(SourceModeFile))
+
+	source, err := CreateLogSource(false, &LogFileConfig{
+		FileLocation:     filepath.Join(dir, "access-*.log"),
+		BufferSize:       3,
+		BufferFullPolicy: BufferFullDrop,
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateLogSource() error = %v", err)
+	}
+	defer source.Close()
+	if _, ok := source.(*MultiFileLogSource); !ok {
+		t.Fatalf("Expected a MultiFileLogSource for a glob, got %T", source)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(sourceDroppedLines.WithLabelValues(SourceModeFile))-before < 7 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if dropped := testutil.ToFloat64(sourceDroppedLines.WithLabelValues(SourceModeFile)) - before; dropped != 7 {
+		t.Errorf("Expected 7 dropped lines, got %v", dropped)
+	}
+	for i := 0; i < 3; i++ {
+		if line := <-source.ReadLines(); line.Text != fmt.Sprintf("line %d", i) {
+			t.Errorf("Expected the buffered line %d, got %q", i, line.Text)
+		}
+	}
+
+	if _, err := NewMultiFileLogSource([]string{path}, time.Hour, 0, "sometimes"); err == nil {
+		t.Error("Expected an error for an unknown buffer full policy")
+	}
+}
+
+// TestParseLogFiles tests splitting the -log-files value
+func TestParseLogFiles(t *testing.T) {
+	got := ParseLogFiles(" /var/log/a.log, /var/log/access-*.log ,,")
+	sort.Strings(got)
+	expected := []string{"/var/log/a.log", "/var/log/access-*.log"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseLogFiles() = %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,109 @@
+package logprocessing
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Names of the default normalizeURL substitutions, which custom rules can replace and DisabledRules
+// can turn off
+const (
+	NormalizationRuleID          = "id"
+	NormalizationRuleUUID        = "uuid"
+	NormalizationRuleToken       = "token"
+	NormalizationRuleQueryParams = "query_params"
+)
+
+// NormalizationRule is a regex substitution normalizeURL applies to request paths
+type NormalizationRule struct {
+	// Name identifies the rule. A custom rule named like a default rule replaces it.
+	Name string `json:"Name"`
+	// Pattern is the regex replaced with Replacement everywhere in the path, e.g. `/item-\d+(/|$|\?)`.
+	// Capture groups can be referenced as $1.
+	Pattern     string         `json:"Pattern"`
+	Replacement string         `json:"Replacement"`
+	Regex       *regexp.Regexp `json:"-"`
+}
+
+// defaultNormalizationRules replace numeric ids, UUIDs, long alphanumeric tokens and query strings,
+// in this order
+var defaultNormalizationRules = []NormalizationRule{
+	{
+		Name:        NormalizationRuleID,
+		Pattern:     `/\d+(/|$|\?)`,
+		Replacement: "/{id}$1",
+	},
+	{
+		Name:        NormalizationRuleUUID,
+		Pattern:     `/[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}(/|$|\?)`,
+		Replacement: "/{uuid}$1",
+	},
+	{
+		Name:        NormalizationRuleToken,
+		Pattern:     `/[a-zA-Z0-9]{20,}(/|$|\?)`,
+		Replacement: "/{token}$1",
+	},
+	{
+		Name:        NormalizationRuleQueryParams,
+		Pattern:     `\?.*`,
+		Replacement: "?{query_params}",
+	},
+}
+
+func init() {
+	for i := range defaultNormalizationRules {
+		defaultNormalizationRules[i].Regex = regexp.MustCompile(defaultNormalizationRules[i].Pattern)
+	}
+}
+
+// compileNormalizationRules compiles the custom rules and returns the substitutions normalizeURL
+// applies, in order: custom rules first, so they take precedence, then the default rules, each
+// replaced by the custom rule of the same name if any. Rules named in disabled are left out.
+func compileNormalizationRules(custom []NormalizationRule, disabled []string) ([]NormalizationRule, error) {
+	overrides := make(map[string]NormalizationRule)
+	var rules []NormalizationRule
+	for i := range custom {
+		if custom[i].Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+		regex, err := regexp.Compile(custom[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for rule %s: %w", custom[i].Name, err)
+		}
+		custom[i].Regex = regex
+		if isDefaultNormalizationRule(custom[i].Name) {
+			overrides[custom[i].Name] = custom[i]
+			continue
+		}
+		rules = append(rules, custom[i])
+	}
+
+	for _, rule := range defaultNormalizationRules {
+		if override, ok := overrides[rule.Name]; ok {
+			rule = override
+		}
+		rules = append(rules, rule)
+	}
+
+	for _, name := range disabled {
+		if !slices.ContainsFunc(rules, func(rule NormalizationRule) bool { return rule.Name == name }) {
+			return nil, fmt.Errorf("cannot disable unknown rule %q", name)
+		}
+	}
+	return slices.DeleteFunc(rules, func(rule NormalizationRule) bool { return slices.Contains(disabled, rule.Name) }), nil
+}
+
+// isDefaultNormalizationRule reports whether name is the name of a default rule
+func isDefaultNormalizationRule(name string) bool {
+	return slices.ContainsFunc(defaultNormalizationRules, func(rule NormalizationRule) bool { return rule.Name == name })
+}
+
+// substitutions returns the rules normalizeURL applies: the compiled rules of the loaded config, or
+// the default rules when no config was loaded
+func (n URLNormalization) substitutions() []NormalizationRule {
+	if n.rules == nil {
+		return defaultNormalizationRules
+	}
+	return n.rules
+}
@@ -0,0 +1,192 @@
+package logprocessing
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// TestNormalizeURLCustomRules tests custom substitutions taking precedence over and replacing the default ones
+func TestNormalizeURLCustomRules(t *testing.T) {
+	rules, err := compileNormalizationRules([]NormalizationRule{
+		{Name: "sku", Pattern: `/SKU[0-9A-Z]{20,}(/|$|\?)`, Replacement: "/{sku}$1"},
+		// Only collapse long numeric ids, keeping short ones such as API versions
+		{Name: NormalizationRuleID, Pattern: `/\d{6,}(/|$|\?)`, Replacement: "/{id}$1"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("compileNormalizationRules() error = %v", err)
+	}
+	normalization := URLNormalization{rules: rules}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "custom rule applied before the token rule", path: "/products/SKU0123456789ABCDEFGHIJ/reviews", expected: "/products/{sku}/reviews"},
+		{name: "default token rule still applied", path: "/files/abcdefghijklmnopqrstuvwxyz", expected: "/files/{token}"},
+		{name: "replaced id rule keeps short numbers", path: "/v/2/orders/1234567", expected: "/v/2/orders/{id}"},
+		{name: "default uuid rule still applied", path: "/users/123e4567-e89b-12d3-a456-426614174000", expected: "/users/{uuid}"},
+		{name: "default query params rule still applied", path: "/search?q=x", expected: "/search?{query_params}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeURL("other-service", tt.path, nil, normalization); got != tt.expected {
+				t.Errorf("normalizeURL() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestNormalizeURLDisabledRules tests turning off default substitutions globally and per target
+func TestNormalizeURLDisabledRules(t *testing.T) {
+	rules, err := compileNormalizationRules(nil, []string{NormalizationRuleToken})
+	if err != nil {
+		t.Fatalf("compileNormalizationRules() error = %v", err)
+	}
+	normalization := URLNormalization{rules: rules}
+
+	if got := normalizeURL("other-service", "/articles/how-to-configure-traefik/42", nil, normalization); got != "/articles/how-to-configure-traefik/{id}" {
+		t.Errorf("Expected the id rule to still apply, got %s", got)
+	}
+	if got := normalizeURL("other-service", "/files/abcdefghijklmnopqrstuvwxyz", nil, normalization); got != "/files/abcdefghijklmnopqrstuvwxyz" {
+		t.Errorf("Expected the disabled token rule not to apply, got %s", got)
+	}
+
+	// A target can turn off rules on top of the global settings
+	oldNormalization := urlNormalization
+	defer func() {
+		urlNormalization = oldNormalization
+	}()
+	urlNormalization = normalization
+	target := urlNormalizationFor(&shared.RuntimeConfig{DisabledNormalizationRules: []string{NormalizationRuleQueryParams}})
+	if got := normalizeURL("other-service", "/files/abcdefghijklmnopqrstuvwxyz?page=2", nil, target); got != "/files/abcdefghijklmnopqrstuvwxyz?page=2" {
+		t.Errorf("Expected the target to keep the query string, got %s", got)
+	}
+	if got := normalizeURL("other-service", "/search?q=x", nil, urlNormalizationFor(nil)); got != "/search?{query_params}" {
+		t.Errorf("Expected other targets to keep the query params rule, got %s", got)
+	}
+}
+
+// TestNormalizeURLPatternWithoutService tests that UrlPerformance URL patterns, which carry no service, apply
+func TestNormalizeURLPatternWithoutService(t *testing.T) {
+	patterns := GetURLPatternsFromConfig(&shared.RuntimeConfig{
+		URLPatterns: []shared.URLPattern{{Pattern: regexp.MustCompile(`^/blog/[a-z-]+$`), Replacement: "/blog/{slug}"}},
+	})
+	got := normalizeURL("websecure-shop-blog-a457d08d5820f79b3e08@kubernetes", "/blog/hello-world", patterns, URLNormalization{})
+	if got != "/blog/{slug}" {
+		t.Errorf("normalizeURL() = %v, want /blog/{slug}", got)
+	}
+}
+
+// TestCompileNormalizationRulesErrors tests rejecting invalid custom rules and unknown disabled rules
+func TestCompileNormalizationRulesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		custom   []NormalizationRule
+		disabled []string
+	}{
+		{name: "invalid pattern", custom: []NormalizationRule{{Name: "broken", Pattern: `/(`}}},
+		{name: "missing name", custom: []NormalizationRule{{Pattern: `/x`}}},
+		{name: "unknown disabled rule", disabled: []string{"slug"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileNormalizationRules(tt.custom, tt.disabled); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	// Custom rules can be disabled by name too
+	rules, err := compileNormalizationRules([]NormalizationRule{{Name: "slug", Pattern: `/[a-z-]+$`, Replacement: "/{slug}"}}, []string{"slug"})
+	if err != nil {
+		t.Fatalf("compileNormalizationRules() error = %v", err)
+	}
+	if len(rules) != len(defaultNormalizationRules) {
+		t.Errorf("Expected only the default rules, got %d rules", len(rules))
+	}
+}
+
+// TestLoadConfigNormalizationRules tests that the rules of the config file are compiled once on load
+func TestLoadConfigNormalizationRules(t *testing.T) {
+	oldTopNPaths := topNPaths
+	oldNormalization := urlNormalization
+	defer func() {
+		topNPaths = oldTopNPaths
+		urlNormalization = oldNormalization
+	}()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"URLNormalization":{"Rules":[{"Name":"slug","Pattern":"/posts/[a-z-]+$","Replacement":"/posts/{slug}"}],"DisabledRules":["id"]}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if config.URLNormalization.Rules[0].Regex == nil {
+		t.Error("Expected the custom rule to be compiled")
+	}
+	if got := normalizeURL("other-service", "/users/42/posts/hello-world", nil, urlNormalization); got != "/users/42/posts/{slug}" {
+		t.Errorf("normalizeURL() = %v, want /users/42/posts/{slug}", got)
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"URLNormalization":{"DisabledRules":["ids"]}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected an error for an unknown disabled rule")
+	}
+}
+
+// TestNormalizeURLKeepQueryParams tests keeping whitelisted query parameters while collapsing the others
+func TestNormalizeURLKeepQueryParams(t *testing.T) {
+	normalization := URLNormalization{KeepQueryParams: []string{"type", "format"}}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "other params collapsed", path: "/export?type=pdf&token=abc", expected: "/export?type=pdf&{other_params}"},
+		{name: "only kept params", path: "/export?format=a4&type=pdf", expected: "/export?format=a4&type=pdf"},
+		{name: "no kept params", path: "/export?token=abc&page=2", expected: "/export?{query_params}"},
+		{name: "kept params after others", path: "/export?token=abc&type=csv", expected: "/export?type=csv&{other_params}"},
+		{name: "empty parameters ignored", path: "/export?&type=pdf&", expected: "/export?type=pdf"},
+		{name: "escaped key matched", path: "/export?%74ype=pdf", expected: "/export?%74ype=pdf"},
+		{name: "ids still replaced", path: "/reports/42?type=pdf", expected: "/reports/{id}?type=pdf"},
+		{name: "no query string", path: "/export", expected: "/export"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeURL("other-service", tt.path, nil, normalization); got != tt.expected {
+				t.Errorf("normalizeURL() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	// Per target, the CRD setting replaces the global one
+	oldNormalization := urlNormalization
+	defer func() {
+		urlNormalization = oldNormalization
+	}()
+	urlNormalization = normalization
+	target := urlNormalizationFor(&shared.RuntimeConfig{KeepQueryParams: []string{"token"}})
+	if got := normalizeURL("other-service", "/export?type=pdf&token=abc", nil, target); got != "/export?token=abc&{other_params}" {
+		t.Errorf("Expected the target's parameters to be kept, got %s", got)
+	}
+	if got := normalizeURL("other-service", "/export?type=pdf&token=abc", nil, urlNormalizationFor(&shared.RuntimeConfig{})); got != "/export?type=pdf&{other_params}" {
+		t.Errorf("Expected the global parameters without a target override, got %s", got)
+	}
+
+	// Disabling the query_params rule keeps the whole query string
+	disabled := urlNormalizationFor(&shared.RuntimeConfig{DisabledNormalizationRules: []string{NormalizationRuleQueryParams}})
+	if got := normalizeURL("other-service", "/export?type=pdf&token=abc", nil, disabled); got != "/export?type=pdf&token=abc" {
+		t.Errorf("Expected the query string to be kept with the rule disabled, got %s", got)
+	}
+}
@@ -22,6 +22,53 @@ var operatorConfig = &OperatorModeConfig{
 	enabled: false,
 }
 
+var (
+	// routerProviders maps additional Traefik provider suffixes (e.g. file, docker) to the
+	// target kind their routers are matched as. Their router names are expected to follow
+	// the namespace-name[-hash] convention.
+	routerProviders      = make(map[string]string)
+	routerProvidersMutex sync.RWMutex
+
+	// unknownProviders remembers which unrecognized providers have already been logged
+	unknownProviders sync.Map
+
+	// passthroughProviders maps the non-Kubernetes Traefik providers to the target kind their routers
+	// are classified as unless they are registered with RegisterRouterProvider. Their router names
+	// carry no namespace, so the full name is used as target name and they match no UrlPerformance.
+	passthroughProviders = map[string]string{
+		"file":     "File",
+		"docker":   "Docker",
+		"internal": "TraefikInternal",
+	}
+)
+
+// RegisterRouterProvider maps an additional Traefik provider suffix to a target kind so its
+// routers can be matched against UrlPerformance configs
+func RegisterRouterProvider(provider, targetKind string) {
+	routerProvidersMutex.Lock()
+	defer routerProvidersMutex.Unlock()
+
+	routerProviders[provider] = targetKind
+	logger.Infof("Routers from provider @%s will be matched as %s", provider, targetKind)
+}
+
+// ParseRouterProviders parses a comma-separated list of provider=kind pairs (e.g. "file=IngressRoute,docker=Ingress")
+func ParseRouterProviders(value string) (map[string]string, error) {
+	providers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid router provider mapping %q, expected provider=kind", pair)
+		}
+		providers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return providers, nil
+}
+
 // SetOperatorMode enables operator mode and sets the config manager
 func SetOperatorMode(enabled bool, cm shared.ConfigManager) {
 	operatorConfig.mu.Lock()
@@ -44,70 +91,100 @@ func IsOperatorMode() bool {
 
 // ShouldProcessRouter checks if a router should be processed based on CRD configs
 func ShouldProcessRouter(routerName string) (bool, *shared.RuntimeConfig) {
-	if !IsOperatorMode() {
-		// Not in operator mode - use legacy config file approach
-		return true, nil
-	}
-
-	operatorConfig.mu.RLock()
-	cm := operatorConfig.configManager
-	operatorConfig.mu.RUnlock()
+	decision := decideRouter(routerName)
+	return decision.Process, decision.config
+}
 
-	if cm == nil {
+// decideRouter explains whether the lines of a router are processed like explainRouter, logging
+// why routers are skipped
+func decideRouter(routerName string) routerDecision {
+	decision := explainRouter(routerName)
+	switch {
+	case decision.Reason == routerReasonNoConfigManager:
 		logger.Warn("Operator mode enabled but no config manager available")
-		return false, nil
-	}
-
-	// Parse router name to extract namespace and target name
-	namespace, targetName, targetKind := parseRouterName(routerName)
-	if namespace == "" || targetName == "" {
-		logger.Debugf("Could not parse router name: %s", routerName)
-		return false, nil
+	case !decision.Process:
+		logger.Debugf("Skipping router %s: %s", routerName, decision.Reason)
 	}
+	return decision
+}
 
-	// Build config key
-	configKey := fmt.Sprintf("%s-%s", namespace, targetName)
-
-	// Get configuration
-	config, exists := cm.GetConfig(configKey)
-	if !exists {
-		logger.Debugf("No configuration found for: %s (router: %s)", configKey, routerName)
-		return false, nil
+// topNLimitFor returns how many top paths are tracked for the router: the CollectNTop of its
+// UrlPerformance in operator mode, or the global TopNPaths when no config matches
+func topNLimitFor(routerName string) int {
+	if !IsOperatorMode() {
+		return currentTopNPaths()
 	}
-
-	if !config.Enabled {
-		logger.Debugf("Configuration disabled for: %s", configKey)
-		return false, nil
+	if _, config := ShouldProcessRouter(routerName); config != nil && config.CollectNTop > 0 {
+		return config.CollectNTop
 	}
+	return currentTopNPaths()
+}
 
-	// Verify target kind matches
-	if targetKind != config.TargetKind {
-		logger.Debugf("Target kind mismatch for %s: got %s, expected %s", configKey, targetKind, config.TargetKind)
-		return false, nil
+// targetKindMatches reports whether a router of the parsed kind belongs to a target of the
+// configured kind. Traefik names IngressRouteTCP routers like IngressRoute routers, so routers
+// parsed as IngressRoute also match IngressRouteTCP targets.
+func targetKindMatches(parsedKind, configuredKind string) bool {
+	if parsedKind == configuredKind {
+		return true
 	}
-
-	return true, config
+	return parsedKind == "IngressRoute" && configuredKind == "IngressRouteTCP"
 }
 
-// parseRouterName parses the router name from Traefik logs
+// parseRouterName parses the router name from Traefik logs. The configured router name patterns
+// are tried first, see SetRouterNamePatterns; routers none of them match are parsed by the
+// built-in heuristic for the naming conventions of the Traefik Kubernetes providers.
 func parseRouterName(routerName string) (namespace, targetName, targetKind string) {
 	// Remove provider suffix
+	provider := ""
 	if idx := strings.Index(routerName, "@"); idx != -1 {
-		provider := routerName[idx+1:]
-		routerName = routerName[:idx]
-
-		switch provider {
-		case "kubernetes":
-			targetKind = "Ingress"
-		case "kubernetescrd":
-			targetKind = "IngressRoute"
+		routerName, provider = routerName[:idx], routerName[idx+1:]
+	}
+
+	if namespace, targetName, targetKind, ok := matchRouterNamePattern(provider, routerName); ok {
+		return namespace, targetName, targetKind
+	}
+
+	genericProvider := false
+	if provider != "" {
+		kind, ok := providerTargetKind(provider)
+		if !ok {
+			if kind, passthrough := passthroughProviders[provider]; passthrough {
+				// Example: dashboard@internal, whoami@docker
+				return "", routerName, kind
+			}
+			if _, logged := unknownProviders.LoadOrStore(provider, true); !logged {
+				logger.Warnf("Unrecognized router provider @%s, routers from it will be skipped in operator mode", provider)
+			}
+			return "", "", ""
 		}
+		targetKind = kind
+		genericProvider = provider != "kubernetes" && provider != "kubernetescrd"
 	}
 
 	parts := strings.Split(routerName, "-")
 
+	if genericProvider {
+		// Format: namespace-name[-hash]
+		// Example: shop-checkout-api@file
+		if len(parts) >= 2 {
+			namespace = parts[0]
+			end := len(parts)
+			if end > 2 && isHexString(parts[end-1]) {
+				end--
+			}
+			targetName = strings.Join(parts[1:end], "-")
+		}
+		return namespace, targetName, targetKind
+	}
+
 	if targetKind == "IngressRoute" {
-		// Format: namespace-resourceName-hash
+		// Format: namespace-resourceName-index for IngressRouteUDP, which has no rule to hash
+		// Example: dns-coredns-udp-0
+		if last := parts[len(parts)-1]; len(parts) >= 3 && isRouteIndex(last) {
+			return parts[0], strings.Join(parts[1:len(parts)-1], "-"), "IngressRouteUDP"
+		}
+
+		// Format: namespace-resourceName-hash, for IngressRoute and IngressRouteTCP
 		// Example: mahfil-dev-mahfil-api-server-ingressroute-http-a457d08d5820f79b3e08
 		if len(parts) >= 2 {
 			namespace = parts[0]
@@ -137,6 +214,36 @@ func parseRouterName(routerName string) (namespace, targetName, targetKind strin
 	return namespace, targetName, targetKind
 }
 
+// providerTargetKind returns the target kind routers of a provider are matched as, and whether the
+// provider is known: one of the Traefik Kubernetes providers or registered with RegisterRouterProvider
+func providerTargetKind(provider string) (string, bool) {
+	switch provider {
+	case "kubernetes":
+		return "Ingress", true
+	case "kubernetescrd":
+		return "IngressRoute", true
+	}
+
+	routerProvidersMutex.RLock()
+	defer routerProvidersMutex.RUnlock()
+	kind, ok := routerProviders[provider]
+	return kind, ok
+}
+
+// isRouteIndex checks if a router name part is the decimal route index Traefik appends to
+// IngressRouteUDP routers, which is shorter than the hashes of other routers
+func isRouteIndex(s string) bool {
+	if s == "" || len(s) >= 12 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // isHexString checks if a string is a hexadecimal string
 func isHexString(s string) bool {
 	matched, _ := regexp.MatchString("^[0-9a-f]{12,}$", s)
@@ -173,6 +280,7 @@ func ApplyOperatorConfigToLog(entry *traefikLogConfig, runtimeConfig *shared.Run
 		if regex != nil && regex.MatchString(entry.RequestPath) {
 			logger.Debugf("Path %s matches ignore pattern for %s",
 				entry.RequestPath, runtimeConfig.Key)
+			countFilteredLine(FilterReasonIgnoredPath)
 			return false
 		}
 	}
@@ -189,6 +297,7 @@ func ApplyOperatorConfigToLog(entry *traefikLogConfig, runtimeConfig *shared.Run
 		if !matched {
 			logger.Debugf("Path %s does not match any whitelist pattern for %s",
 				entry.RequestPath, runtimeConfig.Key)
+			countFilteredLine(FilterReasonNotWhitelisted)
 			return false
 		}
 	}
@@ -219,6 +328,26 @@ func MergePathsWithOperatorConfig(path string, runtimeConfig *shared.RuntimeConf
 	return path
 }
 
+// urlNormalizationFor returns the URL normalization settings of a target, applying its overrides of
+// the global settings. A nil target uses the global settings.
+func urlNormalizationFor(target *shared.RuntimeConfig) URLNormalization {
+	normalization := currentURLNormalization()
+	if target == nil {
+		return normalization
+	}
+	if target.StripTrailingSlash != nil {
+		normalization.StripTrailingSlash = *target.StripTrailingSlash
+	}
+	if target.LowercasePath != nil {
+		normalization.LowercasePath = *target.LowercasePath
+	}
+	if target.KeepQueryParams != nil {
+		normalization.KeepQueryParams = target.KeepQueryParams
+	}
+	normalization.skippedRules = target.DisabledNormalizationRules
+	return normalization
+}
+
 // GetURLPatternsFromConfig returns URL patterns from runtime config
 func GetURLPatternsFromConfig(runtimeConfig *shared.RuntimeConfig) []URLPattern {
 	if runtimeConfig == nil {
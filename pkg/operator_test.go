@@ -1,6 +1,7 @@
 package logprocessing
 
 import (
+	"reflect"
 	"regexp"
 	"testing"
 
@@ -483,6 +484,31 @@ func TestGetURLPatternsFromConfig(t *testing.T) {
 	}
 }
 
+// TestURLNormalizationFor tests that a target's slash and case settings override the global ones
+func TestURLNormalizationFor(t *testing.T) {
+	oldNormalization := urlNormalization
+	defer func() {
+		urlNormalization = oldNormalization
+	}()
+	urlNormalization = URLNormalization{StripTrailingSlash: true, DottedTokenMinParts: 3, DottedTokenMinLength: 12}
+
+	if got := urlNormalizationFor(nil); !reflect.DeepEqual(got, urlNormalization) {
+		t.Errorf("Expected the global settings without a target, got %+v", got)
+	}
+	if got := urlNormalizationFor(&shared.RuntimeConfig{}); !reflect.DeepEqual(got, urlNormalization) {
+		t.Errorf("Expected the global settings without overrides, got %+v", got)
+	}
+
+	disabled, enabled := false, true
+	got := urlNormalizationFor(&shared.RuntimeConfig{StripTrailingSlash: &disabled, LowercasePath: &enabled})
+	if got.StripTrailingSlash || !got.LowercasePath {
+		t.Errorf("Expected the target overrides, got %+v", got)
+	}
+	if got.DottedTokenMinParts != 3 || !urlNormalization.StripTrailingSlash {
+		t.Errorf("Expected the other settings kept and the global settings untouched, got %+v", got)
+	}
+}
+
 // TestMainOperator tests the MainOperator function
 func TestMainOperator(t *testing.T) {
 	// MainOperator primarily logs, so we just verify it doesn't panic
@@ -558,6 +584,120 @@ func TestShouldProcessRouter(t *testing.T) {
 	}
 }
 
+// TestShouldProcessRouterConfigKeyCollision tests that configs for namespace/name pairs whose
+// "-" join is identical don't match each other's routers
+func TestShouldProcessRouterConfigKeyCollision(t *testing.T) {
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+
+	// Ingress "api-web" in namespace "shop"
+	routerName := "websecure-shop-api-web-a457d08d5820f79b3e08@kubernetes"
+
+	colliding := &shared.RuntimeConfig{Key: shared.ConfigKey("shop-api", "web"), TargetKind: "Ingress", Enabled: true}
+	cm := &patternsConfigManager{configs: []*shared.RuntimeConfig{colliding}}
+	operatorConfig = &OperatorModeConfig{enabled: true, configManager: cm}
+
+	if process, _ := ShouldProcessRouter(routerName); process {
+		t.Error("Expected the config of Ingress shop-api/web not to match a router of shop/api-web")
+	}
+
+	matching := &shared.RuntimeConfig{Key: shared.ConfigKey("shop", "api-web"), TargetKind: "Ingress", Enabled: true}
+	cm.configs = append(cm.configs, matching)
+
+	process, config := ShouldProcessRouter(routerName)
+	if !process {
+		t.Fatal("Expected the router to be processed with the config of shop/api-web")
+	}
+	if config != matching {
+		t.Errorf("ShouldProcessRouter() config key = %s, want %s", config.Key, matching.Key)
+	}
+}
+
+// TestParseRouterNameTraefikRouteKinds tests the router names of IngressRouteTCP and
+// IngressRouteUDP targets
+func TestParseRouterNameTraefikRouteKinds(t *testing.T) {
+	tests := []struct {
+		name              string
+		routerName        string
+		expectedNamespace string
+		expectedTarget    string
+		expectedKind      string
+	}{
+		{
+			name:              "IngressRouteTCP router is named like an IngressRoute router",
+			routerName:        "db-postgres-tcp-a457d08d5820f79b3e08@kubernetescrd",
+			expectedNamespace: "db",
+			expectedTarget:    "postgres-tcp",
+			expectedKind:      "IngressRoute",
+		},
+		{
+			name:              "IngressRouteUDP router ends with the route index",
+			routerName:        "dns-coredns-udp-0@kubernetescrd",
+			expectedNamespace: "dns",
+			expectedTarget:    "coredns-udp",
+			expectedKind:      "IngressRouteUDP",
+		},
+		{
+			name:              "IngressRouteUDP router with a later route",
+			routerName:        "dns-coredns-12@kubernetescrd",
+			expectedNamespace: "dns",
+			expectedTarget:    "coredns",
+			expectedKind:      "IngressRouteUDP",
+		},
+		{
+			name:              "numeric hash is not a route index",
+			routerName:        "shop-api-123456789012@kubernetescrd",
+			expectedNamespace: "shop",
+			expectedTarget:    "api",
+			expectedKind:      "IngressRoute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, targetName, targetKind := parseRouterName(tt.routerName)
+
+			if namespace != tt.expectedNamespace {
+				t.Errorf("Expected namespace '%s', got '%s'", tt.expectedNamespace, namespace)
+			}
+			if targetName != tt.expectedTarget {
+				t.Errorf("Expected target '%s', got '%s'", tt.expectedTarget, targetName)
+			}
+			if targetKind != tt.expectedKind {
+				t.Errorf("Expected target kind '%s', got '%s'", tt.expectedKind, targetKind)
+			}
+		})
+	}
+}
+
+// TestShouldProcessRouterTraefikRouteKinds tests that TCP and UDP routers match the configs of
+// IngressRouteTCP and IngressRouteUDP targets
+func TestShouldProcessRouterTraefikRouteKinds(t *testing.T) {
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+
+	cm := &patternsConfigManager{configs: []*shared.RuntimeConfig{
+		{Key: shared.ConfigKey("db", "postgres"), TargetKind: "IngressRouteTCP", Enabled: true},
+		{Key: shared.ConfigKey("dns", "coredns"), TargetKind: "IngressRouteUDP", Enabled: true},
+		{Key: shared.ConfigKey("shop", "web"), TargetKind: "IngressRouteUDP", Enabled: true},
+	}}
+	operatorConfig = &OperatorModeConfig{enabled: true, configManager: cm}
+
+	if process, _ := ShouldProcessRouter("db-postgres-a457d08d5820f79b3e08@kubernetescrd"); !process {
+		t.Error("Expected the TCP router to match the IngressRouteTCP config")
+	}
+	if process, _ := ShouldProcessRouter("dns-coredns-0@kubernetescrd"); !process {
+		t.Error("Expected the UDP router to match the IngressRouteUDP config")
+	}
+	if process, _ := ShouldProcessRouter("shop-web-a457d08d5820f79b3e08@kubernetescrd"); process {
+		t.Error("Expected a hashed router not to match an IngressRouteUDP config")
+	}
+}
+
 // TestOperatorModeConfigStruct tests the OperatorModeConfig struct
 func TestOperatorModeConfigStruct(t *testing.T) {
 	cm := &mockConfigManager{}
@@ -604,3 +744,163 @@ func (m *mockConfigManager) GetAllConfigs() []*shared.RuntimeConfig {
 		},
 	}
 }
+
+// TestParseRouterNameAdditionalProviders tests router names from non-Kubernetes providers
+func TestParseRouterNameAdditionalProviders(t *testing.T) {
+	// Save original state
+	routerProvidersMutex.Lock()
+	oldProviders := routerProviders
+	routerProviders = make(map[string]string)
+	routerProvidersMutex.Unlock()
+	defer func() {
+		routerProvidersMutex.Lock()
+		routerProviders = oldProviders
+		routerProvidersMutex.Unlock()
+	}()
+
+	RegisterRouterProvider("file", "IngressRoute")
+	RegisterRouterProvider("docker", "Ingress")
+
+	tests := []struct {
+		name              string
+		routerName        string
+		expectedNamespace string
+		expectedTarget    string
+		expectedKind      string
+	}{
+		{
+			name:              "file provider router",
+			routerName:        "shop-checkout-api@file",
+			expectedNamespace: "shop",
+			expectedTarget:    "checkout-api",
+			expectedKind:      "IngressRoute",
+		},
+		{
+			name:              "docker provider router with hash",
+			routerName:        "shop-web-a457d08d5820f79b3e08@docker",
+			expectedNamespace: "shop",
+			expectedTarget:    "web",
+			expectedKind:      "Ingress",
+		},
+		{
+			name:              "unknown provider is skipped",
+			routerName:        "shop-web@consulcatalog",
+			expectedNamespace: "",
+			expectedTarget:    "",
+			expectedKind:      "",
+		},
+		{
+			name:              "unknown provider logged once and still skipped",
+			routerName:        "shop-api@consulcatalog",
+			expectedNamespace: "",
+			expectedTarget:    "",
+			expectedKind:      "",
+		},
+		{
+			name:              "kubernetescrd provider is unchanged",
+			routerName:        "shop-api-a457d08d5820f79b3e08@kubernetescrd",
+			expectedNamespace: "shop",
+			expectedTarget:    "api",
+			expectedKind:      "IngressRoute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, targetName, targetKind := parseRouterName(tt.routerName)
+
+			if namespace != tt.expectedNamespace {
+				t.Errorf("Expected namespace '%s', got '%s'", tt.expectedNamespace, namespace)
+			}
+			if targetName != tt.expectedTarget {
+				t.Errorf("Expected target '%s', got '%s'", tt.expectedTarget, targetName)
+			}
+			if targetKind != tt.expectedKind {
+				t.Errorf("Expected target kind '%s', got '%s'", tt.expectedKind, targetKind)
+			}
+		})
+	}
+
+	if _, logged := unknownProviders.Load("consulcatalog"); !logged {
+		t.Error("Expected unknown provider to be remembered after logging")
+	}
+}
+
+// TestParseRouterProviders tests parsing of the provider=kind flag value
+func TestParseRouterProviders(t *testing.T) {
+	providers, err := ParseRouterProviders("file=IngressRoute, docker=Ingress,")
+	if err != nil {
+		t.Fatalf("ParseRouterProviders() error = %v", err)
+	}
+	if len(providers) != 2 || providers["file"] != "IngressRoute" || providers["docker"] != "Ingress" {
+		t.Errorf("Unexpected providers: %v", providers)
+	}
+
+	if _, err := ParseRouterProviders("file"); err == nil {
+		t.Error("Expected error for mapping without kind")
+	}
+}
+
+// TestParseRouterNamePassthroughProviders tests that routers of the file, docker and internal
+// providers are classified by their full name unless their provider is registered
+func TestParseRouterNamePassthroughProviders(t *testing.T) {
+	routerProvidersMutex.Lock()
+	oldProviders := routerProviders
+	routerProviders = make(map[string]string)
+	routerProvidersMutex.Unlock()
+	defer func() {
+		routerProvidersMutex.Lock()
+		routerProviders = oldProviders
+		routerProvidersMutex.Unlock()
+	}()
+
+	tests := []struct {
+		routerName     string
+		expectedTarget string
+		expectedKind   string
+	}{
+		{routerName: "dashboard@internal", expectedTarget: "dashboard", expectedKind: "TraefikInternal"},
+		{routerName: "ping@internal", expectedTarget: "ping", expectedKind: "TraefikInternal"},
+		{routerName: "prometheus@internal", expectedTarget: "prometheus", expectedKind: "TraefikInternal"},
+		{routerName: "web-to-websecure@internal", expectedTarget: "web-to-websecure", expectedKind: "TraefikInternal"},
+		{routerName: "shop-checkout-api@file", expectedTarget: "shop-checkout-api", expectedKind: "File"},
+		{routerName: "whoami-compose@docker", expectedTarget: "whoami-compose", expectedKind: "Docker"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.routerName, func(t *testing.T) {
+			namespace, targetName, targetKind := parseRouterName(tt.routerName)
+			if namespace != "" || targetName != tt.expectedTarget || targetKind != tt.expectedKind {
+				t.Errorf("parseRouterName(%q) = (%q, %q, %q), expected (\"\", %q, %q)", tt.routerName,
+					namespace, targetName, targetKind, tt.expectedTarget, tt.expectedKind)
+			}
+		})
+	}
+
+	labels := GetRouterLabels("dashboard@internal")
+	if labels["ingress"] != "dashboard" || labels["target_kind"] != "TraefikInternal" {
+		t.Errorf("Expected internal router labels, got %v", labels)
+	}
+
+	// A registered provider keeps matching routers with the namespace-name convention
+	RegisterRouterProvider("file", "IngressRoute")
+	namespace, targetName, targetKind := parseRouterName("shop-checkout-api@file")
+	if namespace != "shop" || targetName != "checkout-api" || targetKind != "IngressRoute" {
+		t.Errorf("Expected the registered file provider to parse (shop, checkout-api, IngressRoute), got (%q, %q, %q)",
+			namespace, targetName, targetKind)
+	}
+}
+
+// TestShouldProcessRouterSkipsPassthroughProviders tests that routers without a namespace match no config
+func TestShouldProcessRouterSkipsPassthroughProviders(t *testing.T) {
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+
+	// The mock config manager has a config for every key
+	operatorConfig = &OperatorModeConfig{enabled: true, configManager: &mockConfigManager{}}
+
+	if ok, config := ShouldProcessRouter("dashboard@internal"); ok || config != nil {
+		t.Errorf("Expected dashboard@internal to be skipped in operator mode, got %v, %v", ok, config)
+	}
+}
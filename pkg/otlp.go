@@ -0,0 +1,166 @@
+package logprocessing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	// DefaultOTLPInterval is how often the request duration histogram is exported over OTLP
+	DefaultOTLPInterval = 30 * time.Second
+	// DefaultOTLPServiceName is the service.name resource attribute of the exported metrics
+	DefaultOTLPServiceName = "traefik-officer"
+
+	// OTLPProtocolHTTP exports over OTLP/HTTP with protobuf payloads, to the /v1/metrics path
+	OTLPProtocolHTTP = "http/protobuf"
+	// OTLPProtocolGRPC exports over OTLP/gRPC
+	OTLPProtocolGRPC = "grpc"
+
+	// otlpDurationMetric is the name of the exported request duration histogram
+	otlpDurationMetric = "http.server.request.duration"
+	// otlpScopeName is the instrumentation scope of the exported metrics
+	otlpScopeName = "traefik-officer"
+	// otlpExportTimeout bounds each export, including the last one on shutdown
+	otlpExportTimeout = 10 * time.Second
+)
+
+// OTLPConfig configures exporting the request durations to an OpenTelemetry collector over OTLP,
+// alongside the Prometheus metrics
+type OTLPConfig struct {
+	// Endpoint is the URL of the collector, e.g. http://otel-collector:4318 for OTLP/HTTP, whose
+	// /v1/metrics path metrics are posted to, or http://otel-collector:4317 for OTLP/gRPC. An http
+	// scheme disables TLS.
+	Endpoint string
+	// Protocol is OTLPProtocolHTTP (default) or OTLPProtocolGRPC
+	Protocol string
+	// Interval between exports, DefaultOTLPInterval when not positive
+	Interval time.Duration
+	// ServiceName is the service.name resource attribute, DefaultOTLPServiceName when empty
+	ServiceName string
+}
+
+// otlpInstruments are the instruments fed by recordMetrics while an OTLP export runs
+type otlpInstruments struct {
+	duration metric.Float64Histogram
+}
+
+// otlpInstrumentsActive holds the instruments of the running OTLP export, nil when none runs
+var otlpInstrumentsActive atomic.Pointer[otlpInstruments]
+
+// recordOTLP adds an entry to the OTLP request duration histogram when an export is running
+func recordOTLP(entry *traefikLogConfig) {
+	instruments := otlpInstrumentsActive.Load()
+	if instruments == nil {
+		return
+	}
+	namespace, ingress := endpointLabels(entry.RouterName)
+	instruments.duration.Record(context.Background(), entry.Duration/1000.0, metric.WithAttributes(
+		attribute.String("namespace", namespace),
+		attribute.String("ingress", ingress),
+		attribute.String("status", strconv.Itoa(entry.OriginStatus)),
+	))
+}
+
+// otlpHTTPMetricsURL returns the /v1/metrics URL of an OTLP/HTTP endpoint
+func otlpHTTPMetricsURL(endpoint string) string {
+	metricsURL := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(metricsURL, "/v1/metrics") {
+		metricsURL += "/v1/metrics"
+	}
+	return metricsURL
+}
+
+// newOTLPExporter returns the exporter of the configured protocol
+func newOTLPExporter(ctx context.Context, config OTLPConfig) (sdkmetric.Exporter, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q, expected an http or https URL", config.Endpoint)
+	}
+
+	switch config.Protocol {
+	case "", OTLPProtocolHTTP:
+		return otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpointURL(otlpHTTPMetricsURL(config.Endpoint)),
+			otlpmetrichttp.WithTimeout(otlpExportTimeout))
+	case OTLPProtocolGRPC:
+		return otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpointURL(config.Endpoint),
+			otlpmetricgrpc.WithTimeout(otlpExportTimeout))
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q, expected %s or %s", config.Protocol, OTLPProtocolHTTP, OTLPProtocolGRPC)
+	}
+}
+
+// StartOTLPExporter records the request durations of the processed entries and exports them to
+// the collector every interval until ctx is cancelled, when they are exported a last time. The
+// returned channel is closed once that export is done.
+func StartOTLPExporter(ctx context.Context, config OTLPConfig) (<-chan struct{}, error) {
+	exporter, err := newOTLPExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultOTLPInterval
+	}
+
+	// Failed exports are reported to the global OpenTelemetry error handler
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warnf("Failed to export metrics over OTLP: %v", err)
+	}))
+	logger.Infof("Exporting request durations over OTLP to %s", config.Endpoint)
+	reader := sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval), sdkmetric.WithTimeout(otlpExportTimeout))
+	return startOTLPExport(ctx, config, reader)
+}
+
+// startOTLPExport feeds a request duration histogram collected by reader from recordMetrics
+func startOTLPExport(ctx context.Context, config OTLPConfig, reader sdkmetric.Reader) (<-chan struct{}, error) {
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultOTLPServiceName
+	}
+
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	duration, err := provider.Meter(otlpScopeName).Float64Histogram(otlpDurationMetric,
+		metric.WithDescription("Duration of HTTP requests derived from Traefik access logs"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(latencyHistogramBuckets...))
+	if err != nil {
+		_ = provider.Shutdown(context.Background())
+		return nil, fmt.Errorf("failed to create the OTLP request duration histogram: %w", err)
+	}
+	instruments := &otlpInstruments{duration: duration}
+	otlpInstrumentsActive.Store(instruments)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		otlpInstrumentsActive.CompareAndSwap(instruments, nil)
+
+		// Shutting down collects and exports the histogram a last time
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), otlpExportTimeout)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			logger.Warnf("Failed to export metrics over OTLP on shutdown: %v", err)
+		}
+	}()
+	return done, nil
+}
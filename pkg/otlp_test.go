@@ -0,0 +1,216 @@
+package logprocessing
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpDataPoints returns the data points of the collected request duration histogram keyed by
+// namespace/ingress/status
+func otlpDataPoints(t *testing.T, metrics metricdata.ResourceMetrics) map[string]metricdata.HistogramDataPoint[float64] {
+	t.Helper()
+	if len(metrics.ScopeMetrics) != 1 || metrics.ScopeMetrics[0].Scope.Name != otlpScopeName {
+		t.Fatalf("Unexpected scope metrics %+v", metrics.ScopeMetrics)
+	}
+	collected := metrics.ScopeMetrics[0].Metrics
+	if len(collected) != 1 || collected[0].Name != otlpDurationMetric || collected[0].Unit != "s" {
+		t.Fatalf("Unexpected metrics %+v", collected)
+	}
+	histogram, ok := collected[0].Data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("Expected a float64 histogram, got %T", collected[0].Data)
+	}
+	if histogram.Temporality != metricdata.CumulativeTemporality {
+		t.Errorf("Expected cumulative temporality, got %v", histogram.Temporality)
+	}
+
+	points := make(map[string]metricdata.HistogramDataPoint[float64])
+	for _, point := range histogram.DataPoints {
+		value := func(key attribute.Key) string {
+			v, _ := point.Attributes.Value(key)
+			return v.AsString()
+		}
+		points[value("namespace")+"/"+value("ingress")+"/"+value("status")] = point
+	}
+	return points
+}
+
+// TestOTLPExport tests that the request durations are recorded as histograms per namespace, ingress and status
+func TestOTLPExport(t *testing.T) {
+	resetEndpointStats(t)
+	reader := sdkmetric.NewManualReader()
+	ctx, cancel := context.WithCancel(context.Background())
+	done, err := startOTLPExport(ctx, OTLPConfig{}, reader)
+	if err != nil {
+		t.Fatalf("startOTLPExport() error = %v", err)
+	}
+
+	router := "websecure-shop-orders-a457d08d5820f79b3e08@kubernetes"
+	namespace, ingress := endpointLabels(router)
+	for _, entry := range []traefikLogConfig{
+		{RouterName: router, RequestMethod: "GET", RequestPath: "/orders", OriginStatus: 200, Duration: 3},
+		{RouterName: router, RequestMethod: "GET", RequestPath: "/orders", OriginStatus: 200, Duration: 2000},
+		{RouterName: router, RequestMethod: "POST", RequestPath: "/orders", OriginStatus: 500, Duration: 100},
+	} {
+		recordMetrics(&entry, nil, nil, nil)
+	}
+
+	var collected metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &collected); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if serviceName, _ := collected.Resource.Set().Value("service.name"); serviceName.AsString() != DefaultOTLPServiceName {
+		t.Errorf("Unexpected resource %v", collected.Resource)
+	}
+
+	points := otlpDataPoints(t, collected)
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 data points, got %d", len(points))
+	}
+	ok := points[namespace+"/"+ingress+"/200"]
+	if ok.Count != 2 || math.Abs(ok.Sum-2.003) > 1e-9 {
+		t.Errorf("Expected 2 successful requests taking 2.003s, got %d taking %v", ok.Count, ok.Sum)
+	}
+	if !reflect.DeepEqual(ok.Bounds, latencyHistogramBuckets) {
+		t.Errorf("Expected the latency histogram buckets as bounds, got %v", ok.Bounds)
+	}
+	if ok.BucketCounts[sort.SearchFloat64s(ok.Bounds, 0.003)] != 1 ||
+		ok.BucketCounts[sort.SearchFloat64s(ok.Bounds, 2)] != 1 {
+		t.Errorf("Unexpected bucket counts %v for bounds %v", ok.BucketCounts, ok.Bounds)
+	}
+	if failed := points[namespace+"/"+ingress+"/500"]; failed.Count != 1 {
+		t.Errorf("Expected 1 failed request, got %d", failed.Count)
+	}
+
+	cancel()
+	<-done
+	if otlpInstrumentsActive.Load() != nil {
+		t.Error("Expected the instruments to be detached on shutdown")
+	}
+}
+
+// otlpMetricsCollector is an OTLP/gRPC collector keeping the received export requests
+type otlpMetricsCollector struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	requests []*colmetricpb.ExportMetricsServiceRequest
+}
+
+func (c *otlpMetricsCollector) Export(_ context.Context, request *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	c.add(request)
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *otlpMetricsCollector) add(request *colmetricpb.ExportMetricsServiceRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, request)
+}
+
+// count returns the request count of the last exported data point
+func (c *otlpMetricsCollector) count() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) == 0 {
+		return 0
+	}
+	points := c.requests[len(c.requests)-1].GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()[0].GetHistogram().GetDataPoints()
+	if len(points) != 1 {
+		return 0
+	}
+	return points[0].GetCount()
+}
+
+// TestStartOTLPExporter tests that the histogram is exported on shutdown over OTLP/HTTP and OTLP/gRPC
+func TestStartOTLPExporter(t *testing.T) {
+	router := "websecure-shop-orders-a457d08d5820f79b3e08@kubernetes"
+
+	tests := []struct {
+		name     string
+		protocol string
+		serve    func(t *testing.T, collector *otlpMetricsCollector) string
+	}{
+		{
+			name:     "http",
+			protocol: OTLPProtocolHTTP,
+			serve: func(t *testing.T, collector *otlpMetricsCollector) string {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+						t.Errorf("Expected a protobuf post to /v1/metrics, got %q to %q", r.Header.Get("Content-Type"), r.URL.Path)
+					}
+					body, _ := io.ReadAll(r.Body)
+					request := &colmetricpb.ExportMetricsServiceRequest{}
+					if err := proto.Unmarshal(body, request); err != nil {
+						t.Errorf("Failed to decode the export request: %v", err)
+					}
+					collector.add(request)
+				}))
+				t.Cleanup(server.Close)
+				return server.URL + "/"
+			},
+		},
+		{
+			name:     "grpc",
+			protocol: OTLPProtocolGRPC,
+			serve: func(t *testing.T, collector *otlpMetricsCollector) string {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatalf("Failed to listen: %v", err)
+				}
+				server := grpc.NewServer()
+				colmetricpb.RegisterMetricsServiceServer(server, collector)
+				go func() { _ = server.Serve(listener) }()
+				t.Cleanup(server.Stop)
+				return "http://" + listener.Addr().String()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetEndpointStats(t)
+			collector := &otlpMetricsCollector{}
+			endpoint := tt.serve(t, collector)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done, err := StartOTLPExporter(ctx, OTLPConfig{Endpoint: endpoint, Protocol: tt.protocol, Interval: time.Hour})
+			if err != nil {
+				t.Fatalf("StartOTLPExporter() error = %v", err)
+			}
+			entry := traefikLogConfig{RouterName: router, RequestMethod: "GET", RequestPath: "/orders", OriginStatus: 200, Duration: 5}
+			recordMetrics(&entry, nil, nil, nil)
+
+			cancel()
+			<-done
+			if got := collector.count(); got != 1 {
+				t.Errorf("Expected 1 request exported on shutdown, got %d", got)
+			}
+		})
+	}
+
+	for _, config := range []OTLPConfig{
+		{Endpoint: "otel-collector:4318"},
+		{Endpoint: "http://otel-collector:4318", Protocol: "thrift"},
+	} {
+		if _, err := StartOTLPExporter(context.Background(), config); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
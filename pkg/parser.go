@@ -0,0 +1,51 @@
+package logprocessing
+
+import (
+	"fmt"
+)
+
+// Access log formats selected with -log-format
+const (
+	// LogFormatCLF parses Traefik's common log format, or the AccessLogFormat template when set
+	LogFormatCLF = "clf"
+	// LogFormatJSON parses Traefik's JSON access logs
+	LogFormatJSON = "json"
+	// LogFormatLogfmt parses access logs written as logfmt key=value pairs, e.g. by a Loki pipeline
+	LogFormatLogfmt = "logfmt"
+)
+
+// parserFor returns the parser of a log format; an empty format is LogFormatCLF
+func parserFor(logFormat string) (parser, error) {
+	switch logFormat {
+	case "", LogFormatCLF:
+		return parseLine, nil
+	case LogFormatJSON:
+		return parseJSON, nil
+	case LogFormatLogfmt:
+		return parseLogfmt, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected %s, %s or %s",
+			logFormat, LogFormatCLF, LogFormatJSON, LogFormatLogfmt)
+	}
+}
+
+// ResolveLogFormat returns the access log format to parse. The deprecated jsonLogs flag is an alias
+// for LogFormatJSON. The LogFormat of the config applies when logFormat is left at LogFormatCLF.
+func ResolveLogFormat(logFormat string, jsonLogs bool, configFormat string) (string, error) {
+	if logFormat == "" {
+		logFormat = LogFormatCLF
+	}
+	if jsonLogs {
+		if logFormat != LogFormatCLF && logFormat != LogFormatJSON {
+			return "", fmt.Errorf("-json-logs conflicts with -log-format=%s", logFormat)
+		}
+		return LogFormatJSON, nil
+	}
+	if logFormat == LogFormatCLF && configFormat != "" {
+		logFormat = configFormat
+	}
+	if _, err := parserFor(logFormat); err != nil {
+		return "", err
+	}
+	return logFormat, nil
+}
@@ -0,0 +1,72 @@
+package logprocessing
+
+import (
+	"testing"
+)
+
+// TestParserFor tests that each log format selects the parser of its lines
+func TestParserFor(t *testing.T) {
+	lines := map[string]string{
+		LogFormatCLF:    benchmarkAccessLogLine,
+		LogFormatJSON:   `{"RouterName":"shop-api@kubernetes","RequestMethod":"GET","RequestPath":"/api/users/42","OriginStatus":200,"Duration":25000000}`,
+		LogFormatLogfmt: logfmtAccessLogLine,
+	}
+
+	for _, format := range []string{"", LogFormatCLF, LogFormatJSON, LogFormatLogfmt} {
+		t.Run(format, func(t *testing.T) {
+			parse, err := parserFor(format)
+			if err != nil {
+				t.Fatalf("parserFor(%q) error = %v", format, err)
+			}
+			for lineFormat, line := range lines {
+				_, err := parse(line)
+				want := lineFormat == format || (format == "" && lineFormat == LogFormatCLF)
+				if want && err != nil {
+					t.Errorf("Expected the %q parser to parse a %s line, got %v", format, lineFormat, err)
+				} else if !want && err == nil {
+					t.Errorf("Expected the %q parser to reject a %s line", format, lineFormat)
+				}
+			}
+		})
+	}
+
+	if _, err := parserFor("xml"); err == nil {
+		t.Error("Expected an error for an unknown log format")
+	}
+}
+
+// TestResolveLogFormat tests the precedence of -log-format, the deprecated -json-logs and the config
+func TestResolveLogFormat(t *testing.T) {
+	tests := []struct {
+		name         string
+		logFormat    string
+		jsonLogs     bool
+		configFormat string
+		want         string
+		wantErr      bool
+	}{
+		{name: "default", logFormat: LogFormatCLF, want: LogFormatCLF},
+		{name: "empty", want: LogFormatCLF},
+		{name: "json", logFormat: LogFormatJSON, want: LogFormatJSON},
+		{name: "logfmt", logFormat: LogFormatLogfmt, want: LogFormatLogfmt},
+		{name: "deprecated json-logs", logFormat: LogFormatCLF, jsonLogs: true, want: LogFormatJSON},
+		{name: "json-logs with log-format json", logFormat: LogFormatJSON, jsonLogs: true, want: LogFormatJSON},
+		{name: "json-logs conflicts with logfmt", logFormat: LogFormatLogfmt, jsonLogs: true, wantErr: true},
+		{name: "config applies to default", logFormat: LogFormatCLF, configFormat: LogFormatLogfmt, want: LogFormatLogfmt},
+		{name: "flag overrides config", logFormat: LogFormatJSON, configFormat: LogFormatLogfmt, want: LogFormatJSON},
+		{name: "json-logs overrides config", jsonLogs: true, configFormat: LogFormatLogfmt, want: LogFormatJSON},
+		{name: "unknown", logFormat: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveLogFormat(tt.logFormat, tt.jsonLogs, tt.configFormat)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveLogFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveLogFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
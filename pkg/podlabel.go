@@ -0,0 +1,31 @@
+package logprocessing
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// podRequests is built by buildMetrics
+var podRequests *prometheus.CounterVec
+
+// podLabelEnabled counts the requests per Traefik pod, see SetPodLabel
+var podLabelEnabled atomic.Bool
+
+// SetPodLabel counts the requests per Traefik pod that logged them on pod_requests_total, so
+// unbalanced replicas stand out. The counter has the labels of requests_total plus pod; it's a
+// separate metric because the label names of a metric can't change once registered. It multiplies
+// the series by the number of Traefik pods, so it's off by default.
+func SetPodLabel(enabled bool) {
+	podLabelEnabled.Store(enabled)
+}
+
+// recordPodRequest counts the request on pod_requests_total when enabled and the line was read
+// from a pod
+func recordPodRequest(entry *traefikLogConfig) {
+	if !podLabelEnabled.Load() || entry.Pod == "" {
+		return
+	}
+	podRequests.WithLabelValues(entry.RequestMethod, strconv.Itoa(entry.OriginStatus), entry.RouterName, entry.Pod).Inc()
+}
@@ -0,0 +1,46 @@
+package logprocessing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestPodLabel tests that the pod of a prefixed Kubernetes line is counted on pod_requests_total
+func TestPodLabel(t *testing.T) {
+	resetEndpointStats(t)
+	podRequests.Reset()
+	SetPodLabel(true)
+	defer SetPodLabel(false)
+
+	lines := make(chan LogLine, 4)
+	lines <- LogLine{Text: "[traefik-a] " + benchmarkAccessLogLine, Pod: "traefik-a", Namespace: "ingress"}
+	lines <- LogLine{Text: "[traefik-a] " + benchmarkAccessLogLine, Pod: "traefik-a", Namespace: "ingress"}
+	lines <- LogLine{Text: "[traefik-b] " + benchmarkAccessLogLine, Pod: "traefik-b", Namespace: "ingress"}
+	// Lines not read from a pod aren't counted
+	lines <- LogLine{Text: benchmarkAccessLogLine}
+	close(lines)
+
+	useK8s := true
+	config := TraefikOfficerConfig{AllowedServices: []TraefikService{{Name: "shop-api"}}}
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, config, &useK8s, nil, LogFormatCLF)
+
+	for pod, expected := range map[string]float64{"traefik-a": 2, "traefik-b": 1} {
+		if got := testutil.ToFloat64(podRequests.WithLabelValues("GET", "200", "shop-api@kubernetes", pod)); got != expected {
+			t.Errorf("Expected %v requests served by %s, got %v", expected, pod, got)
+		}
+	}
+	if got := testutil.CollectAndCount(podRequests); got != 2 {
+		t.Errorf("Expected a series per pod, got %d", got)
+	}
+}
+
+// TestPodLabelDisabled tests that requests aren't counted per pod by default
+func TestPodLabelDisabled(t *testing.T) {
+	podRequests.Reset()
+	recordPodRequest(&traefikLogConfig{RouterName: "shop-api@kubernetes", RequestMethod: "GET", OriginStatus: 200, Pod: "traefik-a"})
+	if got := testutil.CollectAndCount(podRequests); got != 0 {
+		t.Errorf("Expected no series by default, got %d", got)
+	}
+}
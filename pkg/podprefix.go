@@ -0,0 +1,19 @@
+package logprocessing
+
+import (
+	"regexp"
+)
+
+// podNamePrefixRegex matches the [pod-name] prefix the Kubernetes log source adds to each line. Pod
+// names are DNS subdomains, so a bracketed common log format timestamp never matches.
+var podNamePrefixRegex = regexp.MustCompile(`^\[([a-z0-9][-a-z0-9.]*)\]\s+`)
+
+// splitPodPrefix returns the pod name and the log text of a line with a [pod-name] prefix, and
+// false for lines without one
+func splitPodPrefix(line string) (pod, text string, ok bool) {
+	m := podNamePrefixRegex.FindStringSubmatchIndex(line)
+	if m == nil {
+		return "", line, false
+	}
+	return line[m[2]:m[3]], line[m[1]:], true
+}
@@ -0,0 +1,86 @@
+package logprocessing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestSplitPodPrefix tests detecting the [pod-name] prefix of Kubernetes lines
+func TestSplitPodPrefix(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         string
+		expectedPod  string
+		expectedText string
+		expectedOK   bool
+	}{
+		{name: "prefixed line", line: "[traefik-7d9f-abc12] 10.0.0.1 - - x", expectedPod: "traefik-7d9f-abc12", expectedText: "10.0.0.1 - - x", expectedOK: true},
+		{name: "prefixed JSON", line: `[traefik-0]  {"RouterName":"x"}`, expectedPod: "traefik-0", expectedText: `{"RouterName":"x"}`, expectedOK: true},
+		{name: "no prefix", line: "10.0.0.1 - - x", expectedText: "10.0.0.1 - - x"},
+		{name: "leading timestamp", line: "[01/Jan/2024:12:00:00 +0000] GET /", expectedText: "[01/Jan/2024:12:00:00 +0000] GET /"},
+		{name: "no space after prefix", line: "[traefik-0]10.0.0.1", expectedText: "[traefik-0]10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod, text, ok := splitPodPrefix(tt.line)
+			if pod != tt.expectedPod || text != tt.expectedText || ok != tt.expectedOK {
+				t.Errorf("splitPodPrefix() = %q, %q, %v, want %q, %q, %v", pod, text, ok, tt.expectedPod, tt.expectedText, tt.expectedOK)
+			}
+		})
+	}
+}
+
+// TestParsePodPrefixedLine tests that lines parse the same with and without the pod prefix
+func TestParsePodPrefixedLine(t *testing.T) {
+	lines := map[string]string{
+		LogFormatCLF:    benchmarkAccessLogLine,
+		LogFormatJSON:   `{"ClientHost":"10.0.0.1","RouterName":"shop-api@kubernetes","RequestMethod":"GET","RequestPath":"/api","OriginStatus":200,"Duration":1000000}`,
+		LogFormatLogfmt: `ClientHost=10.0.0.1 RouterName=shop-api@kubernetes RequestMethod=GET RequestPath=/api OriginStatus=200`,
+	}
+	for format, line := range lines {
+		t.Run(format, func(t *testing.T) {
+			parse, err := parserFor(format)
+			if err != nil {
+				t.Fatalf("parserFor() error = %v", err)
+			}
+			expected, err := parse(line)
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+
+			pod, text, ok := splitPodPrefix("[traefik-a] " + line)
+			if !ok || pod != "traefik-a" {
+				t.Fatalf("Expected the pod prefix to be detected, got %q", pod)
+			}
+			got, err := parse(text)
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if got != expected {
+				t.Errorf("Expected %+v, got %+v", expected, got)
+			}
+		})
+	}
+}
+
+// TestProcessLogsPodPrefix tests that ProcessLogs records the pod of prefixed lines it strips
+func TestProcessLogsPodPrefix(t *testing.T) {
+	resetEndpointStats(t)
+	podRequests.Reset()
+	SetPodLabel(true)
+	defer SetPodLabel(false)
+
+	lines := make(chan LogLine, 1)
+	lines <- LogLine{Text: `[traefik-a] {"RouterName":"shop-api@kubernetes","RequestMethod":"GET","RequestPath":"/api","OriginStatus":200,"Duration":1000000}`}
+	close(lines)
+
+	useK8s := true
+	config := TraefikOfficerConfig{AllowedServices: []TraefikService{{Name: "shop-api"}}}
+	ProcessLogs(context.Background(), &mockLogSource{lines: lines}, config, &useK8s, nil, LogFormatJSON)
+
+	if got := testutil.ToFloat64(podRequests.WithLabelValues("GET", "200", "shop-api@kubernetes", "traefik-a")); got != 1 {
+		t.Errorf("Expected the prefixed JSON line to be counted for its pod, got %v", got)
+	}
+}
@@ -0,0 +1,58 @@
+package logprocessing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// registerPprof serves the runtime profiles under /debug/pprof/ on mux, restricted like the other
+// debug endpoints. Importing net/http/pprof also registers them on http.DefaultServeMux, which is
+// never served.
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", adminGuard(pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", adminGuard(pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", adminGuard(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", adminGuard(pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", adminGuard(pprof.Trace))
+}
+
+// ServePprof starts a server for the runtime profiles alone on addr, e.g. 127.0.0.1:6060, and
+// returns it once it is listening. It is shut down when ctx is cancelled.
+func ServePprof(ctx context.Context, addr string) (*http.Server, error) {
+	if addr == "" {
+		return nil, errors.New("pprof address cannot be empty")
+	}
+
+	mux := http.NewServeMux()
+	registerPprof(mux)
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start pprof server: %w", err)
+	}
+	server := &http.Server{Addr: listener.Addr().String(), Handler: mux}
+	logger.Infof("Serving pprof on %s/debug/pprof/", server.Addr)
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("pprof server error: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("Error shutting down pprof server: %v", err)
+		}
+	}()
+
+	return server, nil
+}
@@ -0,0 +1,60 @@
+package logprocessing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestServePromPprof tests that the runtime profiles are only served on the metrics port when enabled
+func TestServePromPprof(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, enabled := range []bool{false, true} {
+		server, err := ServeProm(ctx, "0", MetricsServerConfig{EnablePprof: enabled})
+		if err != nil {
+			t.Fatalf("ServeProm() error = %v", err)
+		}
+		resp, err := http.Get(localURL("http", server, "/debug/pprof/"))
+		if err != nil {
+			t.Fatalf("GET /debug/pprof/ failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if enabled && (resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "goroutine")) {
+			t.Errorf("Expected the profile index, got %d", resp.StatusCode)
+		}
+		if !enabled && resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404 with pprof disabled, got %d", resp.StatusCode)
+		}
+	}
+}
+
+// TestServePprof tests serving the runtime profiles on their own address
+func TestServePprof(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := ServePprof(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ServePprof() error = %v", err)
+	}
+	resp, err := http.Get("http://" + server.Addr + "/debug/pprof/heap?debug=1")
+	if err != nil {
+		t.Fatalf("GET /debug/pprof/heap failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+	if resp, err := http.Get("http://" + server.Addr + "/metrics"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected only the profiles to be served, got %d for /metrics", resp.StatusCode)
+		}
+	}
+}
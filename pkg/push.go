@@ -0,0 +1,83 @@
+package logprocessing
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	logger "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultPushInterval is how often metrics are pushed to the Pushgateway
+	DefaultPushInterval = 30 * time.Second
+	// DefaultPushJob is the job label metrics are pushed with
+	DefaultPushJob = "traefik_officer"
+
+	// finalPushTimeout bounds the last push on shutdown
+	finalPushTimeout = 10 * time.Second
+)
+
+// PushConfig configures pushing the metrics to a Prometheus Pushgateway, for environments that
+// can't scrape the processor
+type PushConfig struct {
+	// URL of the Pushgateway, e.g. http://pushgateway:9091
+	URL string
+	// Interval between pushes, DefaultPushInterval when not positive
+	Interval time.Duration
+	// Job and Instance group the pushed metrics on the Pushgateway
+	Job      string
+	Instance string
+}
+
+// newPusher returns the pusher of the default registry's metrics for the config
+func newPusher(config PushConfig) *push.Pusher {
+	job := config.Job
+	if job == "" {
+		job = DefaultPushJob
+	}
+	pusher := push.New(config.URL, job).Gatherer(prometheus.DefaultGatherer)
+	if config.Instance != "" {
+		pusher = pusher.Grouping("instance", config.Instance)
+	}
+	return pusher
+}
+
+// StartPushgateway pushes the metrics to the Pushgateway every interval, replacing the metrics of
+// its job and instance, until ctx is cancelled. The metrics are pushed a last time on cancellation
+// so the final counts aren't lost; the returned channel is closed once that push is done. The
+// scrape endpoint keeps working alongside.
+func StartPushgateway(ctx context.Context, config PushConfig) <-chan struct{} {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultPushInterval
+	}
+	pusher := newPusher(config)
+	logger.Infof("Pushing metrics to %s every %s", config.URL, interval)
+
+	pushMetrics := func(ctx context.Context) {
+		if err := pusher.PushContext(ctx); err != nil {
+			logger.Warnf("Failed to push metrics to %s: %v", config.URL, err)
+		}
+	}
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				pushMetrics(ctx)
+			case <-ctx.Done():
+				final, cancel := context.WithTimeout(context.Background(), finalPushTimeout)
+				pushMetrics(final)
+				cancel()
+				return
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,105 @@
+package logprocessing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStartPushgateway tests that the metrics are pushed periodically and on shutdown, grouped by job and instance
+func TestStartPushgateway(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		pushes []string
+		bodies [][]byte
+	)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		pushes = append(pushes, r.Method+" "+r.URL.Path)
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := StartPushgateway(ctx, PushConfig{URL: gateway.URL, Interval: 20 * time.Millisecond, Instance: "edge-1"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		count := len(pushes)
+		mu.Unlock()
+		if count >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected periodic pushes, got %d", count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	before := len(pushes)
+	mu.Unlock()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Pusher did not stop after the context was cancelled")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) <= before {
+		t.Error("Expected a final push on shutdown")
+	}
+	for i, push := range pushes {
+		if push != "PUT /metrics/job/traefik_officer/instance/edge-1" {
+			t.Errorf("Unexpected push %q", push)
+		}
+		if !bytes.Contains(bodies[i], []byte(metricName("active_pod_streams"))) {
+			t.Errorf("Expected push %d to carry the processor metrics", i)
+		}
+	}
+}
+
+// TestStartPushgatewayUnreachable tests that failed pushes don't stop the pusher
+func TestStartPushgatewayUnreachable(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer gateway.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := StartPushgateway(ctx, PushConfig{URL: gateway.URL, Interval: 20 * time.Millisecond})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		count := attempts
+		mu.Unlock()
+		if count >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the pusher to keep retrying, got %d attempts", count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
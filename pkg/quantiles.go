@@ -0,0 +1,61 @@
+package logprocessing
+
+import (
+	"strconv"
+
+	"github.com/beorn7/perks/quantile"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// latencyObjectives maps each published latency quantile to its allowed rank error, like the
+// objectives of a Prometheus summary
+var latencyObjectives = map[float64]float64{
+	0.5:  0.05,
+	0.9:  0.01,
+	0.95: 0.005,
+	0.99: 0.001,
+}
+
+// latencyQuantiles lists the quantiles of latencyObjectives in publishing order
+var latencyQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// observeLatency adds a request duration to the endpoint's quantile estimator. Callers must hold
+// endpointStatsMutex.
+func (s *EndpointStat) observeLatency(duration float64) {
+	if s.latencies == nil {
+		s.latencies = quantile.NewTargeted(latencyObjectives)
+	}
+	s.latencies.Insert(duration)
+}
+
+// latencyQuantileValues returns the estimated latency of each of latencyQuantiles, or nil before
+// the first observation. Callers must hold endpointStatsMutex, as querying compacts the estimator.
+func (s *EndpointStat) latencyQuantileValues() []float64 {
+	if s.latencies == nil || s.latencies.Count() == 0 {
+		return nil
+	}
+	values := make([]float64, len(latencyQuantiles))
+	for i, q := range latencyQuantiles {
+		values[i] = s.latencies.Query(q)
+	}
+	return values
+}
+
+// publishLatencyQuantiles sets the quantile gauges of an endpoint from latencyQuantileValues
+func publishLatencyQuantiles(namespace, ingress, endpoint string, values []float64) {
+	for i, value := range values {
+		endpointLatencyQuantile.WithLabelValues(namespace, ingress, endpoint, formatQuantile(latencyQuantiles[i])).Set(value)
+	}
+}
+
+// deleteLatencyQuantiles removes the quantile gauges of an endpoint and reports whether any existed
+func deleteLatencyQuantiles(namespace, ingress, endpoint string) bool {
+	return endpointLatencyQuantile.DeletePartialMatch(prometheus.Labels{
+		"namespace": namespace, "ingress": ingress, "request_path": endpoint,
+	}) > 0
+}
+
+// formatQuantile renders a quantile label value the way Prometheus summaries do, e.g. "0.95"
+func formatQuantile(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
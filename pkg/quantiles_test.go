@@ -0,0 +1,96 @@
+package logprocessing
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// uniformDurations returns the durations 1..n milliseconds in random order
+func uniformDurations(n int) []float64 {
+	durations := make([]float64, n)
+	for i := range durations {
+		durations[i] = float64(i + 1)
+	}
+	rng := rand.New(rand.NewSource(1))
+	rng.Shuffle(n, func(i, j int) { durations[i], durations[j] = durations[j], durations[i] })
+	return durations
+}
+
+// assertLatencyQuantiles checks the quantile gauges of an endpoint fed with uniformDurations(1000),
+// whose exact quantile q is q seconds, against the rank error of each objective
+func assertLatencyQuantiles(t *testing.T, namespace, ingress, endpoint string) {
+	t.Helper()
+	for _, q := range latencyQuantiles {
+		got := testutil.ToFloat64(endpointLatencyQuantile.WithLabelValues(namespace, ingress, endpoint, formatQuantile(q)))
+		if tolerance := latencyObjectives[q] + 0.001; math.Abs(got-q) > tolerance {
+			t.Errorf("Quantile %s = %v, want %v ± %v", formatQuantile(q), got, q, tolerance)
+		}
+	}
+}
+
+// setupQuantileRouter makes /api/top the only top path of router and returns its endpoint labels
+func setupQuantileRouter(t *testing.T, router string) (namespace, ingress string) {
+	t.Helper()
+	resetEndpointStats(t)
+
+	namespace, ingress = endpointLabels(router)
+	t.Cleanup(func() {
+		series := prometheus.Labels{"namespace": namespace, "ingress": ingress}
+		for _, vec := range []*prometheus.GaugeVec{endpointAvgLatency, endpointMaxLatency, endpointLatencyQuantile} {
+			vec.DeletePartialMatch(series)
+		}
+		endpointRequests.DeletePartialMatch(series)
+		endpointDuration.DeletePartialMatch(series)
+	})
+
+	topPathsMutex.Lock()
+	topPathsPerService[router] = map[string]bool{router + ":/api/top": true}
+	topPathsMutex.Unlock()
+	return namespace, ingress
+}
+
+// TestEndpointLatencyQuantiles tests that the quantile gauges of a top path follow a known
+// latency distribution, and that other paths get no quantile series
+func TestEndpointLatencyQuantiles(t *testing.T) {
+	router := "websecure-quantiles-a457d08d5820f79b3e08@kubernetes"
+	namespace, ingress := setupQuantileRouter(t, router)
+
+	for _, duration := range uniformDurations(1000) {
+		for _, path := range []string{"/api/top", "/api/other"} {
+			updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: path, Duration: duration}, nil, urlNormalization)
+		}
+	}
+
+	assertLatencyQuantiles(t, namespace, ingress, "/api/top")
+	if deleteLatencyQuantiles(namespace, ingress, "/api/other") {
+		t.Error("Expected no quantile series outside the top paths")
+	}
+
+	// Eviction drops the quantile series along with the endpoint
+	endpointStatsMutex.Lock()
+	endpointStats[router+":/api/top"].LastSeen = time.Now().Add(-2 * time.Hour)
+	endpointStatsMutex.Unlock()
+	evictStaleEndpointStats(time.Hour, time.Now())
+	if deleteLatencyQuantiles(namespace, ingress, "/api/top") {
+		t.Error("Expected the quantile series to be deleted on eviction")
+	}
+}
+
+// TestEndpointLatencyQuantilesBatched tests that batched updates feed every request into the estimator
+func TestEndpointLatencyQuantilesBatched(t *testing.T) {
+	router := "websecure-quantiles-batched-a457d08d5820f79b3e08@kubernetes"
+	namespace, ingress := setupQuantileRouter(t, router)
+
+	batcher := NewMetricsBatcher(100, time.Hour)
+	for _, duration := range uniformDurations(1000) {
+		updateMetricsBatched(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: "/api/top", Duration: duration}, nil, urlNormalization, batcher)
+	}
+	batcher.Close()
+
+	assertLatencyQuantiles(t, namespace, ingress, "/api/top")
+}
@@ -0,0 +1,238 @@
+package logprocessing
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// SSHConfig holds the connection details of an access log tailed on a remote host over SSH
+type SSHConfig struct {
+	// Host is the remote host, optionally prefixed with user@. Remote tailing is disabled if empty
+	Host string
+	Port int
+	User string
+	// KeyFile is the private key used to authenticate; the ssh defaults apply if empty
+	KeyFile string
+	// KnownHostsFile replaces the user's known_hosts file when set
+	KnownHostsFile string
+	// LogFile is the path of the access log on the remote host
+	LogFile string
+}
+
+// RemoteTransport opens a stream of access log lines from a remote host. The stream ends when the
+// connection drops; closing it releases the connection and reports why it ended.
+type RemoteTransport interface {
+	Open(ctx context.Context) (io.ReadCloser, error)
+}
+
+// sshTransport tails the remote file with the system ssh client, so key, agent, known hosts and
+// ssh_config handling behave exactly as they do for an operator logging in by hand
+type sshTransport struct {
+	config SSHConfig
+}
+
+// sshArgs returns the ssh arguments following the remote file from its current end
+func (t *sshTransport) sshArgs() []string {
+	args := []string{
+		"-o", "BatchMode=yes",
+		"-o", "ServerAliveInterval=15",
+		"-o", "ServerAliveCountMax=3",
+	}
+	if t.config.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(t.config.Port))
+	}
+	if t.config.User != "" {
+		args = append(args, "-l", t.config.User)
+	}
+	if t.config.KeyFile != "" {
+		args = append(args, "-i", t.config.KeyFile, "-o", "IdentitiesOnly=yes")
+	}
+	if t.config.KnownHostsFile != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+t.config.KnownHostsFile)
+	}
+	return append(args, t.config.Host, "tail -n 0 -F "+shellQuote(t.config.LogFile))
+}
+
+func (t *sshTransport) Open(ctx context.Context) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, "ssh", t.sshArgs()...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting ssh: %w", err)
+	}
+	return &sshStream{ReadCloser: stdout, cmd: cmd, stderr: &stderr}, nil
+}
+
+// sshStream is the output of a running ssh command
+type sshStream struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *strings.Builder
+}
+
+// Close waits for the ssh command and returns its exit error together with what it printed to
+// stderr. The command exits on its own when the connection drops and is killed when the context
+// passed to Open is cancelled.
+func (s *sshStream) Close() error {
+	if err := s.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// shellQuote quotes value for the POSIX shell running the remote command
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// RemoteLogSource tails an access log on a remote host, reconnecting with exponential backoff
+// whenever the connection drops. Each line carries host:path in LogLine.Source.
+type RemoteLogSource struct {
+	transport      RemoteTransport
+	source         string
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	lines          chan LogLine
+
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewRemoteLogSource starts tailing the remote access log over SSH
+func NewRemoteLogSource(config *SSHConfig) (*RemoteLogSource, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("remote log source requires a host")
+	}
+	if config.LogFile == "" {
+		return nil, fmt.Errorf("remote log source requires a log file")
+	}
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return nil, fmt.Errorf("remote log source requires an ssh client: %w", err)
+	}
+
+	return newRemoteLogSource(&sshTransport{config: *config}, config.Host+":"+config.LogFile,
+		initialBackoff, maxBackoff), nil
+}
+
+// newRemoteLogSource starts reading lines from transport
+func newRemoteLogSource(transport RemoteTransport, source string, initial, max time.Duration) *RemoteLogSource {
+	ctx, cancel := context.WithCancel(context.Background())
+	rls := &RemoteLogSource{
+		transport:      transport,
+		source:         source,
+		initialBackoff: initial,
+		maxBackoff:     max,
+		lines:          make(chan LogLine, 100),
+		cancel:         cancel,
+	}
+
+	rls.wg.Add(1)
+	go func() {
+		defer rls.wg.Done()
+		rls.run(ctx)
+	}()
+	return rls
+}
+
+// run keeps a connection open until ctx is cancelled. The backoff restarts once a connection
+// delivered lines, so a long-lived connection that drops is retried quickly.
+func (rls *RemoteLogSource) run(ctx context.Context) {
+	newBackoff := func() wait.Backoff {
+		return wait.Backoff{
+			Steps:    maxRetries,
+			Duration: rls.initialBackoff,
+			Factor:   2.0,
+			Jitter:   0.1,
+			Cap:      rls.maxBackoff,
+		}
+	}
+	backoff := newBackoff()
+
+	for {
+		delivered, err := rls.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if delivered {
+			backoff = newBackoff()
+		}
+
+		delay := backoff.Step()
+		if delay > rls.maxBackoff {
+			delay = rls.maxBackoff
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		UpdateHealthStatus("log_source", "reconnecting", err)
+		logger.Warnf("Remote access log %s disconnected (reconnecting in %v): %v", rls.source, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// stream forwards the lines of one connection and reports whether any line was delivered
+func (rls *RemoteLogSource) stream(ctx context.Context) (bool, error) {
+	stream, err := rls.transport.Open(ctx)
+	if err != nil {
+		return false, err
+	}
+	UpdateHealthStatus("log_source", "running", nil)
+	logger.Infof("Tailing remote access log %s", rls.source)
+
+	delivered := false
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		select {
+		case rls.lines <- LogLine{Text: scanner.Text(), Time: time.Now(), Source: rls.source}:
+			delivered = true
+		case <-ctx.Done():
+			_ = stream.Close()
+			return delivered, nil
+		}
+	}
+
+	scanErr := scanner.Err()
+	closeErr := stream.Close()
+	if scanErr != nil {
+		return delivered, scanErr
+	}
+	return delivered, closeErr
+}
+
+func (rls *RemoteLogSource) ReadLines() <-chan LogLine {
+	return rls.lines
+}
+
+// Close drops the connection and closes the lines channel once the reader has exited
+func (rls *RemoteLogSource) Close() error {
+	rls.closeOnce.Do(func() {
+		rls.cancel()
+		rls.wg.Wait()
+		close(rls.lines)
+	})
+	return nil
+}
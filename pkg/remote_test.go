@@ -0,0 +1,183 @@
+package logprocessing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockRemoteTransport serves one scripted connection per Open call and fails once the script runs out
+type mockRemoteTransport struct {
+	mu          sync.Mutex
+	connections []string
+	opens       int
+}
+
+func (m *mockRemoteTransport) Open(ctx context.Context) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.opens++
+	if len(m.connections) == 0 {
+		return nil, errors.New("connection refused")
+	}
+	output := m.connections[0]
+	m.connections = m.connections[1:]
+	return io.NopCloser(strings.NewReader(output)), nil
+}
+
+func (m *mockRemoteTransport) openCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.opens
+}
+
+// readRemoteLines reads n lines from the source or fails the test after a timeout
+func readRemoteLines(t *testing.T, source LogSource, n int) []LogLine {
+	t.Helper()
+	lines := make([]LogLine, 0, n)
+	timeout := time.After(2 * time.Second)
+	for len(lines) < n {
+		select {
+		case line, ok := <-source.ReadLines():
+			if !ok {
+				t.Fatalf("Lines channel closed after %d of %d lines", len(lines), n)
+			}
+			lines = append(lines, line)
+		case <-timeout:
+			t.Fatalf("Timed out after %d of %d lines", len(lines), n)
+		}
+	}
+	return lines
+}
+
+// TestRemoteLogSourceReconnects tests that lines keep flowing across dropped connections
+func TestRemoteLogSourceReconnects(t *testing.T) {
+	transport := &mockRemoteTransport{connections: []string{"first\nsecond\n", "third\n"}}
+	rls := newRemoteLogSource(transport, "edge-1:/var/log/traefik/access.log", time.Millisecond, 5*time.Millisecond)
+	defer rls.Close()
+
+	lines := readRemoteLines(t, rls, 3)
+	for i, expected := range []string{"first", "second", "third"} {
+		if lines[i].Text != expected {
+			t.Errorf("Line %d: expected %q, got %q", i, expected, lines[i].Text)
+		}
+		if lines[i].Source != "edge-1:/var/log/traefik/access.log" {
+			t.Errorf("Line %d: unexpected source %q", i, lines[i].Source)
+		}
+	}
+
+	// Failed connections keep being retried with backoff
+	deadline := time.Now().Add(2 * time.Second)
+	for transport.openCount() < 5 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if opens := transport.openCount(); opens < 5 {
+		t.Errorf("Expected repeated reconnection attempts, got %d", opens)
+	}
+}
+
+// blockingRemoteTransport serves a connection that stays open until its context is cancelled
+type blockingRemoteTransport struct{}
+
+func (blockingRemoteTransport) Open(ctx context.Context) (io.ReadCloser, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		_, _ = writer.Write([]byte("connected\n"))
+		<-ctx.Done()
+		_ = writer.CloseWithError(ctx.Err())
+	}()
+	return reader, nil
+}
+
+// TestRemoteLogSourceClose tests that Close drops an open connection and closes the lines channel
+func TestRemoteLogSourceClose(t *testing.T) {
+	rls := newRemoteLogSource(blockingRemoteTransport{}, "edge-1:/access.log", time.Millisecond, time.Millisecond)
+	readRemoteLines(t, rls, 1)
+
+	done := make(chan struct{})
+	go func() {
+		_ = rls.Close()
+		_ = rls.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return while a connection was open")
+	}
+
+	if _, ok := <-rls.ReadLines(); ok {
+		t.Error("Expected the lines channel to be closed")
+	}
+}
+
+// TestSSHTransportArgs tests the ssh command built from the connection details
+func TestSSHTransportArgs(t *testing.T) {
+	transport := &sshTransport{config: SSHConfig{
+		Host:           "edge-1.example.com",
+		Port:           2222,
+		User:           "traefik",
+		KeyFile:        "/etc/traefik-officer/id_ed25519",
+		KnownHostsFile: "/etc/traefik-officer/known_hosts",
+		LogFile:        "/var/log/traefik/it's access.log",
+	}}
+
+	expected := []string{
+		"-o", "BatchMode=yes",
+		"-o", "ServerAliveInterval=15",
+		"-o", "ServerAliveCountMax=3",
+		"-p", "2222",
+		"-l", "traefik",
+		"-i", "/etc/traefik-officer/id_ed25519", "-o", "IdentitiesOnly=yes",
+		"-o", "UserKnownHostsFile=/etc/traefik-officer/known_hosts",
+		"edge-1.example.com", `tail -n 0 -F '/var/log/traefik/it'\''s access.log'`,
+	}
+	if args := transport.sshArgs(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("sshArgs() = %q, want %q", args, expected)
+	}
+}
+
+// TestRemoteLogSourceSSHClient tests the ssh transport end to end against a stand-in ssh client
+// that prints a few lines and then exits as if the connection dropped
+func TestRemoteLogSourceSSHClient(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The stand-in ssh client is a shell script")
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"$1 $2\"\necho 'line from edge'\nexit 255\n"
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write ssh stand-in: %v", err)
+	}
+	t.Setenv("PATH", dir)
+
+	rls, err := NewRemoteLogSource(&SSHConfig{Host: "edge-1", LogFile: "/access.log"})
+	if err != nil {
+		t.Fatalf("NewRemoteLogSource() error = %v", err)
+	}
+	defer rls.Close()
+
+	lines := readRemoteLines(t, rls, 2)
+	if lines[0].Text != "-o BatchMode=yes" || lines[1].Text != "line from edge" {
+		t.Errorf("Unexpected lines %+v", lines)
+	}
+}
+
+// TestNewRemoteLogSourceValidation tests that incomplete connection details are rejected
+func TestNewRemoteLogSourceValidation(t *testing.T) {
+	if _, err := NewRemoteLogSource(&SSHConfig{LogFile: "/access.log"}); err == nil {
+		t.Error("Expected an error without a host")
+	}
+	if _, err := NewRemoteLogSource(&SSHConfig{Host: "edge-1"}); err == nil {
+		t.Error("Expected an error without a log file")
+	}
+}
@@ -0,0 +1,123 @@
+package logprocessing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+const (
+	routerReasonNotOperatorMode = "not in operator mode, every router is processed"
+	routerReasonNoConfigManager = "operator mode enabled but no config manager available"
+	routerReasonProcessed       = "router matches an enabled configuration"
+)
+
+// routerDecision explains whether the log lines of a router are processed in operator mode
+type routerDecision struct {
+	Router         string `json:"router"`
+	OperatorMode   bool   `json:"operatorMode"`
+	Namespace      string `json:"namespace"`
+	TargetName     string `json:"targetName"`
+	TargetKind     string `json:"targetKind"`
+	ConfigKey      string `json:"configKey,omitempty"`
+	ConfigFound    bool   `json:"configFound"`
+	ConfigEnabled  bool   `json:"configEnabled"`
+	ConfiguredKind string `json:"configuredKind,omitempty"`
+	Process        bool   `json:"process"`
+	Reason         string `json:"reason"`
+
+	// config is the matching runtime config of a processed router
+	config *shared.RuntimeConfig
+	// filterReason is the lines_filtered_total reason of a skipped router in operator mode
+	filterReason string
+}
+
+// explainRouter parses a router name and looks up its runtime config the way ShouldProcessRouter does,
+// recording every step of the decision
+func explainRouter(routerName string) routerDecision {
+	decision := routerDecision{Router: routerName}
+	if !IsOperatorMode() {
+		// Not in operator mode - use legacy config file approach
+		decision.Process = true
+		decision.Reason = routerReasonNotOperatorMode
+		return decision
+	}
+	decision.OperatorMode = true
+
+	operatorConfig.mu.RLock()
+	cm := operatorConfig.configManager
+	operatorConfig.mu.RUnlock()
+
+	// Routers are skipped for lack of a config unless found otherwise
+	decision.filterReason = FilterReasonNoConfig
+	if cm == nil {
+		decision.Reason = routerReasonNoConfigManager
+		return decision
+	}
+
+	decision.Namespace, decision.TargetName, decision.TargetKind = parseRouterName(routerName)
+
+	if decision.Namespace == "" && decision.TargetName != "" {
+		decision.Reason = fmt.Sprintf("%s router without a namespace, no UrlPerformance can target it", decision.TargetKind)
+		return decision
+	}
+	if decision.Namespace == "" || decision.TargetName == "" {
+		decision.Reason = "could not parse the router name"
+		return decision
+	}
+
+	decision.ConfigKey = shared.ConfigKey(decision.Namespace, decision.TargetName)
+	config, exists := cm.GetConfig(decision.ConfigKey)
+	if !exists {
+		decision.Reason = fmt.Sprintf("no configuration found for %s", decision.ConfigKey)
+		return decision
+	}
+	decision.ConfigFound = true
+	decision.ConfigEnabled = config.Enabled
+	decision.ConfiguredKind = config.TargetKind
+
+	if !config.Enabled {
+		decision.filterReason = FilterReasonDisabled
+		decision.Reason = fmt.Sprintf("configuration disabled for %s", decision.ConfigKey)
+		return decision
+	}
+	if !targetKindMatches(decision.TargetKind, config.TargetKind) {
+		decision.filterReason = FilterReasonKindMismatch
+		decision.Reason = fmt.Sprintf("target kind mismatch for %s: got %s, expected %s",
+			decision.ConfigKey, decision.TargetKind, config.TargetKind)
+		return decision
+	}
+
+	decision.Process = true
+	decision.Reason = routerReasonProcessed
+	decision.filterReason = ""
+	decision.config = config
+	return decision
+}
+
+// debugRouterHandler explains how the router given by the name query parameter is parsed and
+// whether its log lines are processed
+func debugRouterHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "missing name query parameter", http.StatusBadRequest)
+		return
+	}
+
+	decision := explainRouter(name)
+	if !decision.OperatorMode {
+		// Routers aren't parsed to decide outside operator mode, but their labels still are
+		decision.Namespace, decision.TargetName, decision.TargetKind = parseRouterName(name)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(decision)
+}
+
+// RouterDebugHandler returns the admin-guarded /debug/router handler, for servers other than
+// ServeProm's such as the operator's metrics server
+func RouterDebugHandler() http.Handler {
+	return adminGuard(debugRouterHandler)
+}
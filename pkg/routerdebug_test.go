@@ -0,0 +1,87 @@
+package logprocessing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// getRouterDecision calls the router debug handler for a router name
+func getRouterDecision(t *testing.T, routerName string) routerDecision {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/debug/router?name="+routerName, nil)
+	w := httptest.NewRecorder()
+	debugRouterHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var decision routerDecision
+	if err := json.NewDecoder(w.Body).Decode(&decision); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return decision
+}
+
+// TestDebugRouterHandler tests the explanation of a router with a registered config and of one without
+func TestDebugRouterHandler(t *testing.T) {
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+
+	configKey := shared.ConfigKey("shop", "api")
+	operatorConfig = &OperatorModeConfig{
+		enabled: true,
+		configManager: &patternsConfigManager{configs: []*shared.RuntimeConfig{
+			{Key: configKey, Enabled: true, TargetKind: "IngressRoute"},
+		}},
+	}
+
+	decision := getRouterDecision(t, "shop-api-a457d08d5820f79b3e08@kubernetescrd")
+	if !decision.Process || !decision.ConfigFound || !decision.ConfigEnabled {
+		t.Errorf("Expected the registered router to be processed, got %+v", decision)
+	}
+	if decision.Namespace != "shop" || decision.TargetName != "api" || decision.TargetKind != "IngressRoute" {
+		t.Errorf("Unexpected parsed target: %+v", decision)
+	}
+	if decision.ConfigKey != configKey || decision.ConfiguredKind != "IngressRoute" {
+		t.Errorf("Expected config key %s of kind IngressRoute, got %+v", configKey, decision)
+	}
+
+	decision = getRouterDecision(t, "shop-search-a457d08d5820f79b3e08@kubernetescrd")
+	if decision.Process || decision.ConfigFound {
+		t.Errorf("Expected the unregistered router to be skipped, got %+v", decision)
+	}
+	if decision.ConfigKey != shared.ConfigKey("shop", "search") || decision.Reason == "" {
+		t.Errorf("Expected the config key and a reason for the unregistered router, got %+v", decision)
+	}
+}
+
+// TestDebugRouterHandlerOutsideOperatorMode tests that routers are processed and still parsed
+// outside operator mode
+func TestDebugRouterHandlerOutsideOperatorMode(t *testing.T) {
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+	operatorConfig = &OperatorModeConfig{}
+
+	decision := getRouterDecision(t, "dashboard@internal")
+	if !decision.Process || decision.OperatorMode || decision.TargetKind != "TraefikInternal" {
+		t.Errorf("Unexpected decision outside operator mode: %+v", decision)
+	}
+}
+
+// TestDebugRouterHandlerRequiresName tests that the name query parameter is required
+func TestDebugRouterHandlerRequiresName(t *testing.T) {
+	w := httptest.NewRecorder()
+	debugRouterHandler(w, httptest.NewRequest("GET", "/debug/router", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
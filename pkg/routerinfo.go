@@ -0,0 +1,111 @@
+package logprocessing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// maxRouterInfoSeries bounds the number of routers exposed on traefik_officer_router_info
+	maxRouterInfoSeries = 1000
+
+	// routerInfoTTL is how long a router stays exposed after it was last seen
+	routerInfoTTL = time.Hour
+)
+
+// routerInfo is built by buildMetrics
+var routerInfo *prometheus.GaugeVec
+
+// routerInfoEntry is a router exposed on the router info gauge
+type routerInfoEntry struct {
+	labels   []string
+	lastSeen time.Time
+}
+
+var (
+	routerInfoSeen      = make(map[string]*routerInfoEntry)
+	routerInfoLastSweep time.Time
+	routerInfoMutex     sync.Mutex
+
+	// routerInfoNow is replaced in tests
+	routerInfoNow = time.Now
+)
+
+// recordRouterInfo exposes the router on the router info gauge the first time it is seen and refreshes
+// its last seen time afterwards. Routers whose name can't be parsed are not exposed. When the gauge is
+// full the least recently seen router is evicted, and routers not seen within routerInfoTTL are removed.
+func recordRouterInfo(routerName string) {
+	if routerName == "" {
+		return
+	}
+	now := routerInfoNow()
+
+	routerInfoMutex.Lock()
+	defer routerInfoMutex.Unlock()
+
+	if now.Sub(routerInfoLastSweep) >= time.Minute {
+		evictStaleRouterInfo(now)
+		routerInfoLastSweep = now
+	}
+
+	if entry, ok := routerInfoSeen[routerName]; ok {
+		if entry != nil {
+			entry.lastSeen = now
+		}
+		return
+	}
+
+	namespace, targetName, targetKind := parseRouterName(routerName)
+	if targetName == "" {
+		// Remember unparseable routers so they aren't parsed again on every line
+		if len(routerInfoSeen) < maxRouterInfoSeries {
+			routerInfoSeen[routerName] = nil
+		}
+		return
+	}
+
+	if len(routerInfoSeen) >= maxRouterInfoSeries {
+		evictOldestRouterInfo()
+	}
+	entry := &routerInfoEntry{
+		labels:   []string{routerName, namespace, targetName, targetKind},
+		lastSeen: now,
+	}
+	routerInfoSeen[routerName] = entry
+	routerInfo.WithLabelValues(entry.labels...).Set(1)
+}
+
+// evictStaleRouterInfo removes routers not seen within routerInfoTTL. Callers must hold routerInfoMutex.
+func evictStaleRouterInfo(now time.Time) {
+	for router, entry := range routerInfoSeen {
+		if entry == nil {
+			continue
+		}
+		if now.Sub(entry.lastSeen) > routerInfoTTL {
+			routerInfo.DeleteLabelValues(entry.labels...)
+			delete(routerInfoSeen, router)
+		}
+	}
+}
+
+// evictOldestRouterInfo removes the least recently seen router, preferring unparseable entries which
+// have no series. Callers must hold routerInfoMutex.
+func evictOldestRouterInfo() {
+	oldest := ""
+	var oldestEntry *routerInfoEntry
+	for router, entry := range routerInfoSeen {
+		if entry == nil {
+			delete(routerInfoSeen, router)
+			return
+		}
+		if oldestEntry == nil || entry.lastSeen.Before(oldestEntry.lastSeen) {
+			oldest, oldestEntry = router, entry
+		}
+	}
+	if oldestEntry != nil {
+		routerInfo.DeleteLabelValues(oldestEntry.labels...)
+		delete(routerInfoSeen, oldest)
+	}
+}
@@ -0,0 +1,88 @@
+package logprocessing
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// resetRouterInfo clears the router info state and restores the clock after the test
+func resetRouterInfo(t *testing.T) {
+	t.Helper()
+
+	resetState := func() {
+		routerInfoMutex.Lock()
+		routerInfoSeen = make(map[string]*routerInfoEntry)
+		routerInfoLastSweep = time.Time{}
+		routerInfoMutex.Unlock()
+		routerInfo.Reset()
+	}
+	resetState()
+	t.Cleanup(func() {
+		routerInfoNow = time.Now
+		resetState()
+	})
+}
+
+// TestRecordRouterInfo tests that parsed routers get an info series and unparseable ones don't
+func TestRecordRouterInfo(t *testing.T) {
+	resetRouterInfo(t)
+
+	ingressRoute := "mahfil-dev-mahfil-api-server-ingressroute-http-a457d08d5820f79b3e08@kubernetescrd"
+	ingress := "websecure-monitoring-grafana-ingress-grafana-example-com-a457d08d5820f79b3e08@kubernetes"
+
+	for i := 0; i < 3; i++ {
+		recordRouterInfo(ingressRoute)
+	}
+	recordRouterInfo(ingress)
+	recordRouterInfo("dashboard@internal")
+	recordRouterInfo("shop-web@consulcatalog")
+	recordRouterInfo("")
+
+	if got := testutil.ToFloat64(routerInfo.WithLabelValues(ingressRoute, "mahfil", "dev-mahfil-api-server-ingressroute-http", "IngressRoute")); got != 1 {
+		t.Errorf("Expected IngressRoute router info series to be 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(routerInfo.WithLabelValues(ingress, "monitoring", "grafana-ingress-grafana-example-com", "Ingress")); got != 1 {
+		t.Errorf("Expected Ingress router info series to be 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(routerInfo.WithLabelValues("dashboard@internal", "", "dashboard", "TraefikInternal")); got != 1 {
+		t.Errorf("Expected internal router info series to be 1, got %v", got)
+	}
+	if got := testutil.CollectAndCount(routerInfo); got != 3 {
+		t.Errorf("Expected one series per parsed router, got %d", got)
+	}
+}
+
+// TestRouterInfoEviction tests that stale routers are evicted and the series count stays bounded
+func TestRouterInfoEviction(t *testing.T) {
+	resetRouterInfo(t)
+
+	now := time.Now()
+	routerInfoNow = func() time.Time { return now }
+
+	stale := "stale-orders-a457d08d5820f79b3e08@kubernetescrd"
+	recordRouterInfo(stale)
+
+	now = now.Add(routerInfoTTL + time.Minute)
+	recordRouterInfo("shop-checkout-a457d08d5820f79b3e08@kubernetescrd")
+
+	routerInfoMutex.Lock()
+	_, stillSeen := routerInfoSeen[stale]
+	routerInfoMutex.Unlock()
+	if stillSeen {
+		t.Error("Expected the stale router to be evicted")
+	}
+	if got := testutil.CollectAndCount(routerInfo); got != 1 {
+		t.Errorf("Expected only the live router to be exposed, got %d series", got)
+	}
+
+	for i := 0; i < maxRouterInfoSeries+50; i++ {
+		now = now.Add(time.Millisecond)
+		recordRouterInfo(fmt.Sprintf("shop-api%d-a457d08d5820f79b3e08@kubernetescrd", i))
+	}
+	if got := testutil.CollectAndCount(routerInfo); got > maxRouterInfoSeries {
+		t.Errorf("Expected at most %d series, got %d", maxRouterInfoSeries, got)
+	}
+}
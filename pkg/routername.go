@@ -0,0 +1,101 @@
+package logprocessing
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// RouterNamePattern extracts the namespace, target name and target kind of routers from their names,
+// for Traefik setups whose router naming the built-in heuristic of parseRouterName gets wrong, e.g.
+// namespaces containing dashes
+type RouterNamePattern struct {
+	// Provider is the router provider suffix the pattern applies to, e.g. "kubernetes" or "file".
+	// Empty applies the pattern to routers of every provider.
+	Provider string `json:"Provider"`
+	// Pattern is matched against the router name without its @provider suffix. It must capture the
+	// namespace and name groups and may capture a kind group.
+	Pattern string `json:"Pattern"`
+	// TargetKind is the target kind of matching routers when the pattern has no kind group. Defaults
+	// to the kind of the provider: Ingress for kubernetes, IngressRoute for kubernetescrd.
+	TargetKind string `json:"TargetKind"`
+}
+
+// compiledRouterNamePattern is a RouterNamePattern with its compiled regex
+type compiledRouterNamePattern struct {
+	provider   string
+	targetKind string
+	regex      *regexp.Regexp
+}
+
+var (
+	routerNamePatterns      []compiledRouterNamePattern
+	routerNamePatternsMutex sync.RWMutex
+)
+
+// SetRouterNamePatterns replaces the router name patterns tried, in order, before the built-in router
+// name heuristic. Routers no pattern matches are still parsed by the heuristic.
+func SetRouterNamePatterns(patterns []RouterNamePattern) error {
+	compiled, err := compileRouterNamePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	setRouterNamePatterns(compiled)
+	return nil
+}
+
+// compileRouterNamePatterns compiles the router name patterns, which must capture the namespace and name
+func compileRouterNamePatterns(patterns []RouterNamePattern) ([]compiledRouterNamePattern, error) {
+	compiled := make([]compiledRouterNamePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling router name pattern %q: %w", pattern.Pattern, err)
+		}
+		if regex.SubexpIndex("namespace") == -1 || regex.SubexpIndex("name") == -1 {
+			return nil, fmt.Errorf("router name pattern %q must capture the namespace and name groups", pattern.Pattern)
+		}
+		compiled = append(compiled, compiledRouterNamePattern{
+			provider:   pattern.Provider,
+			targetKind: pattern.TargetKind,
+			regex:      regex,
+		})
+	}
+	return compiled, nil
+}
+
+// setRouterNamePatterns replaces the router name patterns with compiled ones
+func setRouterNamePatterns(compiled []compiledRouterNamePattern) {
+	routerNamePatternsMutex.Lock()
+	defer routerNamePatternsMutex.Unlock()
+	routerNamePatterns = compiled
+}
+
+// matchRouterNamePattern parses a router name, without its provider suffix, with the first router
+// name pattern of the provider that matches it
+func matchRouterNamePattern(provider, name string) (namespace, targetName, targetKind string, ok bool) {
+	routerNamePatternsMutex.RLock()
+	defer routerNamePatternsMutex.RUnlock()
+
+	for _, pattern := range routerNamePatterns {
+		if pattern.provider != "" && pattern.provider != provider {
+			continue
+		}
+		match := pattern.regex.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+
+		namespace = match[pattern.regex.SubexpIndex("namespace")]
+		targetName = match[pattern.regex.SubexpIndex("name")]
+		if i := pattern.regex.SubexpIndex("kind"); i != -1 && match[i] != "" {
+			targetKind = match[i]
+		} else if pattern.targetKind != "" {
+			targetKind = pattern.targetKind
+		} else {
+			targetKind, _ = providerTargetKind(provider)
+		}
+		return namespace, targetName, targetKind, true
+	}
+	return "", "", "", false
+}
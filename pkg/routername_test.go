@@ -0,0 +1,140 @@
+package logprocessing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseRouterNameWithPatterns tests that configured router name patterns extract namespaces
+// containing dashes that the built-in heuristic misparses, and that unmatched routers fall back to it
+func TestParseRouterNameWithPatterns(t *testing.T) {
+	t.Cleanup(func() { _ = SetRouterNamePatterns(nil) })
+
+	err := SetRouterNamePatterns([]RouterNamePattern{
+		{
+			Provider: "kubernetes",
+			Pattern:  `^(?:web|websecure)-(?P<namespace>team-[a-z]+-(?:dev|prod))-(?P<name>.+)-[0-9a-f]{12,}$`,
+		},
+		{
+			Provider: "kubernetescrd",
+			Pattern:  `^(?P<namespace>payments-eu-west)-(?P<name>.+)-[0-9a-f]{12,}$`,
+		},
+		{
+			Provider:   "kubernetescrd",
+			Pattern:    `^(?P<namespace>dns-system)-(?P<name>.+)-\d+$`,
+			TargetKind: "IngressRouteUDP",
+		},
+		{
+			Provider: "file",
+			Pattern:  `^(?P<kind>Ingress|IngressRoute)_(?P<namespace>[^_]+)_(?P<name>[^_]+)$`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("SetRouterNamePatterns() error = %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		routerName        string
+		expectedNamespace string
+		expectedTarget    string
+		expectedKind      string
+	}{
+		{
+			name:              "Ingress router in a dash-containing namespace",
+			routerName:        "websecure-team-a-prod-shop-api-shop-example-com-6b3c8a1f2e4d@kubernetes",
+			expectedNamespace: "team-a-prod",
+			expectedTarget:    "shop-api-shop-example-com",
+			expectedKind:      "Ingress",
+		},
+		{
+			name:              "IngressRoute router in a dash-containing namespace",
+			routerName:        "payments-eu-west-checkout-api-a457d08d5820f79b3e08@kubernetescrd",
+			expectedNamespace: "payments-eu-west",
+			expectedTarget:    "checkout-api",
+			expectedKind:      "IngressRoute",
+		},
+		{
+			name:              "pattern with a fixed target kind",
+			routerName:        "dns-system-coredns-udp-0@kubernetescrd",
+			expectedNamespace: "dns-system",
+			expectedTarget:    "coredns-udp",
+			expectedKind:      "IngressRouteUDP",
+		},
+		{
+			name:              "pattern capturing the target kind",
+			routerName:        "IngressRoute_shop-prod_checkout@file",
+			expectedNamespace: "shop-prod",
+			expectedTarget:    "checkout",
+			expectedKind:      "IngressRoute",
+		},
+		{
+			name:              "unmatched router falls back to the heuristic",
+			routerName:        "mahfil-dev-mahfil-api-server-ingressroute-http-a457d08d5820f79b3e08@kubernetescrd",
+			expectedNamespace: "mahfil",
+			expectedTarget:    "dev-mahfil-api-server-ingressroute-http",
+			expectedKind:      "IngressRoute",
+		},
+		{
+			name:              "patterns of other providers are not applied",
+			routerName:        "websecure-team-a-prod-shop-api-6b3c8a1f2e4d@kubernetescrd",
+			expectedNamespace: "websecure",
+			expectedTarget:    "team-a-prod-shop-api",
+			expectedKind:      "IngressRoute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, target, kind := parseRouterName(tt.routerName)
+			if namespace != tt.expectedNamespace || target != tt.expectedTarget || kind != tt.expectedKind {
+				t.Errorf("parseRouterName(%q) = (%q, %q, %q), expected (%q, %q, %q)", tt.routerName,
+					namespace, target, kind, tt.expectedNamespace, tt.expectedTarget, tt.expectedKind)
+			}
+		})
+	}
+}
+
+// TestParseRouterNameDefaultHeuristicMisparsesDashedNamespaces documents why patterns are needed: the
+// heuristic takes the first dash-separated part after the entrypoint as the namespace
+func TestParseRouterNameDefaultHeuristicMisparsesDashedNamespaces(t *testing.T) {
+	namespace, target, _ := parseRouterName("websecure-team-a-prod-shop-api-shop-example-com-6b3c8a1f2e4d@kubernetes")
+	if namespace != "team" || target != "a-prod-shop-api-shop-example-com" {
+		t.Errorf("Expected the heuristic to parse (team, a-prod-shop-api-shop-example-com), got (%q, %q)", namespace, target)
+	}
+}
+
+// TestSetRouterNamePatternsRejectsInvalidPatterns tests that patterns that don't compile or don't
+// capture the namespace and name are rejected
+func TestSetRouterNamePatternsRejectsInvalidPatterns(t *testing.T) {
+	t.Cleanup(func() { _ = SetRouterNamePatterns(nil) })
+
+	for _, pattern := range []string{`^(?P<namespace>[a-z]+`, `^(?P<namespace>[a-z]+)-(.+)$`} {
+		if err := SetRouterNamePatterns([]RouterNamePattern{{Pattern: pattern}}); err == nil {
+			t.Errorf("Expected pattern %q to be rejected", pattern)
+		}
+	}
+}
+
+// TestLoadConfigRouterNamePatterns tests that RouterNamePatterns of the config file are applied
+func TestLoadConfigRouterNamePatterns(t *testing.T) {
+	t.Cleanup(func() { _ = SetRouterNamePatterns(nil) })
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `RouterNamePatterns:
+  - Provider: kubernetescrd
+    Pattern: '^(?P<namespace>payments-eu-west)-(?P<name>.+)-[0-9a-f]{12,}$'
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	namespace, target, _ := parseRouterName("payments-eu-west-checkout-api-a457d08d5820f79b3e08@kubernetescrd")
+	if namespace != "payments-eu-west" || target != "checkout-api" {
+		t.Errorf("Expected (payments-eu-west, checkout-api), got (%q, %q)", namespace, target)
+	}
+}
@@ -0,0 +1,127 @@
+package logprocessing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultServiceRPSWindow is the sliding window traefik_officer_service_rps is averaged over
+	DefaultServiceRPSWindow = time.Minute
+
+	// maxServiceRPSUpdateInterval caps how long the service RPS gauge lags behind the traffic
+	maxServiceRPSUpdateInterval = 5 * time.Second
+)
+
+// serviceRPS is built by buildMetrics
+var serviceRPS *prometheus.GaugeVec
+
+// rateBucket holds the requests counted during the update interval ending at end
+type rateBucket struct {
+	end   time.Time
+	count int64
+}
+
+// serviceRate is the ring of per-interval request counts of a service
+type serviceRate struct {
+	// pending counts the requests since the last update
+	pending int64
+	// since is when counting started, so services younger than the window aren't underestimated
+	since   time.Time
+	buckets []rateBucket
+}
+
+var (
+	serviceRates      = make(map[string]*serviceRate)
+	serviceRatesMutex sync.Mutex
+	// serviceRatesLastUpdate is when the service rates were last updated
+	serviceRatesLastUpdate time.Time
+
+	// serviceRPSNow is replaced in tests
+	serviceRPSNow = time.Now
+)
+
+// recordServiceRequest counts a request of the service towards its RPS gauge
+func recordServiceRequest(service string) {
+	serviceRatesMutex.Lock()
+	defer serviceRatesMutex.Unlock()
+
+	rate := serviceRates[service]
+	if rate == nil {
+		since := serviceRatesLastUpdate
+		if since.IsZero() {
+			since = serviceRPSNow()
+		}
+		rate = &serviceRate{since: since}
+		serviceRates[service] = rate
+	}
+	rate.pending++
+}
+
+// updateServiceRPS closes the current interval of every service and sets its RPS gauge to the
+// requests counted within window divided by the window, or by the time since counting started
+// when that is shorter. Services without requests within window are removed.
+func updateServiceRPS(window time.Duration, now time.Time) {
+	serviceRatesMutex.Lock()
+	defer serviceRatesMutex.Unlock()
+
+	serviceRatesLastUpdate = now
+	cutoff := now.Add(-window)
+	for service, rate := range serviceRates {
+		rate.buckets = append(rate.buckets, rateBucket{end: now, count: rate.pending})
+		rate.pending = 0
+
+		// Drop the intervals that ended before the window
+		first := 0
+		for first < len(rate.buckets) && !rate.buckets[first].end.After(cutoff) {
+			first++
+		}
+		rate.buckets = rate.buckets[first:]
+
+		var requests int64
+		for _, bucket := range rate.buckets {
+			requests += bucket.count
+		}
+		if requests == 0 {
+			serviceRPS.DeleteLabelValues(service)
+			delete(serviceRates, service)
+			continue
+		}
+
+		elapsed := window
+		if rate.since.After(cutoff) {
+			elapsed = now.Sub(rate.since)
+		}
+		if elapsed <= 0 {
+			continue
+		}
+		serviceRPS.WithLabelValues(service).Set(float64(requests) / elapsed.Seconds())
+	}
+}
+
+// StartServiceRPSUpdater periodically recomputes the service RPS gauge over the sliding window,
+// falling back to DefaultServiceRPSWindow for non-positive windows. It stops when stop is closed.
+func StartServiceRPSUpdater(window time.Duration, stop <-chan struct{}) {
+	if window <= 0 {
+		window = DefaultServiceRPSWindow
+	}
+	interval := window
+	if interval > maxServiceRPSUpdateInterval {
+		interval = maxServiceRPSUpdateInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				updateServiceRPS(window, serviceRPSNow())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
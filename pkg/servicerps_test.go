@@ -0,0 +1,96 @@
+package logprocessing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// setServiceRPSClock resets the service rates and makes serviceRPSNow return the returned clock's time
+func setServiceRPSClock(t *testing.T, start time.Time) *time.Time {
+	t.Helper()
+	now := start
+	oldNow := serviceRPSNow
+	serviceRPSNow = func() time.Time { return now }
+
+	reset := func() {
+		serviceRatesMutex.Lock()
+		serviceRates = make(map[string]*serviceRate)
+		serviceRatesLastUpdate = time.Time{}
+		serviceRatesMutex.Unlock()
+		serviceRPS.Reset()
+	}
+	reset()
+	t.Cleanup(func() {
+		serviceRPSNow = oldNow
+		reset()
+	})
+	return &now
+}
+
+// TestServiceRPS tests the RPS gauge over a sliding window driven by a controlled clock
+func TestServiceRPS(t *testing.T) {
+	const service = "shop-api@kubernetes"
+	window := 30 * time.Second
+	now := setServiceRPSClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	updateServiceRPS(window, *now)
+	advance := func(requests int) {
+		for i := 0; i < requests; i++ {
+			recordServiceRequest(service)
+		}
+		*now = now.Add(10 * time.Second)
+		updateServiceRPS(window, *now)
+	}
+	tick := func(requests int) float64 {
+		advance(requests)
+		return testutil.ToFloat64(serviceRPS.WithLabelValues(service))
+	}
+
+	// Before the window is full, the rate is averaged over the time since counting started
+	if got := tick(50); got != 5 {
+		t.Errorf("RPS after 10s = %v, want 5", got)
+	}
+	if got := tick(150); got != 10 {
+		t.Errorf("RPS after 20s = %v, want 10", got)
+	}
+	if got := tick(100); got != 10 {
+		t.Errorf("RPS after 30s = %v, want 10", got)
+	}
+	// The first interval slides out of the window
+	if got := tick(0); got != 250.0/30 {
+		t.Errorf("RPS after 40s = %v, want %v", got, 250.0/30)
+	}
+
+	// Services idle for the whole window are removed
+	advance(0)
+	advance(0)
+	serviceRatesMutex.Lock()
+	_, tracked := serviceRates[service]
+	serviceRatesMutex.Unlock()
+	if tracked {
+		t.Error("Expected the idle service to be removed")
+	}
+	if serviceRPS.DeleteLabelValues(service) {
+		t.Error("Expected the RPS series of the idle service to be deleted")
+	}
+}
+
+// TestServiceRPSRecordMetrics tests that processed requests count towards the RPS of their router
+func TestServiceRPSRecordMetrics(t *testing.T) {
+	resetEndpointStats(t)
+	const router = "websecure-rps-a457d08d5820f79b3e08@kubernetes"
+	now := setServiceRPSClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	updateServiceRPS(time.Minute, *now)
+	for i := 0; i < 12; i++ {
+		recordMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: "/"}, nil, nil, nil)
+	}
+	*now = now.Add(4 * time.Second)
+	updateServiceRPS(time.Minute, *now)
+
+	if got := testutil.ToFloat64(serviceRPS.WithLabelValues(router)); got != 3 {
+		t.Errorf("RPS = %v, want 3", got)
+	}
+}
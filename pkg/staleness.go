@@ -0,0 +1,129 @@
+package logprocessing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	logger "github.com/sirupsen/logrus"
+)
+
+const (
+	// GaugeStalenessDrop deletes the gauges of idle endpoints, so Prometheus marks the series stale
+	GaugeStalenessDrop = "drop"
+	// GaugeStalenessNaN keeps the gauges of idle endpoints but sets them to NaN
+	GaugeStalenessNaN = "nan"
+)
+
+// maxGaugeStalenessSweepInterval caps how long frozen gauges linger past their staleness TTL
+const maxGaugeStalenessSweepInterval = time.Minute
+
+// staleEndpoints holds the keys of endpoints whose gauges were marked stale, guarded by
+// endpointStatsMutex. Their error rate gauges are republished once traffic resumes.
+var staleEndpoints = make(map[string]bool)
+
+// validateGaugeStalenessMode returns an error for modes other than drop, nan or empty (drop)
+func validateGaugeStalenessMode(mode string) error {
+	switch mode {
+	case "", GaugeStalenessDrop, GaugeStalenessNaN:
+		return nil
+	default:
+		return fmt.Errorf("unknown gauge staleness mode %q, expected %q or %q", mode, GaugeStalenessDrop, GaugeStalenessNaN)
+	}
+}
+
+// markStaleEndpointGauges marks the latency and error rate gauges of endpoints not updated within
+// ttl as stale, either by deleting their series or by setting them to NaN, and returns the number
+// of endpoints newly marked. Unlike eviction, the endpoint stats and counters are kept.
+func markStaleEndpointGauges(ttl time.Duration, mode string, now time.Time) int {
+	stale := make([]string, 0)
+	staleMethods := make(map[string][]string)
+
+	endpointStatsMutex.Lock()
+	for key, stat := range endpointStats {
+		if stat.LastSeen.IsZero() || staleEndpoints[key] {
+			continue
+		}
+		if now.Sub(stat.LastSeen) > ttl {
+			staleEndpoints[key] = true
+			stale = append(stale, key)
+			staleMethods[key] = stat.methodNames()
+		}
+	}
+	endpointStatsMutex.Unlock()
+
+	for _, key := range stale {
+		parts := strings.SplitN(key, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		namespace, ingress := endpointLabels(parts[0])
+		for _, gauge := range []*prometheus.GaugeVec{
+			endpointAvgLatency, endpointMaxLatency,
+			endpointErrorRate, endpointClientErrorRate, endpointServerErrorRate,
+		} {
+			// Only series that exist are marked, so idle endpoints outside the top paths stay absent
+			if gauge.DeleteLabelValues(namespace, ingress, parts[1]) && mode == GaugeStalenessNaN {
+				gauge.WithLabelValues(namespace, ingress, parts[1]).Set(math.NaN())
+			}
+		}
+		if deleteMethodLatency(namespace, ingress, parts[1]) && mode == GaugeStalenessNaN {
+			markMethodLatencyStale(namespace, ingress, parts[1], staleMethods[key])
+		}
+		if deleteLatencyQuantiles(namespace, ingress, parts[1]) && mode == GaugeStalenessNaN {
+			for _, q := range latencyQuantiles {
+				endpointLatencyQuantile.WithLabelValues(namespace, ingress, parts[1], formatQuantile(q)).Set(math.NaN())
+			}
+		}
+	}
+
+	if len(stale) > 0 {
+		logger.Debugf("Marked the gauges of %d endpoints idle for more than %s as stale", len(stale), ttl)
+	}
+	return len(stale)
+}
+
+// publishEndpointErrorRates sets the error rate gauges of an endpoint that recorded errors,
+// restoring them after the endpoint's gauges were marked stale
+func publishEndpointErrorRates(namespace, ingress, endpoint string, stat EndpointStat) {
+	if stat.TotalRequests == 0 {
+		return
+	}
+	if stat.ErrorCount > 0 {
+		endpointErrorRate.WithLabelValues(namespace, ingress, endpoint).
+			Set(float64(stat.ErrorCount) / float64(stat.TotalRequests))
+	}
+	if stat.ClientErrorCount > 0 {
+		endpointClientErrorRate.WithLabelValues(namespace, ingress, endpoint).
+			Set(float64(stat.ClientErrorCount) / float64(stat.TotalRequests))
+	}
+	if stat.ServerErrorCount > 0 {
+		endpointServerErrorRate.WithLabelValues(namespace, ingress, endpoint).
+			Set(float64(stat.ServerErrorCount) / float64(stat.TotalRequests))
+	}
+}
+
+// StartGaugeStalenessSweeper periodically marks the gauges of endpoints not updated within ttl as
+// stale using the given mode, so dashboards show missing data instead of frozen values. It stops
+// when stop is closed.
+func StartGaugeStalenessSweeper(ttl time.Duration, mode string, stop <-chan struct{}) {
+	interval := ttl
+	if interval > maxGaugeStalenessSweepInterval {
+		interval = maxGaugeStalenessSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				markStaleEndpointGauges(ttl, mode, now)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,145 @@
+package logprocessing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// setupStaleEndpoints records an idle and an active top path of a router with server errors and
+// returns their endpoint label values
+func setupStaleEndpoints(t *testing.T, router string, now time.Time) (namespace, ingress string) {
+	t.Helper()
+	resetEndpointStats(t)
+
+	namespace, ingress = endpointLabels(router)
+	t.Cleanup(func() {
+		series := prometheus.Labels{"namespace": namespace, "ingress": ingress}
+		for _, vec := range []*prometheus.GaugeVec{
+			endpointAvgLatency, endpointMaxLatency, endpointErrorRate, endpointClientErrorRate, endpointServerErrorRate,
+			endpointLatencyQuantile,
+		} {
+			vec.DeletePartialMatch(series)
+		}
+		endpointRequests.DeletePartialMatch(series)
+		endpointDuration.DeletePartialMatch(series)
+	})
+
+	topPathsMutex.Lock()
+	topPathsPerService[router] = map[string]bool{router + ":/api/idle": true, router + ":/api/active": true}
+	topPathsMutex.Unlock()
+
+	for _, path := range []string{"/api/idle", "/api/active"} {
+		updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 500, RouterName: router, RequestPath: path, Duration: 10}, nil, urlNormalization)
+	}
+
+	endpointStatsMutex.Lock()
+	endpointStats[router+":/api/idle"].LastSeen = now.Add(-10 * time.Minute)
+	endpointStats[router+":/api/active"].LastSeen = now.Add(-time.Minute)
+	endpointStatsMutex.Unlock()
+	return namespace, ingress
+}
+
+// TestMarkStaleEndpointGaugesDrop tests that idle endpoints lose their gauges after the TTL while
+// their stats and counters are kept
+func TestMarkStaleEndpointGaugesDrop(t *testing.T) {
+	router := "websecure-stale-drop-a457d08d5820f79b3e08@kubernetes"
+	now := time.Now()
+	namespace, ingress := setupStaleEndpoints(t, router, now)
+
+	if marked := markStaleEndpointGauges(5*time.Minute, GaugeStalenessDrop, now); marked != 1 {
+		t.Errorf("Expected 1 endpoint marked stale, got %d", marked)
+	}
+	if marked := markStaleEndpointGauges(5*time.Minute, GaugeStalenessDrop, now); marked != 0 {
+		t.Errorf("Expected stale endpoints to be marked once, got %d", marked)
+	}
+
+	// Deleting an already deleted series reports false
+	for name, gauge := range map[string]*prometheus.GaugeVec{
+		"average latency": endpointAvgLatency, "max latency": endpointMaxLatency,
+		"error rate": endpointErrorRate, "server error rate": endpointServerErrorRate,
+	} {
+		if gauge.DeleteLabelValues(namespace, ingress, "/api/idle") {
+			t.Errorf("Expected the idle %s series to be dropped", name)
+		}
+		if !gauge.DeleteLabelValues(namespace, ingress, "/api/active") {
+			t.Errorf("Expected the active %s series to be kept", name)
+		}
+	}
+	if v := testutil.ToFloat64(endpointRequests.WithLabelValues(namespace, ingress, "/api/idle", "GET", "500")); v != 1 {
+		t.Errorf("Expected the idle request counter to be kept, got %v", v)
+	}
+
+	endpointStatsMutex.RLock()
+	_, exists := endpointStats[router+":/api/idle"]
+	endpointStatsMutex.RUnlock()
+	if !exists {
+		t.Error("Expected the idle endpoint stats to be kept")
+	}
+}
+
+// TestMarkStaleEndpointGaugesNaN tests that the nan mode keeps the idle series with a NaN value
+func TestMarkStaleEndpointGaugesNaN(t *testing.T) {
+	router := "websecure-stale-nan-a457d08d5820f79b3e08@kubernetes"
+	now := time.Now()
+	namespace, ingress := setupStaleEndpoints(t, router, now)
+
+	if marked := markStaleEndpointGauges(5*time.Minute, GaugeStalenessNaN, now); marked != 1 {
+		t.Errorf("Expected 1 endpoint marked stale, got %d", marked)
+	}
+
+	for name, gauge := range map[string]*prometheus.GaugeVec{
+		"average latency": endpointAvgLatency, "max latency": endpointMaxLatency, "error rate": endpointErrorRate,
+	} {
+		if v := testutil.ToFloat64(gauge.WithLabelValues(namespace, ingress, "/api/idle")); !math.IsNaN(v) {
+			t.Errorf("Expected the idle %s to be NaN, got %v", name, v)
+		}
+		if v := testutil.ToFloat64(gauge.WithLabelValues(namespace, ingress, "/api/active")); math.IsNaN(v) {
+			t.Errorf("Expected the active %s to keep its value", name)
+		}
+	}
+
+	// The client error rate never had a series, so none is created
+	if endpointClientErrorRate.DeleteLabelValues(namespace, ingress, "/api/idle") {
+		t.Error("Expected no client error rate series for the idle endpoint")
+	}
+}
+
+// TestStaleEndpointRevived tests that traffic on a stale endpoint republishes its gauges
+func TestStaleEndpointRevived(t *testing.T) {
+	router := "websecure-stale-revive-a457d08d5820f79b3e08@kubernetes"
+	now := time.Now()
+	namespace, ingress := setupStaleEndpoints(t, router, now)
+	markStaleEndpointGauges(5*time.Minute, GaugeStalenessNaN, now)
+
+	updateMetrics(&traefikLogConfig{RequestMethod: "GET", OriginStatus: 200, RouterName: router, RequestPath: "/api/idle", Duration: 30}, nil, urlNormalization)
+
+	if v := testutil.ToFloat64(endpointErrorRate.WithLabelValues(namespace, ingress, "/api/idle")); v != 0.5 {
+		t.Errorf("Expected the error rate to be republished as 0.5, got %v", v)
+	}
+	if v := testutil.ToFloat64(endpointMaxLatency.WithLabelValues(namespace, ingress, "/api/idle")); v != 0.03 {
+		t.Errorf("Expected the max latency to be republished as 0.03, got %v", v)
+	}
+
+	endpointStatsMutex.RLock()
+	stale := staleEndpoints[router+":/api/idle"]
+	endpointStatsMutex.RUnlock()
+	if stale {
+		t.Error("Expected the revived endpoint to no longer be marked stale")
+	}
+}
+
+// TestValidateGaugeStalenessMode tests the accepted staleness modes
+func TestValidateGaugeStalenessMode(t *testing.T) {
+	for _, mode := range []string{"", GaugeStalenessDrop, GaugeStalenessNaN} {
+		if err := validateGaugeStalenessMode(mode); err != nil {
+			t.Errorf("validateGaugeStalenessMode(%q) error = %v", mode, err)
+		}
+	}
+	if err := validateGaugeStalenessMode("zero"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
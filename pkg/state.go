@@ -0,0 +1,212 @@
+package logprocessing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+const (
+	stateFileVersion = 1
+	// restoredTopPathsGraceCycles is the number of top paths updates during which restored top
+	// paths stay selected, so they don't drop out before live traffic has been observed
+	restoredTopPathsGraceCycles = 3
+)
+
+// persistedState is the content of the state file
+type persistedState struct {
+	Version       int                     `json:"version"`
+	SavedAt       time.Time               `json:"savedAt"`
+	TopPaths      map[string][]string     `json:"topPaths"`
+	EndpointStats map[string]EndpointStat `json:"endpointStats"`
+}
+
+var (
+	// Top paths restored from the state file, kept selected for the first few update cycles.
+	// Guarded by topPathsMutex.
+	restoredTopPaths       map[string]map[string]bool
+	restoredTopPathsCycles int
+
+	// stateSavedAt is when the state file restored by LoadState was saved, guarded by endpointStatsMutex
+	stateSavedAt time.Time
+)
+
+// SaveState writes the top paths selection and the endpoint stats to path.
+// The file is replaced atomically so a crash never leaves a truncated state behind.
+func SaveState(path string) error {
+	state := persistedState{
+		Version:  stateFileVersion,
+		SavedAt:  time.Now().UTC(),
+		TopPaths: make(map[string][]string),
+	}
+
+	topPathsMutex.RLock()
+	for service, paths := range topPathsPerService {
+		for key := range paths {
+			state.TopPaths[service] = append(state.TopPaths[service], key)
+		}
+	}
+	topPathsMutex.RUnlock()
+
+	endpointStatsMutex.RLock()
+	state.EndpointStats = make(map[string]EndpointStat, len(endpointStats))
+	for key, stat := range endpointStats {
+		saved := *stat
+		// Copy the per-method latency, which is encoded after the lock is released
+		saved.Methods = make(map[string]*MethodLatency, len(stat.Methods))
+		for method, latency := range stat.Methods {
+			copied := *latency
+			saved.Methods[method] = &copied
+		}
+		state.EndpointStats[key] = saved
+	}
+	endpointStatsMutex.RUnlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
+
+// restoredStateSavedAt returns when the state file restored on startup was saved, or the zero time
+func restoredStateSavedAt() time.Time {
+	endpointStatsMutex.RLock()
+	defer endpointStatsMutex.RUnlock()
+	return stateSavedAt
+}
+
+// LoadState restores the top paths selection and the endpoint stats from path.
+// A missing state file is not an error. Restored stats are added to any stats already collected.
+func LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Infof("No state file at %s, starting with empty top paths", path)
+			return nil
+		}
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if state.Version != stateFileVersion {
+		return fmt.Errorf("unsupported state file version %d", state.Version)
+	}
+
+	endpointStatsMutex.Lock()
+	stateSavedAt = state.SavedAt
+	for key, restored := range state.EndpointStats {
+		stat := endpointStats[key]
+		if stat == nil {
+			stat = &EndpointStat{}
+			endpointStats[key] = stat
+		}
+		stat.TotalRequests += restored.TotalRequests
+		stat.TotalDuration += restored.TotalDuration
+		restoredMean := restored.MeanDuration
+		if restoredMean == 0 && restored.TotalRequests > 0 {
+			// State saved before the running mean was tracked
+			restoredMean = restored.TotalDuration / float64(restored.TotalRequests)
+		}
+		stat.addToMean(restored.TotalRequests, restoredMean)
+		if restored.MaxDuration > stat.MaxDuration {
+			stat.MaxDuration = restored.MaxDuration
+		}
+		if restored.LastSeen.After(stat.LastSeen) {
+			stat.LastSeen = restored.LastSeen
+		}
+		stat.ErrorCount += restored.ErrorCount
+		stat.ClientErrorCount += restored.ClientErrorCount
+		stat.ServerErrorCount += restored.ServerErrorCount
+		for method, latency := range restored.Methods {
+			stat.observeMethodLatency(method, latency.TotalRequests, latency.MeanDuration, latency.MaxDuration)
+		}
+	}
+	endpointStatsMutex.Unlock()
+
+	topPathsMutex.Lock()
+	restoredTopPaths = make(map[string]map[string]bool, len(state.TopPaths))
+	for service, keys := range state.TopPaths {
+		if topPathsPerService[service] == nil {
+			topPathsPerService[service] = make(map[string]bool)
+		}
+		restoredTopPaths[service] = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			topPathsPerService[service][key] = true
+			restoredTopPaths[service][key] = true
+		}
+	}
+	restoredTopPathsCycles = restoredTopPathsGraceCycles
+	topPathsMutex.Unlock()
+
+	logger.Infof("Restored %d top paths and %d endpoint stats from %s (saved at %s)",
+		countTotalTopPaths(restoredTopPaths), len(state.EndpointStats), path, state.SavedAt.Format(time.RFC3339))
+	return nil
+}
+
+// applyRestoredTopPaths keeps restored top paths selected during the grace cycles.
+// Must be called with topPathsMutex held.
+func applyRestoredTopPaths() {
+	if restoredTopPathsCycles <= 0 {
+		return
+	}
+	restoredTopPathsCycles--
+
+	for service, keys := range restoredTopPaths {
+		if topPathsPerService[service] == nil {
+			topPathsPerService[service] = make(map[string]bool)
+		}
+		for key := range keys {
+			topPathsPerService[service][key] = true
+		}
+	}
+
+	if restoredTopPathsCycles == 0 {
+		restoredTopPaths = nil
+	}
+}
+
+// StartStatePersister saves the state to path every interval until stop is closed
+func StartStatePersister(path string, interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := SaveState(path); err != nil {
+					logger.Errorf("Failed to save state: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
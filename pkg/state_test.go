@@ -0,0 +1,171 @@
+package logprocessing
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestSaveLoadStateRoundTrip tests that top paths and endpoint stats survive a save and restore
+func TestSaveLoadStateRoundTrip(t *testing.T) {
+	resetEndpointStats(t)
+	oldTopNPaths := topNPaths
+	defer func() {
+		topNPaths = oldTopNPaths
+		topPathsMutex.Lock()
+		restoredTopPaths = nil
+		restoredTopPathsCycles = 0
+		topPathsMutex.Unlock()
+	}()
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	endpointStatsMutex.Lock()
+	endpointStats["shop-api:/slow"] = &EndpointStat{TotalRequests: 10, TotalDuration: 20, MeanDuration: 2, MaxDuration: 4, ErrorCount: 2, ServerErrorCount: 2,
+		Methods: map[string]*MethodLatency{"GET": {TotalRequests: 10, MeanDuration: 2, MaxDuration: 4}}}
+	endpointStats["shop-api:/fast"] = &EndpointStat{TotalRequests: 100, TotalDuration: 1, MeanDuration: 0.01, MaxDuration: 0.1}
+	endpointStatsMutex.Unlock()
+
+	topPathsMutex.Lock()
+	topPathsPerService["shop-api"] = map[string]bool{"shop-api:/slow": true}
+	topPathsMutex.Unlock()
+
+	if err := SaveState(statePath); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	// Simulate a restart
+	endpointStatsMutex.Lock()
+	endpointStats = make(map[string]*EndpointStat)
+	endpointStatsMutex.Unlock()
+	topPathsMutex.Lock()
+	topPathsPerService = make(map[string]map[string]bool)
+	topPathsMutex.Unlock()
+
+	if err := LoadState(statePath); err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+
+	endpointStatsMutex.RLock()
+	slow := endpointStats["shop-api:/slow"]
+	fast := endpointStats["shop-api:/fast"]
+	endpointStatsMutex.RUnlock()
+
+	if slow == nil || !reflect.DeepEqual(*slow, EndpointStat{TotalRequests: 10, TotalDuration: 20, MeanDuration: 2, MaxDuration: 4, ErrorCount: 2, ServerErrorCount: 2,
+		Methods: map[string]*MethodLatency{"GET": {TotalRequests: 10, MeanDuration: 2, MaxDuration: 4}}}) {
+		t.Errorf("Unexpected restored stat for /slow: %+v", slow)
+	}
+	if fast == nil || fast.TotalRequests != 100 {
+		t.Errorf("Unexpected restored stat for /fast: %+v", fast)
+	}
+
+	topPathsMutex.RLock()
+	isTopPath := topPathsPerService["shop-api"]["shop-api:/slow"]
+	topPathsMutex.RUnlock()
+	if !isTopPath {
+		t.Error("Expected /slow to be a top path right after restore")
+	}
+}
+
+// TestRestoredTopPathsGraceCycles tests that restored top paths are reconciled with live stats
+func TestRestoredTopPathsGraceCycles(t *testing.T) {
+	resetEndpointStats(t)
+	oldTopNPaths := topNPaths
+	defer func() {
+		topNPaths = oldTopNPaths
+		topPathsMutex.Lock()
+		restoredTopPaths = nil
+		restoredTopPathsCycles = 0
+		topPathsMutex.Unlock()
+	}()
+	topNPaths = 1
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	topPathsMutex.Lock()
+	topPathsPerService["shop-api"] = map[string]bool{"shop-api:/restored": true}
+	topPathsMutex.Unlock()
+	if err := SaveState(statePath); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	topPathsMutex.Lock()
+	topPathsPerService = make(map[string]map[string]bool)
+	topPathsMutex.Unlock()
+	if err := LoadState(statePath); err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+
+	// Live traffic only has a different, slower path
+	endpointStatsMutex.Lock()
+	endpointStats["shop-api:/live"] = &EndpointStat{TotalRequests: 1, TotalDuration: 5, MeanDuration: 5, MaxDuration: 5}
+	endpointStatsMutex.Unlock()
+
+	for cycle := 1; cycle <= restoredTopPathsGraceCycles+1; cycle++ {
+		updateTopPaths()
+
+		topPathsMutex.RLock()
+		restored := topPathsPerService["shop-api"]["shop-api:/restored"]
+		live := topPathsPerService["shop-api"]["shop-api:/live"]
+		topPathsMutex.RUnlock()
+
+		if !live {
+			t.Errorf("Cycle %d: expected live path to be selected", cycle)
+		}
+		inGrace := cycle <= restoredTopPathsGraceCycles
+		if restored != inGrace {
+			t.Errorf("Cycle %d: restored path selected = %v, expected %v", cycle, restored, inGrace)
+		}
+	}
+}
+
+// TestLoadStateMissingOrInvalid tests restoring from a missing or corrupt state file
+func TestLoadStateMissingOrInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := LoadState(filepath.Join(dir, "missing.json")); err != nil {
+		t.Errorf("Expected no error for a missing state file, got %v", err)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadState(corrupt); err == nil {
+		t.Error("Expected an error for a corrupt state file")
+	}
+
+	wrongVersion := filepath.Join(dir, "version.json")
+	if err := os.WriteFile(wrongVersion, []byte(`{"version":99}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadState(wrongVersion); err == nil {
+		t.Error("Expected an error for an unsupported state file version")
+	}
+}
+
+// TestLoadStateWithoutMeanDuration tests restoring stats saved before the running mean was tracked
+func TestLoadStateWithoutMeanDuration(t *testing.T) {
+	resetEndpointStats(t)
+	defer func() {
+		topPathsMutex.Lock()
+		restoredTopPaths = nil
+		restoredTopPathsCycles = 0
+		topPathsMutex.Unlock()
+	}()
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	content := `{"version":1,"endpointStats":{"shop-api:/slow":{"TotalRequests":4,"TotalDuration":10,"MaxDuration":4}}}`
+	if err := os.WriteFile(statePath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadState(statePath); err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+
+	endpointStatsMutex.RLock()
+	defer endpointStatsMutex.RUnlock()
+	if stat := endpointStats["shop-api:/slow"]; stat == nil || stat.MeanDuration != 2.5 {
+		t.Errorf("Expected MeanDuration 2.5 derived from the sum, got %+v", stat)
+	}
+}
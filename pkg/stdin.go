@@ -0,0 +1,72 @@
+package logprocessing
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// StdinLogSource reads access log lines from standard input, e.g. piped `kubectl logs` output or a
+// captured log file, and closes its lines channel at EOF
+type StdinLogSource struct {
+	lines     chan LogLine
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewStdinLogSource creates a log source reading os.Stdin
+func NewStdinLogSource() *StdinLogSource {
+	return newStdinLogSource(os.Stdin)
+}
+
+// newStdinLogSource creates a log source reading lines from reader until EOF
+func newStdinLogSource(reader io.Reader) *StdinLogSource {
+	sls := &StdinLogSource{
+		lines: make(chan LogLine, defaultLineBufferSize),
+		done:  make(chan struct{}),
+	}
+
+	go func() {
+		defer close(sls.lines)
+
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			if !sls.send(LogLine{Text: scanner.Text(), Time: time.Now()}) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			sls.send(LogLine{Time: time.Now(), Err: err})
+		}
+	}()
+
+	return sls
+}
+
+// send delivers a line unless the source was closed
+func (sls *StdinLogSource) send(line LogLine) bool {
+	select {
+	case <-sls.done:
+		return false
+	default:
+	}
+
+	select {
+	case sls.lines <- line:
+		return true
+	case <-sls.done:
+		return false
+	}
+}
+
+func (sls *StdinLogSource) ReadLines() <-chan LogLine {
+	return sls.lines
+}
+
+// Close stops delivering lines. Standard input itself is left open, as it belongs to the process.
+func (sls *StdinLogSource) Close() error {
+	sls.closeOnce.Do(func() { close(sls.done) })
+	return nil
+}
@@ -0,0 +1,103 @@
+package logprocessing
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestStdinLogSourceReadsUntilEOF tests that every line of the input is delivered and the channel
+// is closed at EOF
+func TestStdinLogSourceReadsUntilEOF(t *testing.T) {
+	input := bytes.NewBufferString("first line\nsecond line\nlast line without newline")
+	source := newStdinLogSource(input)
+	defer source.Close()
+
+	var texts []string
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case line, ok := <-source.ReadLines():
+			if !ok {
+				expected := []string{"first line", "second line", "last line without newline"}
+				if len(texts) != len(expected) {
+					t.Fatalf("Expected lines %v, got %v", expected, texts)
+				}
+				for i := range expected {
+					if texts[i] != expected[i] {
+						t.Errorf("Line %d: expected %q, got %q", i, expected[i], texts[i])
+					}
+				}
+				return
+			}
+			if line.Err != nil {
+				t.Fatalf("Unexpected error line: %v", line.Err)
+			}
+			texts = append(texts, line.Text)
+		case <-timeout:
+			t.Fatalf("Timed out waiting for EOF, got %v", texts)
+		}
+	}
+}
+
+// TestStdinLogSourceClose tests that closing the source stops delivery without draining the input
+func TestStdinLogSourceClose(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < defaultLineBufferSize*2; i++ {
+		input.WriteString("line\n")
+	}
+	source := newStdinLogSource(&input)
+	if err := source.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	_ = source.Close()
+
+	count := 0
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-source.ReadLines():
+			if !ok {
+				if count >= defaultLineBufferSize*2 {
+					t.Errorf("Expected delivery to stop after Close, got all %d lines", count)
+				}
+				return
+			}
+			count++
+		case <-timeout:
+			t.Fatal("Timed out waiting for the lines channel to close")
+		}
+	}
+}
+
+// TestCreateLogSourceStdinExclusive tests that stdin mode can't be combined with other sources
+func TestCreateLogSourceStdinExclusive(t *testing.T) {
+	tests := []struct {
+		name   string
+		useK8s bool
+		config LogFileConfig
+	}{
+		{name: "with kubernetes", useK8s: true, config: LogFileConfig{Stdin: true}},
+		{name: "with ssh", config: LogFileConfig{Stdin: true, Remote: SSHConfig{Host: "traefik-1"}}},
+		{name: "with log files", config: LogFileConfig{Stdin: true, LogFiles: "/var/log/a.log,/var/log/b.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if source, err := CreateLogSource(tt.useK8s, &config, &K8SConfig{}); err == nil {
+				_ = source.Close()
+				t.Error("Expected an error combining -stdin with another source")
+			}
+		})
+	}
+
+	source, err := CreateLogSource(false, &LogFileConfig{Stdin: true}, nil)
+	if err != nil {
+		t.Fatalf("CreateLogSource() error = %v", err)
+	}
+	defer source.Close()
+	if _, ok := source.(*StdinLogSource); !ok {
+		t.Errorf("Expected a *StdinLogSource, got %T", source)
+	}
+}
@@ -0,0 +1,112 @@
+package logprocessing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// targetActivity tracks the endpoints seen for one UrlPerformance config key
+type targetActivity struct {
+	// endpoints holds the endpoint stats keys (router:path) matched to the config
+	endpoints map[string]struct{}
+	lastSeen  time.Time
+}
+
+var (
+	// targetActivities maps config keys to the traffic observed for their target in operator mode
+	targetActivities      = make(map[string]*targetActivity)
+	targetActivitiesMutex sync.RWMutex
+)
+
+// recordTargetPath records a log line of a router matched to the config key and its normalized endpoint
+func recordTargetPath(configKey, service, endpoint string, now time.Time) {
+	targetActivitiesMutex.Lock()
+	defer targetActivitiesMutex.Unlock()
+
+	activity := targetActivities[configKey]
+	if activity == nil {
+		activity = &targetActivity{endpoints: make(map[string]struct{})}
+		targetActivities[configKey] = activity
+	}
+	activity.endpoints[service+":"+endpoint] = struct{}{}
+	if now.After(activity.lastSeen) {
+		activity.lastSeen = now
+	}
+}
+
+// forgetTargetPaths removes evicted endpoint stats keys from the monitored paths of every target
+func forgetTargetPaths(endpointKeys []string) {
+	targetActivitiesMutex.Lock()
+	defer targetActivitiesMutex.Unlock()
+
+	for _, activity := range targetActivities {
+		for _, key := range endpointKeys {
+			delete(activity.endpoints, key)
+		}
+	}
+}
+
+// GetTargetStats returns the number of unique paths and the time of the last log line seen for a config key
+func GetTargetStats(configKey string) (shared.TargetStats, bool) {
+	targetActivitiesMutex.RLock()
+	defer targetActivitiesMutex.RUnlock()
+
+	activity, ok := targetActivities[configKey]
+	if !ok {
+		return shared.TargetStats{}, false
+	}
+	return shared.TargetStats{MonitoredPaths: len(activity.endpoints), LastSeen: activity.lastSeen}, true
+}
+
+// ResetTargetMetrics deletes the endpoint stats and series of the endpoints matched to a config
+// key, and its detailed histogram, e.g. once its UrlPerformance was deleted
+func ResetTargetMetrics(configKey string) {
+	targetActivitiesMutex.Lock()
+	activity := targetActivities[configKey]
+	delete(targetActivities, configKey)
+	targetActivitiesMutex.Unlock()
+
+	if activity != nil {
+		keys := make([]string, 0, len(activity.endpoints))
+		endpointStatsMutex.Lock()
+		for key := range activity.endpoints {
+			keys = append(keys, key)
+			delete(endpointStats, key)
+			delete(staleEndpoints, key)
+		}
+		endpointStatsMutex.Unlock()
+		deleteEndpointSeries(keys)
+	}
+
+	detailedHistogramsMutex.Lock()
+	if existing := detailedHistograms[configKey]; existing != nil {
+		prometheus.Unregister(existing.vec)
+		delete(detailedHistograms, configKey)
+	}
+	detailedHistogramsMutex.Unlock()
+}
+
+// processorTargetStats exposes the log processor's per-target stats to the controller
+type processorTargetStats struct{}
+
+func (processorTargetStats) GetTargetStats(key string) (shared.TargetStats, bool) {
+	return GetTargetStats(key)
+}
+
+// NewTargetStatsProvider returns a provider reading the per-target stats of the log processor
+func NewTargetStatsProvider() shared.TargetStatsProvider {
+	return processorTargetStats{}
+}
+
+func (processorTargetStats) ResetTargetMetrics(key string) {
+	ResetTargetMetrics(key)
+}
+
+// NewTargetMetricsResetter returns a resetter deleting the per-target metrics of the log processor
+func NewTargetMetricsResetter() shared.TargetMetricsResetter {
+	return processorTargetStats{}
+}
@@ -0,0 +1,138 @@
+package logprocessing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mithucste30/traefik-officer-operator/shared"
+)
+
+// resetTargetActivities replaces the per-target stats for the duration of a test
+func resetTargetActivities(t *testing.T) {
+	targetActivitiesMutex.Lock()
+	old := targetActivities
+	targetActivities = make(map[string]*targetActivity)
+	targetActivitiesMutex.Unlock()
+
+	t.Cleanup(func() {
+		targetActivitiesMutex.Lock()
+		targetActivities = old
+		targetActivitiesMutex.Unlock()
+	})
+}
+
+// TestTargetStatsCountsObservedPaths tests that the monitored paths of a target grow as the
+// processor sees new normalized paths, and that other routers don't count towards it
+func TestTargetStatsCountsObservedPaths(t *testing.T) {
+	resetEndpointStats(t)
+	resetTargetActivities(t)
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+
+	configKey := shared.ConfigKey("shop", "checkout")
+	operatorConfig = &OperatorModeConfig{
+		enabled: true,
+		configManager: &patternsConfigManager{configs: []*shared.RuntimeConfig{
+			{Key: configKey, TargetKind: "Ingress", Enabled: true},
+		}},
+	}
+	router := "websecure-shop-checkout-a457d08d5820f79b3e08@kubernetes"
+
+	process := func(routerName string, paths ...string) {
+		source := &mockLogSource{lines: make(chan LogLine, len(paths))}
+		for _, path := range paths {
+			source.lines <- LogLine{Text: fmt.Sprintf(
+				`{"RouterName":%q,"RequestMethod":"GET","RequestPath":%q,"OriginStatus":200,"Duration":1000000}`,
+				routerName, path)}
+		}
+		_ = source.Close()
+		useK8s := true // Disable log rotation
+		ProcessLogs(context.Background(), source, TraefikOfficerConfig{}, &useK8s, nil, LogFormatJSON)
+	}
+
+	if _, ok := GetTargetStats(configKey); ok {
+		t.Fatal("Expected no stats before any log line")
+	}
+
+	before := time.Now()
+	process(router, "/cart", "/cart", "/pay")
+	stats, ok := GetTargetStats(configKey)
+	if !ok || stats.MonitoredPaths != 2 {
+		t.Fatalf("Expected 2 monitored paths, got %+v (found %v)", stats, ok)
+	}
+	if stats.LastSeen.Before(before) {
+		t.Errorf("Expected LastSeen after %v, got %v", before, stats.LastSeen)
+	}
+
+	process(router, "/cart", "/orders")
+	process("websecure-shop-search-a457d08d5820f79b3e08@kubernetes", "/search")
+	if stats, _ := GetTargetStats(configKey); stats.MonitoredPaths != 3 {
+		t.Errorf("Expected 3 monitored paths after a new path, got %d", stats.MonitoredPaths)
+	}
+
+	// Evicted endpoints are no longer monitored
+	endpointStatsMutex.Lock()
+	endpointStats[router+":/pay"].LastSeen = time.Now().Add(-2 * time.Hour)
+	endpointStatsMutex.Unlock()
+	evictStaleEndpointStats(time.Hour, time.Now())
+	if stats, _ := NewTargetStatsProvider().GetTargetStats(configKey); stats.MonitoredPaths != 2 {
+		t.Errorf("Expected 2 monitored paths after eviction, got %d", stats.MonitoredPaths)
+	}
+}
+
+// TestResetTargetMetrics tests that resetting a target deletes the stats and series of its endpoints
+// while other routers keep theirs
+func TestResetTargetMetrics(t *testing.T) {
+	resetEndpointStats(t)
+	resetTargetActivities(t)
+	oldConfig := operatorConfig
+	defer func() {
+		operatorConfig = oldConfig
+	}()
+
+	configKey := shared.ConfigKey("shop", "cart")
+	operatorConfig = &OperatorModeConfig{
+		enabled: true,
+		configManager: &patternsConfigManager{configs: []*shared.RuntimeConfig{
+			{Key: configKey, TargetKind: "Ingress", Enabled: true},
+			{Key: shared.ConfigKey("shop", "search"), TargetKind: "Ingress", Enabled: true},
+		}},
+	}
+	router := "websecure-shop-cart-a457d08d5820f79b3e08@kubernetes"
+	other := "websecure-shop-search-a457d08d5820f79b3e08@kubernetes"
+
+	source := &mockLogSource{lines: make(chan LogLine, 3)}
+	for _, line := range [][2]string{{router, "/cart"}, {router, "/pay"}, {other, "/search"}} {
+		source.lines <- LogLine{Text: fmt.Sprintf(
+			`{"RouterName":%q,"RequestMethod":"GET","RequestPath":%q,"OriginStatus":200,"Duration":1000000}`,
+			line[0], line[1])}
+	}
+	_ = source.Close()
+	useK8s := true // Disable log rotation
+	ProcessLogs(context.Background(), source, TraefikOfficerConfig{}, &useK8s, nil, LogFormatJSON)
+
+	if stats, ok := GetTargetStats(configKey); !ok || stats.MonitoredPaths != 2 {
+		t.Fatalf("Expected 2 monitored paths before the reset, got %+v (found %v)", stats, ok)
+	}
+
+	NewTargetMetricsResetter().ResetTargetMetrics(configKey)
+
+	if _, ok := GetTargetStats(configKey); ok {
+		t.Error("Expected no target stats after the reset")
+	}
+	endpointStatsMutex.RLock()
+	_, cartFound := endpointStats[router+":/cart"]
+	_, searchFound := endpointStats[other+":/search"]
+	endpointStatsMutex.RUnlock()
+	if cartFound || !searchFound {
+		t.Errorf("Expected only the endpoints of the target to be deleted, cart found %v, search found %v", cartFound, searchFound)
+	}
+	namespace, ingress := endpointLabels(router)
+	if endpointAvgLatency.DeleteLabelValues(namespace, ingress, "/cart") {
+		t.Error("Expected the latency series of the target to be deleted")
+	}
+}
@@ -0,0 +1,57 @@
+package logprocessing
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// tlsOtherLabel replaces TLS versions and ciphers outside the known sets, bounding cardinality
+const tlsOtherLabel = "other"
+
+var (
+	// tlsHandshakes is built by buildMetrics
+	tlsHandshakes *prometheus.CounterVec
+
+	// knownTLSCiphers holds the names of every cipher suite Go (and thus Traefik) can negotiate
+	knownTLSCiphers = func() map[string]bool {
+		ciphers := make(map[string]bool)
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			ciphers[suite.Name] = true
+		}
+		return ciphers
+	}()
+)
+
+// normalizeTLSVersion maps the logged TLS version, e.g. "1.3", "TLS 1.3" or "TLSv1.3", to one of
+// 1.0, 1.1, 1.2 and 1.3, or tlsOtherLabel for anything else
+func normalizeTLSVersion(version string) string {
+	v := strings.ToLower(strings.TrimSpace(version))
+	v = strings.TrimPrefix(v, "tls")
+	v = strings.TrimLeft(v, "v ")
+	switch v {
+	case "1.0", "1.1", "1.2", "1.3":
+		return v
+	default:
+		return tlsOtherLabel
+	}
+}
+
+// normalizeTLSCipher returns the cipher suite name if Go knows it, or tlsOtherLabel
+func normalizeTLSCipher(cipher string) string {
+	if knownTLSCiphers[cipher] {
+		return cipher
+	}
+	return tlsOtherLabel
+}
+
+// observeTLSHandshake counts the TLS version and cipher of an entry; lines without TLS fields,
+// such as common log format lines, aren't counted
+func observeTLSHandshake(entry *traefikLogConfig) {
+	if entry.TLSVersion == "" {
+		return
+	}
+	namespace, _ := endpointLabels(entry.RouterName)
+	tlsHandshakes.WithLabelValues(namespace, normalizeTLSVersion(entry.TLSVersion), normalizeTLSCipher(entry.TLSCipher)).Inc()
+}
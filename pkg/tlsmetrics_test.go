@@ -0,0 +1,58 @@
+package logprocessing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestNormalizeTLSVersion tests mapping logged TLS versions to the known set
+func TestNormalizeTLSVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.3": "1.3", "TLS 1.2": "1.2", "TLSv1.1": "1.1", "tls1.0": "1.0",
+		"": tlsOtherLabel, "SSLv3": tlsOtherLabel, "1.4": tlsOtherLabel, "random-garbage": tlsOtherLabel,
+	}
+	for version, expected := range tests {
+		if got := normalizeTLSVersion(version); got != expected {
+			t.Errorf("normalizeTLSVersion(%q) = %q, want %q", version, got, expected)
+		}
+	}
+}
+
+// TestTLSHandshakesFromJSON tests that TLS fields of JSON log lines are parsed and counted with
+// unknown values collapsed, and that lines without TLS aren't counted
+func TestTLSHandshakesFromJSON(t *testing.T) {
+	resetEndpointStats(t)
+	router := "websecure-tlsshop-checkout-a457d08d5820f79b3e08@kubernetes"
+	namespace, _ := endpointLabels(router)
+	t.Cleanup(func() {
+		tlsHandshakes.DeletePartialMatch(prometheus.Labels{"namespace": namespace})
+	})
+
+	lines := []string{
+		`{"RouterName":%q,"RequestMethod":"GET","RequestPath":"/","OriginStatus":200,"tls_version":"1.3","tls_cipher":"TLS_AES_128_GCM_SHA256"}`,
+		`{"RouterName":%q,"RequestMethod":"GET","RequestPath":"/","OriginStatus":200,"tls_version":"1.3","tls_cipher":"TLS_AES_128_GCM_SHA256"}`,
+		`{"RouterName":%q,"RequestMethod":"GET","RequestPath":"/","OriginStatus":200,"tls_version":"1.2","tls_cipher":"made-up-cipher"}`,
+		`{"RouterName":%q,"RequestMethod":"GET","RequestPath":"/","OriginStatus":200}`,
+	}
+	for _, line := range lines {
+		entry, err := parseJSON(fmt.Sprintf(line, router))
+		if err != nil {
+			t.Fatalf("parseJSON() error = %v", err)
+		}
+		updateMetrics(&entry, nil, urlNormalization)
+	}
+
+	if got := testutil.ToFloat64(tlsHandshakes.WithLabelValues(namespace, "1.3", "TLS_AES_128_GCM_SHA256")); got != 2 {
+		t.Errorf("Expected 2 TLS 1.3 requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(tlsHandshakes.WithLabelValues(namespace, "1.2", tlsOtherLabel)); got != 1 {
+		t.Errorf("Expected the unknown cipher to be counted as %q, got %v", tlsOtherLabel, got)
+	}
+	// The line without TLS fields isn't counted under any label
+	if deleted := tlsHandshakes.DeletePartialMatch(prometheus.Labels{"namespace": namespace}); deleted != 2 {
+		t.Errorf("Expected 2 TLS series, got %d", deleted)
+	}
+}
@@ -1,13 +1,14 @@
 package logprocessing
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	logger "github.com/sirupsen/logrus"
+	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -66,22 +67,30 @@ func parseJSON(line string) (traefikLogConfig, error) {
 	var err error
 	var jsonLog traefikLogConfig
 
+	// Failures are logged, rate-limited per reason, by ProcessLogs
 	if !json.Valid([]byte(line)) {
-		err := fmt.Errorf("invalid JSON format in log line: %s", line)
-		logger.Error(err)
-		return traefikLogConfig{}, err
+		return traefikLogConfig{}, errInvalidJSON
 	}
 
 	if err := json.Unmarshal([]byte(line), &jsonLog); err != nil {
-		logger.Errorf("Failed to unmarshal JSON log: %v", err)
-		return traefikLogConfig{}, fmt.Errorf("failed to unmarshal JSON log: %w", err)
+		return traefikLogConfig{}, withReason(fmt.Errorf("failed to unmarshal JSON log: %w", err), errInvalidJSON)
 	}
 
-	jsonLog.Duration = jsonLog.Duration / 1000000 // JSON Logs format latency in nanoseconds, convert to ms
-	jsonLog.Overhead = jsonLog.Overhead / 1000000 // sane for overhead metrics
+	// Nested schemas: fill the fields with a configured dot-path, keeping flat keys as fallback
+	if hasJSONFieldPaths() {
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &raw); err == nil {
+			applyJSONFieldPaths(raw, &jsonLog)
+		}
+	}
+
+	// JSON Logs format latency in nanoseconds unless a pipeline converted it, see SetJSONDurationUnit
+	jsonLog.Duration = jsonLog.Duration / jsonDurationDivisor
+	jsonLog.Overhead = jsonLog.Overhead / jsonDurationDivisor
 
 	logger.Debugf("JSON Parsed: %+v", jsonLog)
 	logger.Debugf("ClientHost: %s", jsonLog.ClientHost)
+	logger.Debugf("ClientIP: %s", jsonLog.clientIP())
 	logger.Debugf("StartUTC: %s", jsonLog.StartUTC)
 	logger.Debugf("RouterName: %s", jsonLog.RouterName)
 	logger.Debugf("RequestMethod: %s", jsonLog.RequestMethod)
@@ -96,38 +105,68 @@ func parseJSON(line string) (traefikLogConfig, error) {
 	return jsonLog, err
 }
 
-func isAccessLogLine(line string) bool {
-	if len(line) == 0 {
-		return false
-	}
-
+var (
 	// Look for common access log patterns
 	// Pattern 1: Starts with IP address (IPv4 or IPv6)
-	ipv4Pattern := `^\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}`
-	ipv6Pattern := `^[0-9a-fA-F:]+`
+	ipv4LineRegex = regexp.MustCompile(`^\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}`)
+	ipv6LineRegex = regexp.MustCompile(`^[0-9a-fA-F:]+`)
 
 	// Pattern 2: Starts with [pod-name] followed by IP address
-	podPattern := `^\[[^\]]+\]\s+\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}`
+	podLineRegex = regexp.MustCompile(`^\[[^\]]+\]\s+\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}`)
+
+	// Pattern 3: A timestamp in common log format
+	commonLogTimeRegex = regexp.MustCompile(`\[\d{2}/[A-Za-z]{3}/\d{4}:\d{2}:\d{2}:\d{2} [\+\-]\d{4}\]`)
+)
+
+// accessLogRegex matches a Traefik access log line in common log format
+var accessLogRegex = regexp.MustCompile(
+	`(\S+)` + // 1 - ClientHost
+		`\s-\s` + // - - Spaces
+		`(\S+)\s` + // 2 - ClientUsername
+		`\[([^]]+)\]\s` + // 3 - StartUTC
+		`"(\S*)\s?` + // 4 - RequestMethod
+		`((?:[^"\\]|\\.)*?)` + // 5 - RequestPath, may contain spaces and escaped quotes
+		`(?:\s(HTTP/[\d.]+))?"\s` + // 6 - RequestProtocol
+		`(\S+)\s` + // 7 - OriginStatus
+		`(\S+)\s` + // 8 - OriginContentSize
+		`("?\S+"?)\s` + // 9 - Referrer
+		`("[^"]*"|-)\s` + // 10 - User-Agent
+		`(\S+)\s` + // 11 - RequestCount
+		`("[^"]*"|-)\s` + // 12 - FrontendName
+		`("[^"]*"|-)\s` + // 13 - BackendURL
+		`(\S+)`, // 14 - Duration
+)
+
+// unescapeRequestPath reverts the backslash escaping of quotes and backslashes in a logged request line
+func unescapeRequestPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(path)
+}
+
+func isAccessLogLine(line string) bool {
+	if len(line) == 0 {
+		return false
+	}
 
 	// Check for IPv4 at start of line
-	if matched, _ := regexp.MatchString(ipv4Pattern, line); matched {
+	if ipv4LineRegex.MatchString(line) {
 		return true
 	}
 
 	// Check for IPv6 at start of line
-	if matched, _ := regexp.MatchString(ipv6Pattern, line); matched {
+	if ipv6LineRegex.MatchString(line) {
 		return true
 	}
 
 	// Check for pod name prefix with [pod-name] format
-	if matched, _ := regexp.MatchString(podPattern, line); matched {
+	if podLineRegex.MatchString(line) {
 		return true
 	}
 
 	// Additional check for common log patterns that might indicate an access log
-	// This catches lines that have a timestamp in common log format
-	commonLogPattern := `\[\d{2}/[A-Za-z]{3}/\d{4}:\d{2}:\d{2}:\d{2} [\+\-]\d{4}\]`
-	if matched, _ := regexp.MatchString(commonLogPattern, line); matched {
+	if commonLogTimeRegex.MatchString(line) {
 		return true
 	}
 
@@ -138,43 +177,24 @@ func parseLine(line string) (traefikLogConfig, error) {
 	// Skip empty lines
 	line = strings.TrimSpace(line)
 	if line == "" {
-		return traefikLogConfig{}, errors.New("empty line")
+		return traefikLogConfig{}, errEmptyLine
+	}
+
+	// Lines logged with a custom format are matched against it instead of common log format
+	if format := currentAccessLogFormat(); format != nil {
+		return format.parse(line)
 	}
 
 	// Quick check if this looks like an access log line
 	if !isAccessLogLine(line) {
 		logger.Debugf("Skipping non-access log line: %s", line)
-		return traefikLogConfig{}, errors.New("not an access log line")
-	}
-
-	var buffer bytes.Buffer
-	buffer.WriteString(`(\S+)`)                  // 1 - ClientHost
-	buffer.WriteString(`\s-\s`)                  // - - Spaces
-	buffer.WriteString(`(\S+)\s`)                // 2 - ClientUsername
-	buffer.WriteString(`\[([^]]+)\]\s`)          // 3 - StartUTC
-	buffer.WriteString(`"(\S*)\s?`)              // 4 - RequestMethod
-	buffer.WriteString(`((?:[^"]*(?:\\")?)*)\s`) // 5 - RequestPath
-	buffer.WriteString(`([^"]*)"\s`)             // 6 - RequestProtocol
-	buffer.WriteString(`(\S+)\s`)                // 7 - OriginStatus
-	buffer.WriteString(`(\S+)\s`)                // 8 - OriginContentSize
-	buffer.WriteString(`("?\S+"?)\s`)            // 9 - Referrer
-	buffer.WriteString(`("\S+")\s`)              // 10 - User-Agent
-	buffer.WriteString(`(\S+)\s`)                // 11 - RequestCount
-	buffer.WriteString(`("[^"]*"|-)\s`)          // 12 - FrontendName
-	buffer.WriteString(`("[^"]*"|-)\s`)          // 13 - BackendURL
-	buffer.WriteString(`(\S+)`)                  // 14 - Duration
-
-	regex, err := regexp.Compile(buffer.String())
-	if err != nil {
-		err = fmt.Errorf("failed to compile regex: %w", err)
-		logger.Error(err)
-		return traefikLogConfig{}, err
+		return traefikLogConfig{}, errNotAccessLog
 	}
 
-	submatch := regex.FindStringSubmatch(line)
+	submatch := accessLogRegex.FindStringSubmatch(line)
 	if len(submatch) <= 13 {
 		logger.Debugf("Line doesn't match access log format (matched %d parts): %s", len(submatch), line)
-		return traefikLogConfig{}, errors.New("invalid access log format")
+		return traefikLogConfig{}, errInvalidFormat
 	}
 
 	var log traefikLogConfig
@@ -184,7 +204,7 @@ func parseLine(line string) (traefikLogConfig, error) {
 	log.ClientHost = submatch[1]
 	log.StartUTC = submatch[3]
 	log.RequestMethod = submatch[4]
-	log.RequestPath = submatch[5]
+	log.RequestPath = unescapeRequestPath(submatch[5])
 	log.RequestProtocol = submatch[6]
 
 	// Parse status code
@@ -192,7 +212,7 @@ func parseLine(line string) (traefikLogConfig, error) {
 		log.OriginStatus = status
 	} else {
 		logger.Debugf("Invalid status code '%s' in line: %s", submatch[7], line)
-		parseErr = errors.New("invalid status code")
+		parseErr = errInvalidStatus
 	}
 
 	// Parse content size
@@ -200,7 +220,7 @@ func parseLine(line string) (traefikLogConfig, error) {
 		log.OriginContentSize = size
 	} else {
 		logger.Debugf("Invalid content size '%s' in line: %s", submatch[8], line)
-		parseErr = errors.New("invalid content size")
+		parseErr = withReason(errors.New("invalid content size"), errInvalidField)
 	}
 
 	// Parse request count
@@ -208,18 +228,20 @@ func parseLine(line string) (traefikLogConfig, error) {
 		log.RequestCount = count
 	} else {
 		logger.Debugf("Invalid request count '%s' in line: %s", submatch[11], line)
-		parseErr = errors.New("invalid request count")
+		parseErr = withReason(errors.New("invalid request count"), errInvalidField)
 	}
 
+	if userAgent := strings.Trim(submatch[10], "\""); userAgent != "-" {
+		log.UserAgent = userAgent
+	}
 	log.RouterName = strings.Trim(submatch[12], "\"")
 
 	// Parse duration
-	latencyStr := strings.Trim(submatch[14], "ms")
-	if duration, err := strconv.ParseFloat(latencyStr, 64); err == nil {
+	if duration, err := parseDurationMs(submatch[14]); err == nil {
 		log.Duration = duration
 	} else {
-		logger.Debugf("Invalid duration '%s' in line: %s", latencyStr, line)
-		parseErr = errors.New("invalid duration")
+		logger.Debugf("Invalid duration '%s' in line: %s", submatch[14], line)
+		parseErr = errInvalidDuration
 	}
 
 	//if logger.GetLevel() >= logger.DebugLevel {
@@ -229,10 +251,58 @@ func parseLine(line string) (traefikLogConfig, error) {
 	return log, parseErr
 }
 
-// Helper function to check if a string is in a slice
+// durationUnits are the unit suffixes of logged durations with their length in milliseconds. "ms"
+// comes before "s" so the longest suffix matches first.
+var durationUnits = []struct {
+	suffix string
+	scale  float64
+}{
+	{"ms", 1},
+	{"µs", 1e-3},
+	{"us", 1e-3},
+	{"ns", 1e-6},
+	{"s", 1e3},
+}
 
+// parseDurationMs parses a logged duration such as "150ms", "1.2s" or "900µs" into milliseconds,
+// like the nanoseconds of JSON logs are converted. A bare number is already in milliseconds.
+func parseDurationMs(value string) (float64, error) {
+	number, scale := value, 1.0
+	for _, unit := range durationUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			number, scale = strings.TrimSuffix(value, unit.suffix), unit.scale
+			break
+		}
+	}
+	duration, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration * scale, nil
+}
+
+// isNamespaceOnly reports whether an AllowedServices entry has a namespace and no name, so it allows
+// every router of the namespace
+func isNamespaceOnly(s TraefikService) bool {
+	return strings.TrimSpace(s.Name) == "" && strings.TrimSpace(s.Namespace) != ""
+}
+
+// routerInNamespace reports whether the namespace parsed from a router name is namespace
+func routerInNamespace(router, namespace string) bool {
+	parsed, _, _ := parseRouterName(router)
+	return parsed != "" && parsed == strings.TrimSpace(namespace)
+}
+
+// Helper function to check if a string is in a slice. Namespace-only entries match the routers
+// of their namespace.
 func contains(slice []TraefikService, item string) bool {
 	for _, s := range slice {
+		if isNamespaceOnly(s) {
+			if routerInNamespace(item, s.Namespace) {
+				return true
+			}
+			continue
+		}
 		name := BuildServiceName(s.Namespace, s.Name, "-")
 		if name == item {
 			return true
@@ -241,8 +311,16 @@ func contains(slice []TraefikService, item string) bool {
 	return false
 }
 
+// startsWith reports whether item starts with the service name of an entry. Namespace-only entries
+// match the routers of their namespace.
 func startsWith(slice []TraefikService, item string) bool {
 	for _, s := range slice {
+		if isNamespaceOnly(s) {
+			if routerInNamespace(item, s.Namespace) {
+				return true
+			}
+			continue
+		}
 		name := BuildServiceName(s.Namespace, s.Name, "-")
 		if strings.HasPrefix(item, name) {
 			return true
@@ -251,13 +329,16 @@ func startsWith(slice []TraefikService, item string) bool {
 	return false
 }
 
+// pathStat is the per-path summary used to rank the top paths of a service
+type pathStat struct {
+	service       string
+	path          string
+	avgLatency    float64
+	totalRequests int64
+}
+
 func updateTopPaths() {
 	logger.Debug("******** Updating top paths... ***********")
-	type pathStat struct {
-		service    string
-		path       string
-		avgLatency float64
-	}
 
 	// Group paths by service
 	servicePaths := make(map[string][]pathStat)
@@ -275,9 +356,10 @@ func updateTopPaths() {
 
 			// Add to service's path list
 			servicePaths[service] = append(servicePaths[service], pathStat{
-				service:    service,
-				path:       path,
-				avgLatency: stat.TotalDuration / float64(stat.TotalRequests),
+				service:       service,
+				path:          path,
+				avgLatency:    stat.MeanDuration,
+				totalRequests: stat.TotalRequests,
 			})
 		}
 	}
@@ -291,13 +373,20 @@ func updateTopPaths() {
 
 	// For each service, find its top N paths
 	for service, paths := range servicePaths {
-		// Sort paths by average latency (highest first)
+		// Sort paths by average latency (highest first). Ties are broken by request count
+		// (highest first) and then by path so the top N set doesn't flap between cycles.
 		sort.Slice(paths, func(i, j int) bool {
-			return paths[i].avgLatency > paths[j].avgLatency
+			if paths[i].avgLatency != paths[j].avgLatency {
+				return paths[i].avgLatency > paths[j].avgLatency
+			}
+			if paths[i].totalRequests != paths[j].totalRequests {
+				return paths[i].totalRequests > paths[j].totalRequests
+			}
+			return paths[i].path < paths[j].path
 		})
 
 		// Take top N paths for this service
-		limit := topNPaths
+		limit := topNLimitFor(service)
 		if limit > len(paths) {
 			limit = len(paths)
 		}
@@ -315,6 +404,35 @@ func updateTopPaths() {
 		logger.Debugf("Updated top paths. Service: %s, Total top paths: %d \n",
 			service, countTotalTopPaths(topPathsPerService))
 	}
+
+	applyRestoredTopPaths()
+	updateTopNMembership(servicePaths)
+}
+
+// inTopNSeries tracks the series of the endpoint_in_top_n gauge, guarded by topPathsMutex
+var inTopNSeries = make(map[[2]string]struct{})
+
+// updateTopNMembership sets the endpoint_in_top_n gauge for every tracked endpoint and deletes
+// the series of endpoints that are no longer tracked. Must be called with topPathsMutex held.
+func updateTopNMembership(servicePaths map[string][]pathStat) {
+	current := make(map[[2]string]struct{}, len(inTopNSeries))
+	for service, paths := range servicePaths {
+		for _, p := range paths {
+			value := 0.0
+			if topPathsPerService[service][fmt.Sprintf("%s:%s", service, p.path)] {
+				value = 1
+			}
+			endpointInTopN.WithLabelValues(service, p.path).Set(value)
+			current[[2]string{service, p.path}] = struct{}{}
+		}
+	}
+
+	for series := range inTopNSeries {
+		if _, ok := current[series]; !ok {
+			endpointInTopN.DeleteLabelValues(series[0], series[1])
+		}
+	}
+	inTopNSeries = current
 }
 
 // Helper function to count total top paths across all services
@@ -327,18 +445,32 @@ func countTotalTopPaths(tps map[string]map[string]bool) int {
 }
 
 func StartTopPathsUpdater(interval time.Duration) {
+	go runTopPathsUpdater(interval, nil)
+}
+
+// StartTopPathsUpdaterUntil is StartTopPathsUpdater for embedding processes; the updater stops
+// when stop is closed
+func StartTopPathsUpdaterUntil(interval time.Duration, stop <-chan struct{}) {
+	go runTopPathsUpdater(interval, stop)
+}
+
+// runTopPathsUpdater recomputes the top paths every interval and returns once stop is closed
+func runTopPathsUpdater(interval time.Duration, stop <-chan struct{}) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logger.Errorf("Recovered in startTopPathsUpdater: %v", r)
-			}
-		}()
-		for range ticker.C {
-			updateTopPaths()
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Recovered in startTopPathsUpdater: %v", r)
 		}
 	}()
+	for {
+		select {
+		case <-ticker.C:
+			updateTopPaths()
+		case <-stop:
+			return
+		}
+	}
 }
 
 func extractServiceName(routerName string) string {
@@ -447,14 +579,20 @@ func MapRouterNameToKubernetesService(routerName string, serviceNames []string)
 }
 
 // normalizeURL applies URL patterns to normalize endpoints
-func normalizeURL(serviceName, path string, urlPatterns []URLPattern) string {
-	// First, try service-specific patterns
+func normalizeURL(serviceName, path string, urlPatterns []URLPattern, normalization URLNormalization) string {
+	if normalization.DecodePath {
+		path = decodePath(path)
+	}
+	// Canonicalize slashes and case first, so the patterns and substitutions below see one form
+	path = normalizePathForm(path, normalization)
+
+	// First, try service-specific patterns. Patterns without a service, such as the ones of a
+	// UrlPerformance, which only apply to its targets anyway, match every service.
 	for _, pattern := range urlPatterns {
 		patternServiceName := BuildServiceName(pattern.Namespace, pattern.ServiceName, "-")
-		if patternServiceName == serviceName && pattern.Regex != nil {
+		if (patternServiceName == "" || patternServiceName == serviceName) && pattern.Regex != nil {
 			if pattern.Regex.MatchString(path) {
-				match := regexp.MustCompile(pattern.Regex.String())
-				return match.ReplaceAllString(path, pattern.Replacement)
+				return pattern.Regex.ReplaceAllString(path, pattern.Replacement)
 			}
 		}
 	}
@@ -462,23 +600,165 @@ func normalizeURL(serviceName, path string, urlPatterns []URLPattern) string {
 	// Default normalization - replace IDs and UUIDs
 	normalized := path
 
-	// Replace numeric IDs
-	re1 := regexp.MustCompile(`/\d+(/|$|\?)`)
-	normalized = re1.ReplaceAllString(normalized, "/{id}$1")
+	if normalization.StripMatrixParams {
+		normalized = matrixParamsRegex.ReplaceAllString(normalized, "")
+	}
+	if normalization.CollapseDottedTokens {
+		normalized = collapseDottedTokens(normalized, normalization.DottedTokenMinParts, normalization.DottedTokenMinLength)
+	}
 
-	// Replace UUIDs
-	re2 := regexp.MustCompile(`/[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}(/|$|\?)`)
-	normalized = re2.ReplaceAllString(normalized, "/{uuid}$1")
+	// Replace IDs, UUIDs, tokens and query params, plus any custom substitutions
+	for _, rule := range normalization.substitutions() {
+		if slices.Contains(normalization.skippedRules, rule.Name) {
+			continue
+		}
+		if rule.Name == NormalizationRuleQueryParams && len(normalization.KeepQueryParams) > 0 {
+			normalized = keepQueryParams(normalized, normalization.KeepQueryParams)
+			continue
+		}
+		normalized = rule.Regex.ReplaceAllString(normalized, rule.Replacement)
+	}
 
-	// Replace other common patterns (long alphanumeric strings)
-	re3 := regexp.MustCompile(`/[a-zA-Z0-9]{20,}(/|$|\?)`)
-	normalized = re3.ReplaceAllString(normalized, "/{token}$1")
+	return normalized
+}
 
-	// Replace query params
-	re4 := regexp.MustCompile(`\?.*`)
-	normalized = re4.ReplaceAllString(normalized, "?{query_params}")
+var (
+	// matrixParamsRegex matches matrix parameters such as ";jsessionid=ABC" up to the next segment or query
+	matrixParamsRegex = regexp.MustCompile(`;[^/?]*`)
+	// dottedTokenPartRegex matches a single part of a dotted hash-like segment
+	dottedTokenPartRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
 
-	return normalized
+// keepQueryParams replaces the query string of a path with its parameters listed in keep, in their
+// original order, followed by {other_params} if any other parameter was dropped. A query string
+// without any kept parameter becomes {query_params}.
+func keepQueryParams(path string, keep []string) string {
+	idx := strings.Index(path, "?")
+	if idx == -1 {
+		return path
+	}
+	path, query := path[:idx], path[idx+1:]
+
+	var kept []string
+	dropped := false
+	for _, param := range strings.Split(query, "&") {
+		if param == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if slices.Contains(keep, key) {
+			kept = append(kept, param)
+		} else {
+			dropped = true
+		}
+	}
+
+	if len(kept) == 0 {
+		return path + "?{query_params}"
+	}
+	if dropped {
+		kept = append(kept, "{other_params}")
+	}
+	return path + "?" + strings.Join(kept, "&")
+}
+
+// normalizePathForm strips trailing slashes from and lowercases the path portion of a request path
+// as configured, leaving the query string untouched. The root path "/" is kept.
+func normalizePathForm(path string, normalization URLNormalization) string {
+	if !normalization.StripTrailingSlash && !normalization.LowercasePath {
+		return path
+	}
+
+	query := ""
+	if idx := strings.Index(path, "?"); idx != -1 {
+		path, query = path[:idx], path[idx:]
+	}
+	if normalization.StripTrailingSlash && len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+		if path == "" {
+			path = "/"
+		}
+	}
+	if normalization.LowercasePath {
+		path = strings.ToLower(path)
+	}
+	return path + query
+}
+
+// decodePath percent-decodes the path portion of a request path, leaving the query string untouched.
+// Paths with invalid escapes are returned unchanged.
+func decodePath(path string) string {
+	query := ""
+	if idx := strings.Index(path, "?"); idx != -1 {
+		path, query = path[:idx], path[idx:]
+	}
+
+	decoded, err := url.PathUnescape(path)
+	if err != nil {
+		return path + query
+	}
+	return decoded + query
+}
+
+// collapseDottedTokens replaces path segments made of at least minParts dot-separated parts
+// and at least minLength characters with {token}, when one of the parts looks like a hash (e.g. JWTs
+// or content hashes), so dotted file names such as "bootstrap.bundle.min.js" are kept
+func collapseDottedTokens(path string, minParts, minLength int) string {
+	query := ""
+	if idx := strings.Index(path, "?"); idx != -1 {
+		path, query = path[:idx], path[idx:]
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if len(segment) < minLength {
+			continue
+		}
+		parts := strings.Split(segment, ".")
+		if len(parts) < minParts {
+			continue
+		}
+		isToken, hashLike := true, false
+		for _, part := range parts {
+			if !dottedTokenPartRegex.MatchString(part) {
+				isToken = false
+				break
+			}
+			hashLike = hashLike || isHashLikePart(part)
+		}
+		if isToken && hashLike {
+			segments[i] = "{token}"
+		}
+	}
+
+	return strings.Join(segments, "/") + query
+}
+
+// isHashLikePart reports whether a part of a dotted segment looks like a hash or an encoded value:
+// an alphanumeric part of at least 8 characters with at least two digits (e.g. "3f9a2b1c"), or a
+// part of at least 16 characters mixing upper case, lower case and digits (e.g. base64)
+func isHashLikePart(part string) bool {
+	var upper, lower, digits int
+	alphanumeric := true
+	for _, r := range part {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper++
+		case r >= 'a' && r <= 'z':
+			lower++
+		case r >= '0' && r <= '9':
+			digits++
+		default:
+			alphanumeric = false
+		}
+	}
+	if alphanumeric && len(part) >= 8 && digits >= 2 && upper+lower > 0 {
+		return true
+	}
+	return len(part) >= 16 && upper > 0 && lower > 0 && digits > 0
 }
 
 // homeDir returns the home directory for the current user
@@ -26,6 +26,28 @@ type RuntimeConfig struct {
 	CollectNTop    int
 	Enabled        bool
 	LastUpdated    time.Time
+
+	// DetailedHistogramRegex selects the paths observed into the detailed latency histogram
+	DetailedHistogramRegex []*regexp.Regexp
+	// DetailedHistogramBuckets are the detailed histogram bucket bounds in seconds, nil for the default
+	DetailedHistogramBuckets []float64
+
+	// StripTrailingSlash and LowercasePath override the log processor's URL normalization settings
+	// of the same name for the target, nil keeps the global setting
+	StripTrailingSlash *bool
+	LowercasePath      *bool
+	// DisabledNormalizationRules turns off the log processor's URL normalization substitutions of
+	// these names, e.g. "token", for the target
+	DisabledNormalizationRules []string
+	// KeepQueryParams overrides the query parameters the log processor keeps in normalized paths for
+	// the target, nil keeps the global setting
+	KeepQueryParams []string
+}
+
+// ConfigKey returns the key of the runtime configuration for a target. Kubernetes names can't
+// contain "/", so unlike a "-" join, distinct namespace/name pairs never produce the same key.
+func ConfigKey(namespace, name string) string {
+	return namespace + "/" + name
 }
 
 // ConfigManager interface for getting runtime configurations
@@ -34,3 +56,23 @@ type ConfigManager interface {
 	GetConfig(key string) (*RuntimeConfig, bool)
 	GetAllConfigs() []*RuntimeConfig
 }
+
+// TargetStats summarizes the traffic the log processor observed for a runtime configuration
+type TargetStats struct {
+	// MonitoredPaths is the number of unique normalized paths seen for the target
+	MonitoredPaths int
+	// LastSeen is when the last log line matching the target was processed
+	LastSeen time.Time
+}
+
+// TargetStatsProvider interface for reading per-target traffic stats
+// This allows the log processor to report back to the controller
+type TargetStatsProvider interface {
+	GetTargetStats(key string) (TargetStats, bool)
+}
+
+// TargetMetricsResetter interface for deleting the metrics the log processor exported for a target
+// This allows the controller to clean up after a deleted UrlPerformance
+type TargetMetricsResetter interface {
+	ResetTargetMetrics(key string)
+}
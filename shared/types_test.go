@@ -166,3 +166,28 @@ func (m *MockConfigManager) GetAllConfigs() []*RuntimeConfig {
 	}
 	return configs
 }
+
+func TestConfigKey(t *testing.T) {
+	tests := []struct {
+		namespace string
+		name      string
+		expected  string
+	}{
+		{namespace: "a-b", name: "c", expected: "a-b/c"},
+		{namespace: "a", name: "b-c", expected: "a/b-c"},
+		{namespace: "shop", name: "api", expected: "shop/api"},
+	}
+
+	seen := make(map[string]string)
+	for _, tt := range tests {
+		key := ConfigKey(tt.namespace, tt.name)
+		if key != tt.expected {
+			t.Errorf("ConfigKey(%q, %q) = %q, want %q", tt.namespace, tt.name, key, tt.expected)
+		}
+		pair := tt.namespace + " " + tt.name
+		if other, ok := seen[key]; ok {
+			t.Errorf("ConfigKey collision between %q and %q: %q", other, pair, key)
+		}
+		seen[key] = pair
+	}
+}